@@ -0,0 +1,532 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/client"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const usage = `Usage: botctl [flags] <command>
+
+Commands:
+  health                                  bot health check
+  limit list                              trade limits
+  limit create {file.json|-}              create trade limit
+  limit update {file.json|-}              update trade limit
+  limit enable {SYMBOL}                   enable trade limit
+  limit disable {SYMBOL}                  disable trade limit
+  limit delete {SYMBOL}                   delete trade limit without opened position
+  limit archive {SYMBOL}                  archive trade limit without opened position
+  limit export {json|csv}                 export trade limits
+  limit import {file.json|file.csv|-}     import trade limits, --dry-run only validates
+  position list                           opened positions
+  order pending                           pending exchange orders
+  order buy {SYMBOL} {PRICE}              manual buy order
+  order sell {SYMBOL} {PRICE}             manual sell order
+  order extra-charge {ID} {file.json|-}   update extra charge options of order
+  stack                                   trade stack
+  swap list                               available swap chains
+  swap actions [LIMIT]                    the latest swap actions (default 50)
+  swap action {ID}                        swap action legs with exchange statuses
+
+Flags (environment variables BOTCTL_HOST, BOTCTL_API_KEY, BOTCTL_API_SECRET, BOTCTL_BOT_UUID):
+`
+
+func main() {
+	flags := flag.NewFlagSet("botctl", flag.ExitOnError)
+	host := flags.String("host", getEnv("BOTCTL_HOST", "http://localhost:8090"), "bot API host")
+	apiKey := flags.String("api-key", os.Getenv("BOTCTL_API_KEY"), "API key")
+	apiSecret := flags.String("api-secret", os.Getenv("BOTCTL_API_SECRET"), "API secret")
+	botUuid := flags.String("bot-uuid", os.Getenv("BOTCTL_BOT_UUID"), "bot uuid, is used if API key is empty (read scope)")
+	output := flags.String("output", "table", "output format: table or json")
+	dryRun := flags.Bool("dry-run", false, "validate import without saving")
+	flags.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flags.PrintDefaults()
+	}
+	_ = flags.Parse(os.Args[1:])
+
+	if *output != "table" && *output != "json" {
+		fmt.Fprintf(os.Stderr, "Output '%s' is invalid\n", *output)
+		os.Exit(2)
+	}
+
+	ctl := botCtl{
+		Client: &client.BotApiClient{
+			Host:      *host,
+			ApiKey:    *apiKey,
+			ApiSecret: *apiSecret,
+			BotUuid:   *botUuid,
+		},
+		Json:   *output == "json",
+		DryRun: *dryRun,
+		Stdout: os.Stdout,
+		Stdin:  os.Stdin,
+	}
+
+	err := ctl.Run(flags.Args())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err.Error())
+		if errors.Is(err, errUsage) {
+			flags.Usage()
+			os.Exit(2)
+		}
+		os.Exit(1)
+	}
+}
+
+var errUsage = errors.New("Command is invalid")
+
+type botCtl struct {
+	Client *client.BotApiClient
+	Json   bool
+	DryRun bool
+	Stdout io.Writer
+	Stdin  io.Reader
+}
+
+func (b *botCtl) Run(args []string) error {
+	if len(args) == 0 {
+		return errUsage
+	}
+
+	command := strings.Join(args[0:min(2, len(args))], " ")
+
+	switch {
+	case args[0] == "health":
+		health, err := b.Client.GetHealth()
+		if err != nil {
+			return err
+		}
+
+		return b.print(health, []string{"BOT", "ML", "DB", "REDIS", "BINANCE", "CORES"}, [][]string{{
+			health.Bot.BotUuid,
+			health.MlStatus,
+			health.DbStatus,
+			health.RedisStatus,
+			health.BinanceStatus,
+			strconv.Itoa(health.Cores),
+		}})
+	case command == "limit list":
+		limits, err := b.Client.GetTradeLimits()
+		if err != nil {
+			return err
+		}
+
+		return b.printTradeLimits(limits...)
+	case command == "limit create" || command == "limit update":
+		if len(args) < 3 {
+			return errUsage
+		}
+
+		var limit model.TradeLimit
+		err := b.readJson(args[2], &limit)
+		if err != nil {
+			return err
+		}
+
+		if args[1] == "create" {
+			limit, err = b.Client.CreateTradeLimit(limit)
+		} else {
+			limit, err = b.Client.UpdateTradeLimit(limit)
+		}
+		if err != nil {
+			return err
+		}
+
+		return b.printTradeLimits(limit)
+	case command == "limit enable" || command == "limit disable":
+		if len(args) < 3 {
+			return errUsage
+		}
+
+		limit, err := b.Client.GetTradeLimit(strings.ToUpper(args[2]))
+		if err != nil {
+			return err
+		}
+
+		limit.IsEnabled = args[1] == "enable"
+		updated, err := b.Client.UpdateTradeLimit(*limit)
+		if err != nil {
+			return err
+		}
+
+		return b.printTradeLimits(updated)
+	case command == "limit delete" || command == "limit archive":
+		if len(args) < 3 {
+			return errUsage
+		}
+
+		symbol := strings.ToUpper(args[2])
+		var err error
+		if args[1] == "delete" {
+			err = b.Client.DeleteTradeLimit(symbol)
+		} else {
+			err = b.Client.ArchiveTradeLimit(symbol)
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = fmt.Fprintf(b.Stdout, "Trade limit %s: %sd\n", symbol, args[1])
+
+		return err
+	case command == "limit export":
+		format := "json"
+		if len(args) > 2 {
+			format = args[2]
+		}
+
+		content, err := b.Client.ExportTradeLimits(format)
+		if err != nil {
+			return err
+		}
+
+		_, err = b.Stdout.Write(content)
+
+		return err
+	case command == "limit import":
+		if len(args) < 3 {
+			return errUsage
+		}
+
+		content, err := b.readFile(args[2])
+		if err != nil {
+			return err
+		}
+
+		format := "json"
+		if strings.HasSuffix(args[2], ".csv") || (len(content) > 0 && content[0] != '[') {
+			format = "csv"
+		}
+
+		report, importErr := b.Client.ImportTradeLimits(content, format, b.DryRun)
+
+		rows := make([][]string, 0)
+		for _, symbol := range report.Created {
+			rows = append(rows, []string{symbol, "created", ""})
+		}
+		for _, symbol := range report.Updated {
+			rows = append(rows, []string{symbol, "updated", ""})
+		}
+		for _, violation := range report.Violations {
+			rows = append(rows, []string{violation.Symbol, "invalid", violation.Message})
+		}
+
+		err = b.print(report, []string{"SYMBOL", "RESULT", "MESSAGE"}, rows)
+		if importErr != nil {
+			return importErr
+		}
+
+		return err
+	case command == "position list":
+		positions, err := b.Client.GetPositions()
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0)
+		for _, position := range positions {
+			rows = append(rows, []string{
+				position.Symbol,
+				strconv.FormatInt(position.Order.Id, 10),
+				formatFloat(position.Order.Price),
+				formatFloat(position.KLine.Close),
+				formatFloat(position.SellPrice),
+				formatFloat(float64(position.Percent)),
+				formatFloat(position.Profit),
+				formatFloat(position.TargetProfit),
+			})
+		}
+
+		return b.print(positions, []string{"SYMBOL", "ORDER", "BUY PRICE", "PRICE", "SELL PRICE", "PERCENT", "PROFIT", "TARGET PROFIT"}, rows)
+	case command == "order pending":
+		orders, err := b.Client.GetPendingOrders()
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0)
+		for _, order := range orders {
+			rows = append(rows, []string{
+				order.Symbol,
+				order.BinanceOrder.Side,
+				order.BinanceOrder.Status,
+				formatFloat(order.BinanceOrder.Price),
+				formatFloat(order.BinanceOrder.OrigQty),
+				formatFloat(order.BinanceOrder.ExecutedQty),
+				formatFloat(order.KLine.Close),
+			})
+		}
+
+		return b.print(orders, []string{"SYMBOL", "SIDE", "STATUS", "PRICE", "QTY", "EXECUTED", "CURRENT PRICE"}, rows)
+	case command == "order buy" || command == "order sell":
+		if len(args) < 4 {
+			return errUsage
+		}
+
+		price, err := strconv.ParseFloat(args[3], 64)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Price '%s' is invalid", args[3]))
+		}
+
+		manual := model.ManualOrder{
+			Operation: strings.ToUpper(args[1]),
+			Symbol:    strings.ToUpper(args[2]),
+			Price:     price,
+		}
+		err = b.Client.PostManualOrder(manual)
+		if err != nil {
+			return err
+		}
+
+		return b.print(manual, []string{"SYMBOL", "OPERATION", "PRICE"}, [][]string{{
+			manual.Symbol,
+			manual.Operation,
+			formatFloat(manual.Price),
+		}})
+	case command == "order extra-charge":
+		if len(args) < 4 {
+			return errUsage
+		}
+
+		orderId, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Order id '%s' is invalid", args[2]))
+		}
+
+		options := model.UpdateOrderExtraChargeOptions{OrderId: orderId}
+		err = b.readJson(args[3], &options.ExtraChargeOptions)
+		if err != nil {
+			return err
+		}
+
+		order, err := b.Client.UpdateExtraCharge(options)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0)
+		for _, option := range order.ExtraChargeOptions {
+			rows = append(rows, []string{
+				strconv.FormatInt(order.Id, 10),
+				strconv.FormatInt(option.Index, 10),
+				formatFloat(float64(option.Percent)),
+				formatFloat(option.AmountUsdt),
+			})
+		}
+
+		return b.print(order, []string{"ORDER", "INDEX", "PERCENT", "AMOUNT USDT"}, rows)
+	case args[0] == "stack":
+		stack, err := b.Client.GetTradeStack()
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0)
+		for _, item := range stack {
+			rows = append(rows, []string{
+				strconv.FormatInt(item.Index, 10),
+				item.Symbol,
+				formatFloat(item.Price),
+				formatFloat(float64(item.Percent)),
+				formatFloat(item.BudgetUsdt),
+				strconv.FormatBool(item.HasEnoughBalance),
+				strconv.FormatBool(item.IsBuyLocked),
+			})
+		}
+
+		return b.print(stack, []string{"INDEX", "SYMBOL", "PRICE", "PERCENT", "BUDGET USDT", "ENOUGH BALANCE", "BUY LOCKED"}, rows)
+	case command == "swap list":
+		chains, err := b.Client.GetSwapChains()
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0)
+		for _, chain := range chains {
+			rows = append(rows, []string{
+				strconv.FormatInt(chain.Id, 10),
+				chain.Title,
+				chain.Type,
+				formatFloat(float64(chain.Percent)),
+				formatFloat(float64(chain.MaxPercent)),
+			})
+		}
+
+		return b.print(chains, []string{"ID", "TITLE", "TYPE", "PERCENT", "MAX PERCENT"}, rows)
+	case command == "swap actions":
+		limit := int64(50)
+		if len(args) > 2 {
+			parsed, err := strconv.ParseInt(args[2], 10, 64)
+			if err != nil || parsed <= 0 {
+				return errors.New(fmt.Sprintf("Limit '%s' is invalid", args[2]))
+			}
+			limit = parsed
+		}
+
+		actions, err := b.Client.GetSwapActions(limit)
+		if err != nil {
+			return err
+		}
+
+		rows := make([][]string, 0)
+		for _, action := range actions {
+			rows = append(rows, []string{
+				strconv.FormatInt(action.Id, 10),
+				strconv.FormatInt(action.OrderId, 10),
+				action.Asset,
+				action.Status,
+				strings.Join([]string{action.SwapOneSymbol, action.SwapTwoSymbol, action.SwapThreeSymbol}, " > "),
+				strings.Join([]string{formatStatus(action.SwapOneExternalStatus), formatStatus(action.SwapTwoExternalStatus), formatStatus(action.SwapThreeExternalStatus)}, " > "),
+				formatFloat(action.StartQuantity),
+				formatOptionalFloat(action.EndQuantity),
+			})
+		}
+
+		return b.print(actions, []string{"ID", "ORDER", "ASSET", "STATUS", "LEGS", "LEG STATUSES", "START QTY", "END QTY"}, rows)
+	case command == "swap action":
+		if len(args) < 3 {
+			return errUsage
+		}
+
+		id, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return errors.New(fmt.Sprintf("Swap action id '%s' is invalid", args[2]))
+		}
+
+		action, err := b.Client.GetSwapAction(id)
+		if err != nil {
+			return err
+		}
+
+		legs := []struct {
+			symbol     string
+			price      float64
+			externalId *int64
+			status     *string
+			timestamp  *int64
+		}{
+			{action.SwapOneSymbol, action.SwapOnePrice, action.SwapOneExternalId, action.SwapOneExternalStatus, action.SwapOneTimestamp},
+			{action.SwapTwoSymbol, action.SwapTwoPrice, action.SwapTwoExternalId, action.SwapTwoExternalStatus, action.SwapTwoTimestamp},
+			{action.SwapThreeSymbol, action.SwapThreePrice, action.SwapThreeExternalId, action.SwapThreeExternalStatus, action.SwapThreeTimestamp},
+		}
+		rows := make([][]string, 0)
+		for index, leg := range legs {
+			rows = append(rows, []string{
+				strconv.FormatInt(action.Id, 10),
+				action.Status,
+				strconv.Itoa(index + 1),
+				leg.symbol,
+				formatFloat(leg.price),
+				formatOptionalInt(leg.externalId),
+				formatStatus(leg.status),
+				formatOptionalInt(leg.timestamp),
+			})
+		}
+
+		return b.print(action, []string{"ID", "STATUS", "LEG", "SYMBOL", "PRICE", "EXTERNAL ID", "LEG STATUS", "TIMESTAMP"}, rows)
+	}
+
+	return errUsage
+}
+
+func (b *botCtl) printTradeLimits(limits ...model.TradeLimit) error {
+	rows := make([][]string, 0)
+	for _, limit := range limits {
+		rows = append(rows, []string{
+			strconv.FormatInt(limit.Id, 10),
+			limit.Symbol,
+			strconv.FormatBool(limit.IsEnabled),
+			formatFloat(limit.USDTLimit),
+			formatFloat(limit.MinProfitPercent),
+			strconv.Itoa(len(limit.ExtraChargeOptions)),
+		})
+	}
+
+	return b.print(limits, []string{"ID", "SYMBOL", "ENABLED", "USDT LIMIT", "MIN PROFIT %", "EXTRA CHARGES"}, rows)
+}
+
+func (b *botCtl) print(value any, headers []string, rows [][]string) error {
+	if b.Json {
+		encoded, err := json.MarshalIndent(value, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(b.Stdout, string(encoded))
+
+		return err
+	}
+
+	writer := tabwriter.NewWriter(b.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, strings.Join(headers, "\t"))
+	for _, row := range rows {
+		fmt.Fprintln(writer, strings.Join(row, "\t"))
+	}
+
+	return writer.Flush()
+}
+
+// readFile reads file, "-" means stdin
+func (b *botCtl) readFile(path string) ([]byte, error) {
+	if path == "-" {
+		return io.ReadAll(b.Stdin)
+	}
+
+	return os.ReadFile(path)
+}
+
+func (b *botCtl) readJson(path string, value any) error {
+	content, err := b.readFile(path)
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(content, value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
+
+func formatOptionalFloat(value *float64) string {
+	if value == nil {
+		return "-"
+	}
+
+	return formatFloat(*value)
+}
+
+func formatOptionalInt(value *int64) string {
+	if value == nil {
+		return "-"
+	}
+
+	return strconv.FormatInt(*value, 10)
+}
+
+// formatStatus shows leg which is not placed yet as "-"
+func formatStatus(status *string) string {
+	if status == nil {
+		return "-"
+	}
+
+	return *status
+}
+
+func getEnv(name string, defaultValue string) string {
+	value := os.Getenv(name)
+	if value == "" {
+		return defaultValue
+	}
+
+	return value
+}
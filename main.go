@@ -1,43 +1,124 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	_ "github.com/go-sql-driver/mysql"
-	"github.com/gorilla/websocket"
 	"github.com/joho/godotenv"
 	"gitlab.com/open-soft/go-crypto-bot/src/client"
 	"gitlab.com/open-soft/go-crypto-bot/src/config"
+	"gitlab.com/open-soft/go-crypto-bot/src/event"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
 	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
 	"log"
 	"os"
+	"os/signal"
 	"slices"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 )
 
-func getStreamBatch(tradeLimits []model.SymbolInterface, events []string) [][]string {
-	streamBatch := make([][]string, 0)
+// sleepContext waits given duration, returns earlier if ctx is cancelled
+func sleepContext(ctx context.Context, duration time.Duration) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(duration):
+	}
+}
 
-	streams := make([]string, 0)
+// connectBot connects websocket API, checks API key and loads opened Binance orders of bot (account) trade limits
+func connectBot(bot *config.Container, wsDsn string) bool {
+	bot.Binance.Connect(wsDsn) // "wss://testnet.binance.vision/ws-api/v3"
 
-	for _, tradeLimit := range tradeLimits {
-		for i := 0; i < len(events); i++ {
-			event := events[i]
-			streams = append(streams, fmt.Sprintf("%s%s", strings.ToLower(tradeLimit.GetSymbol()), event))
-		}
+	usdtBalance, err := bot.BalanceService.GetAssetBalance("USDT", false)
+	if err != nil {
+		log.Printf("[%s] Balance check error: %s", bot.CurrentBot.BotUuid, err.Error())
 
-		if len(streams) >= 24 {
-			streamBatch = append(streamBatch, streams)
-			streams = make([]string, 0)
+		if err.Error() == model.BinanceErrorInvalidAPIKeyOrPermissions {
+			log.Println("Notify SaaS system about error")
+			bot.CallbackManager.Error(
+				*bot.CurrentBot,
+				model.BinanceErrorInvalidAPIKeyOrPermissions,
+				"Please check API Key permissions or IP address binding",
+				true,
+			)
+
+			return false
 		}
 	}
+	log.Printf("[%s] API Key permission check passed, balance is: %.2f", bot.CurrentBot.BotUuid, usdtBalance)
+	bot.Binance.APIKeyCheckCompleted = true
 
-	if len(streams) > 0 {
-		streamBatch = append(streamBatch, streams)
+	symbols := make([]string, 0)
+	for _, limit := range bot.ExchangeRepository.GetTradeLimits() {
+		symbols = append(symbols, limit.Symbol)
 	}
 
-	return streamBatch
+	binanceOrders, err := bot.Binance.GetOpenedOrders()
+	if err == nil {
+		for _, binanceOrder := range binanceOrders {
+			if !slices.Contains(symbols, binanceOrder.Symbol) {
+				log.Printf("[%s] binance order %d skipped", binanceOrder.Symbol, binanceOrder.OrderId)
+
+				continue
+			}
+
+			log.Printf("[%s] loaded binance order %d", binanceOrder.Symbol, binanceOrder.OrderId)
+			bot.OrderRepository.SetBinanceOrder(binanceOrder)
+		}
+	}
+
+	return true
+}
+
+// startBotWorkers starts trade limit updates, reconciliation and position push of bot (account)
+func startBotWorkers(ctx context.Context, bot *config.Container, botConfig model.Config) {
+	go bot.NotifierRouter.Run(ctx, time.Second*time.Duration(botConfig.Notifier.QueueSeconds))
+	go bot.DecisionJournal.Run(ctx)
+	go bot.GridService.Run(ctx, time.Second*time.Duration(botConfig.Schedule.GridSeconds))
+	go bot.DcaService.Run(ctx, time.Minute*time.Duration(botConfig.Schedule.DcaMinutes))
+
+	go func() {
+		for ctx.Err() == nil {
+			bot.MakerService.UpdateLimits()
+			time.Sleep(time.Minute * time.Duration(botConfig.Schedule.UpdateLimitsMinutes))
+		}
+	}()
+
+	go func() {
+		for ctx.Err() == nil {
+			report := bot.OrderReconciler.Reconcile()
+			log.Printf(
+				"[%s] Reconciliation finished, symbols: %d, skipped: %d, discrepancies: %d",
+				bot.CurrentBot.BotUuid,
+				report.Symbols,
+				len(report.Skipped),
+				len(report.Discrepancies),
+			)
+			time.Sleep(time.Minute * time.Duration(botConfig.Schedule.ReconciliationMinutes))
+		}
+	}()
+
+	go func() {
+		// positions are pushed only when changed and only if there is websocket subscriber
+		pushed := make(map[string]string)
+		for ctx.Err() == nil {
+			if bot.EventHub.HasSubscribers(event.TopicPosition) {
+				for _, position := range bot.PositionService.GetPositions() {
+					encoded, _ := json.Marshal(position)
+					if pushed[position.Symbol] != string(encoded) {
+						pushed[position.Symbol] = string(encoded)
+						bot.EventHub.Publish(event.TopicPosition, position.Symbol, position)
+					}
+				}
+			}
+			time.Sleep(time.Millisecond * time.Duration(botConfig.Schedule.PositionPushMilliseconds))
+		}
+	}()
 }
 
 func main() {
@@ -50,91 +131,98 @@ func main() {
 		}
 	}
 
-	container := config.InitServiceContainer()
-	defer container.Db.Close()
-	defer container.DbSwap.Close()
-	container.PythonMLBridge.Initialize()
-	defer container.PythonMLBridge.Finalize()
-	container.StartHttpServer()
-	log.Printf("Bot [%s] is initialized successfully", container.CurrentBot.BotUuid)
+	botConfig, err := config.LoadConfig(config.GetConfigPath())
 
-	container.Binance.Connect(os.Getenv("BINANCE_WS_DSN")) // "wss://testnet.binance.vision/ws-api/v3"
+	// usage: ./main config validate
+	if len(os.Args) > 2 && os.Args[1] == "config" && os.Args[2] == "validate" {
+		if err != nil {
+			log.Println(err)
+			os.Exit(1)
+		}
+
+		log.Printf("Config %s is valid", config.GetConfigPath())
+		os.Exit(0)
+	}
 
-	usdtBalance, err := container.BalanceService.GetAssetBalance("USDT", false)
 	if err != nil {
-		log.Printf("Balance check error: %s", err.Error())
+		log.Fatal(err)
+	}
 
-		if err.Error() == model.BinanceErrorInvalidAPIKeyOrPermissions {
-			log.Println("Notify SaaS system about error")
-			container.CallbackManager.Error(
-				*container.CurrentBot,
-				model.BinanceErrorInvalidAPIKeyOrPermissions,
-				"Please check API Key permissions or IP address binding",
-				true,
-			)
-			os.Exit(0)
+	// SIGTERM stops new decisions, in-flight orders are drained before exit
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	streamCtx, closeStreams := context.WithCancel(context.Background())
+	defer closeStreams()
+
+	container := config.InitServiceContainer(botConfig)
+
+	// usage: ./main api-client create {name} {read|trade|admin}
+	if len(os.Args) > 4 && os.Args[1] == "api-client" && os.Args[2] == "create" {
+		apiClient, err := service.GenerateApiClient(os.Args[3], os.Args[4])
+		if err != nil {
+			log.Fatal(err)
+		}
+		_, err = container.ApiClientRepository.Create(apiClient)
+		if err != nil {
+			log.Fatal(err)
 		}
+
+		log.Printf("API client [%s] is created, scope: %s", apiClient.Name, apiClient.Scope)
+		fmt.Printf("API Key: %s\nAPI Secret: %s\n", apiClient.ApiKey, apiClient.ApiSecret)
+		os.Exit(0)
 	}
-	log.Printf("API Key permission check passed, balance is: %.2f", usdtBalance)
-	container.Binance.APIKeyCheckCompleted = true
 
-	tradeLimits := container.ExchangeRepository.GetTradeLimits()
-	symbols := make([]string, 0)
-	for _, limit := range tradeLimits {
-		symbols = append(symbols, limit.Symbol)
+	// usage: ./main account create {binanceApiKey} {binanceApiSecret}
+	if len(os.Args) > 4 && os.Args[1] == "account" && os.Args[2] == "create" {
+		account, err := container.AccountService.Create(os.Args[3], os.Args[4])
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		log.Printf("Account is created, it is started with bot.multiAccount enabled")
+		fmt.Printf("Bot UUID: %s\n", account.BotUuid)
+		os.Exit(0)
 	}
 
-	binanceOrders, err := container.Binance.GetOpenedOrders()
-	if err == nil {
-		for _, binanceOrder := range binanceOrders {
-			if !slices.Contains(symbols, binanceOrder.Symbol) {
-				log.Printf("[%s] binance order %d skipped", binanceOrder.Symbol, binanceOrder.OrderId)
+	if botConfig.Bot.MultiAccount {
+		container.InitAccounts()
+	}
+	container.PythonMLBridge.Initialize()
+	defer container.PythonMLBridge.Finalize()
+	container.StartHttpServer()
+	log.Printf("Bot [%s] is initialized successfully", container.CurrentBot.BotUuid)
 
-				continue
-			}
+	if !connectBot(&container, botConfig.Binance.WsDsn) {
+		os.Exit(0)
+	}
 
-			log.Printf("[%s] loaded binance order %d", binanceOrder.Symbol, binanceOrder.OrderId)
-			container.OrderRepository.SetBinanceOrder(binanceOrder)
+	// accounts with invalid API key are not traded
+	bots := []*config.Container{&container}
+	for _, account := range container.Accounts {
+		if connectBot(account, botConfig.Binance.WsDsn) {
+			bots = append(bots, account)
 		}
 	}
 
-	// Wait 5 seconds, here API can update some settings...
-	time.Sleep(time.Second * 5)
 	eventChannel := make(chan []byte)
 	depthChannel := make(chan model.Depth)
-	runChannel := make(chan string)
+
+	for _, bot := range bots {
+		startBotWorkers(ctx, bot, botConfig)
+	}
 
 	go func(container *config.Container) {
-		for {
-			container.MakerService.UpdateLimits()
-			time.Sleep(time.Minute * 5)
+		for ctx.Err() == nil {
+			start := time.Now()
+			if container.Db.PingContext(ctx) == nil {
+				metrics.ObserveSince(metrics.MySQLPingSeconds, start)
+			}
+			time.Sleep(time.Second * 15)
 		}
 	}(&container)
 
-	if container.IsMasterBot {
-		container.MakerService.UpdateSwapPairs()
-	}
-
 	if container.IsMasterBot {
 		swapKlineChannel := make(chan []byte)
-		defer close(swapKlineChannel)
-
-		go func(container *config.Container) {
-			for {
-				baseAssets := make([]string, 0)
-				for _, pair := range container.ExchangeRepository.GetSwapPairs() {
-					if !slices.Contains(baseAssets, pair.BaseAsset) {
-						baseAssets = append(baseAssets, pair.BaseAsset)
-					}
-				}
-
-				for _, baseAsset := range baseAssets {
-					container.SwapManager.CalculateSwapOptions(baseAsset)
-				}
-
-				time.Sleep(time.Millisecond * 250)
-			}
-		}(&container)
 
 		// existing swaps real time monitoring
 		go func(container *config.Container) {
@@ -177,52 +265,63 @@ func main() {
 			}
 		}(&container)
 
-		swapWebsockets := make([]*websocket.Conn, 0)
-
-		swapPairCollection := make([]model.SymbolInterface, 0)
-		for _, swapPair := range container.ExchangeRepository.GetSwapPairs() {
-			swapPairCollection = append(swapPairCollection, swapPair)
-		}
-
-		for index, streamBatchItem := range getStreamBatch(swapPairCollection, []string{"@kline_1m", "@depth20@1000ms"}) {
-			swapWebsockets = append(swapWebsockets, client.Listen(fmt.Sprintf(
-				"%s/stream?streams=%s",
-				"wss://stream.binance.com:9443",
-				strings.Join(streamBatchItem, "/"),
-			), swapKlineChannel, []string{}, 10000+int64(index)))
+		// swap pairs are subscribed and unsubscribed without restart
+		swapSubscriptionManager := &service.SubscriptionManager{
+			Name:        "swap",
+			Events:      []string{"@kline_1m", "@depth20@1000ms"},
+			MaxStreams:  24,
+			BatchOffset: 10000,
+			SymbolProvider: func() []service.StreamSymbol {
+				symbols := make([]service.StreamSymbol, 0)
+				for _, swapPair := range container.ExchangeRepository.GetSwapPairs() {
+					symbols = append(symbols, service.StreamSymbol{Symbol: swapPair.Symbol})
+				}
 
-			log.Printf("Swap batch %d websocket: %s", index, strings.Join(streamBatchItem, ", "))
+				return symbols
+			},
+			Connect: func(batch int64) client.StreamConnectionInterface {
+				connection := &client.StreamConnection{
+					Address: fmt.Sprintf("%s/stream", botConfig.Binance.SwapStreamDsn),
+					Channel: swapKlineChannel,
+					Batch:   fmt.Sprintf("%d", batch),
+				}
+				connection.Start(streamCtx)
 
-			defer swapWebsockets[index].Close()
+				return connection
+			},
+		}
+		for _, bot := range bots {
+			bot.HealthService.StreamProviders = append(bot.HealthService.StreamProviders, swapSubscriptionManager)
 		}
-	}
-
-	for _, limit := range tradeLimits {
-		go func(limit model.TradeLimit, container *config.Container) {
-			for {
-				// todo: write to database and read from database
-				err := container.PythonMLBridge.LearnModel(limit.Symbol)
-				if err != nil {
-					log.Printf("[%s] %s", limit.Symbol, err.Error())
-					container.TimeService.WaitSeconds(60)
-					continue
-				}
 
-				container.TimeService.WaitSeconds(3600 * 6)
-			}
-		}(limit, &container)
-		// learn every 1000 minutes
+		// one of master bots is elected to update swap pairs, calculate swap options and consume swap streams
+		go container.LeaderElection.Run(streamCtx, func(leaderCtx context.Context) {
+			container.MakerService.UpdateSwapPairs()
+
+			// term is finished when swap options are not calculated anymore
+			calculated := make(chan struct{})
+			go func(container *config.Container) {
+				defer close(calculated)
+				for leaderCtx.Err() == nil {
+					baseAssets := make([]string, 0)
+					for _, pair := range container.ExchangeRepository.GetSwapPairs() {
+						if !slices.Contains(baseAssets, pair.BaseAsset) {
+							baseAssets = append(baseAssets, pair.BaseAsset)
+						}
+					}
 
-		go func(symbol string, container *config.Container) {
-			for {
-				currentDecisions := container.ExchangeRepository.GetDecisions(symbol)
+					for _, baseAsset := range baseAssets {
+						container.SwapManager.CalculateSwapOptions(baseAsset)
+					}
 
-				if len(currentDecisions) > 0 {
-					container.MakerService.Make(symbol, currentDecisions)
+					sleepContext(leaderCtx, time.Millisecond*250)
 				}
-				time.Sleep(time.Millisecond * 500)
-			}
-		}(limit.Symbol, &container)
+			}(&container)
+
+			swapSubscriptionManager.Watch(leaderCtx, time.Second*time.Duration(botConfig.Schedule.SubscriptionSeconds))
+			swapSubscriptionManager.Close()
+			<-calculated
+		})
 	}
 
 	predictChannel := make(chan string)
@@ -279,8 +378,11 @@ func main() {
 			case strings.Contains(string(message), "aggTrade"):
 				var tradeEvent model.TradeEvent
 				json.Unmarshal(message, &tradeEvent)
+				// market decisions are calculated once and shared by accounts
 				smaDecision := container.SmaTradeStrategy.Decide(tradeEvent.Trade)
-				container.ExchangeRepository.SetDecision(smaDecision, tradeEvent.Trade.Symbol)
+				for _, bot := range bots {
+					bot.ExchangeRepository.SetDecision(smaDecision, tradeEvent.Trade.Symbol)
+				}
 
 				go func(channel chan string, symbol string) {
 					predictChannel <- symbol
@@ -298,9 +400,12 @@ func main() {
 				}(predictChannel, kLine.Symbol)
 
 				baseKLineDecision := container.BaseKLineStrategy.Decide(kLine)
-				container.ExchangeRepository.SetDecision(baseKLineDecision, kLine.Symbol)
-				orderBasedDecision := container.OrderBasedStrategy.Decide(kLine)
-				container.ExchangeRepository.SetDecision(orderBasedDecision, kLine.Symbol)
+				for _, bot := range bots {
+					bot.ExchangeRepository.SetDecision(baseKLineDecision, kLine.Symbol)
+					// order based decision depends on account orders
+					orderBasedDecision := bot.OrderBasedStrategy.Decide(kLine)
+					bot.ExchangeRepository.SetDecision(orderBasedDecision, kLine.Symbol)
+				}
 
 				break
 			case strings.Contains(string(message), "depth20"):
@@ -309,7 +414,9 @@ func main() {
 
 				depth := event.Depth.ToDepth(strings.ToUpper(strings.ReplaceAll(event.Stream, "@depth20@100ms", "")))
 				depthDecision := container.MarketDepthStrategy.Decide(depth)
-				container.ExchangeRepository.SetDecision(depthDecision, depth.Symbol)
+				for _, bot := range bots {
+					bot.ExchangeRepository.SetDecision(depthDecision, depth.Symbol)
+				}
 				go func() {
 					depthChannel <- depth
 				}()
@@ -325,53 +432,100 @@ func main() {
 		}
 	}(&container)
 
-	websockets := make([]*websocket.Conn, 0)
-
-	tradeLimitCollection := make([]model.SymbolInterface, 0)
-	hasBtcUsdt := false
-	hasEthUsdt := false
-	for _, limit := range tradeLimits {
-		tradeLimitCollection = append(tradeLimitCollection, limit)
+	// trade limit symbols of each bot (account), they are refreshed by subscription sync
+	botSymbols := sync.Map{}
+
+	// trade limits are subscribed and unsubscribed without restart, BTCUSDT and ETHUSDT are used for price interpolation
+	tradeSubscriptionManager := &service.SubscriptionManager{
+		Name:       "trade",
+		Events:     []string{"@aggTrade", "@kline_1m@2000ms", "@depth20@100ms"},
+		MaxStreams: 24,
+		SymbolProvider: func() []service.StreamSymbol {
+			symbols := []service.StreamSymbol{{Symbol: "BTCUSDT"}, {Symbol: "ETHUSDT"}}
+			for _, bot := range bots {
+				limitSymbols := make([]string, 0)
+				for _, limit := range bot.ExchangeRepository.GetTradeLimits() {
+					limitSymbols = append(limitSymbols, limit.Symbol)
+					symbols = append(symbols, service.StreamSymbol{Symbol: limit.Symbol, HasWorker: true})
+				}
+				botSymbols.Store(bot.CurrentBot.BotUuid, limitSymbols)
+			}
 
-		go func(tradeLimit model.TradeLimit) {
+			return symbols
+		},
+		StartWorker: func(workerCtx context.Context, symbol string) {
 			klineAmount := 0
+			for _, kline := range container.Binance.GetKLines(symbol, "1m", 200) {
+				klineAmount++
+				container.ExchangeRepository.AddKLine(kline.ToKLine(symbol))
+			}
+			log.Printf("Loaded history %s -> %d klines", symbol, klineAmount)
+
+			// worker is stopped when model learning is stopped, symbol can be started again after it
+			learned := make(chan struct{})
+			go func() {
+				defer close(learned)
+				for workerCtx.Err() == nil {
+					// todo: write to database and read from database
+					err := container.PythonMLBridge.LearnModel(symbol)
+					if err != nil {
+						log.Printf("[%s] %s", symbol, err.Error())
+						sleepContext(workerCtx, time.Second*60)
+						continue
+					}
 
-			history := container.Binance.GetKLines(tradeLimit.GetSymbol(), "1m", 200)
+					sleepContext(workerCtx, time.Hour*time.Duration(botConfig.Schedule.LearnHours))
+				}
+			}()
 
-			for _, kline := range history {
-				klineAmount++
-				container.ExchangeRepository.AddKLine(kline.ToKLine(tradeLimit.GetSymbol()))
+			for workerCtx.Err() == nil {
+				for _, bot := range bots {
+					limitSymbols, ok := botSymbols.Load(bot.CurrentBot.BotUuid)
+					if !ok || !slices.Contains(limitSymbols.([]string), symbol) {
+						continue
+					}
+
+					currentDecisions := bot.ExchangeRepository.GetDecisions(symbol)
+
+					if len(currentDecisions) > 0 {
+						bot.MakerService.Make(symbol, currentDecisions)
+					}
+				}
+				sleepContext(workerCtx, time.Millisecond*time.Duration(botConfig.Schedule.DecisionMilliseconds))
 			}
-			log.Printf("Loaded history %s -> %d klines", tradeLimit.Symbol, klineAmount)
-		}(limit)
-		if "BTCUSDT" == limit.GetSymbol() {
-			hasBtcUsdt = true
-		}
-		if "ETHUSDT" == limit.GetSymbol() {
-			hasEthUsdt = true
-		}
-	}
+			<-learned
+		},
+		Connect: func(batch int64) client.StreamConnectionInterface {
+			connection := &client.StreamConnection{
+				Address: fmt.Sprintf("%s/stream", botConfig.Binance.StreamDsn),
+				Channel: eventChannel,
+				Batch:   fmt.Sprintf("%d", batch),
+			}
+			connection.Start(streamCtx)
 
-	if !hasBtcUsdt {
-		tradeLimitCollection = append(tradeLimitCollection, model.DummySymbol{Symbol: "BTCUSDT"})
+			return connection
+		},
 	}
-	if !hasEthUsdt {
-		tradeLimitCollection = append(tradeLimitCollection, model.DummySymbol{Symbol: "ETHUSDT"})
+	for _, bot := range bots {
+		bot.HealthService.StreamProviders = append(bot.HealthService.StreamProviders, tradeSubscriptionManager)
 	}
+	go tradeSubscriptionManager.Watch(ctx, time.Second*time.Duration(botConfig.Schedule.SubscriptionSeconds))
 
-	for index, streamBatchItem := range getStreamBatch(tradeLimitCollection, []string{"@aggTrade", "@kline_1m@2000ms", "@depth20@100ms"}) {
-		websockets = append(websockets, client.Listen(fmt.Sprintf(
-			"%s/stream?streams=%s",
-			os.Getenv("BINANCE_STREAM_DSN"),
-			strings.Join(streamBatchItem, "/"),
-		), eventChannel, []string{}, int64(index)))
-
-		log.Printf("Batch %d websocket: %s", index, strings.Join(streamBatchItem, ", "))
+	<-ctx.Done()
+	log.Printf("Bot [%s] is shutting down...", container.CurrentBot.BotUuid)
+	for _, bot := range bots {
+		bot.ShutdownService.Shutdown()
+	}
 
-		defer websockets[index].Close()
+	// market data is still consumed while orders are finished or parked
+	deadline := time.Now().Add(time.Second * time.Duration(botConfig.Shutdown.Timeout))
+	for _, bot := range bots {
+		if !bot.ShutdownService.Wait(time.Until(deadline)) {
+			log.Printf("[%s] Shutdown timeout reached, %d operations are not finished", bot.CurrentBot.BotUuid, bot.ShutdownService.GetOperations())
+		}
 	}
 
-	// just to keep running
-	runChannel <- "run"
-	log.Panic("Stopped")
+	closeStreams()
+	container.Shutdown(time.Second * 5)
+	log.Printf("Bot [%s] is stopped", container.CurrentBot.BotUuid)
 }
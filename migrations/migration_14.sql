@@ -0,0 +1,20 @@
+create table `order_event`
+(
+    id            int auto_increment primary key,
+    bot_id        int unsigned                                not null,
+    order_id      int                                         default null,
+    symbol        CHAR(20)                                    not null,
+    external_id   bigint                                      default null,
+    operation     CHAR(4)                                     not null,
+    type          CHAR(32)                                    not null,
+    reason        CHAR(32)                                    default null,
+    price         double                                      not null,
+    current_price double                                      default null,
+    quantity      double                                      not null,
+    executed_qty  double                                      not null default 0,
+    details       varchar(1024)                               default null,
+    created_at    datetime                                    not null,
+    constraint order_event_bot_fk foreign key (bot_id) references `bots` (id)
+);
+create index order_event_order_idx on order_event (order_id);
+create index order_event_external_idx on order_event (symbol, external_id);
@@ -0,0 +1,30 @@
+create table `api_client`
+(
+    id           int auto_increment primary key,
+    bot_id       int unsigned                                not null,
+    name         varchar(64)                                 not null,
+    api_key      CHAR(64)                                    not null,
+    api_secret   CHAR(64)                                    not null,
+    scope        CHAR(8)                                     not null,
+    is_enabled   tinyint(1)                                  not null default 1,
+    created_at   datetime                                    not null,
+    last_used_at datetime                                    default null,
+    constraint api_client_bot_fk foreign key (bot_id) references `bots` (id),
+    constraint api_client_key_unique unique (api_key)
+);
+
+create table `api_audit`
+(
+    id            int auto_increment primary key,
+    bot_id        int unsigned                                not null,
+    api_client_id int                                         default null,
+    client_name   varchar(64)                                 not null,
+    method        CHAR(8)                                     not null,
+    path          varchar(255)                                not null,
+    query         varchar(1024)                               default null,
+    status        int                                         not null,
+    remote_addr   varchar(64)                                 not null,
+    created_at    datetime                                    not null,
+    constraint api_audit_bot_fk foreign key (bot_id) references `bots` (id)
+);
+create index api_audit_client_idx on api_audit (api_client_id);
@@ -0,0 +1 @@
+alter table trade_limit add column archived_at datetime default null;
@@ -0,0 +1,3 @@
+alter table bots add column binance_api_key varchar(255) default null;
+alter table bots add column binance_api_secret varchar(255) default null;
+alter table bots add column is_enabled tinyint(1) not null default 1;
@@ -0,0 +1,16 @@
+create table `notification_queue`
+(
+    id              int auto_increment primary key,
+    bot_id          int unsigned                                not null,
+    channel         varchar(64)                                 not null,
+    event           CHAR(16)                                    not null,
+    payload         json                                        not null,
+    status          CHAR(16)                                    not null default 'pending',
+    attempts        int                                         not null default 0,
+    next_attempt_at datetime                                    not null,
+    last_error      varchar(1024)                               default null,
+    created_at      datetime                                    not null,
+    sent_at         datetime                                    default null,
+    constraint notification_queue_bot_fk foreign key (bot_id) references `bots` (id)
+);
+create index notification_queue_due_idx on notification_queue (bot_id, status, next_attempt_at);
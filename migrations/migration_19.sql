@@ -0,0 +1,18 @@
+create table `lot_ledger`
+(
+    id          int auto_increment primary key,
+    bot_id      int unsigned                                not null,
+    asset       varchar(16)                                 not null,
+    symbol      varchar(32)                                 not null,
+    order_id    int                                         default null,
+    external_id bigint                                      not null,
+    type        CHAR(16)                                    not null,
+    quantity    double                                      not null,
+    price       double                                      not null,
+    value       double                                      not null,
+    fee         double                                      not null default 0,
+    created_at  datetime                                    not null,
+    constraint lot_ledger_bot_fk foreign key (bot_id) references `bots` (id),
+    constraint lot_ledger_fill_uniq unique (bot_id, symbol, external_id, type, asset)
+);
+create index lot_ledger_asset_idx on lot_ledger (bot_id, asset, created_at);
@@ -0,0 +1,26 @@
+create table `order_strategy`
+(
+    id         int auto_increment primary key,
+    bot_id     int unsigned                                not null,
+    order_id   int                                         not null,
+    strategy   varchar(64)                                 not null,
+    score      double                                      not null,
+    created_at datetime                                    not null,
+    constraint order_strategy_bot_fk foreign key (bot_id) references `bots` (id),
+    constraint order_strategy_order_fk foreign key (order_id) references `orders` (id),
+    constraint order_strategy_uniq unique (order_id, strategy)
+);
+create table `performance_snapshot`
+(
+    id          int auto_increment primary key,
+    bot_id      int unsigned                                not null,
+    symbol      varchar(32)                                 not null,
+    reason      CHAR(16)                                    not null,
+    trade_limit json                                        not null,
+    stats       json                                        not null,
+    period_from datetime                                    default null,
+    period_to   datetime                                    not null,
+    created_at  datetime                                    not null,
+    constraint performance_snapshot_bot_fk foreign key (bot_id) references `bots` (id)
+);
+create index performance_snapshot_symbol_idx on performance_snapshot (bot_id, symbol, period_to);
@@ -0,0 +1,24 @@
+create table `decision_journal`
+(
+    id                int auto_increment primary key,
+    bot_id            int unsigned                                not null,
+    symbol            varchar(32)                                 not null,
+    operation         CHAR(16)                                    not null,
+    result            CHAR(16)                                    not null,
+    reason            varchar(255)                                not null default '',
+    buy_score         double                                      not null,
+    sell_score        double                                      not null,
+    hold_score        double                                      not null,
+    last_price        double                                      not null default 0,
+    price             double                                      not null default 0,
+    quantity          double                                      not null default 0,
+    order_id          int                                         default null,
+    decisions         json                                        not null,
+    trade_limit       json                                        default null,
+    manual_order      json                                        default null,
+    price_calculation json                                        default null,
+    created_at        datetime                                    not null,
+    constraint decision_journal_bot_fk foreign key (bot_id) references `bots` (id)
+);
+create index decision_journal_symbol_idx on decision_journal (bot_id, symbol, created_at);
+create index decision_journal_created_idx on decision_journal (bot_id, created_at);
@@ -0,0 +1,43 @@
+alter table `trade_limit`
+    add column mode         CHAR(16) not null default 'position',
+    add column grid_options json              default null;
+create table `grid`
+(
+    id           int auto_increment primary key,
+    bot_id       int unsigned                                not null,
+    symbol       varchar(32)                                 not null,
+    lower_price  double                                      not null,
+    upper_price  double                                      not null,
+    levels       int                                         not null,
+    usdt_limit   double                                      not null,
+    status       CHAR(16)                                    not null,
+    trades       int                                         not null default 0,
+    fees         double                                      not null default 0,
+    realized_pnl double                                      not null default 0,
+    created_at   datetime                                    not null,
+    stopped_at   datetime                                    default null,
+    constraint grid_bot_fk foreign key (bot_id) references `bots` (id)
+);
+create index grid_symbol_idx on grid (bot_id, symbol, status);
+create table `grid_level`
+(
+    id                   int auto_increment primary key,
+    grid_id              int                                         not null,
+    level_index          int                                         not null,
+    buy_price            double                                      not null,
+    sell_price           double                                      not null,
+    quantity             double                                      not null,
+    status               CHAR(16)                                    not null,
+    external_id          bigint                                      default null,
+    buy_external_id      bigint                                      default null,
+    bought_price         double                                      not null default 0,
+    bought_quantity      double                                      not null default 0,
+    buy_commission       double                                      not null default 0,
+    buy_commission_asset varchar(16)                                 not null default '',
+    bought_at            datetime                                    default null,
+    buy_order_id         int                                         default null,
+    trades               int                                         not null default 0,
+    realized_pnl         double                                      not null default 0,
+    constraint grid_level_grid_fk foreign key (grid_id) references `grid` (id),
+    constraint grid_level_uniq unique (grid_id, level_index)
+);
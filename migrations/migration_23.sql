@@ -0,0 +1,35 @@
+create table `dca_plan`
+(
+    id                   int auto_increment primary key,
+    bot_id               int unsigned                                not null,
+    symbol               varchar(32)                                 not null,
+    amount_usdt          double                                      not null,
+    interval_hours       int                                         not null,
+    max_price            double                                      not null default 0,
+    use_price_calculator tinyint(1)                                  not null default 0,
+    budget_usdt          double                                      not null default 0,
+    spent_usdt           double                                      not null default 0,
+    quantity             double                                      not null default 0,
+    orders               int                                         not null default 0,
+    status               CHAR(16)                                    not null,
+    next_buy_at          bigint                                      not null default 0,
+    last_message         varchar(255)                                not null default '',
+    created_at           datetime                                    not null,
+    constraint dca_plan_bot_fk foreign key (bot_id) references `bots` (id)
+);
+create table `dca_order`
+(
+    id               int auto_increment primary key,
+    plan_id          int                                         not null,
+    symbol           varchar(32)                                 not null,
+    external_id      bigint                                      not null,
+    price            double                                      not null,
+    quantity         double                                      not null,
+    amount_usdt      double                                      not null,
+    commission       double                                      not null default 0,
+    commission_asset varchar(16)                                 not null default '',
+    created_at       datetime                                    not null,
+    constraint dca_order_plan_fk foreign key (plan_id) references `dca_plan` (id),
+    constraint dca_order_external_uniq unique (symbol, external_id)
+);
+create index dca_order_plan_idx on dca_order (plan_id, created_at);
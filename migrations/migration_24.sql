@@ -0,0 +1,2 @@
+alter table `trade_limit`
+    add column take_profit_options json default null;
@@ -0,0 +1,20 @@
+alter table `trade_limit`
+    add column execution_options json default null;
+create table `order_child`
+(
+    id                int auto_increment primary key,
+    bot_id            int unsigned                                not null,
+    order_id          int                                         default null,
+    symbol            varchar(32)                                 not null,
+    external_id       bigint                                      not null,
+    operation         CHAR(8)                                     not null,
+    algorithm         CHAR(16)                                    not null,
+    price             double                                      not null,
+    quantity          double                                      not null,
+    executed_quantity double                                      not null,
+    status            CHAR(32)                                    not null,
+    created_at        datetime                                    not null,
+    constraint order_child_bot_fk foreign key (bot_id) references `bots` (id),
+    constraint order_child_external_uniq unique (symbol, external_id)
+);
+create index order_child_order_idx on order_child (order_id);
@@ -0,0 +1,2 @@
+alter table `trade_limit`
+    add column order_type CHAR(16) not null default 'LIMIT';
@@ -0,0 +1,2 @@
+alter table `grid_level`
+    add column client_order_id varchar(36) default null after external_id;
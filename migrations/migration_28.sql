@@ -0,0 +1,26 @@
+-- one row per symbol: archived rows of symbols which were created again and older duplicates are moved to trade_limit_duplicate,
+-- the newest row is kept, moved rows can be compared and restored manually
+create table trade_limit_duplicate like trade_limit;
+
+insert into trade_limit_duplicate
+select archived.* from trade_limit archived
+where archived.archived_at is not null and exists (
+    select 1 from trade_limit active
+    where active.archived_at is null and active.bot_id = archived.bot_id and active.symbol = archived.symbol and active.id != archived.id
+);
+
+delete archived from trade_limit archived
+    join trade_limit active on active.bot_id = archived.bot_id and active.symbol = archived.symbol and active.id != archived.id
+where archived.archived_at is not null and active.archived_at is null;
+
+insert into trade_limit_duplicate
+select older.* from trade_limit older
+where exists (
+    select 1 from trade_limit newer
+    where newer.bot_id = older.bot_id and newer.symbol = older.symbol and newer.id > older.id
+);
+
+delete older from trade_limit older
+    join trade_limit newer on newer.bot_id = older.bot_id and newer.symbol = older.symbol and newer.id > older.id;
+
+alter table trade_limit add constraint trade_limit_bot_symbol_uniq unique (bot_id, symbol);
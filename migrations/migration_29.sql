@@ -0,0 +1,2 @@
+-- notifications are claimed by one worker before sending
+alter table notification_queue add claim_id varchar(36) default null after status;
@@ -10,28 +10,52 @@ import (
 	uuid2 "github.com/google/uuid"
 	"github.com/gorilla/websocket"
 	"github.com/redis/go-redis/v9"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
 	"gitlab.com/open-soft/go-crypto-bot/src/model"
 	"log"
 	"net/http"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
 type ExchangeOrderAPIInterface interface {
 	LimitOrder(symbol string, quantity float64, price float64, operation string, timeInForce string) (model.BinanceOrder, error)
+	LimitMakerOrder(symbol string, quantity float64, price float64, operation string) (model.BinanceOrder, error)
 	QueryOrder(symbol string, orderId int64) (model.BinanceOrder, error)
 	CancelOrder(symbol string, orderId int64) (model.BinanceOrder, error)
+	CancelReplaceOrder(symbol string, orderId int64, quantity float64, price float64, operation string, orderType string) (model.BinanceOrder, error)
 	GetOpenedOrders() ([]model.BinanceOrder, error)
 }
 
+type ExchangeOrderHistoryAPIInterface interface {
+	QueryOrder(symbol string, orderId int64) (model.BinanceOrder, error)
+	GetOpenedOrdersBySymbol(symbol string) ([]model.BinanceOrder, error)
+	GetTrades(order model.Order) ([]model.MyTrade, error)
+}
+
+type ExchangeGridAPIInterface interface {
+	LimitOrderWithClientId(symbol string, quantity float64, price float64, operation string, timeInForce string, clientOrderId string) (model.BinanceOrder, error)
+	QueryOrder(symbol string, orderId int64) (model.BinanceOrder, error)
+	QueryOrderByClientId(symbol string, clientOrderId string) (model.BinanceOrder, error)
+	CancelOrder(symbol string, orderId int64) (model.BinanceOrder, error)
+	GetTrades(order model.Order) ([]model.MyTrade, error)
+}
+
 type ExchangePriceAPIInterface interface {
 	GetDepth(symbol string) (model.OrderBook, error)
 	GetKLines(symbol string, interval string, limit int64) []model.KLineHistory
 	GetKLinesCached(symbol string, interval string, limit int64) []model.KLine
 }
 
+type ExchangeTradeLimitAPIInterface interface {
+	GetExchangeData(symbols []string) (*model.ExchangeInfo, error)
+	GetOpenedOrders() ([]model.BinanceOrder, error)
+}
+
 type Binance struct {
 	ApiKey    string
 	ApiSecret string
@@ -46,6 +70,9 @@ type Binance struct {
 	WaitMode             bool
 	Connected            bool
 	APIKeyCheckCompleted bool
+	closed               atomic.Bool
+	writerOnce           sync.Once
+	mu                   sync.Mutex
 }
 
 func (b *Binance) CheckWait() {
@@ -56,13 +83,15 @@ func (b *Binance) CheckWait() {
 	}
 }
 
+// Connect blocks until websocket API connection is established, connection is restored with exponential backoff
 func (b *Binance) Connect(address string) {
-	connection, _, err := websocket.DefaultDialer.Dial(address, nil)
-	if err != nil {
-		b.Connected = false
-		log.Printf("Binance WS [%s]: %s, wait and reconnect...", address, err.Error())
-		time.Sleep(time.Second * 10)
-		b.Connect(address)
+	b.writerOnce.Do(func() {
+		go b.writeMessages()
+	})
+
+	reconnect := backoff{Min: defaultReconnectDelay * 5, Max: maxReconnectDelay}
+	connection := b.dial(address, &reconnect)
+	if connection == nil {
 		return
 	}
 
@@ -73,33 +102,89 @@ func (b *Binance) Connect(address string) {
 
 	// reader channel
 	go func() {
-		for {
-			_, message, err := connection.ReadMessage()
-			if err != nil {
-				log.Println("read: ", err)
-
-				_ = connection.Close()
-				b.Connected = false
-				log.Printf("Binance WS, wait and reconnect...")
-				time.Sleep(time.Second * 10)
-				b.Connect(address)
+		for connection != nil {
+			connectedAt := time.Now()
+			err := readMessages(context.Background(), connection, heartbeat{
+				PingInterval: defaultPingInterval,
+				ReadTimeout:  defaultReadTimeout,
+				MaxLifetime:  defaultMaxLifetime,
+			}, func(message []byte) {
+				b.Channel <- message
+			}, nil)
+			_ = connection.Close()
+			b.setConnection(nil)
+
+			if b.closed.Load() {
+				log.Printf("Binance WS closed")
 				return
 			}
 
-			b.Channel <- message
+			if errors.Is(err, errConnectionRotation) || time.Since(connectedAt) > stableConnection {
+				reconnect.Reset()
+			}
+			log.Println("read: ", err)
+			log.Printf("Binance WS, wait and reconnect...")
+			connection = b.dial(address, &reconnect)
 		}
 	}()
+}
 
-	// writer channel
-	go func() {
-		for {
-			serialized := <-b.SocketWriter
-			_ = b.connection.WriteMessage(websocket.TextMessage, serialized)
+func (b *Binance) dial(address string, reconnect *backoff) *websocket.Conn {
+	for !b.closed.Load() {
+		connection, _, err := websocket.DefaultDialer.Dial(address, nil)
+		if err != nil {
+			delay := reconnect.Next()
+			log.Printf("Binance WS [%s]: %s, reconnect in %s...", address, err.Error(), delay)
+			time.Sleep(delay)
+			continue
 		}
-	}()
+
+		b.setConnection(connection)
+
+		return connection
+	}
+
+	return nil
+}
+
+func (b *Binance) setConnection(connection *websocket.Conn) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
 
 	b.connection = connection
-	b.Connected = true
+	b.Connected = connection != nil
+}
+
+// writeMessages is single writer, message is written again after reconnect if connection is lost
+func (b *Binance) writeMessages() {
+	for serialized := range b.SocketWriter {
+		for !b.closed.Load() {
+			b.mu.Lock()
+			connection := b.connection
+			err := errors.New("Binance WS is disconnected")
+			if connection != nil {
+				_ = connection.SetWriteDeadline(time.Now().Add(time.Second * 10))
+				err = connection.WriteMessage(websocket.TextMessage, serialized)
+			}
+			b.mu.Unlock()
+
+			if err == nil {
+				break
+			}
+			time.Sleep(time.Second)
+		}
+	}
+}
+
+// Close stops websocket API connection without reconnect
+func (b *Binance) Close() {
+	b.closed.Store(true)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.connection != nil {
+		_ = b.connection.Close()
+	}
 }
 
 func (b *Binance) socketRequest(req model.SocketRequest, channel chan []byte) {
@@ -130,6 +215,7 @@ func (b *Binance) socketRequest(req model.SocketRequest, channel chan []byte) {
 
 			if strings.Contains(string(msg), req.Id) {
 				//log.Printf("[%s], %s", req.Method, string(msg))
+				b.observeRateLimits(msg)
 				channel <- msg
 				return
 			}
@@ -142,6 +228,19 @@ func (b *Binance) socketRequest(req model.SocketRequest, channel chan []byte) {
 	b.SocketWriter <- serialized
 }
 
+func (b *Binance) observeRateLimits(msg []byte) {
+	var response model.SocketRateLimitResponse
+	if json.Unmarshal(msg, &response) != nil {
+		return
+	}
+
+	for _, rateLimit := range response.RateLimits {
+		if rateLimit.RateLimitType == "REQUEST_WEIGHT" && rateLimit.Interval == "MINUTE" {
+			metrics.BinanceRequestWeight.Set(float64(rateLimit.Count))
+		}
+	}
+}
+
 func (b *Binance) QueryOrder(symbol string, orderId int64) (model.BinanceOrder, error) {
 	b.CheckWait()
 
@@ -171,6 +270,36 @@ func (b *Binance) QueryOrder(symbol string, orderId int64) (model.BinanceOrder,
 	return response.Result, nil
 }
 
+// QueryOrderByClientId returns order placed with client order id, error "Order does not exist." if it was not placed
+func (b *Binance) QueryOrderByClientId(symbol string, clientOrderId string) (model.BinanceOrder, error) {
+	b.CheckWait()
+
+	channel := make(chan []byte)
+	defer close(channel)
+
+	socketRequest := model.SocketRequest{
+		Id:     uuid2.New().String(),
+		Method: "order.status",
+		Params: make(map[string]any),
+	}
+	socketRequest.Params["apiKey"] = b.ApiKey
+	socketRequest.Params["origClientOrderId"] = clientOrderId
+	socketRequest.Params["symbol"] = symbol
+	socketRequest.Params["timestamp"] = time.Now().Unix() * 1000
+	socketRequest.Params["signature"] = b.signature(socketRequest.Params)
+	b.socketRequest(socketRequest, channel)
+	message := <-channel
+
+	var response model.BinanceOrderResponse
+	json.Unmarshal(message, &response)
+
+	if response.Error != nil {
+		return model.BinanceOrder{}, errors.New(response.Error.GetMessage())
+	}
+
+	return response.Result, nil
+}
+
 func (b *Binance) CancelOrder(symbol string, orderId int64) (model.BinanceOrder, error) {
 	b.CheckWait()
 
@@ -200,6 +329,49 @@ func (b *Binance) CancelOrder(symbol string, orderId int64) (model.BinanceOrder,
 	return response.Result, nil
 }
 
+// CancelReplaceOrder cancels NEW order and places new one atomically, nothing is placed if cancel fails
+// and error is returned if any of both is failed
+func (b *Binance) CancelReplaceOrder(symbol string, orderId int64, quantity float64, price float64, operation string, orderType string) (model.BinanceOrder, error) {
+	b.CheckWait()
+
+	channel := make(chan []byte)
+	defer close(channel)
+
+	socketRequest := model.SocketRequest{
+		Id:     uuid2.New().String(),
+		Method: "order.cancelReplace",
+		Params: make(map[string]any),
+	}
+	socketRequest.Params["apiKey"] = b.ApiKey
+	socketRequest.Params["symbol"] = symbol
+	socketRequest.Params["cancelReplaceMode"] = "STOP_ON_FAILURE"
+	socketRequest.Params["cancelOrderId"] = orderId
+	// partially filled order is not replaced, otherwise new order would have full quantity
+	socketRequest.Params["cancelRestrictions"] = "ONLY_NEW"
+	socketRequest.Params["side"] = operation
+	socketRequest.Params["type"] = orderType
+	if orderType == model.OrderTypeLimit {
+		socketRequest.Params["timeInForce"] = model.TimeInForceGtc
+	}
+	socketRequest.Params["quantity"] = strconv.FormatFloat(quantity, 'f', -1, 64)
+	socketRequest.Params["price"] = strconv.FormatFloat(price, 'f', -1, 64)
+	socketRequest.Params["timestamp"] = time.Now().Unix() * 1000
+	socketRequest.Params["signature"] = b.signature(socketRequest.Params)
+	b.socketRequest(socketRequest, channel)
+	message := <-channel
+
+	var response model.BinanceCancelReplaceResponse
+	json.Unmarshal(message, &response)
+
+	if response.Error != nil {
+		log.Printf("[%s] Cancel Replace Order: %s -> %s", symbol, response.Error.GetMessage(), socketRequest)
+
+		return model.BinanceOrder{}, errors.New(response.Error.GetMessage())
+	}
+
+	return response.Result.NewOrderResponse, nil
+}
+
 func (b *Binance) UserDataStreamStart() (model.UserDataStreamStart, error) {
 	b.CheckWait()
 
@@ -252,6 +424,17 @@ func (b *Binance) GetDepth(symbol string) (model.OrderBook, error) {
 }
 
 func (b *Binance) GetOpenedOrders() ([]model.BinanceOrder, error) {
+	return b.getOpenedOrders(make(map[string]any))
+}
+
+func (b *Binance) GetOpenedOrdersBySymbol(symbol string) ([]model.BinanceOrder, error) {
+	params := make(map[string]any)
+	params["symbol"] = symbol
+
+	return b.getOpenedOrders(params)
+}
+
+func (b *Binance) getOpenedOrders(params map[string]any) ([]model.BinanceOrder, error) {
 	b.CheckWait()
 
 	channel := make(chan []byte)
@@ -260,7 +443,7 @@ func (b *Binance) GetOpenedOrders() ([]model.BinanceOrder, error) {
 	socketRequest := model.SocketRequest{
 		Id:     uuid2.New().String(),
 		Method: "openOrders.status",
-		Params: make(map[string]any),
+		Params: params,
 	}
 	socketRequest.Params["apiKey"] = b.ApiKey
 	socketRequest.Params["timestamp"] = time.Now().Unix() * 1000
@@ -458,6 +641,11 @@ func (b *Binance) GetTrades(order model.Order) ([]model.MyTrade, error) {
 }
 
 func (b *Binance) LimitOrder(symbol string, quantity float64, price float64, operation string, timeInForce string) (model.BinanceOrder, error) {
+	return b.LimitOrderWithClientId(symbol, quantity, price, operation, timeInForce, "")
+}
+
+// LimitOrderWithClientId places limit order with own client order id, order can be found by it when exchange response is lost
+func (b *Binance) LimitOrderWithClientId(symbol string, quantity float64, price float64, operation string, timeInForce string, clientOrderId string) (model.BinanceOrder, error) {
 	b.CheckWait()
 
 	channel := make(chan []byte)
@@ -488,6 +676,35 @@ func (b *Binance) LimitOrder(symbol string, quantity float64, price float64, ope
 	// Brokerages will typically limit the maximum time you can keep a GTC order open (active) to 90 days.
 	socketRequest.Params["timeInForce"] = timeInForce
 	socketRequest.Params["price"] = strconv.FormatFloat(price, 'f', -1, 64)
+	if clientOrderId != "" {
+		socketRequest.Params["newClientOrderId"] = clientOrderId
+	}
+
+	return b.placeOrder(symbol, socketRequest, channel)
+}
+
+// LimitMakerOrder places post-only order, it is rejected with BinanceErrorOrderWouldMatch if it would take liquidity
+func (b *Binance) LimitMakerOrder(symbol string, quantity float64, price float64, operation string) (model.BinanceOrder, error) {
+	b.CheckWait()
+
+	channel := make(chan []byte)
+	defer close(channel)
+
+	socketRequest := model.SocketRequest{
+		Id:     uuid2.New().String(),
+		Method: "order.place",
+		Params: make(map[string]any),
+	}
+	socketRequest.Params["symbol"] = symbol
+	socketRequest.Params["side"] = operation
+	socketRequest.Params["type"] = model.OrderTypeLimitMaker
+	socketRequest.Params["quantity"] = strconv.FormatFloat(quantity, 'f', -1, 64)
+	socketRequest.Params["price"] = strconv.FormatFloat(price, 'f', -1, 64)
+
+	return b.placeOrder(symbol, socketRequest, channel)
+}
+
+func (b *Binance) placeOrder(symbol string, socketRequest model.SocketRequest, channel chan []byte) (model.BinanceOrder, error) {
 	socketRequest.Params["apiKey"] = b.ApiKey
 	socketRequest.Params["timestamp"] = time.Now().Unix() * 1000
 	socketRequest.Params["signature"] = b.signature(socketRequest.Params)
@@ -0,0 +1,267 @@
+package client
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BotApiClient calls bot HTTP API v1, request is signed with API key or authorized by botUuid (read scope)
+type BotApiClient struct {
+	HttpClient *http.Client
+	Host       string
+	ApiKey     string
+	ApiSecret  string
+	BotUuid    string
+}
+
+type botApiErrorEnvelope struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func SignApiRequest(secret string, timestamp int64, method string, requestUri string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(fmt.Sprintf("%d%s%s", timestamp, method, requestUri)))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (b *BotApiClient) Request(method string, path string, query url.Values, payload any, result any) error {
+	body := make([]byte, 0)
+	if payload != nil {
+		encoded, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		body = encoded
+	}
+
+	status, content, err := b.send(method, path, query, body, "application/json")
+	if err != nil {
+		return err
+	}
+
+	if status >= http.StatusBadRequest {
+		return b.getError(method, path, status, content)
+	}
+
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(content, result)
+}
+
+func (b *BotApiClient) send(method string, path string, query url.Values, body []byte, contentType string) (int, []byte, error) {
+	if query == nil {
+		query = url.Values{}
+	}
+	if b.ApiKey == "" && b.BotUuid != "" {
+		query.Set("botUuid", b.BotUuid)
+	}
+
+	requestUri := "/api/v1" + path
+	if len(query) > 0 {
+		requestUri = requestUri + "?" + query.Encode()
+	}
+
+	req, err := http.NewRequest(method, strings.TrimSuffix(b.Host, "/")+requestUri, bytes.NewReader(body))
+	if err != nil {
+		return 0, nil, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	if b.BotUuid != "" {
+		req.Header.Set(model.ApiHeaderBotUuid, b.BotUuid)
+	}
+
+	if b.ApiKey != "" {
+		timestamp := time.Now().UnixMilli()
+		req.Header.Set(model.ApiHeaderKey, b.ApiKey)
+		req.Header.Set(model.ApiHeaderTimestamp, fmt.Sprintf("%d", timestamp))
+		req.Header.Set(model.ApiHeaderSignature, SignApiRequest(b.ApiSecret, timestamp, method, requestUri, body))
+	}
+
+	httpClient := b.HttpClient
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: time.Second * 30}
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+
+	return res.StatusCode, content, err
+}
+
+func (b *BotApiClient) getError(method string, path string, status int, content []byte) error {
+	var envelope botApiErrorEnvelope
+	if json.Unmarshal(content, &envelope) == nil && envelope.Error.Message != "" {
+		return errors.New(fmt.Sprintf("%s %s: %d %s", method, path, envelope.Error.Code, envelope.Error.Message))
+	}
+
+	return errors.New(fmt.Sprintf("%s %s: %d %s", method, path, status, strings.TrimSpace(string(content))))
+}
+
+func (b *BotApiClient) GetTradeLimits() ([]model.TradeLimit, error) {
+	limits := make([]model.TradeLimit, 0)
+	err := b.Request("GET", "/trade/limit/list", nil, nil, &limits)
+
+	return limits, err
+}
+
+func (b *BotApiClient) GetTradeLimit(symbol string) (*model.TradeLimit, error) {
+	limits, err := b.GetTradeLimits()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, limit := range limits {
+		if limit.Symbol == symbol {
+			return &limit, nil
+		}
+	}
+
+	return nil, errors.New(fmt.Sprintf("Trade limit %s is not found", symbol))
+}
+
+func (b *BotApiClient) CreateTradeLimit(limit model.TradeLimit) (model.TradeLimit, error) {
+	var created model.TradeLimit
+	err := b.Request("POST", "/trade/limit/create", nil, limit, &created)
+
+	return created, err
+}
+
+func (b *BotApiClient) UpdateTradeLimit(limit model.TradeLimit) (model.TradeLimit, error) {
+	var updated model.TradeLimit
+	err := b.Request("PUT", "/trade/limit/update", nil, limit, &updated)
+
+	return updated, err
+}
+
+func (b *BotApiClient) DeleteTradeLimit(symbol string) error {
+	return b.Request("DELETE", "/trade/limit/delete", url.Values{"symbol": {symbol}}, nil, nil)
+}
+
+func (b *BotApiClient) ArchiveTradeLimit(symbol string) error {
+	return b.Request("POST", "/trade/limit/archive", url.Values{"symbol": {symbol}}, nil, nil)
+}
+
+// ExportTradeLimits returns file content, format is json or csv
+func (b *BotApiClient) ExportTradeLimits(format string) ([]byte, error) {
+	status, content, err := b.send("GET", "/trade/limit/export", url.Values{"format": {format}}, []byte{}, "application/json")
+	if err != nil {
+		return nil, err
+	}
+	if status >= http.StatusBadRequest {
+		return nil, b.getError("GET", "/trade/limit/export", status, content)
+	}
+
+	return content, nil
+}
+
+// ImportTradeLimits returns report with violations if limits are invalid
+func (b *BotApiClient) ImportTradeLimits(content []byte, format string, dryRun bool) (model.TradeLimitImportReport, error) {
+	var report model.TradeLimitImportReport
+	query := url.Values{"format": {format}}
+	if dryRun {
+		query.Set("dryRun", "1")
+	}
+	contentType := "application/json"
+	if format == "csv" {
+		contentType = "text/csv"
+	}
+
+	status, response, err := b.send("POST", "/trade/limit/import", query, content, contentType)
+	if err != nil {
+		return report, err
+	}
+	if status >= http.StatusBadRequest && status != http.StatusUnprocessableEntity {
+		return report, b.getError("POST", "/trade/limit/import", status, response)
+	}
+
+	err = json.Unmarshal(response, &report)
+	if err == nil && !report.IsValid() {
+		err = errors.New("Trade limits are invalid")
+	}
+
+	return report, err
+}
+
+func (b *BotApiClient) GetPositions() ([]model.Position, error) {
+	positions := make([]model.Position, 0)
+	err := b.Request("GET", "/order/position/list", nil, nil, &positions)
+
+	return positions, err
+}
+
+func (b *BotApiClient) GetPendingOrders() ([]model.PendingOrder, error) {
+	orders := make([]model.PendingOrder, 0)
+	err := b.Request("GET", "/order/pending/list", nil, nil, &orders)
+
+	return orders, err
+}
+
+func (b *BotApiClient) GetTradeStack() ([]model.TradeStackItem, error) {
+	stack := make([]model.TradeStackItem, 0)
+	err := b.Request("GET", "/trade/stack", nil, nil, &stack)
+
+	return stack, err
+}
+
+func (b *BotApiClient) PostManualOrder(manual model.ManualOrder) error {
+	return b.Request("POST", "/order", nil, manual, nil)
+}
+
+func (b *BotApiClient) UpdateExtraCharge(options model.UpdateOrderExtraChargeOptions) (model.Order, error) {
+	var order model.Order
+	err := b.Request("PUT", "/order/extra/charge/update", nil, options, &order)
+
+	return order, err
+}
+
+func (b *BotApiClient) GetSwapChains() ([]model.SwapChainEntity, error) {
+	chains := make([]model.SwapChainEntity, 0)
+	err := b.Request("GET", "/swap/list", nil, nil, &chains)
+
+	return chains, err
+}
+
+func (b *BotApiClient) GetSwapActions(limit int64) ([]model.SwapAction, error) {
+	actions := make([]model.SwapAction, 0)
+	err := b.Request("GET", "/swap/action/list", url.Values{"limit": {strconv.FormatInt(limit, 10)}}, nil, &actions)
+
+	return actions, err
+}
+
+func (b *BotApiClient) GetSwapAction(id int64) (model.SwapAction, error) {
+	var action model.SwapAction
+	err := b.Request("GET", "/swap/action", url.Values{"id": {strconv.FormatInt(id, 10)}}, nil, &action)
+
+	return action, err
+}
+
+func (b *BotApiClient) GetHealth() (model.BotHealth, error) {
+	var health model.BotHealth
+	err := b.Request("GET", "/health/check", nil, nil, &health)
+
+	return health, err
+}
@@ -1,50 +1,353 @@
 package client
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"github.com/gorilla/websocket"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
 	"gitlab.com/open-soft/go-crypto-bot/src/model"
 	"log"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
-func Listen(address string, tradeChannel chan<- []byte, streams []string, connectionId int64) *websocket.Conn {
-	connection, _, err := websocket.DefaultDialer.Dial(address, nil)
-	if err != nil {
-		log.Printf("Binance [err_1] WS Events [%s]: %s, wait and reconnect...", address, err.Error())
-		time.Sleep(time.Second * 3)
-		connectionId++
+const defaultStaleAfter = time.Minute * 2
 
-		return Listen(address, tradeChannel, streams, connectionId)
-	}
+type StreamConnectionInterface interface {
+	Subscribe(streams []string) error
+	Unsubscribe(streams []string) error
+	GetStreams() []string
+	GetHealth() model.StreamHealth
+	Close()
+}
+
+// StreamConnection is combined stream connection ({dsn}/stream), streams are subscribed by SUBSCRIBE request
+// and are subscribed again after reconnect. Stream without messages for StaleAfter is subscribed again.
+type StreamConnection struct {
+	Address        string
+	Channel        chan<- []byte
+	Batch          string
+	PingInterval   time.Duration
+	ReadTimeout    time.Duration
+	MaxLifetime    time.Duration
+	StaleAfter     time.Duration
+	ReconnectDelay time.Duration
+	streams        []string
+	lastMessages   map[string]time.Time
+	resubscribedAt map[string]time.Time
+	connection     *websocket.Conn
+	connectedAt    time.Time
+	lastMessageAt  time.Time
+	reconnects     int64
+	requestId      int64
+	cancel         context.CancelFunc
+	mu             sync.Mutex
+}
+
+// Start reads stream messages until ctx is cancelled, connection is restored with exponential backoff
+func (s *StreamConnection) Start(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	s.cancel = cancel
+	s.mu.Unlock()
 
 	go func() {
-		for {
-			_, message, err := connection.ReadMessage()
+		reconnect := backoff{
+			Min: getDuration(s.ReconnectDelay, defaultReconnectDelay),
+			Max: maxReconnectDelay,
+		}
+
+		for ctx.Err() == nil {
+			connection, _, err := websocket.DefaultDialer.DialContext(ctx, s.Address, nil)
 			if err != nil {
-				log.Printf("Binance [err_2] WS Events, read [%s]: %s", address, err.Error())
+				if ctx.Err() != nil {
+					return
+				}
+
+				delay := reconnect.Next()
+				log.Printf("Binance [err_1] WS Events [%s]: %s, reconnect in %s...", s.Address, err.Error(), delay)
+				s.waitReconnect(ctx, delay)
+				continue
+			}
 
-				_ = connection.Close()
-				log.Printf("Binance [err_2] WS Events, wait and reconnect...")
-				time.Sleep(time.Second * 3)
-				connectionId++
-				Listen(address, tradeChannel, streams, connectionId)
+			connectedAt := s.onConnect(connection)
+			err = readMessages(ctx, connection, heartbeat{
+				PingInterval: getDuration(s.PingInterval, defaultPingInterval),
+				ReadTimeout:  getDuration(s.ReadTimeout, defaultReadTimeout),
+				MaxLifetime:  getDuration(s.MaxLifetime, defaultMaxLifetime),
+			}, func(message []byte) {
+				s.touch(message)
+				select {
+				case s.Channel <- message:
+				case <-ctx.Done():
+				}
+			}, s.resubscribeStale)
+			_ = connection.Close()
+			s.onDisconnect()
+
+			if ctx.Err() != nil {
+				log.Printf("Binance WS Events [%s] closed", s.Address)
 				return
 			}
 
-			tradeChannel <- message
+			if errors.Is(err, errConnectionRotation) {
+				log.Printf("Binance WS Events [%s] connection lifetime is reached, reconnect...", s.Address)
+				reconnect.Reset()
+				s.waitReconnect(ctx, 0)
+				continue
+			}
+
+			if time.Since(connectedAt) > stableConnection {
+				reconnect.Reset()
+			}
+			delay := reconnect.Next()
+			log.Printf("Binance [err_2] WS Events, read [%s]: %s, reconnect in %s...", s.Address, err.Error(), delay)
+			s.waitReconnect(ctx, delay)
 		}
 	}()
+}
+
+// Close stops connection without reconnect
+func (s *StreamConnection) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.cancel != nil {
+		s.cancel()
+	}
+}
+
+func (s *StreamConnection) Subscribe(streams []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	added := make([]string, 0)
+	for _, stream := range streams {
+		if !slices.Contains(s.streams, stream) {
+			added = append(added, stream)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	s.streams = append(s.streams, added...)
+	for _, stream := range added {
+		s.getLastMessages()[stream] = time.Now()
+	}
+
+	// not connected: streams are subscribed on connect
+	if s.connection == nil {
+		return nil
+	}
+
+	return s.write("SUBSCRIBE", added)
+}
+
+func (s *StreamConnection) Unsubscribe(streams []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	removed := make([]string, 0)
+	for _, stream := range streams {
+		if slices.Contains(s.streams, stream) {
+			removed = append(removed, stream)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	s.streams = slices.DeleteFunc(s.streams, func(stream string) bool {
+		return slices.Contains(removed, stream)
+	})
+	for _, stream := range removed {
+		delete(s.getLastMessages(), stream)
+		delete(s.resubscribedAt, stream)
+	}
+
+	if s.connection == nil {
+		return nil
+	}
+
+	return s.write("UNSUBSCRIBE", removed)
+}
+
+func (s *StreamConnection) GetStreams() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.streams)
+}
+
+func (s *StreamConnection) GetHealth() model.StreamHealth {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	health := model.StreamHealth{
+		Batch:         s.Batch,
+		Address:       s.Address,
+		Status:        model.StreamStatusOk,
+		ConnectedAt:   formatStreamTime(s.connectedAt),
+		LastMessageAt: formatStreamTime(s.lastMessageAt),
+		Reconnects:    s.reconnects,
+		Streams:       len(s.streams),
+		StaleStreams:  s.getStaleStreams(),
+	}
+
+	if len(health.StaleStreams) > 0 {
+		health.Status = model.StreamStatusStale
+	}
+	if s.connection == nil {
+		health.Status = model.StreamStatusDisconnected
+	}
+
+	return health
+}
 
-	if len(streams) > 0 {
-		socketRequest := model.SocketStreamsRequest{
-			Id:     connectionId,
-			Method: "SUBSCRIBE",
-			Params: streams,
+func (s *StreamConnection) onConnect(connection *websocket.Conn) time.Time {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.connection = connection
+	s.connectedAt = time.Now()
+	// streams have fresh grace period after reconnect
+	s.resubscribedAt = make(map[string]time.Time)
+	for _, stream := range s.streams {
+		s.getLastMessages()[stream] = s.connectedAt
+	}
+
+	if len(s.streams) > 0 {
+		err := s.write("SUBSCRIBE", s.streams)
+		if err != nil {
+			log.Printf("Binance WS Events [%s] subscribe: %s", s.Address, err.Error())
+		}
+	}
+
+	return s.connectedAt
+}
+
+func (s *StreamConnection) onDisconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.connection = nil
+}
+
+func (s *StreamConnection) waitReconnect(ctx context.Context, delay time.Duration) {
+	select {
+	case <-ctx.Done():
+		return
+	case <-time.After(delay):
+	}
+
+	s.mu.Lock()
+	s.reconnects++
+	s.mu.Unlock()
+	metrics.WebsocketReconnectsTotal.WithLabelValues(s.Batch).Inc()
+}
+
+func (s *StreamConnection) touch(message []byte) {
+	stream := getStreamName(message)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastMessageAt = time.Now()
+	if stream != "" && slices.Contains(s.streams, stream) {
+		s.getLastMessages()[stream] = s.lastMessageAt
+	}
+}
+
+// resubscribeStale subscribes again streams without messages, once per StaleAfter
+func (s *StreamConnection) resubscribeStale() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.connection == nil {
+		return
+	}
+
+	staleAfter := getDuration(s.StaleAfter, defaultStaleAfter)
+	streams := make([]string, 0)
+	for _, stream := range s.getStaleStreams() {
+		if time.Since(s.resubscribedAt[stream]) < staleAfter {
+			continue
+		}
+
+		s.resubscribedAt[stream] = time.Now()
+		streams = append(streams, stream)
+	}
+
+	if len(streams) == 0 {
+		return
+	}
+
+	log.Printf("Binance WS Events [%s] stale streams: %s, resubscribe...", s.Address, strings.Join(streams, ", "))
+	metrics.StreamStaleTotal.WithLabelValues(s.Batch).Add(float64(len(streams)))
+	_ = s.write("UNSUBSCRIBE", streams)
+	err := s.write("SUBSCRIBE", streams)
+	if err != nil {
+		log.Printf("Binance WS Events [%s] resubscribe: %s", s.Address, err.Error())
+	}
+}
+
+// getStaleStreams must be called under lock
+func (s *StreamConnection) getStaleStreams() []string {
+	staleAfter := getDuration(s.StaleAfter, defaultStaleAfter)
+	stale := make([]string, 0)
+	for _, stream := range s.streams {
+		if time.Since(s.getLastMessages()[stream]) > staleAfter {
+			stale = append(stale, stream)
 		}
-		serialized, _ := json.Marshal(socketRequest)
-		_ = connection.WriteMessage(websocket.TextMessage, serialized)
 	}
 
-	return connection
+	return stale
+}
+
+// getLastMessages must be called under lock
+func (s *StreamConnection) getLastMessages() map[string]time.Time {
+	if s.lastMessages == nil {
+		s.lastMessages = make(map[string]time.Time)
+	}
+
+	return s.lastMessages
+}
+
+// write must be called under lock, websocket connection supports one concurrent writer
+func (s *StreamConnection) write(method string, streams []string) error {
+	s.requestId++
+	serialized, _ := json.Marshal(model.SocketStreamsRequest{
+		Id:     s.requestId,
+		Method: method,
+		Params: streams,
+	})
+	_ = s.connection.SetWriteDeadline(time.Now().Add(time.Second * 10))
+
+	return s.connection.WriteMessage(websocket.TextMessage, serialized)
+}
+
+// getStreamName reads stream from combined message {"stream":"<name>","data":...} without full decoding
+func getStreamName(message []byte) string {
+	prefix := []byte(`{"stream":"`)
+	if !bytes.HasPrefix(message, prefix) {
+		return ""
+	}
+
+	end := bytes.IndexByte(message[len(prefix):], '"')
+	if end < 0 {
+		return ""
+	}
+
+	return string(message[len(prefix) : len(prefix)+end])
+}
+
+func formatStreamTime(value time.Time) string {
+	if value.IsZero() {
+		return ""
+	}
+
+	return value.Format("2006-01-02 15:04:05")
 }
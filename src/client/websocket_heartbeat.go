@@ -0,0 +1,124 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"github.com/gorilla/websocket"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultPingInterval   = time.Second * 30
+	defaultReadTimeout    = time.Second * 90
+	defaultMaxLifetime    = time.Hour * 23 // Binance closes connection after 24 hours
+	defaultReconnectDelay = time.Second
+	maxReconnectDelay     = time.Minute
+	stableConnection      = time.Minute // backoff is reset if connection was alive longer
+)
+
+var errConnectionRotation = errors.New("connection lifetime is reached")
+
+type heartbeat struct {
+	PingInterval time.Duration
+	ReadTimeout  time.Duration
+	MaxLifetime  time.Duration
+}
+
+// backoff is exponential reconnect delay: min, min*2, min*4 ... max
+type backoff struct {
+	Min     time.Duration
+	Max     time.Duration
+	attempt int
+}
+
+func (b *backoff) Next() time.Duration {
+	delay := b.Min
+	for i := 0; i < b.attempt && delay < b.Max; i++ {
+		delay = delay * 2
+	}
+	if delay >= b.Max {
+		return b.Max
+	}
+	b.attempt++
+
+	return delay
+}
+
+func (b *backoff) Reset() {
+	b.attempt = 0
+}
+
+// readMessages reads connection until error or ctx is cancelled. Connection is pinged every PingInterval,
+// read fails if neither message nor ping/pong is received within ReadTimeout (silently stalled connection)
+// and connection is closed with errConnectionRotation after MaxLifetime.
+func readMessages(ctx context.Context, connection *websocket.Conn, h heartbeat, onMessage func(message []byte), onTick func()) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	rotated := atomic.Bool{}
+	go func() {
+		ticker := time.NewTicker(h.PingInterval)
+		defer ticker.Stop()
+		lifetime := time.NewTimer(h.MaxLifetime)
+		defer lifetime.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				_ = connection.Close()
+				return
+			case <-lifetime.C:
+				rotated.Store(true)
+				_ = connection.Close()
+				return
+			case <-ticker.C:
+				_ = connection.WriteControl(websocket.PingMessage, nil, time.Now().Add(time.Second*10))
+				if onTick != nil {
+					onTick()
+				}
+			}
+		}
+	}()
+
+	extend := func() error {
+		return connection.SetReadDeadline(time.Now().Add(h.ReadTimeout))
+	}
+	_ = extend()
+	connection.SetPongHandler(func(string) error {
+		return extend()
+	})
+	connection.SetPingHandler(func(data string) error {
+		_ = extend()
+		err := connection.WriteControl(websocket.PongMessage, []byte(data), time.Now().Add(time.Second*10))
+		if errors.Is(err, websocket.ErrCloseSent) {
+			return nil
+		}
+
+		return err
+	})
+
+	for {
+		_, message, err := connection.ReadMessage()
+		if err != nil {
+			if rotated.Load() {
+				return errConnectionRotation
+			}
+
+			return err
+		}
+
+		_ = extend()
+		onMessage(message)
+	}
+}
+
+func getDuration(value time.Duration, defaultValue time.Duration) time.Duration {
+	if value > 0 {
+		return value
+	}
+
+	return defaultValue
+}
@@ -0,0 +1,308 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gopkg.in/yaml.v3"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+const DefaultConfigPath = "config.yaml"
+
+func DefaultConfig() model.Config {
+	return model.Config{
+		Bot: model.BotConfig{
+			IsMasterBot: true,
+		},
+		Http: model.HttpConfig{
+			Port: 8080,
+		},
+		Api: model.ApiConfig{
+			CorsOrigins: []string{"*"},
+			RecvWindow:  5000,
+			LegacyScope: model.ApiScopeRead,
+		},
+		Database: model.DatabaseConfig{
+			MaxIdleConns:    64,
+			MaxOpenConns:    64,
+			ConnMaxLifetime: 60,
+		},
+		Binance: model.BinanceConfig{
+			SwapStreamDsn: "wss://stream.binance.com:9443",
+		},
+		Maker: model.MakerConfig{
+			MinDecisions:         4.00,
+			HoldScore:            75.00,
+			JournalRepeatSeconds: 300,
+			JournalRetentionDays: 30,
+		},
+		Swap: model.SwapConfig{
+			Enabled:                        true,
+			MinPercentValid:                1.15,
+			OrderOnProfitPercent:           -1.00,
+			OpenedSellOrderFromHoursOpened: 2,
+			TurboSwapProfitPercent:         20.00,
+			LegTimeInForce:                 model.TimeInForceGtc,
+		},
+		LossSecurity: model.LossSecurityConfig{
+			MlEnabled:            true,
+			InterpolationEnabled: true,
+		},
+		MachineLearning: model.MachineLearningConfig{
+			Learning:           true,
+			BaseKLineMlEnabled: true,
+			// own net: ATOM, XMR, XLM, DOT, ADA, XRP
+			BtcDependent:           []string{"LTC", "ZEC", "ATOM", "XMR", "DOT", "XRP", "BCH", "ADA", "ETH", "DOGE", "PERP", "NEO"},
+			EthDependent:           []string{"SHIB", "LINK", "UNI", "NEAR", "XLM", "ETC", "MATIC", "SOL", "BNB", "AVAX", "TRX"},
+			ExcludeDependedDataset: []string{"SHIBUSDT", "BTCUSDT"},
+		},
+		AutoTrade: model.AutoTradeConfig{
+			Host: "https://api.autotrade.cloud",
+		},
+		Schedule: model.ScheduleConfig{
+			UpdateLimitsMinutes:      5,
+			ReconciliationMinutes:    10,
+			LearnHours:               6,
+			DecisionMilliseconds:     500,
+			PositionPushMilliseconds: 1000,
+			SubscriptionSeconds:      10,
+			LeaderLeaseSeconds:       15,
+			GridSeconds:              10,
+			DcaMinutes:               5,
+		},
+		Shutdown: model.ShutdownConfig{
+			Timeout: 60,
+		},
+		Notifier: model.NotifierConfig{
+			MaxAttempts:  10,
+			QueueSeconds: 5,
+			Channels:     make([]model.NotifierChannelConfig, 0),
+		},
+	}
+}
+
+// LoadConfig reads defaults, then yaml file (if exists), then environment variables
+func LoadConfig(path string) (model.Config, error) {
+	config := DefaultConfig()
+
+	content, err := os.ReadFile(path)
+	if err == nil {
+		err = yaml.Unmarshal(content, &config)
+		if err != nil {
+			return config, errors.New(fmt.Sprintf("Config file %s is invalid: %s", path, err.Error()))
+		}
+	} else if !os.IsNotExist(err) {
+		return config, err
+	}
+
+	err = applyEnv(&config)
+	if err != nil {
+		return config, err
+	}
+
+	return config, ValidateConfig(config)
+}
+
+func GetConfigPath() string {
+	path := os.Getenv("CONFIG_PATH")
+	if path == "" {
+		return DefaultConfigPath
+	}
+
+	return path
+}
+
+func applyEnv(config *model.Config) error {
+	stringValues := map[string]*string{
+		"BOT_UUID":           &config.Bot.Uuid,
+		"DATABASE_DSN":       &config.Database.Dsn,
+		"REDIS_DSN":          &config.Redis.Dsn,
+		"REDIS_PASSWORD":     &config.Redis.Password,
+		"BINANCE_API_KEY":    &config.Binance.ApiKey,
+		"BINANCE_API_SECRET": &config.Binance.ApiSecret,
+		"BINANCE_WS_DSN":     &config.Binance.WsDsn,
+		"BINANCE_STREAM_DSN": &config.Binance.StreamDsn,
+		"AUTOTRADE_HOST":     &config.AutoTrade.Host,
+		"ACCOUNT_SECRET_KEY": &config.Bot.AccountSecretKey,
+	}
+	for name, value := range stringValues {
+		if env, ok := os.LookupEnv(name); ok {
+			*value = env
+		}
+	}
+
+	integers := map[string]*int64{
+		"HTTP_PORT":        &config.Http.Port,
+		"SHUTDOWN_TIMEOUT": &config.Shutdown.Timeout,
+	}
+	for name, value := range integers {
+		if env, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.ParseInt(env, 10, 64)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Env %s must be integer: %s", name, err.Error()))
+			}
+			*value = parsed
+		}
+	}
+
+	booleans := map[string]*bool{
+		"IS_MASTER_BOT": &config.Bot.IsMasterBot,
+		"MULTI_ACCOUNT": &config.Bot.MultiAccount,
+		"SWAP_ENABLED":  &config.Swap.Enabled,
+	}
+	for name, value := range booleans {
+		if env, ok := os.LookupEnv(name); ok {
+			parsed, err := strconv.ParseBool(env)
+			if err != nil {
+				return errors.New(fmt.Sprintf("Env %s must be boolean: %s", name, err.Error()))
+			}
+			*value = parsed
+		}
+	}
+
+	return nil
+}
+
+func ValidateConfig(config model.Config) error {
+	violations := make([]string, 0)
+
+	required := map[string]string{
+		"bot.uuid":          config.Bot.Uuid,
+		"database.dsn":      config.Database.Dsn,
+		"redis.dsn":         config.Redis.Dsn,
+		"binance.apiKey":    config.Binance.ApiKey,
+		"binance.apiSecret": config.Binance.ApiSecret,
+		"binance.wsDsn":     config.Binance.WsDsn,
+		"binance.streamDsn": config.Binance.StreamDsn,
+	}
+	for name, value := range required {
+		if value == "" {
+			violations = append(violations, fmt.Sprintf("%s is required", name))
+		}
+	}
+
+	if config.Bot.MultiAccount && config.Bot.AccountSecretKey == "" {
+		violations = append(violations, "bot.accountSecretKey is required for multi account")
+	}
+	if config.Http.Port <= 0 || config.Http.Port > 65535 {
+		violations = append(violations, fmt.Sprintf("http.port %d is invalid", config.Http.Port))
+	}
+	if config.Api.RecvWindow <= 0 {
+		violations = append(violations, "api.recvWindow must be positive")
+	}
+	if config.Api.LegacyScope != "" && !model.IsValidApiScope(config.Api.LegacyScope) {
+		violations = append(violations, fmt.Sprintf("api.legacyScope '%s' is invalid", config.Api.LegacyScope))
+	}
+	if config.Database.MaxOpenConns <= 0 {
+		violations = append(violations, "database.maxOpenConns must be positive")
+	}
+	if config.Database.MaxIdleConns < 0 || config.Database.MaxIdleConns > config.Database.MaxOpenConns {
+		violations = append(violations, "database.maxIdleConns must be between 0 and database.maxOpenConns")
+	}
+	if config.Maker.MinDecisions <= 0 {
+		violations = append(violations, "maker.minDecisions must be positive")
+	}
+	if config.Maker.HoldScore < 0 || config.Maker.HoldScore > 100 {
+		violations = append(violations, "maker.holdScore must be between 0 and 100")
+	}
+	if config.Swap.Enabled && config.Swap.MinPercentValid <= 0 {
+		violations = append(violations, "swap.minPercentValid must be positive")
+	}
+	if !slices.Contains(model.SwapLegTimeInForceList, config.Swap.LegTimeInForce) {
+		violations = append(violations, fmt.Sprintf("swap.legTimeInForce must be one of: %s", strings.Join(model.SwapLegTimeInForceList, ", ")))
+	}
+	if config.Swap.Enabled && config.Binance.SwapStreamDsn == "" && config.Bot.IsMasterBot {
+		violations = append(violations, "binance.swapStreamDsn is required for master bot")
+	}
+	schedule := map[string]int64{
+		"schedule.updateLimitsMinutes":      config.Schedule.UpdateLimitsMinutes,
+		"schedule.reconciliationMinutes":    config.Schedule.ReconciliationMinutes,
+		"schedule.learnHours":               config.Schedule.LearnHours,
+		"schedule.decisionMilliseconds":     config.Schedule.DecisionMilliseconds,
+		"schedule.positionPushMilliseconds": config.Schedule.PositionPushMilliseconds,
+		"schedule.subscriptionSeconds":      config.Schedule.SubscriptionSeconds,
+		"schedule.leaderLeaseSeconds":       config.Schedule.LeaderLeaseSeconds,
+		"schedule.gridSeconds":              config.Schedule.GridSeconds,
+		"schedule.dcaMinutes":               config.Schedule.DcaMinutes,
+		"shutdown.timeout":                  config.Shutdown.Timeout,
+		"notifier.maxAttempts":              config.Notifier.MaxAttempts,
+		"notifier.queueSeconds":             config.Notifier.QueueSeconds,
+		"maker.journalRepeatSeconds":        config.Maker.JournalRepeatSeconds,
+		"maker.journalRetentionDays":        config.Maker.JournalRetentionDays,
+	}
+	for name, value := range schedule {
+		if value <= 0 {
+			violations = append(violations, fmt.Sprintf("%s must be positive", name))
+		}
+	}
+
+	violations = append(violations, validateNotifierChannels(config.Notifier.Channels)...)
+
+	if len(violations) > 0 {
+		slices.Sort(violations)
+		return errors.New(fmt.Sprintf("Config is invalid: %s", strings.Join(violations, "; ")))
+	}
+
+	return nil
+}
+
+func validateNotifierChannels(channels []model.NotifierChannelConfig) []string {
+	violations := make([]string, 0)
+	names := make([]string, 0)
+
+	for index, channel := range channels {
+		prefix := fmt.Sprintf("notifier.channels[%d]", index)
+
+		if channel.Name == "" {
+			violations = append(violations, fmt.Sprintf("%s.name is required", prefix))
+		} else if slices.Contains(names, channel.Name) {
+			violations = append(violations, fmt.Sprintf("%s.name '%s' is duplicated", prefix, channel.Name))
+		}
+		names = append(names, channel.Name)
+
+		for _, event := range channel.Events {
+			if !slices.Contains(model.NotificationEvents, event) {
+				violations = append(violations, fmt.Sprintf("%s.events '%s' is invalid", prefix, event))
+			}
+		}
+
+		_, err := template.New(channel.Name).Parse(channel.Template)
+		if err != nil {
+			violations = append(violations, fmt.Sprintf("%s.template is invalid: %s", prefix, err.Error()))
+		}
+
+		required := make(map[string]string)
+		switch channel.Type {
+		case model.NotifierTypeTelegram:
+			required["botToken"] = channel.BotToken
+			required["chatId"] = channel.ChatId
+		case model.NotifierTypeSlack, model.NotifierTypeAutoTrade:
+			required["url"] = channel.Url
+		case model.NotifierTypeWebhook:
+			required["url"] = channel.Url
+			required["secret"] = channel.Secret
+		case model.NotifierTypeEmail:
+			required["smtpHost"] = channel.SmtpHost
+			required["from"] = channel.From
+			required["to"] = strings.Join(channel.To, ",")
+			if channel.SmtpPort <= 0 {
+				violations = append(violations, fmt.Sprintf("%s.smtpPort must be positive", prefix))
+			}
+		default:
+			violations = append(violations, fmt.Sprintf("%s.type '%s' is invalid", prefix, channel.Type))
+		}
+
+		for name, value := range required {
+			if value == "" {
+				violations = append(violations, fmt.Sprintf("%s.%s is required", prefix, name))
+			}
+		}
+	}
+
+	return violations
+}
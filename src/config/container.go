@@ -4,31 +4,35 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/redis/go-redis/v9"
 	"gitlab.com/open-soft/go-crypto-bot/src/client"
 	"gitlab.com/open-soft/go-crypto-bot/src/controller"
+	"gitlab.com/open-soft/go-crypto-bot/src/event"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
 	"gitlab.com/open-soft/go-crypto-bot/src/model"
 	"gitlab.com/open-soft/go-crypto-bot/src/repository"
 	"gitlab.com/open-soft/go-crypto-bot/src/service"
 	"log"
 	"net/http"
 	"os"
+	"slices"
 	"sync"
 	"time"
 )
 
-func InitServiceContainer() Container {
-	db, err := sql.Open("mysql", os.Getenv("DATABASE_DSN")) // root:go_crypto_bot@tcp(mysql:3306)/go_crypto_bot
+func InitServiceContainer(config model.Config) Container {
+	db, err := sql.Open("mysql", config.Database.Dsn) // root:go_crypto_bot@tcp(mysql:3306)/go_crypto_bot
 
-	db.SetMaxIdleConns(64)
-	db.SetMaxOpenConns(64)
-	db.SetConnMaxLifetime(time.Minute)
+	db.SetMaxIdleConns(config.Database.MaxIdleConns)
+	db.SetMaxOpenConns(config.Database.MaxOpenConns)
+	db.SetConnMaxLifetime(time.Second * time.Duration(config.Database.ConnMaxLifetime))
 
-	swapDb, err := sql.Open("mysql", os.Getenv("DATABASE_DSN")) // root:go_crypto_bot@tcp(mysql:3306)/go_crypto_bot
+	swapDb, err := sql.Open("mysql", config.Database.Dsn) // root:go_crypto_bot@tcp(mysql:3306)/go_crypto_bot
 
-	swapDb.SetMaxIdleConns(64)
-	swapDb.SetMaxOpenConns(64)
-	swapDb.SetConnMaxLifetime(time.Minute)
+	swapDb.SetMaxIdleConns(config.Database.MaxIdleConns)
+	swapDb.SetMaxOpenConns(config.Database.MaxOpenConns)
+	swapDb.SetConnMaxLifetime(time.Second * time.Duration(config.Database.ConnMaxLifetime))
 
 	if err != nil {
 		log.Fatal(fmt.Sprintf("MySQL can't connect: %s", err.Error()))
@@ -36,40 +40,22 @@ func InitServiceContainer() Container {
 
 	var ctx = context.Background()
 	rdb := redis.NewClient(&redis.Options{
-		Addr:     os.Getenv("REDIS_DSN"),      // redis:6379,
-		Password: os.Getenv("REDIS_PASSWORD"), // redis password
-		DB:       0,                           // use default DB
+		Addr:     config.Redis.Dsn,      // redis:6379,
+		Password: config.Redis.Password, // redis password
+		DB:       config.Redis.Db,
 	})
+	rdb.AddHook(metrics.RedisHook{})
 
-	httpClient := http.Client{}
-	binance := client.Binance{
-		ApiKey:               os.Getenv("BINANCE_API_KEY"),
-		ApiSecret:            os.Getenv("BINANCE_API_SECRET"),
-		HttpClient:           &httpClient,
-		Channel:              make(chan []byte),
-		SocketWriter:         make(chan []byte),
-		RDB:                  rdb,
-		Ctx:                  &ctx,
-		WaitMode:             false,
-		APIKeyCheckCompleted: false,
-		Connected:            false,
-	}
-
-	frameService := service.FrameService{
+	botRepository := repository.BotRepository{
+		DB:      db,
 		RDB:     rdb,
 		Ctx:     &ctx,
-		Binance: &binance,
-	}
-
-	botRepository := repository.BotRepository{
-		DB:  db,
-		RDB: rdb,
-		Ctx: &ctx,
+		BotUuid: config.Bot.Uuid,
 	}
 
 	currentBot := botRepository.GetCurrentBot()
 	if currentBot == nil {
-		botUuid := os.Getenv("BOT_UUID")
+		botUuid := config.Bot.Uuid
 		currentBot := &model.Bot{
 			BotUuid: botUuid,
 		}
@@ -84,6 +70,61 @@ func InitServiceContainer() Container {
 		}
 	}
 
+	container := newContainer(config, db, swapDb, rdb, currentBot, nil)
+	container.BotRepository = &botRepository
+	container.AccountService = &service.AccountService{
+		BotRepository: &botRepository,
+		SecretKey:     config.Bot.AccountSecretKey,
+	}
+
+	return container
+}
+
+// InitAccounts creates containers of enabled accounts (bots table), each account has own Binance credentials,
+// order executor and trade limits, market data (streams, klines, predictions, swap pairs) is shared with main bot
+func (c *Container) InitAccounts() {
+	for _, bot := range c.AccountService.GetAccounts() {
+		bot := bot
+		config := *c.Config
+		config.Bot.Uuid = bot.BotUuid
+		config.Bot.IsMasterBot = false
+		config.Binance.ApiKey = bot.BinanceApiKey
+		config.Binance.ApiSecret = bot.BinanceApiSecret
+
+		account := newContainer(config, c.Db, c.DbSwap, c.RDB, &bot, c)
+		c.Accounts = append(c.Accounts, &account)
+		log.Printf("Account [%s] is initialized", bot.BotUuid)
+	}
+}
+
+// GetBots returns main bot and accounts containers
+func (c *Container) GetBots() []*Container {
+	return append([]*Container{c}, c.Accounts...)
+}
+
+func newContainer(config model.Config, db *sql.DB, swapDb *sql.DB, rdb *redis.Client, currentBot *model.Bot, market *Container) Container {
+	var ctx = context.Background()
+
+	httpClient := http.Client{}
+	binance := client.Binance{
+		ApiKey:               config.Binance.ApiKey,
+		ApiSecret:            config.Binance.ApiSecret,
+		HttpClient:           &httpClient,
+		Channel:              make(chan []byte),
+		SocketWriter:         make(chan []byte),
+		RDB:                  rdb,
+		Ctx:                  &ctx,
+		WaitMode:             false,
+		APIKeyCheckCompleted: false,
+		Connected:            false,
+	}
+
+	frameService := service.FrameService{
+		RDB:     rdb,
+		Ctx:     &ctx,
+		Binance: &binance,
+	}
+
 	balanceService := service.BalanceService{
 		Binance:    &binance,
 		RDB:        rdb,
@@ -91,12 +132,22 @@ func InitServiceContainer() Container {
 		CurrentBot: currentBot,
 	}
 
+	notificationRepository := repository.NotificationRepository{
+		DB:         db,
+		CurrentBot: currentBot,
+	}
+	notifierRouter := service.NotifierRouter{
+		Channels:               getNotifierChannels(config),
+		NotificationRepository: &notificationRepository,
+		CurrentBot:             currentBot,
+		MaxAttempts:            config.Notifier.MaxAttempts,
+	}
 	callbackManager := service.CallbackManager{
-		AutoTradeHost: "https://api.autotrade.cloud",
+		NotifierRouter: &notifierRouter,
 	}
 
-	isMasterBot := true
-	swapEnabled := true
+	isMasterBot := config.Bot.IsMasterBot
+	swapEnabled := config.Swap.Enabled
 
 	orderRepository := repository.OrderRepository{
 		DB:         db,
@@ -104,11 +155,16 @@ func InitServiceContainer() Container {
 		Ctx:        &ctx,
 		CurrentBot: currentBot,
 	}
+	eventHub := event.Hub{}
 	exchangeRepository := repository.ExchangeRepository{
 		DB:         db,
 		RDB:        rdb,
 		Ctx:        &ctx,
 		CurrentBot: currentBot,
+		EventHub:   &eventHub,
+	}
+	if market != nil {
+		exchangeRepository.MarketBot = market.CurrentBot
 	}
 	swapRepository := repository.SwapRepository{
 		DB:         swapDb,
@@ -116,6 +172,32 @@ func InitServiceContainer() Container {
 		Ctx:        &ctx,
 		CurrentBot: currentBot,
 	}
+	orderEventRepository := repository.OrderEventRepository{
+		DB:         db,
+		CurrentBot: currentBot,
+	}
+	lotRepository := repository.LotRepository{
+		DB:         db,
+		CurrentBot: currentBot,
+	}
+	childOrderRepository := repository.ChildOrderRepository{
+		DB:         db,
+		CurrentBot: currentBot,
+	}
+	performanceRepository := repository.PerformanceRepository{
+		DB:         db,
+		CurrentBot: currentBot,
+	}
+	lotLedger := service.LotLedger{
+		LotRepository:      &lotRepository,
+		SwapRepository:     &swapRepository,
+		ExchangeRepository: &exchangeRepository,
+	}
+	orderEventRecorder := service.OrderEventRecorder{
+		OrderEventRepository: &orderEventRepository,
+		EventHub:             &eventHub,
+		NotifierRouter:       &notifierRouter,
+	}
 
 	formatter := service.Formatter{}
 	chartService := service.ChartService{
@@ -132,12 +214,9 @@ func InitServiceContainer() Container {
 	}
 
 	// Swap Settings
-	swapMinPercentValid := 1.15
-	swapOrderOnProfitPercent := -1.00
-	var swapOpenedSellOrderFromHoursOpened int64 = 2
-	//swapMinPercentValid := 0.1
-	//swapOrderOnProfitPercent := 10.00
-	//var swapOpenedSellOrderFromHoursOpened int64 = 0
+	swapMinPercentValid := config.Swap.MinPercentValid
+	swapOrderOnProfitPercent := config.Swap.OrderOnProfitPercent
+	swapOpenedSellOrderFromHoursOpened := config.Swap.OpenedSellOrderFromHoursOpened
 
 	swapValidator := service.SwapValidator{
 		Binance:        &binance,
@@ -146,31 +225,29 @@ func InitServiceContainer() Container {
 		SwapMinPercent: swapMinPercentValid,
 	}
 
-	lockTradeChannel := make(chan model.Lock)
-
-	// own net: ATOM, XMR, XLM, DOT, ADA, XRP
-	btcDependent := []string{"LTC", "ZEC", "ATOM", "XMR", "DOT", "XRP", "BCH", "ADA", "ETH", "DOGE", "PERP", "NEO"}
-	etcDependent := []string{"SHIB", "LINK", "UNI", "NEAR", "XLM", "ETC", "MATIC", "SOL", "BNB", "AVAX", "TRX"}
-
-	pythonMLBridge := service.PythonMLBridge{
+	pythonMLBridge := &service.PythonMLBridge{
 		DataSetBuilder: &service.DataSetBuilder{
-			ExcludeDependedDataset: []string{"SHIBUSDT", "BTCUSDT"},
-			BtcDependent:           btcDependent,
-			EthDependent:           etcDependent,
+			ExcludeDependedDataset: config.MachineLearning.ExcludeDependedDataset,
+			BtcDependent:           config.MachineLearning.BtcDependent,
+			EthDependent:           config.MachineLearning.EthDependent,
 		},
 		ExchangeRepository: &exchangeRepository,
 		SwapRepository:     &swapRepository,
 		CurrentBot:         currentBot,
 		RDB:                rdb,
 		Ctx:                &ctx,
-		Learning:           true,
+		Learning:           config.MachineLearning.Learning,
+	}
+	// python interpreter is initialized once per process, models are learned on shared market data
+	if market != nil {
+		pythonMLBridge = market.PythonMLBridge
 	}
 
 	timeService := service.TimeService{}
 
 	lossSecurity := service.LossSecurity{
-		MlEnabled:            true,
-		InterpolationEnabled: true,
+		MlEnabled:            config.LossSecurity.MlEnabled,
+		InterpolationEnabled: config.LossSecurity.InterpolationEnabled,
 		Formatter:            &formatter,
 		ExchangeRepository:   &exchangeRepository,
 		Binance:              &binance,
@@ -193,67 +270,169 @@ func InitServiceContainer() Container {
 		Formatter:          &formatter,
 	}
 
+	shutdownService := service.ShutdownService{}
+
 	orderExecutor := service.OrderExecutor{
-		TradeStack:         &tradeStack,
-		LossSecurity:       &lossSecurity,
-		CurrentBot:         currentBot,
-		TimeService:        &timeService,
-		BalanceService:     &balanceService,
-		Binance:            &binance,
-		OrderRepository:    &orderRepository,
-		ExchangeRepository: &exchangeRepository,
-		PriceCalculator:    &priceCalculator,
-		CallbackManager:    &callbackManager,
-		SwapRepository:     &swapRepository,
+		TradeStack:           &tradeStack,
+		LossSecurity:         &lossSecurity,
+		CurrentBot:           currentBot,
+		TimeService:          &timeService,
+		BalanceService:       &balanceService,
+		Binance:              &binance,
+		OrderRepository:      &orderRepository,
+		ExchangeRepository:   &exchangeRepository,
+		PriceCalculator:      &priceCalculator,
+		CallbackManager:      &callbackManager,
+		OrderEventRecorder:   &orderEventRecorder,
+		LotLedger:            &lotLedger,
+		ChildOrderRepository: &childOrderRepository,
+		ShutdownService:      &shutdownService,
+		SwapRepository:       &swapRepository,
 		SwapExecutor: &service.SwapExecutor{
-			BalanceService:  &balanceService,
-			SwapRepository:  &swapRepository,
-			OrderRepository: &orderRepository,
-			Binance:         &binance,
-			Formatter:       &formatter,
-			TimeService:     &timeService,
+			BalanceService:     &balanceService,
+			SwapRepository:     &swapRepository,
+			OrderRepository:    &orderRepository,
+			Binance:            &binance,
+			Formatter:          &formatter,
+			TimeService:        &timeService,
+			OrderEventRecorder: &orderEventRecorder,
+			ShutdownService:    &shutdownService,
+			LotLedger:          &lotLedger,
+			TimeInForce:        config.Swap.LegTimeInForce,
 		},
 		SwapValidator:          &swapValidator,
 		Formatter:              &formatter,
 		SwapSellOrderDays:      swapOpenedSellOrderFromHoursOpened,
 		SwapEnabled:            swapEnabled,
 		SwapProfitPercent:      swapOrderOnProfitPercent,
-		TurboSwapProfitPercent: 20.00,
+		TurboSwapProfitPercent: config.Swap.TurboSwapProfitPercent,
 		Lock:                   make(map[string]bool),
 		TradeLockMutex:         sync.RWMutex{},
-		LockChannel:            &lockTradeChannel,
 		CancelRequestMap:       make(map[string]bool),
 	}
 
+	decisionJournal := service.DecisionJournal{
+		DecisionJournalRepository: &repository.DecisionJournalRepository{
+			DB:         db,
+			CurrentBot: currentBot,
+		},
+		TimeService:   &timeService,
+		RepeatSeconds: config.Maker.JournalRepeatSeconds,
+		RetentionDays: config.Maker.JournalRetentionDays,
+	}
 	makerService := service.MakerService{
-		TradeStack:         &tradeStack,
-		OrderExecutor:      &orderExecutor,
+		TradeStack:            &tradeStack,
+		OrderExecutor:         &orderExecutor,
+		OrderRepository:       &orderRepository,
+		ExchangeRepository:    &exchangeRepository,
+		Binance:               &binance,
+		Formatter:             &formatter,
+		MinDecisions:          config.Maker.MinDecisions,
+		HoldScore:             config.Maker.HoldScore,
+		CurrentBot:            currentBot,
+		PriceCalculator:       &priceCalculator,
+		ShutdownService:       &shutdownService,
+		PerformanceRepository: &performanceRepository,
+		DecisionJournal:       &decisionJournal,
+	}
+
+	orderReconciler := service.OrderReconciler{
+		Binance:              &binance,
+		OrderRepository:      &orderRepository,
+		ExchangeRepository:   &exchangeRepository,
+		BalanceService:       &balanceService,
+		CallbackManager:      &callbackManager,
+		OrderEventRecorder:   &orderEventRecorder,
+		LotLedger:            &lotLedger,
+		ChildOrderRepository: &childOrderRepository,
+		TradeLock:            &orderExecutor,
+		TimeService:          &timeService,
+		ShutdownService:      &shutdownService,
+		CurrentBot:           currentBot,
+	}
+
+	gridService := service.GridService{
+		GridRepository: &repository.GridRepository{
+			DB:         db,
+			CurrentBot: currentBot,
+		},
 		OrderRepository:    &orderRepository,
 		ExchangeRepository: &exchangeRepository,
 		Binance:            &binance,
+		BalanceService:     &balanceService,
 		Formatter:          &formatter,
-		MinDecisions:       4.00,
-		HoldScore:          75.00,
-		CurrentBot:         currentBot,
+		LotLedger:          &lotLedger,
+		TimeService:        &timeService,
+		ShutdownService:    &shutdownService,
+	}
+
+	dcaService := service.DcaService{
+		DcaRepository: &repository.DcaRepository{
+			DB:         db,
+			CurrentBot: currentBot,
+		},
+		OrderExecutor:      &orderExecutor,
+		ExchangeRepository: &exchangeRepository,
 		PriceCalculator:    &priceCalculator,
+		Formatter:          &formatter,
+		TimeService:        &timeService,
 	}
 
-	orderController := controller.OrderController{
+	positionService := service.PositionService{
 		RDB:                rdb,
 		Ctx:                &ctx,
 		OrderRepository:    &orderRepository,
 		ExchangeRepository: &exchangeRepository,
 		Formatter:          &formatter,
 		PriceCalculator:    &priceCalculator,
-		CurrentBot:         currentBot,
-		LossSecurity:       &lossSecurity,
-		OrderExecutor:      &orderExecutor,
+	}
+
+	pnlService := service.PnlService{
+		OrderRepository:    &orderRepository,
+		SwapRepository:     &swapRepository,
+		ExchangeRepository: &exchangeRepository,
+	}
+	performanceService := service.PerformanceService{
+		OrderRepository:       &orderRepository,
+		PnlService:            &pnlService,
+		ExchangeRepository:    &exchangeRepository,
+		PerformanceRepository: &performanceRepository,
+	}
+	orderController := controller.OrderController{
+		RDB:                  rdb,
+		Ctx:                  &ctx,
+		OrderRepository:      &orderRepository,
+		ExchangeRepository:   &exchangeRepository,
+		OrderEventRepository: &orderEventRepository,
+		ChildOrderRepository: &childOrderRepository,
+		OrderEventRecorder:   &orderEventRecorder,
+		Formatter:            &formatter,
+		PriceCalculator:      &priceCalculator,
+		CurrentBot:           currentBot,
+		LossSecurity:         &lossSecurity,
+		OrderExecutor:        &orderExecutor,
+		OrderReconciler:      &orderReconciler,
+		PositionService:      &positionService,
+		PnlService:           &pnlService,
+		PerformanceService:   &performanceService,
+		LotLedger:            &lotLedger,
+	}
+
+	tradeLimitService := service.TradeLimitService{
+		ExchangeRepository: &exchangeRepository,
+		OrderRepository:    &orderRepository,
+		Binance:            &binance,
+		ChangeListener:     &performanceService,
 	}
 
 	tradeController := controller.TradeController{
 		CurrentBot:         currentBot,
 		ExchangeRepository: &exchangeRepository,
 		TradeStack:         &tradeStack,
+		TradeLimitService:  &tradeLimitService,
+		PerformanceService: &performanceService,
+		DecisionJournal:    &decisionJournal,
+		GridService:        &gridService,
 	}
 
 	swapManager := service.SwapManager{
@@ -277,7 +456,7 @@ func InitServiceContainer() Container {
 	baseKLineStrategy := service.BaseKLineStrategy{
 		ExchangeRepository: &exchangeRepository,
 		Formatter:          &formatter,
-		MlEnabled:          true,
+		MlEnabled:          config.MachineLearning.BaseKLineMlEnabled,
 	}
 	orderBasedStrategy := service.OrderBasedStrategy{
 		ExchangeRepository: exchangeRepository,
@@ -295,18 +474,21 @@ func InitServiceContainer() Container {
 		Binance:            &binance,
 	}
 
-	go func() {
-		for {
-			lock := <-lockTradeChannel
-			orderExecutor.TradeLockMutex.Lock()
-			orderExecutor.Lock[lock.Symbol] = lock.IsLocked
-			orderExecutor.TradeLockMutex.Unlock()
-		}
-	}()
+	hostname, _ := os.Hostname()
+	leaderElection := service.LeaderElection{
+		LeaderLeaseStorage: &repository.LeaderRepository{
+			RDB: rdb,
+			Ctx: &ctx,
+		},
+		Key:      service.LeaderMasterBotKey,
+		Identity: fmt.Sprintf("%s@%s:%d", currentBot.BotUuid, hostname, os.Getpid()),
+		LeaseTtl: time.Second * time.Duration(config.Schedule.LeaderLeaseSeconds),
+	}
 
 	healthService := service.HealthService{
+		LeaderElection:     &leaderElection,
 		ExchangeRepository: &exchangeRepository,
-		PythonMLBridge:     &pythonMLBridge,
+		PythonMLBridge:     pythonMLBridge,
 		Binance:            &binance,
 		CurrentBot:         currentBot,
 		DB:                 swapDb,
@@ -314,9 +496,38 @@ func InitServiceContainer() Container {
 		Ctx:                &ctx,
 	}
 
+	apiClientRepository := repository.ApiClientRepository{
+		DB:         db,
+		RDB:        rdb,
+		Ctx:        &ctx,
+		CurrentBot: currentBot,
+	}
+	apiGuard := controller.ApiGuard{
+		ApiAuthenticator: &service.ApiAuthenticator{
+			ApiClientRepository: &apiClientRepository,
+			CurrentBot:          currentBot,
+			RecvWindow:          config.Api.RecvWindow,
+			LegacyScope:         config.Api.LegacyScope,
+		},
+		ApiAuditRepository: &apiClientRepository,
+		CorsOrigins:        config.Api.CorsOrigins,
+	}
+	apiClientController := controller.ApiClientController{
+		ApiClientRepository: &apiClientRepository,
+	}
+	dcaController := controller.DcaController{
+		DcaService: &dcaService,
+	}
+
+	websocketController := controller.WebsocketController{
+		EventHub:    &eventHub,
+		CorsOrigins: config.Api.CorsOrigins,
+	}
+
 	botController := controller.BotController{
 		HealthService: &healthService,
 		CurrentBot:    currentBot,
+		Config:        &config,
 	}
 
 	return Container{
@@ -325,20 +536,34 @@ func InitServiceContainer() Container {
 		HealthService:       &healthService,
 		Db:                  db,
 		DbSwap:              swapDb,
+		RDB:                 rdb,
 		CurrentBot:          currentBot,
 		CallbackManager:     &callbackManager,
+		NotifierRouter:      &notifierRouter,
+		DecisionJournal:     &decisionJournal,
+		GridService:         &gridService,
+		DcaService:          &dcaService,
 		BalanceService:      &balanceService,
 		TimeService:         &timeService,
 		Binance:             &binance,
-		PythonMLBridge:      &pythonMLBridge,
+		PythonMLBridge:      pythonMLBridge,
 		SwapRepository:      &swapRepository,
 		ExchangeRepository:  &exchangeRepository,
 		OrderRepository:     &orderRepository,
 		ExchangeController:  &exchangeController,
 		TradeController:     &tradeController,
 		OrderController:     &orderController,
+		ApiClientController: &apiClientController,
+		DcaController:       &dcaController,
+		WebsocketController: &websocketController,
+		ApiClientRepository: &apiClientRepository,
+		EventHub:            &eventHub,
+		PositionService:     &positionService,
+		ApiGuard:            &apiGuard,
 		MakerService:        &makerService,
 		OrderExecutor:       &orderExecutor,
+		OrderReconciler:     &orderReconciler,
+		ShutdownService:     &shutdownService,
 		SwapManager:         &swapManager,
 		SwapUpdater:         &swapUpdater,
 		SmaTradeStrategy:    &smaStrategy,
@@ -346,6 +571,8 @@ func InitServiceContainer() Container {
 		OrderBasedStrategy:  &orderBasedStrategy,
 		BaseKLineStrategy:   &baseKLineStrategy,
 		IsMasterBot:         isMasterBot,
+		LeaderElection:      &leaderElection,
+		Config:              &config,
 	}
 }
 
@@ -355,8 +582,17 @@ type Container struct {
 	HealthService       *service.HealthService
 	Db                  *sql.DB
 	DbSwap              *sql.DB
+	RDB                 *redis.Client
+	HttpServer          *http.Server
 	CurrentBot          *model.Bot
+	BotRepository       *repository.BotRepository
+	AccountService      *service.AccountService
+	Accounts            []*Container
 	CallbackManager     *service.CallbackManager
+	NotifierRouter      *service.NotifierRouter
+	DecisionJournal     *service.DecisionJournal
+	GridService         *service.GridService
+	DcaService          *service.DcaService
 	BalanceService      *service.BalanceService
 	TimeService         *service.TimeService
 	Binance             *client.Binance
@@ -367,8 +603,17 @@ type Container struct {
 	ExchangeController  *controller.ExchangeController
 	TradeController     *controller.TradeController
 	OrderController     *controller.OrderController
+	ApiClientController *controller.ApiClientController
+	DcaController       *controller.DcaController
+	WebsocketController *controller.WebsocketController
+	ApiClientRepository *repository.ApiClientRepository
+	EventHub            *event.Hub
+	PositionService     *service.PositionService
+	ApiGuard            *controller.ApiGuard
 	MakerService        *service.MakerService
 	OrderExecutor       *service.OrderExecutor
+	OrderReconciler     *service.OrderReconciler
+	ShutdownService     *service.ShutdownService
 	SwapManager         *service.SwapManager
 	SwapUpdater         *service.SwapUpdater
 	SmaTradeStrategy    *service.SmaTradeStrategy
@@ -376,29 +621,180 @@ type Container struct {
 	BaseKLineStrategy   *service.BaseKLineStrategy
 	OrderBasedStrategy  *service.OrderBasedStrategy
 	IsMasterBot         bool
+	LeaderElection      *service.LeaderElection
+	Config              *model.Config
+}
+
+// GetRoutes returns API routes, they are served with /api/v1 prefix and by legacy path
+func (c *Container) GetRoutes() []controller.Route {
+	read := model.ApiScopeRead
+	trade := model.ApiScopeTrade
+	admin := model.ApiScopeAdmin
+
+	return []controller.Route{
+		{Method: "GET", Path: "/kline/list/", Scope: read, Tag: "exchange", Summary: "KLine list by symbol", Handler: c.ExchangeController.GetKlineListAction},
+		{Method: "GET", Path: "/depth/", Scope: read, Tag: "exchange", Summary: "Order book depth by symbol", Handler: c.ExchangeController.GetDepthAction},
+		{Method: "GET", Path: "/trade/list/", Scope: read, Tag: "exchange", Summary: "Trade list by symbol", Handler: c.ExchangeController.GetTradeListAction},
+		{Method: "GET", Path: "/swap/list", Scope: read, Tag: "exchange", Summary: "Swap chain list", Handler: c.ExchangeController.GetSwapListAction},
+		{Method: "GET", Path: "/swap/action/list", Scope: read, Tag: "exchange", Summary: "The latest swap actions with leg statuses", Query: []string{"limit"}, Handler: c.ExchangeController.GetSwapActionListAction},
+		{Method: "GET", Path: "/swap/action", Scope: read, Tag: "exchange", Summary: "Swap action with leg statuses", Query: []string{"id"}, Handler: c.ExchangeController.GetSwapActionAction},
+		{Method: "GET", Path: "/chart/list", Scope: read, Tag: "exchange", Summary: "Chart data", Query: []string{"symbol"}, Handler: c.ExchangeController.GetChartListAction},
+		{Method: "GET", Path: "/order/list", Scope: read, Tag: "order", Summary: "Opened order list", Handler: c.OrderController.GetOrderListAction},
+		{Method: "PUT", Path: "/order/extra/charge/update", Scope: trade, Tag: "order", Summary: "Update order extra charge", Handler: c.OrderController.UpdateExtraChargeAction},
+		{Method: "GET", Path: "/order/pending/list", Scope: read, Tag: "order", Summary: "Pending order list", Handler: c.OrderController.GetPendingOrderListAction},
+		{Method: "GET", Path: "/order/position/list", Scope: read, Tag: "order", Summary: "Position list", Handler: c.OrderController.GetPositionListAction},
+		{Method: "POST", Path: "/order", Scope: trade, Tag: "order", Summary: "Create manual order", Handler: c.OrderController.PostManualOrderAction},
+		{Method: "GET", Path: "/order/trade/list", Scope: read, Tag: "order", Summary: "Order trade list", Handler: c.OrderController.GetOrderTradeListAction},
+		{Method: "GET", Path: "/order/pnl", Scope: read, Tag: "order", Summary: "Realized PnL by period and symbol, unrealized PnL and fee totals", Query: []string{"period"}, Handler: c.OrderController.GetPnlReportAction},
+		{Method: "GET", Path: "/order/capital-gains", Scope: read, Tag: "order", Summary: "Capital gains of disposals matched with lots (FIFO, LIFO or average cost) as JSON or CSV", Query: []string{"method", "from", "to", "format"}, Handler: c.OrderController.GetCapitalGainsAction},
+		{Method: "GET", Path: "/order/pnl/equity", Scope: read, Tag: "order", Summary: "Equity curve (cumulative PnL)", Handler: c.OrderController.GetEquityCurveAction},
+		{Method: "GET", Path: "/order/performance", Scope: read, Tag: "order", Summary: "Win rate, profit factor, Sharpe/Sortino, drawdown and capital utilisation overall, per symbol and per strategy", Query: []string{"from", "to"}, Handler: c.OrderController.GetPerformanceAction},
+		{Method: "GET", Path: "/order/events", Scope: read, Tag: "order", Summary: "Order event list", Query: []string{"orderId"}, Handler: c.OrderController.GetOrderEventListAction},
+		{Method: "GET", Path: "/order/children", Scope: read, Tag: "order", Summary: "Child orders of order executed by TWAP or iceberg algorithm", Query: []string{"orderId"}, Handler: c.OrderController.GetChildOrderListAction},
+		{Method: "GET", Path: "/order/reconciliation", Scope: read, Tag: "order", Summary: "Last reconciliation report", Handler: c.OrderController.GetReconciliationReportAction},
+		{Method: "GET", Path: "/trade/limit/list", Scope: read, Tag: "trade", Summary: "Trade limit list", Handler: c.TradeController.GetTradeLimitsAction},
+		{Method: "GET", Path: "/trade/stack", Scope: read, Tag: "trade", Summary: "Trade stack", Handler: c.TradeController.GetTradeStackAction},
+		{Method: "POST", Path: "/trade/limit/create", Scope: admin, Tag: "trade", Summary: "Create trade limit", Handler: c.TradeController.CreateTradeLimitAction},
+		{Method: "PUT", Path: "/trade/limit/update", Scope: admin, Tag: "trade", Summary: "Update trade limit", Handler: c.TradeController.UpdateTradeLimitAction},
+		{Method: "DELETE", Path: "/trade/limit/delete", Scope: admin, Tag: "trade", Summary: "Delete trade limit without opened position", Query: []string{"symbol"}, Handler: c.TradeController.DeleteTradeLimitAction},
+		{Method: "POST", Path: "/trade/limit/archive", Scope: admin, Tag: "trade", Summary: "Archive trade limit without opened position", Query: []string{"symbol"}, Handler: c.TradeController.ArchiveTradeLimitAction},
+		{Method: "GET", Path: "/trade/limit/export", Scope: read, Tag: "trade", Summary: "Export trade limits as JSON or CSV", Query: []string{"format"}, Handler: c.TradeController.ExportTradeLimitsAction},
+		{Method: "POST", Path: "/trade/limit/import", Scope: admin, Tag: "trade", Summary: "Import trade limits from JSON or CSV validated by exchange filters", Query: []string{"format", "dryRun"}, Handler: c.TradeController.ImportTradeLimitsAction},
+		{Method: "GET", Path: "/trade/limit/performance", Scope: read, Tag: "trade", Summary: "Performance of current trade limit configuration compared with previous snapshots", Query: []string{"symbol"}, Handler: c.TradeController.GetTradeLimitPerformanceAction},
+		{Method: "POST", Path: "/trade/limit/performance/snapshot", Scope: admin, Tag: "trade", Summary: "Close trade limit performance period by manual snapshot", Query: []string{"symbol"}, Handler: c.TradeController.CreatePerformanceSnapshotAction},
+		{Method: "GET", Path: "/grid/list", Scope: read, Tag: "trade", Summary: "Grids of trade limits in grid mode with levels, trades, fees and realized PnL", Query: []string{"symbol"}, Handler: c.TradeController.GetGridListAction},
+		{Method: "GET", Path: "/dca/plan/list", Scope: read, Tag: "dca", Summary: "DCA accumulation plans with spent budget, bought quantity and average price", Handler: c.DcaController.GetPlanListAction},
+		{Method: "POST", Path: "/dca/plan/create", Scope: trade, Tag: "dca", Summary: "Create DCA plan for symbol with trade limit", Handler: c.DcaController.CreatePlanAction},
+		{Method: "PUT", Path: "/dca/plan/update", Scope: trade, Tag: "dca", Summary: "Update DCA plan settings or status (active, paused)", Handler: c.DcaController.UpdatePlanAction},
+		{Method: "GET", Path: "/dca/plan/orders", Scope: read, Tag: "dca", Summary: "Executed orders of DCA plan", Query: []string{"planId", "limit"}, Handler: c.DcaController.GetOrderListAction},
+		{Method: "GET", Path: "/decision/journal", Scope: read, Tag: "trade", Summary: "Journal of maker decisions (executed, skipped, failed) with strategy scores and price calculation", Query: []string{"symbol", "limit"}, Handler: c.TradeController.GetDecisionJournalAction},
+		{Method: "GET", Path: "/health/check", Scope: read, Tag: "bot", Summary: "Bot health check", Handler: c.BotController.GetHealthCheck},
+		{Method: "GET", Path: "/config", Scope: admin, Tag: "bot", Summary: "Bot config without secrets", Handler: c.BotController.GetConfigAction},
+		{Method: "GET", Path: "/ws", Scope: read, Tag: "stream", Summary: "WebSocket push of positions, decisions, order and swap events", Query: []string{"topics", "symbols"}, Handler: c.WebsocketController.GetStreamAction},
+		{Method: "GET", Path: "/client/list", LegacyPath: "/api/client/list", Scope: admin, Tag: "api", Summary: "API client list", Handler: c.ApiClientController.GetApiClientListAction},
+		{Method: "POST", Path: "/client/create", LegacyPath: "/api/client/create", Scope: admin, Tag: "api", Summary: "Create API client", Handler: c.ApiClientController.PostApiClientAction},
+		{Method: "POST", Path: "/client/disable", LegacyPath: "/api/client/disable", Scope: admin, Tag: "api", Summary: "Disable API client", Query: []string{"id"}, Handler: c.ApiClientController.DisableApiClientAction},
+		{Method: "GET", Path: "/audit/list", LegacyPath: "/api/audit/list", Scope: admin, Tag: "api", Summary: "API audit list", Query: []string{"limit"}, Handler: c.ApiClientController.GetApiAuditListAction},
+	}
 }
 
 func (c *Container) StartHttpServer() {
-	// configure controllers
-	http.HandleFunc("/kline/list/", c.ExchangeController.GetKlineListAction)
-	http.HandleFunc("/depth/", c.ExchangeController.GetDepthAction)
-	http.HandleFunc("/trade/list/", c.ExchangeController.GetTradeListAction)
-	http.HandleFunc("/swap/list", c.ExchangeController.GetSwapListAction)
-	http.HandleFunc("/chart/list", c.ExchangeController.GetChartListAction)
-	http.HandleFunc("/order/list", c.OrderController.GetOrderListAction)
-	http.HandleFunc("/order/extra/charge/update", c.OrderController.UpdateExtraChargeAction)
-	http.HandleFunc("/order/pending/list", c.OrderController.GetPendingOrderListAction)
-	http.HandleFunc("/order/position/list", c.OrderController.GetPositionListAction)
-	http.HandleFunc("/order", c.OrderController.PostManualOrderAction)
-	http.HandleFunc("/order/trade/list", c.OrderController.GetOrderTradeListAction)
-	http.HandleFunc("/trade/limit/list", c.TradeController.GetTradeLimitsAction)
-	http.HandleFunc("/trade/stack", c.TradeController.GetTradeStackAction)
-	http.HandleFunc("/trade/limit/create", c.TradeController.CreateTradeLimitAction)
-	http.HandleFunc("/trade/limit/update", c.TradeController.UpdateTradeLimitAction)
-	http.HandleFunc("/health/check", c.BotController.GetHealthCheck)
+	// configure controllers, request is handled by bot (account) container
+	routers := make(map[string]http.Handler)
+	// legacy path -> bot uuid -> handler, request method is routed by the same handler as versioned API
+	legacyHandlers := make(map[string]map[string]http.Handler)
+	for _, bot := range c.GetBots() {
+		routes := bot.GetRoutes()
+		legacyRoutes := make(map[string][]controller.Route)
+		for _, route := range routes {
+			legacyRoutes[route.GetLegacyPath()] = append(legacyRoutes[route.GetLegacyPath()], route)
+		}
+		for path, pathRoutes := range legacyRoutes {
+			if legacyHandlers[path] == nil {
+				legacyHandlers[path] = make(map[string]http.Handler)
+			}
+			legacyHandlers[path][bot.CurrentBot.BotUuid] = controller.MethodHandler(bot.ApiGuard, pathRoutes)
+		}
+
+		routers[bot.CurrentBot.BotUuid] = &controller.Router{
+			Prefix:   controller.ApiV1Prefix,
+			ApiGuard: bot.ApiGuard,
+			Routes:   routes,
+			Title:    "Go crypto bot API",
+			Version:  "1.0.0",
+		}
+	}
+
+	for path, handlers := range legacyHandlers {
+		http.Handle(path, c.GetBotHandler(handlers))
+	}
+	http.Handle(controller.ApiV1Prefix+"/", c.GetBotHandler(routers))
+	http.Handle("/metrics", promhttp.Handler())
 
 	// Start HTTP server!
+	c.HttpServer = &http.Server{Addr: fmt.Sprintf(":%d", c.Config.Http.Port)}
 	go func() {
-		_ = http.ListenAndServe(":8080", nil)
+		err := c.HttpServer.ListenAndServe()
+		if err != nil && err != http.ErrServerClosed {
+			log.Printf("HTTP server: %s", err.Error())
+		}
 	}()
 }
+
+// GetBotHandler selects bot handler by X-BOT-UUID header or botUuid query, main bot handles request by default
+func (c *Container) GetBotHandler(handlers map[string]http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		botUuid := req.Header.Get(model.ApiHeaderBotUuid)
+		if botUuid == "" {
+			botUuid = req.URL.Query().Get("botUuid")
+		}
+
+		handler, ok := handlers[botUuid]
+		if !ok {
+			handler = handlers[c.CurrentBot.BotUuid]
+		}
+
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// Shutdown closes HTTP server, websocket API and storage connections
+func (c *Container) Shutdown(timeout time.Duration) {
+	if c.HttpServer != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		err := c.HttpServer.Shutdown(ctx)
+		if err != nil {
+			log.Printf("HTTP server shutdown: %s", err.Error())
+		}
+	}
+
+	for _, bot := range c.GetBots() {
+		bot.Binance.Close()
+	}
+
+	err := c.RDB.Close()
+	if err != nil {
+		log.Printf("Redis close: %s", err.Error())
+	}
+
+	err = c.Db.Close()
+	if err != nil {
+		log.Printf("MySQL close: %s", err.Error())
+	}
+
+	err = c.DbSwap.Close()
+	if err != nil {
+		log.Printf("MySQL swap close: %s", err.Error())
+	}
+}
+
+// getNotifierChannels builds configured channels, legacy autotrade host is kept as channel for buy, sell and error
+func getNotifierChannels(config model.Config) []service.NotifierChannel {
+	channelConfigs := slices.Clone(config.Notifier.Channels)
+	hasAutoTrade := slices.ContainsFunc(channelConfigs, func(channel model.NotifierChannelConfig) bool {
+		return channel.Type == model.NotifierTypeAutoTrade
+	})
+	if config.AutoTrade.Host != "" && !hasAutoTrade {
+		channelConfigs = append(channelConfigs, model.NotifierChannelConfig{
+			Name:   model.NotifierTypeAutoTrade,
+			Type:   model.NotifierTypeAutoTrade,
+			Events: []string{model.NotificationEventBuy, model.NotificationEventSell, model.NotificationEventError},
+			Url:    config.AutoTrade.Host,
+		})
+	}
+
+	httpClient := &http.Client{Timeout: time.Second * 10}
+	channels := make([]service.NotifierChannel, 0)
+	for _, channelConfig := range channelConfigs {
+		channel, err := service.NewNotifierChannel(channelConfig, httpClient)
+		if err != nil {
+			log.Printf("Notifier channel %s is skipped: %s", channelConfig.Name, err.Error())
+			continue
+		}
+		channels = append(channels, *channel)
+	}
+
+	return channels
+}
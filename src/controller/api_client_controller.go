@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+type ApiClientController struct {
+	ApiClientRepository *repository.ApiClientRepository
+}
+
+func (a *ApiClientController) GetApiClientListAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	encoded, _ := json.Marshal(a.ApiClientRepository.GetList())
+	fmt.Fprintf(w, string(encoded))
+}
+
+func (a *ApiClientController) PostApiClientAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var request struct {
+		Name  string `json:"name"`
+		Scope string `json:"scope"`
+	}
+	body, _ := io.ReadAll(req.Body)
+	err := json.Unmarshal(body, &request)
+	if err != nil || request.Name == "" {
+		http.Error(w, "Name and scope are required", http.StatusBadRequest)
+
+		return
+	}
+
+	apiClient, err := service.GenerateApiClient(request.Name, request.Scope)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	id, err := a.ApiClientRepository.Create(apiClient)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+	apiClient.Id = *id
+
+	// secret is shown only once
+	encoded, _ := json.Marshal(model.ApiClientCredentials{
+		ApiClient: apiClient,
+		ApiSecret: apiClient.ApiSecret,
+	})
+	fmt.Fprintf(w, string(encoded))
+}
+
+func (a *ApiClientController) DisableApiClientAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	id, err := strconv.ParseInt(req.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "id is required", http.StatusBadRequest)
+
+		return
+	}
+
+	err = a.ApiClientRepository.Disable(id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	fmt.Fprintf(w, "OK")
+}
+
+func (a *ApiClientController) GetApiAuditListAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, err := strconv.ParseInt(req.URL.Query().Get("limit"), 10, 64)
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	encoded, _ := json.Marshal(a.ApiClientRepository.GetAuditList(limit))
+	fmt.Fprintf(w, string(encoded))
+}
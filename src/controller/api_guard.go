@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// ApiGuard sets CORS headers, authorizes request and writes audit for mutating calls
+type ApiGuard struct {
+	ApiAuthenticator   service.ApiAuthenticatorInterface
+	ApiAuditRepository repository.ApiAuditStorageInterface
+	CorsOrigins        []string
+}
+
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusResponseWriter) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+// Protect requires given scope, mutating (not GET) requests are written to audit
+func (g *ApiGuard) Protect(scope string, handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		g.setCorsHeaders(w, req)
+
+		if req.Method == "OPTIONS" {
+			fmt.Fprintf(w, "OK")
+			return
+		}
+
+		apiClient, err := g.ApiAuthenticator.Authenticate(req, scope)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+
+			return
+		}
+
+		if req.Method == "GET" {
+			handler(w, req)
+
+			return
+		}
+
+		writer := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler(writer, req)
+		g.audit(apiClient, req, writer.status)
+	}
+}
+
+func (g *ApiGuard) audit(apiClient *model.ApiClient, req *http.Request, status int) {
+	audit := model.ApiAudit{
+		ClientName: apiClient.Name,
+		Method:     req.Method,
+		Path:       req.URL.Path,
+		Status:     status,
+		RemoteAddr: req.RemoteAddr,
+	}
+	if !apiClient.IsLegacy() {
+		audit.ApiClientId = &apiClient.Id
+	}
+	if req.URL.RawQuery != "" {
+		query := req.URL.Query()
+		query.Del("botUuid")
+		encoded := query.Encode()
+		audit.Query = &encoded
+	}
+
+	_, err := g.ApiAuditRepository.CreateAudit(audit)
+	if err != nil {
+		log.Printf("API audit [%s %s] is not saved: %s", req.Method, req.URL.Path, err.Error())
+	}
+}
+
+func (g *ApiGuard) setCorsHeaders(w http.ResponseWriter, req *http.Request) {
+	origin := req.Header.Get("Origin")
+
+	if slices.Contains(g.CorsOrigins, "*") {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+	} else if origin != "" && slices.Contains(g.CorsOrigins, origin) {
+		w.Header().Set("Access-Control-Allow-Origin", origin)
+		w.Header().Set("Vary", "Origin")
+	}
+
+	w.Header().Set("Access-Control-Allow-Headers", strings.Join([]string{
+		"Content-Type",
+		model.ApiHeaderKey,
+		model.ApiHeaderTimestamp,
+		model.ApiHeaderSignature,
+		model.ApiHeaderBotUuid,
+	}, ", "))
+}
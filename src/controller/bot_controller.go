@@ -11,22 +11,21 @@ import (
 type BotController struct {
 	HealthService *service.HealthService
 	CurrentBot    *model.Bot
+	Config        *model.Config
 }
 
 func (b *BotController) GetHealthCheck(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	botUuid := req.URL.Query().Get("botUuid")
-
-	if botUuid != b.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-
-		return
-	}
 	health := b.HealthService.HealthCheck()
 
 	encoded, _ := json.Marshal(health)
 	fmt.Fprintf(w, string(encoded))
 }
+
+func (b *BotController) GetConfigAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	encoded, _ := json.Marshal(b.Config.Redacted())
+	fmt.Fprintf(w, string(encoded))
+}
@@ -0,0 +1,87 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+type DcaController struct {
+	DcaService *service.DcaService
+}
+
+func (d *DcaController) GetPlanListAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	encoded, _ := json.Marshal(d.DcaService.GetPlans())
+	fmt.Fprintf(w, string(encoded))
+}
+
+func (d *DcaController) CreatePlanAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var plan model.DcaPlan
+	err := json.NewDecoder(req.Body).Decode(&plan)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+	plan.Symbol = strings.ToUpper(plan.Symbol)
+
+	plan, err = d.DcaService.CreatePlan(plan)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	encoded, _ := json.Marshal(plan)
+	fmt.Fprintf(w, string(encoded))
+}
+
+func (d *DcaController) UpdatePlanAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	var plan model.DcaPlan
+	err := json.NewDecoder(req.Body).Decode(&plan)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+	plan.Symbol = strings.ToUpper(plan.Symbol)
+
+	plan, err = d.DcaService.UpdatePlan(plan)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
+	encoded, _ := json.Marshal(plan)
+	fmt.Fprintf(w, string(encoded))
+}
+
+func (d *DcaController) GetOrderListAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	planId, err := strconv.ParseInt(req.URL.Query().Get("planId"), 10, 64)
+	if err != nil {
+		http.Error(w, "planId is required", http.StatusBadRequest)
+
+		return
+	}
+
+	limit, err := strconv.ParseInt(req.URL.Query().Get("limit"), 10, 64)
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	encoded, _ := json.Marshal(d.DcaService.GetOrders(planId, limit))
+	fmt.Fprintf(w, string(encoded))
+}
@@ -9,6 +9,7 @@ import (
 	ExchangeRepository "gitlab.com/open-soft/go-crypto-bot/src/repository"
 	"gitlab.com/open-soft/go-crypto-bot/src/service"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -23,18 +24,8 @@ type ExchangeController struct {
 }
 
 func (e *ExchangeController) GetKlineListAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	botUuid := req.URL.Query().Get("botUuid")
-
-	if botUuid != e.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-
-		return
-	}
-
 	symbol := strings.TrimPrefix(req.URL.Path, "/kline/list/")
 
 	list := e.ExchangeRepository.KLineList(symbol, true, 200)
@@ -43,18 +34,8 @@ func (e *ExchangeController) GetKlineListAction(w http.ResponseWriter, req *http
 }
 
 func (e *ExchangeController) GetDepthAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	botUuid := req.URL.Query().Get("botUuid")
-
-	if botUuid != e.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-
-		return
-	}
-
 	symbol := strings.TrimPrefix(req.URL.Path, "/depth/")
 
 	list := e.ExchangeRepository.GetDepth(symbol)
@@ -63,18 +44,8 @@ func (e *ExchangeController) GetDepthAction(w http.ResponseWriter, req *http.Req
 }
 
 func (e *ExchangeController) GetTradeListAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	botUuid := req.URL.Query().Get("botUuid")
-
-	if botUuid != e.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-
-		return
-	}
-
 	symbol := strings.TrimPrefix(req.URL.Path, "/trade/list/")
 
 	list := e.ExchangeRepository.TradeList(symbol)
@@ -83,36 +54,50 @@ func (e *ExchangeController) GetTradeListAction(w http.ResponseWriter, req *http
 }
 
 func (e *ExchangeController) GetSwapListAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	botUuid := req.URL.Query().Get("botUuid")
+	list := e.SwapRepository.GetAvailableSwapChains()
+	encoded, _ := json.Marshal(list)
+	fmt.Fprintf(w, string(encoded))
+}
 
-	if botUuid != e.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+func (e *ExchangeController) GetSwapActionListAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-		return
+	limit, err := strconv.ParseInt(req.URL.Query().Get("limit"), 10, 64)
+	if err != nil || limit <= 0 {
+		limit = 50
 	}
 
-	list := e.SwapRepository.GetAvailableSwapChains()
+	list := e.SwapRepository.GetSwapActions(limit)
 	encoded, _ := json.Marshal(list)
 	fmt.Fprintf(w, string(encoded))
 }
 
-func (e *ExchangeController) GetChartListAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+func (e *ExchangeController) GetSwapActionAction(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	botUuid := req.URL.Query().Get("botUuid")
+	id, err := strconv.ParseInt(req.URL.Query().Get("id"), 10, 64)
+	if err != nil {
+		http.Error(w, "id is required", http.StatusBadRequest)
+
+		return
+	}
 
-	if botUuid != e.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+	action, err := e.SwapRepository.GetSwapAction(id)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Swap action %d is not found", id), http.StatusNotFound)
 
 		return
 	}
 
+	encoded, _ := json.Marshal(action)
+	fmt.Fprintf(w, string(encoded))
+}
+
+func (e *ExchangeController) GetChartListAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
 	symbol := req.URL.Query().Get("symbol")
 
 	symbolFilter := make([]string, 0)
@@ -10,169 +10,117 @@ import (
 	"gitlab.com/open-soft/go-crypto-bot/src/service"
 	"net/http"
 	"slices"
-	"time"
+	"strconv"
 )
 
 type OrderController struct {
-	RDB                *redis.Client
-	Ctx                *context.Context
-	OrderRepository    *ExchangeRepository.OrderRepository
-	ExchangeRepository *ExchangeRepository.ExchangeRepository
-	Formatter          *service.Formatter
-	PriceCalculator    *service.PriceCalculator
-	CurrentBot         *model.Bot
-	LossSecurity       *service.LossSecurity
-	OrderExecutor      *service.OrderExecutor
+	RDB                  *redis.Client
+	Ctx                  *context.Context
+	OrderRepository      *ExchangeRepository.OrderRepository
+	ExchangeRepository   *ExchangeRepository.ExchangeRepository
+	OrderEventRepository *ExchangeRepository.OrderEventRepository
+	ChildOrderRepository *ExchangeRepository.ChildOrderRepository
+	OrderEventRecorder   *service.OrderEventRecorder
+	Formatter            *service.Formatter
+	PriceCalculator      *service.PriceCalculator
+	CurrentBot           *model.Bot
+	LossSecurity         *service.LossSecurity
+	OrderExecutor        *service.OrderExecutor
+	OrderReconciler      *service.OrderReconciler
+	PositionService      *service.PositionService
+	PnlService           *service.PnlService
+	PerformanceService   *service.PerformanceService
+	LotLedger            *service.LotLedger
 }
 
 func (o *OrderController) GetOrderTradeListAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	if req.Method == "OPTIONS" {
-		fmt.Fprintf(w, "OK")
-		return
-	}
+	list := o.OrderRepository.GetTrades()
+	encoded, _ := json.Marshal(list)
+	fmt.Fprintf(w, string(encoded))
+}
 
-	if req.Method != "GET" {
-		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+func (o *OrderController) GetPnlReportAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-		return
+	period := req.URL.Query().Get("period")
+	if period == "" {
+		period = model.PnlPeriodDay
 	}
 
-	botUuid := req.URL.Query().Get("botUuid")
-
-	if botUuid != o.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+	report, err := o.PnlService.GetReport(period)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 
 		return
 	}
 
-	list := o.OrderRepository.GetTrades()
-	encoded, _ := json.Marshal(list)
+	encoded, _ := json.Marshal(report)
 	fmt.Fprintf(w, string(encoded))
 }
 
-func (o *OrderController) GetPositionListAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+func (o *OrderController) GetEquityCurveAction(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if req.Method == "OPTIONS" {
-		fmt.Fprintf(w, "OK")
-		return
-	}
+	encoded, _ := json.Marshal(o.PnlService.GetEquityCurve())
+	fmt.Fprintf(w, string(encoded))
+}
 
-	botUuid := req.URL.Query().Get("botUuid")
+// GetCapitalGainsAction matches disposals from date range with lots (?method=fifo|lifo|average&from=2024-01-01&to=2024-12-31&format=csv)
+func (o *OrderController) GetPerformanceAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	if botUuid != o.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+	summary, err := o.PerformanceService.GetSummary(req.URL.Query().Get("from"), req.URL.Query().Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 
 		return
 	}
 
-	if req.Method != "GET" {
-		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
+	encoded, _ := json.Marshal(summary)
+	fmt.Fprintf(w, string(encoded))
+}
 
-		return
+func (o *OrderController) GetCapitalGainsAction(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	method := query.Get("method")
+	if method == "" {
+		method = model.LotMethodFifo
 	}
 
-	positions := make([]model.Position, 0)
+	gains, err := o.LotLedger.GetCapitalGains(method, query.Get("from"), query.Get("to"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 
-	for _, limit := range o.ExchangeRepository.GetTradeLimits() {
-		openedOrder, err := o.OrderRepository.GetOpenedOrderCached(limit.Symbol, "BUY")
-		if err != nil {
-			continue
-		}
+		return
+	}
 
-		kLine := o.ExchangeRepository.GetLastKLine(limit.Symbol)
-		if kLine == nil {
-			continue
-		}
+	if query.Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=capital_gains_%s_%s_%s.csv", method, query.Get("from"), query.Get("to")))
+		_ = o.LotLedger.WriteCsv(w, gains)
 
-		var sellPrice float64
-
-		binanceOrder := o.OrderRepository.GetBinanceOrder(openedOrder.Symbol, "SELL")
-		executedQty := 0.00
-		origQty := openedOrder.ExecutedQuantity
-
-		if binanceOrder != nil {
-			sellPrice = binanceOrder.Price
-			origQty = binanceOrder.OrigQty
-			executedQty = binanceOrder.ExecutedQty
-		} else {
-			sellPriceCacheKey := fmt.Sprintf("sell-price-%d", openedOrder.Id)
-			sellPriceCached := o.RDB.Get(*o.Ctx, sellPriceCacheKey).Val()
-			if len(sellPriceCached) > 0 {
-				_ = json.Unmarshal([]byte(sellPriceCached), &sellPrice)
-			} else {
-				sellPrice = o.PriceCalculator.CalculateSell(limit, openedOrder)
-				encoded, _ := json.Marshal(sellPrice)
-				o.RDB.Set(*o.Ctx, sellPriceCacheKey, string(encoded), time.Hour)
-			}
-		}
+		return
+	}
 
-		predictedPrice, err := o.ExchangeRepository.GetPredict(limit.Symbol)
-		if predictedPrice > 0.00 {
-			predictedPrice = o.Formatter.FormatPrice(limit, predictedPrice)
-		}
+	w.Header().Set("Content-Type", "application/json")
+	encoded, _ := json.Marshal(gains)
+	fmt.Fprintf(w, string(encoded))
+}
 
-		interpolation := o.PriceCalculator.InterpolatePrice(limit.Symbol)
-		if interpolation.BtcInterpolationUsdt > 0.00 {
-			interpolation.BtcInterpolationUsdt = o.Formatter.FormatPrice(limit, interpolation.BtcInterpolationUsdt)
-		}
-		if interpolation.EthInterpolationUsdt > 0.00 {
-			interpolation.EthInterpolationUsdt = o.Formatter.FormatPrice(limit, interpolation.EthInterpolationUsdt)
-		}
+func (o *OrderController) GetPositionListAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-		positions = append(positions, model.Position{
-			Symbol:         limit.Symbol,
-			Order:          openedOrder,
-			KLine:          *kLine,
-			Percent:        openedOrder.GetProfitPercent(kLine.Close),
-			SellPrice:      sellPrice,
-			Profit:         o.Formatter.ToFixed(openedOrder.GetQuoteProfit(kLine.Close), 2),
-			TargetProfit:   o.Formatter.ToFixed(openedOrder.GetQuoteProfit(sellPrice), 2),
-			PredictedPrice: predictedPrice,
-			Interpolation:  interpolation,
-			ExecutedQty:    executedQty,
-			OrigQty:        origQty,
-			ManualOrderConfig: model.ManualOrderConfig{
-				PriceStep:     limit.MinPrice,
-				MinClosePrice: openedOrder.GetManualMinClosePrice(),
-			},
-		})
-	}
+	positions := o.PositionService.GetPositions()
 
 	encoded, _ := json.Marshal(positions)
 	fmt.Fprintf(w, string(encoded))
 }
 
 func (o *OrderController) UpdateExtraChargeAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	if req.Method == "OPTIONS" {
-		fmt.Fprintf(w, "OK")
-		return
-	}
-
-	botUuid := req.URL.Query().Get("botUuid")
-
-	if botUuid != o.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-
-		return
-	}
-
-	if req.Method != "PUT" {
-		http.Error(w, "Only PUT method is allowed", http.StatusMethodNotAllowed)
-
-		return
-	}
-
 	var options model.UpdateOrderExtraChargeOptions
 
 	// Try to decode the request body into the struct. If there is an error,
@@ -226,29 +174,8 @@ func (o *OrderController) UpdateExtraChargeAction(w http.ResponseWriter, req *ht
 }
 
 func (o *OrderController) GetPendingOrderListAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	if req.Method == "OPTIONS" {
-		fmt.Fprintf(w, "OK")
-		return
-	}
-
-	botUuid := req.URL.Query().Get("botUuid")
-
-	if botUuid != o.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-
-		return
-	}
-
-	if req.Method != "GET" {
-		http.Error(w, "Only GET method is allowed", http.StatusMethodNotAllowed)
-
-		return
-	}
-
 	pending := make([]model.PendingOrder, 0)
 
 	for _, limit := range o.ExchangeRepository.GetTradeLimits() {
@@ -290,52 +217,16 @@ func (o *OrderController) GetPendingOrderListAction(w http.ResponseWriter, req *
 }
 
 func (o *OrderController) GetOrderListAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	if req.Method == "OPTIONS" {
-		fmt.Fprintf(w, "OK")
-		return
-	}
-
-	botUuid := req.URL.Query().Get("botUuid")
-
-	if botUuid != o.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-
-		return
-	}
-
 	list := o.OrderRepository.GetList()
 	encoded, _ := json.Marshal(list)
 	fmt.Fprintf(w, string(encoded))
 }
 
 func (o *OrderController) PostManualOrderAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	if req.Method == "OPTIONS" {
-		fmt.Fprintf(w, "OK")
-		return
-	}
-
-	botUuid := req.URL.Query().Get("botUuid")
-
-	if botUuid != o.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-
-		return
-	}
-
-	if req.Method != "POST" {
-		http.Error(w, "Only POST method is allowed", http.StatusMethodNotAllowed)
-
-		return
-	}
-
 	var manual model.ManualOrder
 
 	// Try to decode the request body into the struct. If there is an error,
@@ -420,6 +311,79 @@ func (o *OrderController) PostManualOrderAction(w http.ResponseWriter, req *http
 	manual.Price = o.Formatter.FormatPrice(tradeLimit, manual.Price)
 	o.OrderRepository.SetManualOrder(manual)
 
+	manualEvent := model.OrderEvent{
+		Symbol:    manual.Symbol,
+		Operation: manual.Operation,
+		Type:      model.OrderEventManualOverride,
+		Price:     manual.Price,
+	}
+	if err == nil {
+		manualEvent.OrderId = &opened.Id
+	}
+	if binanceOrder != nil {
+		manualEvent.ExternalId = &binanceOrder.OrderId
+		manualEvent.Quantity = binanceOrder.OrigQty
+		manualEvent.ExecutedQty = binanceOrder.ExecutedQty
+		manualDetails := fmt.Sprintf("Order price %f is replaced by manual price", binanceOrder.Price)
+		manualEvent.Details = &manualDetails
+	}
+	lastKline := o.ExchangeRepository.GetLastKLine(manual.Symbol)
+	if lastKline != nil {
+		manualEvent.CurrentPrice = &lastKline.Close
+	}
+	o.OrderEventRecorder.Record(manualEvent)
+
 	encoded, _ := json.Marshal(manual)
 	fmt.Fprintf(w, string(encoded))
 }
+
+func (o *OrderController) GetOrderEventListAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderId, err := strconv.ParseInt(req.URL.Query().Get("orderId"), 10, 64)
+	if err != nil {
+		http.Error(w, "orderId is required", http.StatusBadRequest)
+
+		return
+	}
+
+	order, err := o.OrderRepository.Find(orderId)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+
+		return
+	}
+
+	list := o.OrderEventRepository.GetOrderEvents(order)
+	encoded, _ := json.Marshal(list)
+	fmt.Fprintf(w, string(encoded))
+}
+
+func (o *OrderController) GetChildOrderListAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	orderId, err := strconv.ParseInt(req.URL.Query().Get("orderId"), 10, 64)
+	if err != nil {
+		http.Error(w, "orderId is required", http.StatusBadRequest)
+
+		return
+	}
+
+	encoded, _ := json.Marshal(o.ChildOrderRepository.GetChildOrders(orderId))
+	fmt.Fprintf(w, string(encoded))
+}
+
+func (o *OrderController) GetReconciliationReportAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	report := o.OrderReconciler.GetLastReport()
+
+	if report == nil {
+		http.Error(w, "Reconciliation is not finished yet", http.StatusNotFound)
+
+		return
+	}
+
+	encoded, _ := json.Marshal(report)
+	fmt.Fprintf(w, string(encoded))
+}
@@ -0,0 +1,277 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+const ApiV1Prefix = "/api/v1"
+
+type Route struct {
+	Method     string
+	Path       string // trailing slash matches any path suffix (symbol)
+	LegacyPath string // path without version prefix, empty if it is the same as Path
+	Scope      string
+	Summary    string
+	Tag        string
+	Query      []string
+	Handler    http.HandlerFunc
+}
+
+func (r Route) GetLegacyPath() string {
+	if r.LegacyPath != "" {
+		return r.LegacyPath
+	}
+
+	return r.Path
+}
+
+func (r Route) Match(path string) bool {
+	if strings.HasSuffix(r.Path, "/") {
+		return strings.HasPrefix(path, r.Path) && len(path) > len(r.Path)
+	}
+
+	return path == r.Path
+}
+
+// Router serves versioned API: request logging -> JSON error envelope -> method routing -> auth and CORS
+type Router struct {
+	Prefix   string
+	ApiGuard *ApiGuard
+	Routes   []Route
+	Title    string
+	Version  string
+}
+
+type ApiErrorEnvelope struct {
+	Error ApiError `json:"error"`
+}
+
+type ApiError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// envelopeResponseWriter replaces plain text error (http.Error) with JSON envelope, JSON error body is kept as is
+type envelopeResponseWriter struct {
+	http.ResponseWriter
+	status  int
+	failed  bool
+	message bytes.Buffer
+}
+
+func (e *envelopeResponseWriter) WriteHeader(status int) {
+	e.status = status
+	if status >= http.StatusBadRequest && !strings.HasPrefix(e.Header().Get("Content-Type"), "application/json") {
+		e.failed = true
+		return
+	}
+
+	e.ResponseWriter.WriteHeader(status)
+}
+
+func (e *envelopeResponseWriter) Write(content []byte) (int, error) {
+	if e.failed {
+		return e.message.Write(content)
+	}
+
+	return e.ResponseWriter.Write(content)
+}
+
+// Hijack is required for websocket upgrade
+func (e *envelopeResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := e.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, errors.New("Hijack is not supported")
+	}
+
+	return hijacker.Hijack()
+}
+
+func (e *envelopeResponseWriter) finish() {
+	if !e.failed {
+		return
+	}
+
+	e.ResponseWriter.Header().Set("Content-Type", "application/json")
+	e.ResponseWriter.WriteHeader(e.status)
+	encoded, _ := json.Marshal(ApiErrorEnvelope{Error: ApiError{
+		Code:    e.status,
+		Message: strings.TrimSpace(e.message.String()),
+	}})
+	_, _ = e.ResponseWriter.Write(encoded)
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	start := time.Now()
+	writer := &envelopeResponseWriter{ResponseWriter: w, status: http.StatusOK}
+
+	defer func() {
+		writer.finish()
+		log.Printf("[API] %s %s %d %.3fs", req.Method, req.URL.Path, writer.status, time.Since(start).Seconds())
+	}()
+
+	path := strings.TrimPrefix(req.URL.Path, r.Prefix)
+
+	if path == "/openapi.json" {
+		writer.Header().Set("Access-Control-Allow-Origin", "*")
+		writer.Header().Set("Content-Type", "application/json")
+		encoded, _ := json.Marshal(r.GetOpenApi())
+		_, _ = writer.Write(encoded)
+
+		return
+	}
+
+	// signature is checked with original request uri, controller receives path without version prefix
+	routes := make([]Route, 0)
+	for _, route := range r.Routes {
+		if route.Match(path) {
+			route.Handler = http.StripPrefix(r.Prefix, route.Handler).ServeHTTP
+			routes = append(routes, route)
+		}
+	}
+
+	if len(routes) == 0 {
+		http.Error(writer, "Not found", http.StatusNotFound)
+
+		return
+	}
+
+	MethodHandler(r.ApiGuard, routes)(writer, req)
+}
+
+// MethodHandler serves routes of the same path by request method, handler of route is protected by its scope.
+// It is shared by versioned and legacy API, so controllers don't check request method
+func MethodHandler(apiGuard *ApiGuard, routes []Route) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		var matched *Route
+		allowed := make([]string, 0)
+		for index, route := range routes {
+			allowed = append(allowed, route.Method)
+			// preflight is answered by api guard of any route
+			if route.Method == req.Method || (matched == nil && req.Method == "OPTIONS") {
+				matched = &routes[index]
+			}
+		}
+
+		if matched == nil {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			http.Error(w, "Method is not allowed", http.StatusMethodNotAllowed)
+
+			return
+		}
+
+		apiGuard.Protect(matched.Scope, matched.Handler)(w, req)
+	}
+}
+
+func (r *Router) GetOpenApi() map[string]any {
+	paths := make(map[string]map[string]any)
+
+	for _, route := range r.Routes {
+		path := r.Prefix + route.Path
+		parameters := make([]map[string]any, 0)
+
+		if strings.HasSuffix(route.Path, "/") {
+			path = path + "{symbol}"
+			parameters = append(parameters, map[string]any{
+				"name":     "symbol",
+				"in":       "path",
+				"required": true,
+				"schema":   map[string]string{"type": "string"},
+			})
+		}
+
+		for _, name := range route.Query {
+			parameters = append(parameters, map[string]any{
+				"name":   name,
+				"in":     "query",
+				"schema": map[string]string{"type": "string"},
+			})
+		}
+
+		operation := map[string]any{
+			"summary":     route.Summary,
+			"tags":        []string{route.Tag},
+			"operationId": strings.ToLower(route.Method) + "_" + strings.ReplaceAll(strings.Trim(route.Path, "/"), "/", "_"),
+			"parameters":  parameters,
+			"security": []map[string][]string{
+				{"apiKey": {route.Scope}, "apiTimestamp": {}, "apiSignature": {}},
+			},
+			"x-scope": route.Scope,
+			"responses": map[string]any{
+				"200": map[string]any{"description": "OK"},
+				"default": map[string]any{
+					"description": "Error",
+					"content": map[string]any{
+						"application/json": map[string]any{
+							"schema": map[string]string{"$ref": "#/components/schemas/Error"},
+						},
+					},
+				},
+			},
+		}
+
+		if route.Method == "POST" || route.Method == "PUT" {
+			operation["requestBody"] = map[string]any{
+				"content": map[string]any{
+					"application/json": map[string]any{
+						"schema": map[string]string{"type": "object"},
+					},
+				},
+			}
+		}
+
+		if _, ok := paths[path]; !ok {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(route.Method)] = operation
+	}
+
+	tags := make([]map[string]string, 0)
+	for _, route := range r.Routes {
+		if !slices.ContainsFunc(tags, func(tag map[string]string) bool { return tag["name"] == route.Tag }) {
+			tags = append(tags, map[string]string{"name": route.Tag})
+		}
+	}
+
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]string{
+			"title":   r.Title,
+			"version": r.Version,
+		},
+		"servers": []map[string]string{{"url": "/"}},
+		"tags":    tags,
+		"paths":   paths,
+		"components": map[string]any{
+			"securitySchemes": map[string]any{
+				"apiKey":       map[string]string{"type": "apiKey", "in": "header", "name": "X-API-KEY"},
+				"apiTimestamp": map[string]string{"type": "apiKey", "in": "header", "name": "X-API-TIMESTAMP"},
+				"apiSignature": map[string]string{"type": "apiKey", "in": "header", "name": "X-API-SIGNATURE"},
+			},
+			"schemas": map[string]any{
+				"Error": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"error": map[string]any{
+							"type": "object",
+							"properties": map[string]any{
+								"code":    map[string]string{"type": "integer"},
+								"message": map[string]string{"type": "string"},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+}
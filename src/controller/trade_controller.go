@@ -7,38 +7,23 @@ import (
 	ExchangeRepository "gitlab.com/open-soft/go-crypto-bot/src/repository"
 	"gitlab.com/open-soft/go-crypto-bot/src/service"
 	"net/http"
+	"strconv"
+	"strings"
 )
 
 type TradeController struct {
 	CurrentBot         *model.Bot
 	ExchangeRepository *ExchangeRepository.ExchangeRepository
 	TradeStack         *service.TradeStack
+	TradeLimitService  *service.TradeLimitService
+	PerformanceService *service.PerformanceService
+	DecisionJournal    *service.DecisionJournal
+	GridService        *service.GridService
 }
 
 func (t *TradeController) UpdateTradeLimitAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	if req.Method == "OPTIONS" {
-		fmt.Fprintf(w, "OK")
-		return
-	}
-
-	botUuid := req.URL.Query().Get("botUuid")
-
-	if botUuid != t.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-
-		return
-	}
-
-	if req.Method != "PUT" {
-		http.Error(w, "Разрешены только PUT методы", http.StatusMethodNotAllowed)
-
-		return
-	}
-
 	var tradeLimit model.TradeLimit
 
 	// Try to decode the request body into the struct. If there is an error,
@@ -57,6 +42,13 @@ func (t *TradeController) UpdateTradeLimitAction(w http.ResponseWriter, req *htt
 		return
 	}
 
+	err = t.TradeLimitService.CheckModeChange(entity, tradeLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
+		return
+	}
+
 	tradeLimit.Id = entity.Id
 	err = t.ExchangeRepository.UpdateTradeLimit(tradeLimit)
 
@@ -66,6 +58,8 @@ func (t *TradeController) UpdateTradeLimitAction(w http.ResponseWriter, req *htt
 		return
 	}
 
+	t.PerformanceService.OnTradeLimitChange(entity, tradeLimit)
+
 	entity, err = t.ExchangeRepository.GetTradeLimit(tradeLimit.Symbol)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusServiceUnavailable)
@@ -78,29 +72,8 @@ func (t *TradeController) UpdateTradeLimitAction(w http.ResponseWriter, req *htt
 }
 
 func (t *TradeController) CreateTradeLimitAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	if req.Method == "OPTIONS" {
-		fmt.Fprintf(w, "OK")
-		return
-	}
-
-	botUuid := req.URL.Query().Get("botUuid")
-
-	if botUuid != t.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
-
-		return
-	}
-
-	if req.Method != "POST" {
-		http.Error(w, "Разрешены только POST методы", http.StatusMethodNotAllowed)
-
-		return
-	}
-
 	var tradeLimit model.TradeLimit
 
 	// Try to decode the request body into the struct. If there is an error,
@@ -139,61 +112,136 @@ func (t *TradeController) CreateTradeLimitAction(w http.ResponseWriter, req *htt
 }
 
 func (t *TradeController) GetTradeLimitsAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
 	w.Header().Set("Content-Type", "application/json")
 
-	if req.Method == "OPTIONS" {
-		fmt.Fprintf(w, "OK")
+	limits := t.ExchangeRepository.GetTradeLimits()
+
+	encodedRes, _ := json.Marshal(limits)
+	fmt.Fprintf(w, string(encodedRes))
+}
+
+func (t *TradeController) GetTradeStackAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	stack := t.TradeStack.GetTradeStack(false, false, false, false, true)
+
+	encodedRes, _ := json.Marshal(stack)
+	fmt.Fprintf(w, string(encodedRes))
+}
+
+func (t *TradeController) DeleteTradeLimitAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	err := t.TradeLimitService.Delete(strings.ToUpper(req.URL.Query().Get("symbol")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
 		return
 	}
 
-	botUuid := req.URL.Query().Get("botUuid")
+	fmt.Fprintf(w, "OK")
+}
+
+func (t *TradeController) ArchiveTradeLimitAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
 
-	if botUuid != t.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+	err := t.TradeLimitService.Archive(strings.ToUpper(req.URL.Query().Get("symbol")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 
 		return
 	}
 
-	if req.Method != "GET" {
-		http.Error(w, "Разрешены только GET методы", http.StatusMethodNotAllowed)
+	fmt.Fprintf(w, "OK")
+}
+
+func (t *TradeController) GetTradeLimitPerformanceAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	performance, err := t.PerformanceService.GetTradeLimitPerformance(strings.ToUpper(req.URL.Query().Get("symbol")))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
 
 		return
 	}
 
-	limits := t.ExchangeRepository.GetTradeLimits()
-
-	encodedRes, _ := json.Marshal(limits)
-	fmt.Fprintf(w, string(encodedRes))
+	encoded, _ := json.Marshal(performance)
+	fmt.Fprintf(w, string(encoded))
 }
 
-func (t *TradeController) GetTradeStackAction(w http.ResponseWriter, req *http.Request) {
-	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+func (t *TradeController) CreatePerformanceSnapshotAction(w http.ResponseWriter, req *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	if req.Method == "OPTIONS" {
-		fmt.Fprintf(w, "OK")
+	snapshot, err := t.PerformanceService.Snapshot(strings.ToUpper(req.URL.Query().Get("symbol")), model.PerformanceSnapshotManual)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+
 		return
 	}
 
-	botUuid := req.URL.Query().Get("botUuid")
+	encoded, _ := json.Marshal(snapshot)
+	fmt.Fprintf(w, string(encoded))
+}
 
-	if botUuid != t.CurrentBot.BotUuid {
-		http.Error(w, "Forbidden", http.StatusForbidden)
+func (t *TradeController) GetDecisionJournalAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limit, err := strconv.ParseInt(req.URL.Query().Get("limit"), 10, 64)
+	if err != nil || limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+
+	encoded, _ := json.Marshal(t.DecisionJournal.GetList(strings.ToUpper(req.URL.Query().Get("symbol")), limit))
+	fmt.Fprintf(w, string(encoded))
+}
+
+func (t *TradeController) GetGridListAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	encoded, _ := json.Marshal(t.GridService.GetGrids(strings.ToUpper(req.URL.Query().Get("symbol"))))
+	fmt.Fprintf(w, string(encoded))
+}
+
+func (t *TradeController) ExportTradeLimitsAction(w http.ResponseWriter, req *http.Request) {
+	limits := t.ExchangeRepository.GetTradeLimits()
+
+	if req.URL.Query().Get("format") == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", "attachment; filename=trade_limits.csv")
+		_ = t.TradeLimitService.WriteCsv(w, limits)
 
 		return
 	}
 
-	if req.Method != "GET" {
-		http.Error(w, "Only GET method are allowed", http.StatusMethodNotAllowed)
+	w.Header().Set("Content-Type", "application/json")
+	encoded, _ := json.Marshal(limits)
+	fmt.Fprintf(w, string(encoded))
+}
+
+// ImportTradeLimitsAction accepts JSON list or CSV (?format=csv), ?dryRun=1 only validates
+func (t *TradeController) ImportTradeLimitsAction(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	limits := make([]model.TradeLimit, 0)
+	var err error
+
+	if req.URL.Query().Get("format") == "csv" {
+		limits, err = t.TradeLimitService.ReadCsv(req.Body)
+	} else {
+		err = json.NewDecoder(req.Body).Decode(&limits)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
 
 		return
 	}
 
-	stack := t.TradeStack.GetTradeStack(false, false, false, false, true)
+	dryRun := req.URL.Query().Get("dryRun") == "1" || req.URL.Query().Get("dryRun") == "true"
+	report := t.TradeLimitService.Import(limits, dryRun)
 
-	encodedRes, _ := json.Marshal(stack)
-	fmt.Fprintf(w, string(encodedRes))
+	encoded, _ := json.Marshal(report)
+	if !report.IsValid() {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	fmt.Fprintf(w, string(encoded))
 }
@@ -0,0 +1,148 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/websocket"
+	"gitlab.com/open-soft/go-crypto-bot/src/event"
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+type WebsocketController struct {
+	EventHub    *event.Hub
+	CorsOrigins []string
+}
+
+// WebsocketCommand is sent by client: {"action":"subscribe","topic":"position","symbols":["ETHUSDT"]}
+type WebsocketCommand struct {
+	Action  string   `json:"action"`
+	Topic   string   `json:"topic"`
+	Symbols []string `json:"symbols"`
+}
+
+type WebsocketReply struct {
+	Action string `json:"action"`
+	Topic  string `json:"topic"`
+	Error  string `json:"error,omitempty"`
+}
+
+func (c *WebsocketController) GetStreamAction(w http.ResponseWriter, req *http.Request) {
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 1024,
+		CheckOrigin: func(r *http.Request) bool {
+			origin := r.Header.Get("Origin")
+
+			return origin == "" || slices.Contains(c.CorsOrigins, "*") || slices.Contains(c.CorsOrigins, origin)
+		},
+	}
+
+	connection, err := upgrader.Upgrade(w, req, nil)
+	if err != nil {
+		log.Printf("Websocket API upgrade: %s", err.Error())
+
+		return
+	}
+
+	subscriber := c.EventHub.Subscribe(256)
+	defer c.EventHub.Unsubscribe(subscriber)
+
+	// topics from query: ?topics=position,order&symbols=ETHUSDT
+	if req.URL.Query().Get("topics") != "" {
+		symbols := make([]string, 0)
+		if req.URL.Query().Get("symbols") != "" {
+			symbols = strings.Split(strings.ToUpper(req.URL.Query().Get("symbols")), ",")
+		}
+		for _, topic := range strings.Split(req.URL.Query().Get("topics"), ",") {
+			if event.IsValidTopic(topic) {
+				subscriber.Subscribe(topic, symbols)
+			}
+		}
+	}
+
+	closed := make(chan struct{})
+	done := make(chan struct{})
+	defer close(done)
+	replies := make(chan WebsocketReply, 16)
+
+	go func() {
+		defer close(closed)
+
+		for {
+			var command WebsocketCommand
+			err := connection.ReadJSON(&command)
+			if err != nil {
+				return
+			}
+
+			select {
+			case replies <- c.handleCommand(subscriber, command):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(time.Second * 30)
+	defer ping.Stop()
+	defer connection.Close()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-req.Context().Done():
+			return
+		case reply := <-replies:
+			err = c.write(connection, reply)
+		case message, ok := <-subscriber.Messages:
+			if !ok {
+				return
+			}
+			err = c.write(connection, message)
+		case <-ping.C:
+			err = connection.WriteControl(websocket.PingMessage, []byte{}, time.Now().Add(time.Second*5))
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+func (c *WebsocketController) handleCommand(subscriber *event.Subscriber, command WebsocketCommand) WebsocketReply {
+	reply := WebsocketReply{Action: command.Action, Topic: command.Topic}
+
+	if !event.IsValidTopic(command.Topic) {
+		reply.Error = fmt.Sprintf("Topic '%s' is invalid", command.Topic)
+
+		return reply
+	}
+
+	symbols := make([]string, 0)
+	for _, symbol := range command.Symbols {
+		symbols = append(symbols, strings.ToUpper(symbol))
+	}
+
+	switch command.Action {
+	case "subscribe":
+		subscriber.Subscribe(command.Topic, symbols)
+	case "unsubscribe":
+		subscriber.Unsubscribe(command.Topic)
+	default:
+		reply.Error = fmt.Sprintf("Action '%s' is invalid", command.Action)
+	}
+
+	return reply
+}
+
+func (c *WebsocketController) write(connection *websocket.Conn, payload any) error {
+	encoded, _ := json.Marshal(payload)
+	_ = connection.SetWriteDeadline(time.Now().Add(time.Second * 10))
+
+	return connection.WriteMessage(websocket.TextMessage, encoded)
+}
@@ -0,0 +1,138 @@
+package event
+
+import (
+	"slices"
+	"sync"
+	"time"
+)
+
+const TopicPosition = "position"
+const TopicDecision = "decision"
+const TopicOrder = "order"
+const TopicSwap = "swap"
+
+func IsValidTopic(topic string) bool {
+	return slices.Contains([]string{TopicPosition, TopicDecision, TopicOrder, TopicSwap}, topic)
+}
+
+type Message struct {
+	Topic     string `json:"topic"`
+	Symbol    string `json:"symbol"`
+	Timestamp int64  `json:"timestamp"`
+	Payload   any    `json:"payload"`
+}
+
+// Subscriber receives messages of subscribed topics, empty symbol list means all symbols
+type Subscriber struct {
+	Messages chan Message
+	topics   map[string][]string
+	mu       sync.RWMutex
+}
+
+func (s *Subscriber) Subscribe(topic string, symbols []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.topics[topic] = symbols
+}
+
+func (s *Subscriber) Unsubscribe(topic string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.topics, topic)
+}
+
+func (s *Subscriber) IsSubscribed(topic string, symbol string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	symbols, ok := s.topics[topic]
+	if !ok {
+		return false
+	}
+
+	return len(symbols) == 0 || slices.Contains(symbols, symbol)
+}
+
+// Hub is in-memory pub/sub for push API, slow subscriber loses messages instead of blocking publisher
+type Hub struct {
+	subscribers map[*Subscriber]struct{}
+	mu          sync.RWMutex
+}
+
+func (h *Hub) Subscribe(bufferSize int) *Subscriber {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.subscribers == nil {
+		h.subscribers = make(map[*Subscriber]struct{})
+	}
+
+	subscriber := &Subscriber{
+		Messages: make(chan Message, bufferSize),
+		topics:   make(map[string][]string),
+	}
+	h.subscribers[subscriber] = struct{}{}
+
+	return subscriber
+}
+
+func (h *Hub) Unsubscribe(subscriber *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[subscriber]; ok {
+		delete(h.subscribers, subscriber)
+		close(subscriber.Messages)
+	}
+}
+
+func (h *Hub) HasSubscribers(topic string) bool {
+	if h == nil {
+		return false
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for subscriber := range h.subscribers {
+		subscriber.mu.RLock()
+		_, ok := subscriber.topics[topic]
+		subscriber.mu.RUnlock()
+
+		if ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Publish is safe for nil hub, it is not configured for CLI commands and tests
+func (h *Hub) Publish(topic string, symbol string, payload any) {
+	if h == nil {
+		return
+	}
+
+	message := Message{
+		Topic:     topic,
+		Symbol:    symbol,
+		Timestamp: time.Now().UnixMilli(),
+		Payload:   payload,
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for subscriber := range h.subscribers {
+		if !subscriber.IsSubscribed(topic, symbol) {
+			continue
+		}
+
+		select {
+		case subscriber.Messages <- message:
+		default:
+		}
+	}
+}
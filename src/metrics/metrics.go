@@ -0,0 +1,126 @@
+package metrics
+
+import (
+	"context"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/redis/go-redis/v9"
+	"net"
+	"strings"
+	"time"
+)
+
+const namespace = "go_crypto_bot"
+
+var (
+	DecisionsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "decisions_total",
+		Help:      "Strategy decisions by operation",
+	}, []string{"strategy", "operation"})
+
+	OrdersTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "orders_total",
+		Help:      "Exchange orders by operation and state (placed, filled, cancelled)",
+	}, []string{"operation", "state"})
+
+	OrderExecutionSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "order_execution_seconds",
+		Help:      "Order waitExecution duration",
+		Buckets:   []float64{1, 5, 15, 30, 60, 300, 900, 1800, 3600, 14400, 86400},
+	}, []string{"operation", "state"})
+
+	SwapLegsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "swap_legs_total",
+		Help:      "Swap legs by leg number and state",
+	}, []string{"leg", "state"})
+
+	SwapRollbacksTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "swap_rollbacks_total",
+		Help:      "Swap rollbacks (leg two) and forced swaps (leg three)",
+	}, []string{"type"})
+
+	WebsocketReconnectsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "websocket_reconnects_total",
+		Help:      "Stream websocket reconnects per batch",
+	}, []string{"batch"})
+
+	StreamStaleTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stream_stale_total",
+		Help:      "Streams without messages which were subscribed again",
+	}, []string{"batch"})
+
+	Leader = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "leader",
+		Help:      "Instance is elected master bot",
+	})
+
+	StreamSymbols = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stream_symbols",
+		Help:      "Symbols with subscribed price streams",
+	}, []string{"name"})
+
+	BinanceRequestWeight = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "binance_request_weight",
+		Help:      "Last known Binance request weight used per minute",
+	})
+
+	StorageLatencySeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "storage_latency_seconds",
+		Help:      "Redis command latency",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	}, []string{"storage", "command"})
+
+	MySQLPingSeconds = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "mysql_ping_seconds",
+		Help:      "MySQL ping round trip every 15 seconds, it is not latency of queries",
+		Buckets:   []float64{0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1},
+	})
+
+	MLDurationSeconds = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "ml_duration_seconds",
+		Help:      "Machine learning learn and predict duration",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.5, 1, 5, 15, 60, 300, 900},
+	}, []string{"operation"})
+)
+
+func ObserveSince(histogram prometheus.Observer, start time.Time) {
+	histogram.Observe(time.Since(start).Seconds())
+}
+
+// RedisHook measures latency of every redis command
+type RedisHook struct{}
+
+func (h RedisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		return next(ctx, network, addr)
+	}
+}
+
+func (h RedisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		defer ObserveSince(StorageLatencySeconds.WithLabelValues("redis", strings.ToLower(cmd.Name())), time.Now())
+
+		return next(ctx, cmd)
+	}
+}
+
+func (h RedisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		defer ObserveSince(StorageLatencySeconds.WithLabelValues("redis", "pipeline"), time.Now())
+
+		return next(ctx, cmds)
+	}
+}
@@ -0,0 +1,55 @@
+package model
+
+import "slices"
+
+const ApiScopeRead = "read"
+const ApiScopeTrade = "trade"
+const ApiScopeAdmin = "admin"
+
+const ApiHeaderKey = "X-API-KEY"
+const ApiHeaderTimestamp = "X-API-TIMESTAMP"
+const ApiHeaderSignature = "X-API-SIGNATURE"
+
+// scopes ordered by privileges, each next includes previous
+var apiScopes = []string{ApiScopeRead, ApiScopeTrade, ApiScopeAdmin}
+
+type ApiClient struct {
+	Id         int64   `json:"id"`
+	Name       string  `json:"name"`
+	ApiKey     string  `json:"apiKey"`
+	ApiSecret  string  `json:"-"`
+	Scope      string  `json:"scope"`
+	IsEnabled  bool    `json:"isEnabled"`
+	CreatedAt  string  `json:"createdAt"`
+	LastUsedAt *string `json:"lastUsedAt"`
+}
+
+func (a *ApiClient) HasScope(scope string) bool {
+	return slices.Index(apiScopes, a.Scope) >= slices.Index(apiScopes, scope) && IsValidApiScope(scope)
+}
+
+// IsLegacy is true for requests authorized by botUuid query parameter
+func (a *ApiClient) IsLegacy() bool {
+	return a.Id == 0
+}
+
+func IsValidApiScope(scope string) bool {
+	return slices.Contains(apiScopes, scope)
+}
+
+type ApiClientCredentials struct {
+	ApiClient
+	ApiSecret string `json:"apiSecret"`
+}
+
+type ApiAudit struct {
+	Id          int64   `json:"id"`
+	ApiClientId *int64  `json:"apiClientId"`
+	ClientName  string  `json:"clientName"`
+	Method      string  `json:"method"`
+	Path        string  `json:"path"`
+	Query       *string `json:"query"`
+	Status      int     `json:"status"`
+	RemoteAddr  string  `json:"remoteAddr"`
+	CreatedAt   string  `json:"createdAt"`
+}
@@ -1,6 +1,21 @@
 package model
 
-import "math"
+import (
+	"math"
+	"time"
+)
+
+const OrderTypeLimit = "LIMIT"
+const OrderTypeLimitMaker = "LIMIT_MAKER" // post-only, rejected by exchange if it would take liquidity
+
+const TimeInForceGtc = "GTC"
+const TimeInForceIoc = "IOC"
+const TimeInForceFok = "FOK"
+
+var TimeInForceList = []string{TimeInForceGtc, TimeInForceIoc, TimeInForceFok}
+
+// SwapLegTimeInForceList has no IOC, partially filled and expired leg would break the swap chain
+var SwapLegTimeInForceList = []string{TimeInForceGtc, TimeInForceFok}
 
 type BinanceOrder struct {
 	OrderId             int64   `json:"orderId"`
@@ -15,6 +30,7 @@ type BinanceOrder struct {
 	Side                string  `json:"side"`
 	WorkingTime         int64   `json:"workingTime"`
 	Timestamp           int64   `json:"time"`
+	UpdateTime          int64   `json:"updateTime"`
 }
 
 func (b *BinanceOrder) IsBuy() bool {
@@ -68,3 +84,14 @@ func (b *BinanceOrder) HasExecutedQuantity() bool {
 func (b *BinanceOrder) GetExecutedQuantity() float64 {
 	return b.ExecutedQty
 }
+
+// GetFilledAt is time of the last order update on exchange, empty if exchange didn't return it
+func (b *BinanceOrder) GetFilledAt() string {
+	for _, timestamp := range []int64{b.UpdateTime, b.TransactTime, b.WorkingTime, b.Timestamp} {
+		if timestamp > 0 {
+			return time.UnixMilli(timestamp).Format("2006-01-02 15:04:05")
+		}
+	}
+
+	return ""
+}
@@ -1,6 +1,11 @@
 package model
 
+const ApiHeaderBotUuid = "X-BOT-UUID"
+
 type Bot struct {
 	Id      int64  `json:"id"`
 	BotUuid string `json:"botUuid"`
+	// account credentials, main bot uses binance.apiKey and binance.apiSecret from config
+	BinanceApiKey    string `json:"-"`
+	BinanceApiSecret string `json:"-"`
 }
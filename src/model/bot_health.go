@@ -21,6 +21,10 @@ type BotHealth struct {
 	DbStatus      string            `json:"dbStatus"`
 	RedisStatus   string            `json:"redisStatus"`
 	BinanceStatus string            `json:"binanceStatus"`
+	StreamStatus  string            `json:"streamStatus"`
+	Leader        string            `json:"leader"`
+	IsLeader      bool              `json:"isLeader"`
+	Streams       []StreamHealth    `json:"streams"`
 	Cores         int               `json:"cores"`
 	Memory        sysstats.MemStats `json:"memory"`
 	LoadAvg       sysstats.LoadAvg  `json:"loadAvg"`
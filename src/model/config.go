@@ -0,0 +1,161 @@
+package model
+
+const ConfigRedacted = "******"
+
+type Config struct {
+	Bot             BotConfig             `yaml:"bot" json:"bot"`
+	Http            HttpConfig            `yaml:"http" json:"http"`
+	Api             ApiConfig             `yaml:"api" json:"api"`
+	Database        DatabaseConfig        `yaml:"database" json:"database"`
+	Redis           RedisConfig           `yaml:"redis" json:"redis"`
+	Binance         BinanceConfig         `yaml:"binance" json:"binance"`
+	Maker           MakerConfig           `yaml:"maker" json:"maker"`
+	Swap            SwapConfig            `yaml:"swap" json:"swap"`
+	LossSecurity    LossSecurityConfig    `yaml:"lossSecurity" json:"lossSecurity"`
+	MachineLearning MachineLearningConfig `yaml:"machineLearning" json:"machineLearning"`
+	AutoTrade       AutoTradeConfig       `yaml:"autoTrade" json:"autoTrade"`
+	Notifier        NotifierConfig        `yaml:"notifier" json:"notifier"`
+	Schedule        ScheduleConfig        `yaml:"schedule" json:"schedule"`
+	Shutdown        ShutdownConfig        `yaml:"shutdown" json:"shutdown"`
+}
+
+type BotConfig struct {
+	Uuid             string `yaml:"uuid" json:"uuid"`
+	IsMasterBot      bool   `yaml:"isMasterBot" json:"isMasterBot"`           // master bot candidate, only elected leader updates swap pairs
+	MultiAccount     bool   `yaml:"multiAccount" json:"multiAccount"`         // run enabled bots (accounts) from bots table in this process
+	AccountSecretKey string `yaml:"accountSecretKey" json:"accountSecretKey"` // encrypts Binance API secrets of accounts in bots table
+}
+
+type HttpConfig struct {
+	Port int64 `yaml:"port" json:"port"`
+}
+
+type ApiConfig struct {
+	CorsOrigins []string `yaml:"corsOrigins" json:"corsOrigins"`
+	RecvWindow  int64    `yaml:"recvWindow" json:"recvWindow"`   // milliseconds
+	LegacyScope string   `yaml:"legacyScope" json:"legacyScope"` // scope for botUuid authorization, empty disables it
+}
+
+type DatabaseConfig struct {
+	Dsn             string `yaml:"dsn" json:"dsn"`
+	MaxIdleConns    int    `yaml:"maxIdleConns" json:"maxIdleConns"`
+	MaxOpenConns    int    `yaml:"maxOpenConns" json:"maxOpenConns"`
+	ConnMaxLifetime int64  `yaml:"connMaxLifetime" json:"connMaxLifetime"` // seconds
+}
+
+type RedisConfig struct {
+	Dsn      string `yaml:"dsn" json:"dsn"`
+	Password string `yaml:"password" json:"password"`
+	Db       int    `yaml:"db" json:"db"`
+}
+
+type BinanceConfig struct {
+	ApiKey        string `yaml:"apiKey" json:"apiKey"`
+	ApiSecret     string `yaml:"apiSecret" json:"apiSecret"`
+	WsDsn         string `yaml:"wsDsn" json:"wsDsn"`
+	StreamDsn     string `yaml:"streamDsn" json:"streamDsn"`
+	SwapStreamDsn string `yaml:"swapStreamDsn" json:"swapStreamDsn"`
+}
+
+type MakerConfig struct {
+	MinDecisions         float64 `yaml:"minDecisions" json:"minDecisions"`
+	HoldScore            float64 `yaml:"holdScore" json:"holdScore"`
+	JournalRepeatSeconds int64   `yaml:"journalRepeatSeconds" json:"journalRepeatSeconds"` // the same skip of symbol is journaled once per period
+	JournalRetentionDays int64   `yaml:"journalRetentionDays" json:"journalRetentionDays"`
+}
+
+type SwapConfig struct {
+	Enabled                        bool    `yaml:"enabled" json:"enabled"`
+	MinPercentValid                float64 `yaml:"minPercentValid" json:"minPercentValid"`
+	OrderOnProfitPercent           float64 `yaml:"orderOnProfitPercent" json:"orderOnProfitPercent"`
+	OpenedSellOrderFromHoursOpened int64   `yaml:"openedSellOrderFromHoursOpened" json:"openedSellOrderFromHoursOpened"`
+	TurboSwapProfitPercent         float64 `yaml:"turboSwapProfitPercent" json:"turboSwapProfitPercent"`
+	LegTimeInForce                 string  `yaml:"legTimeInForce" json:"legTimeInForce"` // GTC or FOK
+}
+
+type LossSecurityConfig struct {
+	MlEnabled            bool `yaml:"mlEnabled" json:"mlEnabled"`
+	InterpolationEnabled bool `yaml:"interpolationEnabled" json:"interpolationEnabled"`
+}
+
+type MachineLearningConfig struct {
+	Learning               bool     `yaml:"learning" json:"learning"`
+	BaseKLineMlEnabled     bool     `yaml:"baseKLineMlEnabled" json:"baseKLineMlEnabled"`
+	BtcDependent           []string `yaml:"btcDependent" json:"btcDependent"`
+	EthDependent           []string `yaml:"ethDependent" json:"ethDependent"`
+	ExcludeDependedDataset []string `yaml:"excludeDependedDataset" json:"excludeDependedDataset"`
+}
+
+type AutoTradeConfig struct {
+	Host string `yaml:"host" json:"host"`
+}
+
+type NotifierConfig struct {
+	MaxAttempts  int64                   `yaml:"maxAttempts" json:"maxAttempts"`   // failed notification is retried with backoff
+	QueueSeconds int64                   `yaml:"queueSeconds" json:"queueSeconds"` // retry queue check
+	Channels     []NotifierChannelConfig `yaml:"channels" json:"channels"`
+}
+
+type NotifierChannelConfig struct {
+	Name     string   `yaml:"name" json:"name"`
+	Type     string   `yaml:"type" json:"type"`         // telegram, slack, email, webhook or autotrade
+	Events   []string `yaml:"events" json:"events"`     // buy, sell, error, swap, risk, empty means all
+	Template string   `yaml:"template" json:"template"` // text/template with Notification fields
+	Url      string   `yaml:"url" json:"url"`           // slack incoming webhook, webhook or autotrade host
+	Secret   string   `yaml:"secret" json:"secret"`     // webhook HMAC-SHA256 secret
+	BotToken string   `yaml:"botToken" json:"botToken"` // telegram
+	ChatId   string   `yaml:"chatId" json:"chatId"`     // telegram
+	SmtpHost string   `yaml:"smtpHost" json:"smtpHost"`
+	SmtpPort int64    `yaml:"smtpPort" json:"smtpPort"`
+	Username string   `yaml:"username" json:"username"`
+	Password string   `yaml:"password" json:"password"`
+	From     string   `yaml:"from" json:"from"`
+	To       []string `yaml:"to" json:"to"`
+}
+
+type ScheduleConfig struct {
+	UpdateLimitsMinutes      int64 `yaml:"updateLimitsMinutes" json:"updateLimitsMinutes"`
+	ReconciliationMinutes    int64 `yaml:"reconciliationMinutes" json:"reconciliationMinutes"`
+	LearnHours               int64 `yaml:"learnHours" json:"learnHours"`
+	DecisionMilliseconds     int64 `yaml:"decisionMilliseconds" json:"decisionMilliseconds"`
+	PositionPushMilliseconds int64 `yaml:"positionPushMilliseconds" json:"positionPushMilliseconds"` // websocket API position updates
+	SubscriptionSeconds      int64 `yaml:"subscriptionSeconds" json:"subscriptionSeconds"`           // trade limit and swap pair changes check
+	LeaderLeaseSeconds       int64 `yaml:"leaderLeaseSeconds" json:"leaderLeaseSeconds"`             // master bot leadership, renewed every third of lease
+	GridSeconds              int64 `yaml:"gridSeconds" json:"gridSeconds"`                           // grid level orders check
+	DcaMinutes               int64 `yaml:"dcaMinutes" json:"dcaMinutes"`                             // due DCA plans check
+}
+
+type ShutdownConfig struct {
+	Timeout int64 `yaml:"timeout" json:"timeout"` // seconds
+}
+
+// Redacted returns copy of config without secrets
+func (c Config) Redacted() Config {
+	redacted := c
+	redacted.Database.Dsn = redactString(c.Database.Dsn)
+	redacted.Redis.Password = redactString(c.Redis.Password)
+	redacted.Binance.ApiKey = redactString(c.Binance.ApiKey)
+	redacted.Binance.ApiSecret = redactString(c.Binance.ApiSecret)
+	redacted.Bot.AccountSecretKey = redactString(c.Bot.AccountSecretKey)
+	redacted.Notifier.Channels = make([]NotifierChannelConfig, 0)
+	for _, channel := range c.Notifier.Channels {
+		channel.Secret = redactString(channel.Secret)
+		channel.BotToken = redactString(channel.BotToken)
+		channel.Password = redactString(channel.Password)
+		// slack incoming webhook url contains token
+		if channel.Type == NotifierTypeSlack {
+			channel.Url = redactString(channel.Url)
+		}
+		redacted.Notifier.Channels = append(redacted.Notifier.Channels, channel)
+	}
+
+	return redacted
+}
+
+func redactString(value string) string {
+	if value == "" {
+		return ""
+	}
+
+	return ConfigRedacted
+}
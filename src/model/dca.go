@@ -0,0 +1,53 @@
+package model
+
+const DcaPlanActive = "active"
+const DcaPlanPaused = "paused"
+const DcaPlanCompleted = "completed" // budget is spent
+
+// DcaPlan buys AmountUsdt of symbol every IntervalHours, bought quantity is not a position and is never sold by bot
+type DcaPlan struct {
+	Id                 int64   `json:"id"`
+	Symbol             string  `json:"symbol"`
+	AmountUsdt         float64 `json:"amountUsdt"`
+	IntervalHours      int64   `json:"intervalHours"`
+	MaxPrice           float64 `json:"maxPrice"`           // 0 - no fixed price ceiling
+	UsePriceCalculator bool    `json:"usePriceCalculator"` // buy price of trade limit (frame, price history) is price ceiling
+	BudgetUsdt         float64 `json:"budgetUsdt"`         // 0 - unlimited
+	SpentUsdt          float64 `json:"spentUsdt"`
+	Quantity           float64 `json:"quantity"` // without commission paid in base asset
+	Orders             int64   `json:"orders"`
+	Status             string  `json:"status"`
+	NextBuyAt          int64   `json:"nextBuyAt"` // unix timestamp
+	LastMessage        string  `json:"lastMessage"`
+	CreatedAt          string  `json:"createdAt"`
+}
+
+func (p DcaPlan) GetAveragePrice() float64 {
+	if p.Quantity <= 0 {
+		return 0.00
+	}
+
+	return p.SpentUsdt / p.Quantity
+}
+
+// GetNextAmount is plan amount limited by remaining budget
+func (p DcaPlan) GetNextAmount() float64 {
+	if p.BudgetUsdt > 0 && p.BudgetUsdt-p.SpentUsdt < p.AmountUsdt {
+		return p.BudgetUsdt - p.SpentUsdt
+	}
+
+	return p.AmountUsdt
+}
+
+type DcaOrder struct {
+	Id              int64   `json:"id"`
+	PlanId          int64   `json:"planId"`
+	Symbol          string  `json:"symbol"`
+	ExternalId      int64   `json:"externalId"`
+	Price           float64 `json:"price"`
+	Quantity        float64 `json:"quantity"`
+	AmountUsdt      float64 `json:"amountUsdt"`
+	Commission      float64 `json:"commission"`
+	CommissionAsset string  `json:"commissionAsset"`
+	CreatedAt       string  `json:"createdAt"`
+}
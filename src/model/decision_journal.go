@@ -0,0 +1,58 @@
+package model
+
+const DecisionJournalBuy = "buy"
+const DecisionJournalSell = "sell"
+const DecisionJournalExtraCharge = "extra_charge"
+
+const DecisionJournalExecuted = "executed"
+const DecisionJournalSkipped = "skipped"
+const DecisionJournalFailed = "failed"
+
+// PriceCorrection is price changed by LossSecurity check
+type PriceCorrection struct {
+	Name string  `json:"name"`
+	From float64 `json:"from"`
+	To   float64 `json:"to"`
+}
+
+// BuyPriceCalculation explains buy price: minimal price of period limited by trade frame (or current price for extra charge),
+// corrected by LossSecurity history check and price correction
+type BuyPriceCalculation struct {
+	Price       float64           `json:"price"`
+	ExtraCharge bool              `json:"extraCharge"`
+	LastPrice   float64           `json:"lastPrice"`
+	MinPrice    float64           `json:"minPrice"`
+	Frame       *Frame            `json:"frame,omitempty"`
+	FramePrice  float64           `json:"framePrice"`
+	FrameError  string            `json:"frameError,omitempty"`
+	Corrections []PriceCorrection `json:"corrections"`
+}
+
+func (c *BuyPriceCalculation) Correct(name string, from float64, to float64) float64 {
+	if from != to {
+		c.Corrections = append(c.Corrections, PriceCorrection{Name: name, From: from, To: to})
+	}
+
+	return to
+}
+
+// DecisionJournalEntry is result of MakerService decision with all its inputs to explain (and replay) the trade
+type DecisionJournalEntry struct {
+	Id               int64                `json:"id"`
+	Symbol           string               `json:"symbol"`
+	Operation        string               `json:"operation"`
+	Result           string               `json:"result"`
+	Reason           string               `json:"reason"`
+	BuyScore         float64              `json:"buyScore"`
+	SellScore        float64              `json:"sellScore"`
+	HoldScore        float64              `json:"holdScore"`
+	LastPrice        float64              `json:"lastPrice"`
+	Price            float64              `json:"price"`
+	Quantity         float64              `json:"quantity"`
+	OrderId          *int64               `json:"orderId"`
+	Decisions        []Decision           `json:"decisions"`
+	TradeLimit       *TradeLimit          `json:"tradeLimit"`
+	ManualOrder      *ManualOrder         `json:"manualOrder"`
+	PriceCalculation *BuyPriceCalculation `json:"priceCalculation"`
+	CreatedAt        string               `json:"createdAt"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+const ExecutionAlgorithmTwap = "twap"
+const ExecutionAlgorithmIceberg = "iceberg"
+
+// ExecutionOptions split position BUY and SELL orders to child orders, empty algorithm places one order
+type ExecutionOptions struct {
+	Algorithm string  `json:"algorithm"` // twap or iceberg
+	Minutes   int64   `json:"minutes"`   // twap: child orders are placed evenly during this period
+	Slices    int64   `json:"slices"`    // twap: amount of child orders
+	ClipUsdt  float64 `json:"clipUsdt"`  // iceberg: visible amount of child order
+}
+
+func (e *ExecutionOptions) Scan(src interface{}) error {
+	if src == nil {
+		return nil
+	}
+
+	return json.Unmarshal(src.([]byte), &e)
+}
+
+func (e ExecutionOptions) Value() (driver.Value, error) {
+	jsonV, err := json.Marshal(e)
+	return string(jsonV), err
+}
+
+// ChildOrder is exchange order placed by execution algorithm, fills of child orders are aggregated to parent order
+type ChildOrder struct {
+	Id               int64   `json:"id"`
+	OrderId          *int64  `json:"orderId"` // parent order, it is set when parent order is saved
+	Symbol           string  `json:"symbol"`
+	ExternalId       int64   `json:"externalId"`
+	Operation        string  `json:"operation"`
+	Algorithm        string  `json:"algorithm"`
+	Price            float64 `json:"price"`
+	Quantity         float64 `json:"quantity"`
+	ExecutedQuantity float64 `json:"executedQuantity"`
+	Status           string  `json:"status"`
+	CreatedAt        string  `json:"createdAt"`
+}
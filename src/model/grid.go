@@ -0,0 +1,92 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+)
+
+const TradeLimitModePosition = "position"
+const TradeLimitModeGrid = "grid"
+
+const GridActive = "active"
+const GridStopped = "stopped"
+
+// level cycle: idle -> buy_placed -> bought -> sell_placed -> idle
+const GridLevelIdle = "idle"
+const GridLevelBuyPlaced = "buy_placed"
+const GridLevelBought = "bought"
+const GridLevelSellPlaced = "sell_placed"
+
+// GridOptions is price range divided to levels, USDTLimit of trade limit is split equally between levels
+type GridOptions struct {
+	LowerPrice float64 `json:"lowerPrice"`
+	UpperPrice float64 `json:"upperPrice"`
+	Levels     int64   `json:"levels"`
+}
+
+func (g *GridOptions) Scan(src interface{}) error {
+	if src == nil {
+		return nil
+	}
+
+	return json.Unmarshal(src.([]byte), &g)
+}
+
+func (g GridOptions) Value() (driver.Value, error) {
+	jsonV, err := json.Marshal(g)
+	return string(jsonV), err
+}
+
+type Grid struct {
+	Id          int64       `json:"id"`
+	Symbol      string      `json:"symbol"`
+	LowerPrice  float64     `json:"lowerPrice"`
+	UpperPrice  float64     `json:"upperPrice"`
+	Levels      int64       `json:"levels"`
+	USDTLimit   float64     `json:"USDTLimit"`
+	Status      string      `json:"status"`
+	Trades      int64       `json:"trades"`
+	Fees        float64     `json:"fees"` // quote asset, commission in third asset is not included
+	RealizedPnl float64     `json:"realizedPnl"`
+	CreatedAt   string      `json:"createdAt"`
+	StoppedAt   *string     `json:"stoppedAt"`
+	GridLevels  []GridLevel `json:"gridLevels"`
+}
+
+func (g Grid) IsConfiguredBy(limit TradeLimit) bool {
+	return g.LowerPrice == limit.GridOptions.LowerPrice &&
+		g.UpperPrice == limit.GridOptions.UpperPrice &&
+		g.Levels == limit.GridOptions.Levels &&
+		g.USDTLimit == limit.USDTLimit
+}
+
+// GridLevel buys at BuyPrice and sells bought quantity at SellPrice (next level price)
+type GridLevel struct {
+	Id                 int64   `json:"id"`
+	GridId             int64   `json:"gridId"`
+	Index              int64   `json:"index"`
+	BuyPrice           float64 `json:"buyPrice"`
+	SellPrice          float64 `json:"sellPrice"`
+	Quantity           float64 `json:"quantity"`
+	Status             string  `json:"status"`
+	ExternalId         *int64  `json:"externalId"`    // resting order on exchange
+	ClientOrderId      *string `json:"clientOrderId"` // order is being placed, ExternalId is recovered by it after restart
+	BuyExternalId      *int64  `json:"buyExternalId"`
+	BoughtPrice        float64 `json:"boughtPrice"`
+	BoughtQuantity     float64 `json:"boughtQuantity"`
+	BuyCommission      float64 `json:"buyCommission"`
+	BuyCommissionAsset string  `json:"buyCommissionAsset"`
+	BoughtAt           *string `json:"boughtAt"`
+	BuyOrderId         *int64  `json:"buyOrderId"` // closed buy order, sells of the same bought quantity close it
+	Trades             int64   `json:"trades"`
+	RealizedPnl        float64 `json:"realizedPnl"`
+}
+
+// GetHeldQuantity is bought quantity without commission paid in base asset
+func (l GridLevel) GetHeldQuantity(baseAsset string) float64 {
+	if l.BuyCommissionAsset == baseAsset {
+		return l.BoughtQuantity - l.BuyCommission
+	}
+
+	return l.BoughtQuantity
+}
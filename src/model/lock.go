@@ -1,6 +0,0 @@
-package model
-
-type Lock struct {
-	Symbol   string
-	IsLocked bool
-}
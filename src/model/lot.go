@@ -0,0 +1,47 @@
+package model
+
+const LotEntryBuy = "buy"
+const LotEntryBuyExtra = "buy_extra"
+const LotEntrySell = "sell"
+const LotEntrySwapIn = "swap_in"
+const LotEntrySwapOut = "swap_out"
+
+const LotMethodFifo = "fifo"
+const LotMethodLifo = "lifo"
+const LotMethodAverage = "average"
+
+var LotMethods = []string{LotMethodFifo, LotMethodLifo, LotMethodAverage}
+
+// LotEntry is fill in lot ledger: buy, buy_extra and swap_in acquire asset lots, sell and swap_out dispose them.
+// Value is USDT paid or received, quantity is net of commission paid in the asset
+type LotEntry struct {
+	Id         int64   `json:"id"`
+	Asset      string  `json:"asset"`
+	Symbol     string  `json:"symbol"`
+	OrderId    *int64  `json:"orderId"`
+	ExternalId int64   `json:"externalId"`
+	Type       string  `json:"type"`
+	Quantity   float64 `json:"quantity"`
+	Price      float64 `json:"price"`
+	Value      float64 `json:"value"`
+	Fee        float64 `json:"fee"` // USDT
+	CreatedAt  string  `json:"createdAt"`
+}
+
+func (e *LotEntry) IsAcquisition() bool {
+	return e.Type == LotEntryBuy || e.Type == LotEntryBuyExtra || e.Type == LotEntrySwapIn
+}
+
+// CapitalGain is disposal matched with acquired lot (or average cost pool)
+type CapitalGain struct {
+	Asset        string  `json:"asset"`
+	Symbol       string  `json:"symbol"`
+	Type         string  `json:"type"`
+	AcquiredAt   string  `json:"acquiredAt"` // empty for average cost or unknown lot
+	DisposedAt   string  `json:"disposedAt"`
+	Quantity     float64 `json:"quantity"`
+	Proceeds     float64 `json:"proceeds"`
+	CostBasis    float64 `json:"costBasis"`
+	Gain         float64 `json:"gain"`
+	UnmatchedLot bool    `json:"unmatchedLot"` // disposed quantity is not covered by recorded lots
+}
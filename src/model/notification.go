@@ -0,0 +1,61 @@
+package model
+
+const NotificationEventBuy = "buy"
+const NotificationEventSell = "sell"
+const NotificationEventError = "error"
+const NotificationEventSwap = "swap"
+const NotificationEventRisk = "risk"
+
+const NotificationStatusPending = "pending"
+const NotificationStatusSending = "sending"
+const NotificationStatusSent = "sent"
+const NotificationStatusFailed = "failed"
+
+const NotifierTypeTelegram = "telegram"
+const NotifierTypeSlack = "slack"
+const NotifierTypeEmail = "email"
+const NotifierTypeWebhook = "webhook"
+const NotifierTypeAutoTrade = "autotrade"
+
+const NotificationHeaderTimestamp = "X-NOTIFICATION-TIMESTAMP"
+const NotificationHeaderSignature = "X-NOTIFICATION-SIGNATURE"
+
+var NotificationEvents = []string{
+	NotificationEventBuy,
+	NotificationEventSell,
+	NotificationEventError,
+	NotificationEventSwap,
+	NotificationEventRisk,
+}
+
+// Notification is rendered by channel template, fields are available in template: {{.Symbol}}, {{.Message}}...
+type Notification struct {
+	Event     string  `json:"event"`
+	BotId     int64   `json:"botId"`
+	BotUuid   string  `json:"botUuid"`
+	Symbol    string  `json:"symbol"`
+	Operation string  `json:"operation"`
+	Price     float64 `json:"price"`
+	Quantity  float64 `json:"quantity"`
+	Code      string  `json:"code"`
+	Message   string  `json:"message"`
+	Stop      bool    `json:"stop"`
+	DateTime  string  `json:"dateTime"`
+}
+
+// NotificationJob is notification queued for channel, failed delivery is retried with backoff
+type NotificationJob struct {
+	Id            int64        `json:"id"`
+	Channel       string       `json:"channel"`
+	Notification  Notification `json:"notification"`
+	Status        string       `json:"status"`
+	Attempts      int64        `json:"attempts"`
+	NextAttemptAt string       `json:"nextAttemptAt"`
+	LastError     *string      `json:"lastError"`
+}
+
+// WebhookNotification is signed webhook payload
+type WebhookNotification struct {
+	Notification Notification `json:"notification"`
+	Text         string       `json:"text"`
+}
@@ -132,6 +132,36 @@ func (o *Order) GetMinClosePrice(limit TradeLimit) float64 {
 	return o.Price * (100 + limit.GetMinProfitPercent().Value()) / 100
 }
 
+func (o *Order) GetTakeProfitPrice(option TakeProfitOption) float64 {
+	return o.Price * (100 + option.Percent.Value()) / 100
+}
+
+// GetTakeProfitTier returns the first take-profit tier which is not sold yet and its remaining quantity,
+// tiers are sold in ascending order of profit percent, trailing tier is not returned
+func (o *Order) GetTakeProfitTier(options TakeProfitOptions, minQuantity float64) (TakeProfitOption, float64, bool) {
+	tiers := make(TakeProfitOptions, len(options))
+	copy(tiers, options)
+	sort.SliceStable(tiers, func(i int, j int) bool {
+		return tiers[i].Percent < tiers[j].Percent
+	})
+
+	sold := o.ExecutedQuantity - o.GetRemainingToSellQuantity()
+	cumulative := 0.00
+
+	for _, tier := range tiers {
+		if tier.IsTrailing() {
+			continue
+		}
+
+		cumulative += o.ExecutedQuantity * tier.QuantityPercent / 100
+		if cumulative-sold >= minQuantity {
+			return tier, cumulative - sold, true
+		}
+	}
+
+	return TakeProfitOption{}, 0.00, false
+}
+
 func (o *Order) GetManualMinClosePrice() float64 {
 	return o.Price * (100 + 0.50) / 100
 }
@@ -205,3 +235,42 @@ func (e ExtraChargeOptions) Value() (driver.Value, error) {
 	jsonV, err := json.Marshal(e)
 	return string(jsonV), err
 }
+
+// TakeProfitOptions are tiers of position sell: QuantityPercent of executed quantity is sold at Percent profit,
+// quantity which is not covered by tiers is sold by trailing tier or by regular sell price if there is no trailing tier
+type TakeProfitOptions []TakeProfitOption
+
+type TakeProfitOption struct {
+	Index           int64   `json:"index"`
+	Percent         Percent `json:"percent"`
+	QuantityPercent float64 `json:"quantityPercent"`
+	TrailingPercent Percent `json:"trailingPercent,omitempty"` // trailing tier sells the rest when price falls from its high
+}
+
+func (t TakeProfitOption) IsTrailing() bool {
+	return t.TrailingPercent > 0
+}
+
+// GetTrailing returns tier which sells the rest of position after the last target
+func (t TakeProfitOptions) GetTrailing() (TakeProfitOption, bool) {
+	for _, option := range t {
+		if option.IsTrailing() {
+			return option, true
+		}
+	}
+
+	return TakeProfitOption{}, false
+}
+
+func (t *TakeProfitOptions) Scan(src interface{}) error {
+	if src == nil {
+		return nil
+	}
+
+	return json.Unmarshal(src.([]byte), &t)
+}
+
+func (t TakeProfitOptions) Value() (driver.Value, error) {
+	jsonV, err := json.Marshal(t)
+	return string(jsonV), err
+}
@@ -0,0 +1,57 @@
+package model
+
+const OrderEventBuyPlaced = "buy_placed"
+const OrderEventSellPlaced = "sell_placed"
+const OrderEventPartialFill = "partial_fill"
+const OrderEventFilled = "filled"
+const OrderEventCancelled = "cancelled"
+const OrderEventExtraCharge = "extra_charge"
+const OrderEventSwapStarted = "swap_started"
+const OrderEventSwapProgress = "swap_progress"
+const OrderEventSwapFinished = "swap_finished"
+const OrderEventSwapCancelled = "swap_cancelled"
+const OrderEventSwapRollback = "swap_rollback"
+const OrderEventManualOverride = "manual_override"
+const OrderEventReconciled = "reconciled"
+const OrderEventAmended = "amended"
+
+const OrderEventReasonSwap = "swap"
+const OrderEventReasonLossSecurity = "loss_security"
+const OrderEventReasonUserRequest = "user_request"
+const OrderEventReasonMaxProfit = "max_profit"
+const OrderEventReasonExtraCharge = "extra_charge"
+const OrderEventReasonTtl = "ttl"
+const OrderEventReasonManualPrice = "manual_price"
+const OrderEventReasonExchange = "exchange"
+const OrderEventReasonSliceEnd = "slice_end"
+
+type OrderEvent struct {
+	Id           int64    `json:"id"`
+	OrderId      *int64   `json:"orderId"`
+	Symbol       string   `json:"symbol"`
+	ExternalId   *int64   `json:"externalId"`
+	Operation    string   `json:"operation"`
+	Type         string   `json:"type"`
+	Reason       *string  `json:"reason"`
+	Price        float64  `json:"price"`
+	CurrentPrice *float64 `json:"currentPrice"`
+	Quantity     float64  `json:"quantity"`
+	ExecutedQty  float64  `json:"executedQty"`
+	Details      *string  `json:"details"`
+	CreatedAt    string   `json:"createdAt"`
+}
+
+func (b *BinanceOrder) GetOrderEvent(eventType string, orderId *int64) OrderEvent {
+	externalId := b.OrderId
+
+	return OrderEvent{
+		OrderId:     orderId,
+		Symbol:      b.Symbol,
+		ExternalId:  &externalId,
+		Operation:   b.Side,
+		Type:        eventType,
+		Price:       b.Price,
+		Quantity:    b.OrigQty,
+		ExecutedQty: b.ExecutedQty,
+	}
+}
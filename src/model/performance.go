@@ -0,0 +1,55 @@
+package model
+
+const PerformanceSnapshotConfigChange = "config_change"
+const PerformanceSnapshotManual = "manual"
+
+// PerformanceStats is computed from realized trades of period, returns are daily PnL divided by USDT limit (capital)
+type PerformanceStats struct {
+	Symbol               string   `json:"symbol,omitempty"`
+	Strategy             string   `json:"strategy,omitempty"`
+	From                 string   `json:"from"`
+	To                   string   `json:"to"`
+	Capital              float64  `json:"capital"`
+	Trades               int64    `json:"trades"`
+	Wins                 int64    `json:"wins"`
+	Losses               int64    `json:"losses"`
+	WinRate              float64  `json:"winRate"` // percent
+	AvgHoursOpened       float64  `json:"avgHoursOpened"`
+	GrossProfit          float64  `json:"grossProfit"`
+	GrossLoss            float64  `json:"grossLoss"`
+	ProfitFactor         *float64 `json:"profitFactor"` // null if there are no losses
+	Fees                 float64  `json:"fees"`
+	NetPnl               float64  `json:"netPnl"`
+	Sharpe               float64  `json:"sharpe"`  // annualized, daily returns
+	Sortino              float64  `json:"sortino"` // annualized, daily returns
+	MaxDrawdown          float64  `json:"maxDrawdown"`
+	MaxDrawdownPercent   float64  `json:"maxDrawdownPercent"` // of capital
+	Positions            int64    `json:"positions"`
+	ExtraChargePositions int64    `json:"extraChargePositions"`
+	ExtraChargeRate      float64  `json:"extraChargeRate"` // percent of positions
+	ExtraChargeBudget    float64  `json:"extraChargeBudget"`
+	CapitalUtilisation   float64  `json:"capitalUtilisation"` // percent of capital invested during period
+}
+
+type PerformanceSummary struct {
+	Overall    PerformanceStats   `json:"overall"`
+	Symbols    []PerformanceStats `json:"symbols"`
+	Strategies []PerformanceStats `json:"strategies"`
+}
+
+// PerformanceSnapshot is stats of trade limit configuration period, it is stored when configuration is changed
+type PerformanceSnapshot struct {
+	Id         int64            `json:"id"`
+	Symbol     string           `json:"symbol"`
+	Reason     string           `json:"reason"`
+	TradeLimit TradeLimit       `json:"tradeLimit"`
+	Stats      PerformanceStats `json:"stats"`
+	CreatedAt  string           `json:"createdAt"`
+}
+
+// TradeLimitPerformance is current configuration period compared with previous ones
+type TradeLimitPerformance struct {
+	TradeLimit TradeLimit            `json:"tradeLimit"`
+	Current    PerformanceStats      `json:"current"`
+	Snapshots  []PerformanceSnapshot `json:"snapshots"`
+}
@@ -0,0 +1,65 @@
+package model
+
+const PnlPeriodDay = "day"
+const PnlPeriodWeek = "week"
+const PnlPeriodMonth = "month"
+
+// RealizedTrade is closed sell order with cost basis of bought quantity (including quantity gained by swaps), net of fees
+type RealizedTrade struct {
+	OrderId      int64   `json:"orderId"`
+	BuyOrderId   int64   `json:"buyOrderId"`
+	Symbol       string  `json:"symbol"`
+	Close        string  `json:"close"`
+	Quantity     float64 `json:"quantity"`
+	CostPrice    float64 `json:"costPrice"`
+	SellPrice    float64 `json:"sellPrice"`
+	GrossPnl     float64 `json:"grossPnl"`
+	Fees         float64 `json:"fees"` // quote asset
+	RealizedPnl  float64 `json:"realizedPnl"`
+	SwapQuantity float64 `json:"swapQuantity"`
+}
+
+type PnlPeriodSummary struct {
+	Period      string  `json:"period"`
+	Symbol      string  `json:"symbol,omitempty"` // empty for overall summary
+	Trades      int64   `json:"trades"`
+	GrossPnl    float64 `json:"grossPnl"`
+	Fees        float64 `json:"fees"`
+	RealizedPnl float64 `json:"realizedPnl"`
+}
+
+// UnrealizedPnl is opened position marked to the latest kline
+type UnrealizedPnl struct {
+	OrderId      int64   `json:"orderId"`
+	Symbol       string  `json:"symbol"`
+	Quantity     float64 `json:"quantity"`
+	CostPrice    float64 `json:"costPrice"`
+	CurrentPrice float64 `json:"currentPrice"`
+	Pnl          float64 `json:"pnl"`
+	Percent      float64 `json:"percent"`
+}
+
+type FeeTotal struct {
+	Asset       string  `json:"asset"`
+	Amount      float64 `json:"amount"`
+	QuoteAmount float64 `json:"quoteAmount"` // 0 if asset price is unknown
+}
+
+type PnlReport struct {
+	Period        string             `json:"period"`
+	RealizedPnl   float64            `json:"realizedPnl"`
+	UnrealizedPnl float64            `json:"unrealizedPnl"`
+	Fees          float64            `json:"fees"`
+	Overall       []PnlPeriodSummary `json:"overall"`
+	Symbols       []PnlPeriodSummary `json:"symbols"`
+	Unrealized    []UnrealizedPnl    `json:"unrealized"`
+	FeeTotals     []FeeTotal         `json:"feeTotals"`
+}
+
+// EquityPoint is cumulative PnL after trade, last point includes unrealized PnL of opened positions
+type EquityPoint struct {
+	Timestamp     int64   `json:"timestamp"`
+	RealizedPnl   float64 `json:"realizedPnl"`
+	UnrealizedPnl float64 `json:"unrealizedPnl"`
+	Equity        float64 `json:"equity"`
+}
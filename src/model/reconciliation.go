@@ -0,0 +1,28 @@
+package model
+
+const ReconciliationOrphanedOrder = "orphaned_exchange_order"
+const ReconciliationStaleCachedOrder = "stale_cached_order"
+const ReconciliationMissedFill = "missed_fill"
+const ReconciliationExecutedQuantity = "executed_quantity"
+const ReconciliationSoldQuantity = "sold_quantity"
+const ReconciliationBalance = "balance"
+
+type ReconciliationDiscrepancy struct {
+	Type       string  `json:"type"`
+	Symbol     string  `json:"symbol"`
+	OrderId    *int64  `json:"orderId"`
+	ExternalId *int64  `json:"externalId"`
+	Expected   float64 `json:"expected"`
+	Actual     float64 `json:"actual"`
+	Fixed      bool    `json:"fixed"`
+	Message    string  `json:"message"`
+}
+
+type ReconciliationReport struct {
+	StartedAt     string                      `json:"startedAt"`
+	FinishedAt    string                      `json:"finishedAt"`
+	Symbols       int                         `json:"symbols"`
+	Skipped       []string                    `json:"skipped"`
+	Error         *string                     `json:"error"`
+	Discrepancies []ReconciliationDiscrepancy `json:"discrepancies"`
+}
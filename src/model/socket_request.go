@@ -26,6 +26,7 @@ type Error struct {
 
 const BinanceErrorInvalidAPIKeyOrPermissions = "binance_error_invalid_api_key_or_permissions"
 const BinanceErrorFilterNotional = "binance_error_filter_notional"
+const BinanceErrorOrderWouldMatch = "binance_error_order_would_match"
 
 func (e *Error) GetMessage() string {
 	if strings.Contains(e.Message, "Invalid API-key, IP, or permissions for action") {
@@ -36,6 +37,10 @@ func (e *Error) GetMessage() string {
 		return BinanceErrorFilterNotional
 	}
 
+	if strings.Contains(e.Message, "Order would immediately match and take") {
+		return BinanceErrorOrderWouldMatch
+	}
+
 	return e.Message
 }
 
@@ -54,6 +59,20 @@ type BinanceOrderResponse struct {
 	Error  *Error       `json:"error"`
 }
 
+type BinanceCancelReplace struct {
+	CancelResult     string       `json:"cancelResult"`
+	NewOrderResult   string       `json:"newOrderResult"`
+	CancelResponse   BinanceOrder `json:"cancelResponse"`
+	NewOrderResponse BinanceOrder `json:"newOrderResponse"`
+}
+
+type BinanceCancelReplaceResponse struct {
+	Id     string               `json:"id"`
+	Status int64                `json:"status"`
+	Result BinanceCancelReplace `json:"result"`
+	Error  *Error               `json:"error"`
+}
+
 type BinanceOrderListResponse struct {
 	Id     string         `json:"id"`
 	Status int64          `json:"status"`
@@ -66,6 +85,12 @@ type RateLimit struct {
 	Interval      string `json:"interval"`
 	IntervalNum   int64  `json:"intervalNum"`
 	Limit         int64  `json:"limit"`
+	Count         int64  `json:"count"`
+}
+
+type SocketRateLimitResponse struct {
+	Id         string      `json:"id"`
+	RateLimits []RateLimit `json:"rateLimits"`
 }
 
 type ExchangeFilter struct {
@@ -0,0 +1,17 @@
+package model
+
+const StreamStatusOk = "ok"
+const StreamStatusStale = "stale"
+const StreamStatusDisconnected = "disconnected"
+
+// StreamHealth is market data websocket connection (batch of streams) state
+type StreamHealth struct {
+	Batch         string   `json:"batch"`
+	Address       string   `json:"address"`
+	Status        string   `json:"status"`
+	ConnectedAt   string   `json:"connectedAt"`
+	LastMessageAt string   `json:"lastMessageAt"`
+	Reconnects    int64    `json:"reconnects"`
+	Streams       int      `json:"streams"`
+	StaleStreams  []string `json:"staleStreams"`
+}
@@ -40,6 +40,31 @@ type TradeLimit struct {
 	BuyPriceHistoryCheckInterval string             `json:"buyPriceHistoryCheckInterval"` //"1d",
 	BuyPriceHistoryCheckPeriod   int64              `json:"buyPriceHistoryCheckPeriod"`   //14,
 	ExtraChargeOptions           ExtraChargeOptions `json:"extraChargeOptions"`
+	Mode                         string             `json:"mode"` // position (default) or grid
+	GridOptions                  GridOptions        `json:"gridOptions"`
+	TakeProfitOptions            TakeProfitOptions  `json:"takeProfitOptions"`
+	ExecutionOptions             ExecutionOptions   `json:"executionOptions"`
+	OrderType                    string             `json:"orderType"` // LIMIT (default) or LIMIT_MAKER
+}
+
+func (t TradeLimit) GetMode() string {
+	if t.Mode == "" {
+		return TradeLimitModePosition
+	}
+
+	return t.Mode
+}
+
+func (t TradeLimit) GetOrderType() string {
+	if t.OrderType == "" {
+		return OrderTypeLimit
+	}
+
+	return t.OrderType
+}
+
+func (t TradeLimit) IsGrid() bool {
+	return t.Mode == TradeLimitModeGrid
 }
 
 func (t TradeLimit) GetMinPrice() float64 {
@@ -100,3 +125,19 @@ func (t *TradeLimit) GetBuyOnFallPercent(order Order, kLine KLine) Percent {
 func (t *TradeLimit) GetClosePrice(buyPrice float64) float64 {
 	return buyPrice * (100 + t.GetMinProfitPercent().Value()) / 100
 }
+
+type TradeLimitViolation struct {
+	Symbol  string `json:"symbol"`
+	Message string `json:"message"`
+}
+
+type TradeLimitImportReport struct {
+	DryRun     bool                  `json:"dryRun"`
+	Created    []string              `json:"created"`
+	Updated    []string              `json:"updated"`
+	Violations []TradeLimitViolation `json:"violations"`
+}
+
+func (r TradeLimitImportReport) IsValid() bool {
+	return len(r.Violations) == 0
+}
@@ -0,0 +1,267 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	ExchangeModel "gitlab.com/open-soft/go-crypto-bot/src/model"
+	"log"
+	"time"
+)
+
+type ApiClientStorageInterface interface {
+	FindByApiKey(apiKey string) (ExchangeModel.ApiClient, error)
+	Touch(apiClient ExchangeModel.ApiClient)
+	RememberSignature(apiKey string, signature string, ttl time.Duration) bool
+}
+
+type ApiAuditStorageInterface interface {
+	CreateAudit(audit ExchangeModel.ApiAudit) (*int64, error)
+}
+
+type ApiClientRepository struct {
+	DB         *sql.DB
+	RDB        *redis.Client
+	Ctx        *context.Context
+	CurrentBot *ExchangeModel.Bot
+}
+
+func (repo *ApiClientRepository) Create(apiClient ExchangeModel.ApiClient) (*int64, error) {
+	res, err := repo.DB.Exec(`
+		INSERT INTO api_client SET
+			bot_id = ?,
+			name = ?,
+			api_key = ?,
+			api_secret = ?,
+			scope = ?,
+			is_enabled = ?,
+			created_at = NOW()
+	`,
+		repo.CurrentBot.Id,
+		apiClient.Name,
+		apiClient.ApiKey,
+		apiClient.ApiSecret,
+		apiClient.Scope,
+		apiClient.IsEnabled,
+	)
+
+	if err != nil {
+		log.Println(err)
+
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	return &lastId, err
+}
+
+func (repo *ApiClientRepository) FindByApiKey(apiKey string) (ExchangeModel.ApiClient, error) {
+	var apiClient ExchangeModel.ApiClient
+	err := repo.DB.QueryRow(`
+		SELECT
+			c.id as Id,
+			c.name as Name,
+			c.api_key as ApiKey,
+			c.api_secret as ApiSecret,
+			c.scope as Scope,
+			c.is_enabled as IsEnabled,
+			c.created_at as CreatedAt,
+			c.last_used_at as LastUsedAt
+		FROM api_client c
+		WHERE c.api_key = ? AND c.bot_id = ?
+	`,
+		apiKey,
+		repo.CurrentBot.Id,
+	).Scan(
+		&apiClient.Id,
+		&apiClient.Name,
+		&apiClient.ApiKey,
+		&apiClient.ApiSecret,
+		&apiClient.Scope,
+		&apiClient.IsEnabled,
+		&apiClient.CreatedAt,
+		&apiClient.LastUsedAt,
+	)
+
+	if err != nil {
+		return apiClient, err
+	}
+
+	return apiClient, nil
+}
+
+func (repo *ApiClientRepository) GetList() []ExchangeModel.ApiClient {
+	res, err := repo.DB.Query(`
+		SELECT
+			c.id as Id,
+			c.name as Name,
+			c.api_key as ApiKey,
+			c.scope as Scope,
+			c.is_enabled as IsEnabled,
+			c.created_at as CreatedAt,
+			c.last_used_at as LastUsedAt
+		FROM api_client c
+		WHERE c.bot_id = ?
+		ORDER BY c.id ASC
+	`,
+		repo.CurrentBot.Id,
+	)
+	defer res.Close()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	list := make([]ExchangeModel.ApiClient, 0)
+
+	for res.Next() {
+		var apiClient ExchangeModel.ApiClient
+		err := res.Scan(
+			&apiClient.Id,
+			&apiClient.Name,
+			&apiClient.ApiKey,
+			&apiClient.Scope,
+			&apiClient.IsEnabled,
+			&apiClient.CreatedAt,
+			&apiClient.LastUsedAt,
+		)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		list = append(list, apiClient)
+	}
+
+	return list
+}
+
+func (repo *ApiClientRepository) Disable(id int64) error {
+	_, err := repo.DB.Exec(`
+		UPDATE api_client SET is_enabled = 0 WHERE id = ? AND bot_id = ?
+	`,
+		id,
+		repo.CurrentBot.Id,
+	)
+
+	return err
+}
+
+func (repo *ApiClientRepository) Touch(apiClient ExchangeModel.ApiClient) {
+	_, err := repo.DB.Exec(`
+		UPDATE api_client SET last_used_at = NOW() WHERE id = ? AND bot_id = ?
+	`,
+		apiClient.Id,
+		repo.CurrentBot.Id,
+	)
+
+	if err != nil {
+		log.Println(err)
+	}
+}
+
+// RememberSignature returns false if signature was already used within ttl (replay)
+func (repo *ApiClientRepository) RememberSignature(apiKey string, signature string, ttl time.Duration) bool {
+	stored, err := repo.RDB.SetNX(
+		*repo.Ctx,
+		fmt.Sprintf("api-signature-%s-%s-bot-%d", apiKey, signature, repo.CurrentBot.Id),
+		"1",
+		ttl,
+	).Result()
+
+	if err != nil {
+		log.Printf("Api signature is not stored: %s", err.Error())
+
+		return false
+	}
+
+	return stored
+}
+
+func (repo *ApiClientRepository) CreateAudit(audit ExchangeModel.ApiAudit) (*int64, error) {
+	res, err := repo.DB.Exec(`
+		INSERT INTO api_audit SET
+			bot_id = ?,
+			api_client_id = ?,
+			client_name = ?,
+			method = ?,
+			path = ?,
+			query = ?,
+			status = ?,
+			remote_addr = ?,
+			created_at = NOW()
+	`,
+		repo.CurrentBot.Id,
+		audit.ApiClientId,
+		audit.ClientName,
+		audit.Method,
+		audit.Path,
+		audit.Query,
+		audit.Status,
+		audit.RemoteAddr,
+	)
+
+	if err != nil {
+		log.Println(err)
+
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	return &lastId, err
+}
+
+func (repo *ApiClientRepository) GetAuditList(limit int64) []ExchangeModel.ApiAudit {
+	res, err := repo.DB.Query(`
+		SELECT
+			a.id as Id,
+			a.api_client_id as ApiClientId,
+			a.client_name as ClientName,
+			a.method as Method,
+			a.path as Path,
+			a.query as Query,
+			a.status as Status,
+			a.remote_addr as RemoteAddr,
+			a.created_at as CreatedAt
+		FROM api_audit a
+		WHERE a.bot_id = ?
+		ORDER BY a.id DESC
+		LIMIT ?
+	`,
+		repo.CurrentBot.Id,
+		limit,
+	)
+	defer res.Close()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	list := make([]ExchangeModel.ApiAudit, 0)
+
+	for res.Next() {
+		var audit ExchangeModel.ApiAudit
+		err := res.Scan(
+			&audit.Id,
+			&audit.ApiClientId,
+			&audit.ClientName,
+			&audit.Method,
+			&audit.Path,
+			&audit.Query,
+			&audit.Status,
+			&audit.RemoteAddr,
+			&audit.CreatedAt,
+		)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		list = append(list, audit)
+	}
+
+	return list
+}
@@ -6,17 +6,17 @@ import (
 	"github.com/redis/go-redis/v9"
 	"gitlab.com/open-soft/go-crypto-bot/src/model"
 	"log"
-	"os"
 )
 
 type BotRepository struct {
-	DB  *sql.DB
-	RDB *redis.Client
-	Ctx *context.Context
+	DB      *sql.DB
+	RDB     *redis.Client
+	Ctx     *context.Context
+	BotUuid string
 }
 
 func (b *BotRepository) GetCurrentBot() *model.Bot {
-	botUuid := os.Getenv("BOT_UUID")
+	botUuid := b.BotUuid
 
 	if len(botUuid) == 0 {
 		panic("'BOT_UUID' variable must be set!")
@@ -43,6 +43,77 @@ func (b *BotRepository) GetCurrentBot() *model.Bot {
 	return &bot
 }
 
+// GetAccounts returns enabled bots with own Binance credentials, they are run in the same process with current bot
+func (b *BotRepository) GetAccounts() []model.Bot {
+	res, err := b.DB.Query(`
+		SELECT
+			b.id as Id,
+			b.uuid as Uuid,
+			b.binance_api_key as BinanceApiKey,
+			IFNULL(b.binance_api_secret, '') as BinanceApiSecret
+		FROM bots b
+		WHERE b.uuid != ? AND b.is_enabled = 1 AND b.binance_api_key IS NOT NULL AND b.binance_api_key != ''
+		ORDER BY b.id`, b.BotUuid,
+	)
+
+	if err != nil {
+		log.Println(err)
+		return make([]model.Bot, 0)
+	}
+	defer res.Close()
+
+	list := make([]model.Bot, 0)
+	for res.Next() {
+		var bot model.Bot
+		err := res.Scan(
+			&bot.Id,
+			&bot.BotUuid,
+			&bot.BinanceApiKey,
+			&bot.BinanceApiSecret,
+		)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		list = append(list, bot)
+	}
+
+	return list
+}
+
+func (b *BotRepository) CreateAccount(bot model.Bot) error {
+	_, err := b.DB.Exec(`
+		INSERT INTO bots SET
+			uuid = ?,
+			binance_api_key = ?,
+			binance_api_secret = ?
+	`, bot.BotUuid, bot.BinanceApiKey, bot.BinanceApiSecret)
+
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+func (b *BotRepository) UpdateAccountSecret(bot model.Bot) error {
+	_, err := b.DB.Exec(`
+		UPDATE bots SET
+			binance_api_secret = ?
+		WHERE id = ?
+	`, bot.BinanceApiSecret, bot.Id)
+
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
 func (b *BotRepository) Create(bot model.Bot) error {
 	_, err := b.DB.Exec(`INSERT INTO bots SET	uuid = ?`, bot.BotUuid)
 
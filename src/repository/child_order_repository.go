@@ -0,0 +1,160 @@
+package repository
+
+import (
+	"database/sql"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"log"
+	"strings"
+)
+
+type ChildOrderStorageInterface interface {
+	CreateChildOrder(child model.ChildOrder) (*int64, error)
+	SetParentOrder(childIds []int64, orderId int64) error
+	GetChildOrders(orderId int64) []model.ChildOrder
+	GetPendingChildOrders(symbol string, operation string) []model.ChildOrder
+}
+
+type ChildOrderRepository struct {
+	DB         *sql.DB
+	CurrentBot *model.Bot
+}
+
+func (repo *ChildOrderRepository) CreateChildOrder(child model.ChildOrder) (*int64, error) {
+	res, err := repo.DB.Exec(`
+		INSERT INTO order_child SET
+			bot_id = ?,
+			order_id = ?,
+			symbol = ?,
+			external_id = ?,
+			operation = ?,
+			algorithm = ?,
+			price = ?,
+			quantity = ?,
+			executed_quantity = ?,
+			status = ?,
+			created_at = NOW()
+	`,
+		repo.CurrentBot.Id,
+		child.OrderId,
+		child.Symbol,
+		child.ExternalId,
+		child.Operation,
+		child.Algorithm,
+		child.Price,
+		child.Quantity,
+		child.ExecutedQuantity,
+		child.Status,
+	)
+
+	if err != nil {
+		log.Println(err)
+
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	return &lastId, err
+}
+
+func (repo *ChildOrderRepository) SetParentOrder(childIds []int64, orderId int64) error {
+	if len(childIds) == 0 {
+		return nil
+	}
+
+	args := []any{orderId, repo.CurrentBot.Id}
+	for _, id := range childIds {
+		args = append(args, id)
+	}
+
+	_, err := repo.DB.Exec(`
+		UPDATE order_child c SET c.order_id = ?
+		WHERE c.bot_id = ? AND c.id IN (?`+strings.Repeat(", ?", len(childIds)-1)+`)
+	`, args...)
+
+	if err != nil {
+		log.Println(err)
+	}
+
+	return err
+}
+
+func (repo *ChildOrderRepository) GetChildOrders(orderId int64) []model.ChildOrder {
+	return repo.getList(`
+		SELECT
+			c.id as Id,
+			c.order_id as OrderId,
+			c.symbol as Symbol,
+			c.external_id as ExternalId,
+			c.operation as Operation,
+			c.algorithm as Algorithm,
+			c.price as Price,
+			c.quantity as Quantity,
+			c.executed_quantity as ExecutedQuantity,
+			c.status as Status,
+			c.created_at as CreatedAt
+		FROM order_child c
+		WHERE c.bot_id = ? AND c.order_id = ?
+		ORDER BY c.id ASC
+	`, repo.CurrentBot.Id, orderId)
+}
+
+// GetPendingChildOrders returns executed child orders which are not saved to parent order yet (bot was stopped during execution)
+func (repo *ChildOrderRepository) GetPendingChildOrders(symbol string, operation string) []model.ChildOrder {
+	return repo.getList(`
+		SELECT
+			c.id as Id,
+			c.order_id as OrderId,
+			c.symbol as Symbol,
+			c.external_id as ExternalId,
+			c.operation as Operation,
+			c.algorithm as Algorithm,
+			c.price as Price,
+			c.quantity as Quantity,
+			c.executed_quantity as ExecutedQuantity,
+			c.status as Status,
+			c.created_at as CreatedAt
+		FROM order_child c
+		WHERE c.bot_id = ? AND c.order_id IS NULL AND c.symbol = ? AND c.operation = ? AND c.executed_quantity > 0
+		ORDER BY c.id ASC
+	`, repo.CurrentBot.Id, symbol, strings.ToLower(operation))
+}
+
+func (repo *ChildOrderRepository) getList(query string, args ...any) []model.ChildOrder {
+	res, err := repo.DB.Query(query, args...)
+
+	list := make([]model.ChildOrder, 0)
+
+	if err != nil {
+		log.Println(err)
+
+		return list
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var child model.ChildOrder
+		err := res.Scan(
+			&child.Id,
+			&child.OrderId,
+			&child.Symbol,
+			&child.ExternalId,
+			&child.Operation,
+			&child.Algorithm,
+			&child.Price,
+			&child.Quantity,
+			&child.ExecutedQuantity,
+			&child.Status,
+			&child.CreatedAt,
+		)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		list = append(list, child)
+	}
+
+	return list
+}
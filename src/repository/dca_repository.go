@@ -0,0 +1,254 @@
+package repository
+
+import (
+	"database/sql"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"log"
+)
+
+type DcaStorageInterface interface {
+	CreatePlan(plan model.DcaPlan) (*int64, error)
+	UpdatePlan(plan model.DcaPlan) error
+	GetPlan(id int64) (model.DcaPlan, error)
+	GetPlans() []model.DcaPlan
+	CreateOrder(order model.DcaOrder) error
+	GetOrders(planId int64, limit int64) []model.DcaOrder
+}
+
+type DcaRepository struct {
+	DB         *sql.DB
+	CurrentBot *model.Bot
+}
+
+func (repo *DcaRepository) CreatePlan(plan model.DcaPlan) (*int64, error) {
+	res, err := repo.DB.Exec(`
+		INSERT INTO dca_plan SET
+			bot_id = ?,
+			symbol = ?,
+			amount_usdt = ?,
+			interval_hours = ?,
+			max_price = ?,
+			use_price_calculator = ?,
+			budget_usdt = ?,
+			status = ?,
+			next_buy_at = ?,
+			created_at = NOW()
+	`,
+		repo.CurrentBot.Id,
+		plan.Symbol,
+		plan.AmountUsdt,
+		plan.IntervalHours,
+		plan.MaxPrice,
+		plan.UsePriceCalculator,
+		plan.BudgetUsdt,
+		plan.Status,
+		plan.NextBuyAt,
+	)
+
+	if err != nil {
+		log.Println(err)
+
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	return &lastId, err
+}
+
+func (repo *DcaRepository) UpdatePlan(plan model.DcaPlan) error {
+	_, err := repo.DB.Exec(`
+		UPDATE dca_plan p SET
+			p.amount_usdt = ?,
+			p.interval_hours = ?,
+			p.max_price = ?,
+			p.use_price_calculator = ?,
+			p.budget_usdt = ?,
+			p.spent_usdt = ?,
+			p.quantity = ?,
+			p.orders = ?,
+			p.status = ?,
+			p.next_buy_at = ?,
+			p.last_message = ?
+		WHERE p.id = ? AND p.bot_id = ?
+	`,
+		plan.AmountUsdt,
+		plan.IntervalHours,
+		plan.MaxPrice,
+		plan.UsePriceCalculator,
+		plan.BudgetUsdt,
+		plan.SpentUsdt,
+		plan.Quantity,
+		plan.Orders,
+		plan.Status,
+		plan.NextBuyAt,
+		plan.LastMessage,
+		plan.Id,
+		repo.CurrentBot.Id,
+	)
+
+	if err != nil {
+		log.Println(err)
+	}
+
+	return err
+}
+
+func (repo *DcaRepository) GetPlan(id int64) (model.DcaPlan, error) {
+	for _, plan := range repo.getPlans("p.bot_id = ? AND p.id = ?", repo.CurrentBot.Id, id) {
+		return plan, nil
+	}
+
+	return model.DcaPlan{}, sql.ErrNoRows
+}
+
+func (repo *DcaRepository) GetPlans() []model.DcaPlan {
+	return repo.getPlans("p.bot_id = ?", repo.CurrentBot.Id)
+}
+
+func (repo *DcaRepository) getPlans(where string, args ...any) []model.DcaPlan {
+	res, err := repo.DB.Query(`
+		SELECT
+			p.id as Id,
+			p.symbol as Symbol,
+			p.amount_usdt as AmountUsdt,
+			p.interval_hours as IntervalHours,
+			p.max_price as MaxPrice,
+			p.use_price_calculator as UsePriceCalculator,
+			p.budget_usdt as BudgetUsdt,
+			p.spent_usdt as SpentUsdt,
+			p.quantity as Quantity,
+			p.orders as Orders,
+			p.status as Status,
+			p.next_buy_at as NextBuyAt,
+			p.last_message as LastMessage,
+			p.created_at as CreatedAt
+		FROM dca_plan p
+		WHERE `+where+`
+		ORDER BY p.id ASC
+	`, args...)
+
+	list := make([]model.DcaPlan, 0)
+
+	if err != nil {
+		log.Println(err)
+
+		return list
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var plan model.DcaPlan
+		err := res.Scan(
+			&plan.Id,
+			&plan.Symbol,
+			&plan.AmountUsdt,
+			&plan.IntervalHours,
+			&plan.MaxPrice,
+			&plan.UsePriceCalculator,
+			&plan.BudgetUsdt,
+			&plan.SpentUsdt,
+			&plan.Quantity,
+			&plan.Orders,
+			&plan.Status,
+			&plan.NextBuyAt,
+			&plan.LastMessage,
+			&plan.CreatedAt,
+		)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		list = append(list, plan)
+	}
+
+	return list
+}
+
+func (repo *DcaRepository) CreateOrder(order model.DcaOrder) error {
+	_, err := repo.DB.Exec(`
+		INSERT INTO dca_order SET
+			plan_id = ?,
+			symbol = ?,
+			external_id = ?,
+			price = ?,
+			quantity = ?,
+			amount_usdt = ?,
+			commission = ?,
+			commission_asset = ?,
+			created_at = NOW()
+	`,
+		order.PlanId,
+		order.Symbol,
+		order.ExternalId,
+		order.Price,
+		order.Quantity,
+		order.AmountUsdt,
+		order.Commission,
+		order.CommissionAsset,
+	)
+
+	if err != nil {
+		log.Println(err)
+	}
+
+	return err
+}
+
+// GetOrders returns the latest orders of plan first
+func (repo *DcaRepository) GetOrders(planId int64, limit int64) []model.DcaOrder {
+	res, err := repo.DB.Query(`
+		SELECT
+			o.id as Id,
+			o.plan_id as PlanId,
+			o.symbol as Symbol,
+			o.external_id as ExternalId,
+			o.price as Price,
+			o.quantity as Quantity,
+			o.amount_usdt as AmountUsdt,
+			o.commission as Commission,
+			o.commission_asset as CommissionAsset,
+			o.created_at as CreatedAt
+		FROM dca_order o
+		INNER JOIN dca_plan p ON p.id = o.plan_id
+		WHERE p.bot_id = ? AND o.plan_id = ?
+		ORDER BY o.id DESC
+		LIMIT ?
+	`, repo.CurrentBot.Id, planId, limit)
+
+	list := make([]model.DcaOrder, 0)
+
+	if err != nil {
+		log.Println(err)
+
+		return list
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var order model.DcaOrder
+		err := res.Scan(
+			&order.Id,
+			&order.PlanId,
+			&order.Symbol,
+			&order.ExternalId,
+			&order.Price,
+			&order.Quantity,
+			&order.AmountUsdt,
+			&order.Commission,
+			&order.CommissionAsset,
+			&order.CreatedAt,
+		)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		list = append(list, order)
+	}
+
+	return list
+}
@@ -0,0 +1,183 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"log"
+)
+
+type DecisionJournalStorageInterface interface {
+	Create(entry model.DecisionJournalEntry) error
+	GetList(symbol string, limit int64) []model.DecisionJournalEntry
+	DeleteOlderThan(days int64) (int64, error)
+}
+
+type DecisionJournalRepository struct {
+	DB         *sql.DB
+	CurrentBot *model.Bot
+}
+
+func (repo *DecisionJournalRepository) Create(entry model.DecisionJournalEntry) error {
+	decisions, _ := json.Marshal(entry.Decisions)
+
+	_, err := repo.DB.Exec(`
+		INSERT INTO decision_journal SET
+			bot_id = ?,
+			symbol = ?,
+			operation = ?,
+			result = ?,
+			reason = ?,
+			buy_score = ?,
+			sell_score = ?,
+			hold_score = ?,
+			last_price = ?,
+			price = ?,
+			quantity = ?,
+			order_id = ?,
+			decisions = ?,
+			trade_limit = ?,
+			manual_order = ?,
+			price_calculation = ?,
+			created_at = NOW()
+	`,
+		repo.CurrentBot.Id,
+		entry.Symbol,
+		entry.Operation,
+		entry.Result,
+		entry.Reason,
+		entry.BuyScore,
+		entry.SellScore,
+		entry.HoldScore,
+		entry.LastPrice,
+		entry.Price,
+		entry.Quantity,
+		entry.OrderId,
+		string(decisions),
+		encodeNullableJson(entry.TradeLimit == nil, entry.TradeLimit),
+		encodeNullableJson(entry.ManualOrder == nil, entry.ManualOrder),
+		encodeNullableJson(entry.PriceCalculation == nil, entry.PriceCalculation),
+	)
+
+	if err != nil {
+		log.Println(err)
+	}
+
+	return err
+}
+
+// GetList returns the latest entries first, empty symbol means all symbols
+func (repo *DecisionJournalRepository) GetList(symbol string, limit int64) []model.DecisionJournalEntry {
+	res, err := repo.DB.Query(`
+		SELECT
+			j.id as Id,
+			j.symbol as Symbol,
+			j.operation as Operation,
+			j.result as Result,
+			j.reason as Reason,
+			j.buy_score as BuyScore,
+			j.sell_score as SellScore,
+			j.hold_score as HoldScore,
+			j.last_price as LastPrice,
+			j.price as Price,
+			j.quantity as Quantity,
+			j.order_id as OrderId,
+			j.decisions as Decisions,
+			j.trade_limit as TradeLimit,
+			j.manual_order as ManualOrder,
+			j.price_calculation as PriceCalculation,
+			j.created_at as CreatedAt
+		FROM decision_journal j
+		WHERE j.bot_id = ? AND (? = '' OR j.symbol = ?)
+		ORDER BY j.id DESC
+		LIMIT ?
+	`,
+		repo.CurrentBot.Id,
+		symbol,
+		symbol,
+		limit,
+	)
+
+	list := make([]model.DecisionJournalEntry, 0)
+
+	if err != nil {
+		log.Println(err)
+
+		return list
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var entry model.DecisionJournalEntry
+		var decisions string
+		var tradeLimit sql.NullString
+		var manualOrder sql.NullString
+		var priceCalculation sql.NullString
+		err := res.Scan(
+			&entry.Id,
+			&entry.Symbol,
+			&entry.Operation,
+			&entry.Result,
+			&entry.Reason,
+			&entry.BuyScore,
+			&entry.SellScore,
+			&entry.HoldScore,
+			&entry.LastPrice,
+			&entry.Price,
+			&entry.Quantity,
+			&entry.OrderId,
+			&decisions,
+			&tradeLimit,
+			&manualOrder,
+			&priceCalculation,
+			&entry.CreatedAt,
+		)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		_ = json.Unmarshal([]byte(decisions), &entry.Decisions)
+		if tradeLimit.Valid {
+			_ = json.Unmarshal([]byte(tradeLimit.String), &entry.TradeLimit)
+		}
+		if manualOrder.Valid {
+			_ = json.Unmarshal([]byte(manualOrder.String), &entry.ManualOrder)
+		}
+		if priceCalculation.Valid {
+			_ = json.Unmarshal([]byte(priceCalculation.String), &entry.PriceCalculation)
+		}
+
+		list = append(list, entry)
+	}
+
+	return list
+}
+
+// DeleteOlderThan removes entries of the bot older than retention days
+func (repo *DecisionJournalRepository) DeleteOlderThan(days int64) (int64, error) {
+	res, err := repo.DB.Exec(`
+		DELETE FROM decision_journal
+		WHERE bot_id = ? AND created_at < DATE_SUB(NOW(), INTERVAL ? DAY)
+	`, repo.CurrentBot.Id, days)
+
+	if err != nil {
+		log.Println(err)
+
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
+
+func encodeNullableJson(isNil bool, value any) *string {
+	if isNil {
+		return nil
+	}
+
+	encoded, _ := json.Marshal(value)
+	encodedString := string(encoded)
+
+	return &encodedString
+}
@@ -7,6 +7,8 @@ import (
 	"errors"
 	"fmt"
 	"github.com/redis/go-redis/v9"
+	"gitlab.com/open-soft/go-crypto-bot/src/event"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
 	model "gitlab.com/open-soft/go-crypto-bot/src/model"
 	"log"
 	"slices"
@@ -31,6 +33,34 @@ type ExchangeTradeInfoInterface interface {
 	GetInterpolation(kLine model.KLine) (model.Interpolation, error)
 }
 
+type KLineReaderInterface interface {
+	GetLastKLine(symbol string) *model.KLine
+}
+
+type TradeLimitReaderInterface interface {
+	GetTradeLimits() []model.TradeLimit
+}
+
+type TradeLimitFinderInterface interface {
+	GetTradeLimits() []model.TradeLimit
+	GetTradeLimit(symbol string) (model.TradeLimit, error)
+}
+
+type GridTradeInfoInterface interface {
+	GetTradeLimits() []model.TradeLimit
+	GetLastKLine(symbol string) *model.KLine
+}
+
+type TradeLimitStorageInterface interface {
+	GetTradeLimits() []model.TradeLimit
+	GetTradeLimit(symbol string) (model.TradeLimit, error)
+	CreateTradeLimit(limit model.TradeLimit) (*int64, error)
+	UpdateTradeLimit(limit model.TradeLimit) error
+	DeleteTradeLimit(limit model.TradeLimit) error
+	ArchiveTradeLimit(limit model.TradeLimit) error
+	ImportTradeLimits(limits []model.TradeLimit) error
+}
+
 type ExchangeRepositoryInterface interface {
 	GetSubscribedSymbols() []model.Symbol
 	GetTradeLimits() []model.TradeLimit
@@ -73,6 +103,16 @@ type ExchangeRepository struct {
 	RDB        *redis.Client
 	Ctx        *context.Context
 	CurrentBot *model.Bot
+	MarketBot  *model.Bot // market data (klines, trades, predictions) owner, accounts share market data of main bot
+	EventHub   *event.Hub
+}
+
+func (e *ExchangeRepository) getMarketBotId() int64 {
+	if e.MarketBot != nil {
+		return e.MarketBot.Id
+	}
+
+	return e.CurrentBot.Id
 }
 
 func (e *ExchangeRepository) GetSubscribedSymbols() []model.Symbol {
@@ -98,8 +138,13 @@ func (e *ExchangeRepository) GetTradeLimits() []model.TradeLimit {
 		    tl.frame_period as FramePeriod,
 		    tl.buy_price_history_check_interval as BuyPriceHistoryCheckInterval,
 		    tl.buy_price_history_check_period as BuyPriceHistoryCheckPeriod,
-		    tl.extra_charge_options as ExtraChargeOptions
-		FROM trade_limit tl WHERE tl.bot_id = ?
+		    tl.extra_charge_options as ExtraChargeOptions,
+		    tl.mode as Mode,
+		    tl.grid_options as GridOptions,
+		    tl.take_profit_options as TakeProfitOptions,
+		    tl.execution_options as ExecutionOptions,
+		    tl.order_type as OrderType
+		FROM trade_limit tl WHERE tl.bot_id = ? AND tl.archived_at IS NULL
 	`, e.CurrentBot.Id)
 	defer res.Close()
 
@@ -126,6 +171,11 @@ func (e *ExchangeRepository) GetTradeLimits() []model.TradeLimit {
 			&tradeLimit.BuyPriceHistoryCheckInterval,
 			&tradeLimit.BuyPriceHistoryCheckPeriod,
 			&tradeLimit.ExtraChargeOptions,
+			&tradeLimit.Mode,
+			&tradeLimit.GridOptions,
+			&tradeLimit.TakeProfitOptions,
+			&tradeLimit.ExecutionOptions,
+			&tradeLimit.OrderType,
 		)
 
 		if err != nil {
@@ -155,9 +205,14 @@ func (e *ExchangeRepository) GetTradeLimit(symbol string) (model.TradeLimit, err
 		    tl.frame_period as FramePeriod,
 		    tl.buy_price_history_check_interval as BuyPriceHistoryCheckInterval,
 		    tl.buy_price_history_check_period as BuyPriceHistoryCheckPeriod,
-		    tl.extra_charge_options as ExtraChargeOptions
+		    tl.extra_charge_options as ExtraChargeOptions,
+		    tl.mode as Mode,
+		    tl.grid_options as GridOptions,
+		    tl.take_profit_options as TakeProfitOptions,
+		    tl.execution_options as ExecutionOptions,
+		    tl.order_type as OrderType
 		FROM trade_limit tl
-		WHERE tl.symbol = ? AND tl.bot_id = ?
+		WHERE tl.symbol = ? AND tl.bot_id = ? AND tl.archived_at IS NULL
 	`,
 		symbol,
 		e.CurrentBot.Id,
@@ -176,6 +231,11 @@ func (e *ExchangeRepository) GetTradeLimit(symbol string) (model.TradeLimit, err
 		&tradeLimit.BuyPriceHistoryCheckInterval,
 		&tradeLimit.BuyPriceHistoryCheckPeriod,
 		&tradeLimit.ExtraChargeOptions,
+		&tradeLimit.Mode,
+		&tradeLimit.GridOptions,
+		&tradeLimit.TakeProfitOptions,
+		&tradeLimit.ExecutionOptions,
+		&tradeLimit.OrderType,
 	)
 	if err != nil {
 		return tradeLimit, err
@@ -184,8 +244,17 @@ func (e *ExchangeRepository) GetTradeLimit(symbol string) (model.TradeLimit, err
 	return tradeLimit, nil
 }
 
+type sqlExecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+}
+
+// CreateTradeLimit creates limit or restores archived limit of the same symbol
 func (e *ExchangeRepository) CreateTradeLimit(limit model.TradeLimit) (*int64, error) {
-	res, err := e.DB.Exec(`
+	return e.createTradeLimit(e.DB, limit)
+}
+
+func (e *ExchangeRepository) createTradeLimit(executor sqlExecutor, limit model.TradeLimit) (*int64, error) {
+	res, err := executor.Exec(`
 		INSERT INTO trade_limit SET
 		    symbol = ?,
 		    usdt_limit = ?,
@@ -200,7 +269,32 @@ func (e *ExchangeRepository) CreateTradeLimit(limit model.TradeLimit) (*int64, e
 		    buy_price_history_check_interval = ?,
 		    buy_price_history_check_period = ?,
 		    extra_charge_options = ?,
+		    mode = ?,
+		    grid_options = ?,
+		    take_profit_options = ?,
+		    execution_options = ?,
+		    order_type = ?,
 		    bot_id = ?
+		ON DUPLICATE KEY UPDATE
+		    id = LAST_INSERT_ID(id),
+		    usdt_limit = VALUES(usdt_limit),
+		    min_price = VALUES(min_price),
+		    min_quantity = VALUES(min_quantity),
+		    min_notional = VALUES(min_notional),
+		    min_profit_percent = VALUES(min_profit_percent),
+		    is_enabled = VALUES(is_enabled),
+		    min_price_minutes_period = VALUES(min_price_minutes_period),
+		    frame_interval = VALUES(frame_interval),
+		    frame_period = VALUES(frame_period),
+		    buy_price_history_check_interval = VALUES(buy_price_history_check_interval),
+		    buy_price_history_check_period = VALUES(buy_price_history_check_period),
+		    extra_charge_options = VALUES(extra_charge_options),
+		    mode = VALUES(mode),
+		    grid_options = VALUES(grid_options),
+		    take_profit_options = VALUES(take_profit_options),
+		    execution_options = VALUES(execution_options),
+		    order_type = VALUES(order_type),
+		    archived_at = NULL
 	`,
 		limit.Symbol,
 		limit.USDTLimit,
@@ -215,6 +309,11 @@ func (e *ExchangeRepository) CreateTradeLimit(limit model.TradeLimit) (*int64, e
 		limit.BuyPriceHistoryCheckInterval,
 		limit.BuyPriceHistoryCheckPeriod,
 		limit.ExtraChargeOptions,
+		limit.GetMode(),
+		limit.GridOptions,
+		limit.TakeProfitOptions,
+		limit.ExecutionOptions,
+		limit.GetOrderType(),
 		e.CurrentBot.Id,
 	)
 
@@ -228,6 +327,30 @@ func (e *ExchangeRepository) CreateTradeLimit(limit model.TradeLimit) (*int64, e
 	return &lastId, err
 }
 
+// ImportTradeLimits creates limits without id and updates the others in one transaction
+func (e *ExchangeRepository) ImportTradeLimits(limits []model.TradeLimit) error {
+	tx, err := e.DB.Begin()
+	if err != nil {
+		return err
+	}
+
+	for _, limit := range limits {
+		if limit.Id > 0 {
+			err = e.updateTradeLimit(tx, limit)
+		} else {
+			_, err = e.createTradeLimit(tx, limit)
+		}
+
+		if err != nil {
+			_ = tx.Rollback()
+
+			return errors.New(fmt.Sprintf("%s: %s", limit.Symbol, err.Error()))
+		}
+	}
+
+	return tx.Commit()
+}
+
 func (e *ExchangeRepository) CreateSwapPair(swapPair model.SwapPair) (*int64, error) {
 	res, err := e.DB.Exec(`
 		INSERT INTO swap_pair SET
@@ -576,7 +699,11 @@ func (e *ExchangeRepository) GetSwapPair(symbol string) (model.SwapPair, error)
 }
 
 func (e *ExchangeRepository) UpdateTradeLimit(limit model.TradeLimit) error {
-	_, err := e.DB.Exec(`
+	return e.updateTradeLimit(e.DB, limit)
+}
+
+func (e *ExchangeRepository) updateTradeLimit(executor sqlExecutor, limit model.TradeLimit) error {
+	_, err := executor.Exec(`
 		UPDATE trade_limit tl SET
 		    tl.symbol = ?,
 		    tl.usdt_limit = ?,
@@ -590,7 +717,12 @@ func (e *ExchangeRepository) UpdateTradeLimit(limit model.TradeLimit) error {
 		    tl.frame_period = ?,
 		    tl.buy_price_history_check_interval = ?,
 		    tl.buy_price_history_check_period = ?,
-		    tl.extra_charge_options = ?
+		    tl.extra_charge_options = ?,
+		    tl.mode = ?,
+		    tl.grid_options = ?,
+		    tl.take_profit_options = ?,
+		    tl.execution_options = ?,
+		    tl.order_type = ?
 		WHERE tl.id = ?
 	`,
 		limit.Symbol,
@@ -606,6 +738,11 @@ func (e *ExchangeRepository) UpdateTradeLimit(limit model.TradeLimit) error {
 		limit.BuyPriceHistoryCheckInterval,
 		limit.BuyPriceHistoryCheckPeriod,
 		limit.ExtraChargeOptions,
+		limit.GetMode(),
+		limit.GridOptions,
+		limit.TakeProfitOptions,
+		limit.ExecutionOptions,
+		limit.GetOrderType(),
 		limit.Id,
 	)
 
@@ -617,8 +754,36 @@ func (e *ExchangeRepository) UpdateTradeLimit(limit model.TradeLimit) error {
 	return nil
 }
 
+func (e *ExchangeRepository) DeleteTradeLimit(limit model.TradeLimit) error {
+	_, err := e.DB.Exec(`DELETE FROM trade_limit WHERE id = ? AND bot_id = ?`, limit.Id, e.CurrentBot.Id)
+
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// ArchiveTradeLimit disables limit and hides it from trading, row is kept as history
+func (e *ExchangeRepository) ArchiveTradeLimit(limit model.TradeLimit) error {
+	_, err := e.DB.Exec(`
+		UPDATE trade_limit tl SET
+		    tl.is_enabled = 0,
+		    tl.archived_at = NOW()
+		WHERE tl.id = ? AND tl.bot_id = ?
+	`, limit.Id, e.CurrentBot.Id)
+
+	if err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
 func (e *ExchangeRepository) GetLastKLine(symbol string) *model.KLine {
-	encodedLast := e.RDB.Get(*e.Ctx, fmt.Sprintf("last-kline-%s-%d", symbol, e.CurrentBot.Id)).Val()
+	encodedLast := e.RDB.Get(*e.Ctx, fmt.Sprintf("last-kline-%s-%d", symbol, e.getMarketBotId())).Val()
 
 	if len(encodedLast) > 0 {
 		var dto model.KLine
@@ -642,17 +807,17 @@ func (e *ExchangeRepository) AddKLine(kLine model.KLine) {
 
 	for _, lastKline := range lastKLines {
 		if lastKline.Timestamp == kLine.Timestamp {
-			e.RDB.LPop(*e.Ctx, fmt.Sprintf("k-lines-%s-%d", kLine.Symbol, e.CurrentBot.Id)).Val()
+			e.RDB.LPop(*e.Ctx, fmt.Sprintf("k-lines-%s-%d", kLine.Symbol, e.getMarketBotId())).Val()
 		}
 	}
 
-	e.RDB.LPush(*e.Ctx, fmt.Sprintf("k-lines-%s-%d", kLine.Symbol, e.CurrentBot.Id), string(encoded))
-	e.RDB.LTrim(*e.Ctx, fmt.Sprintf("k-lines-%s-%d", kLine.Symbol, e.CurrentBot.Id), 0, 2880)
-	e.RDB.Set(*e.Ctx, fmt.Sprintf("last-kline-%s-%d", kLine.Symbol, e.CurrentBot.Id), string(encoded), time.Hour)
+	e.RDB.LPush(*e.Ctx, fmt.Sprintf("k-lines-%s-%d", kLine.Symbol, e.getMarketBotId()), string(encoded))
+	e.RDB.LTrim(*e.Ctx, fmt.Sprintf("k-lines-%s-%d", kLine.Symbol, e.getMarketBotId()), 0, 2880)
+	e.RDB.Set(*e.Ctx, fmt.Sprintf("last-kline-%s-%d", kLine.Symbol, e.getMarketBotId()), string(encoded), time.Hour)
 }
 
 func (e *ExchangeRepository) KLineList(symbol string, reverse bool, size int64) []model.KLine {
-	res := e.RDB.LRange(*e.Ctx, fmt.Sprintf("k-lines-%s-%d", symbol, e.CurrentBot.Id), 0, size).Val()
+	res := e.RDB.LRange(*e.Ctx, fmt.Sprintf("k-lines-%s-%d", symbol, e.getMarketBotId()), 0, size).Val()
 	list := make([]model.KLine, 0)
 
 	for _, str := range res {
@@ -735,7 +900,7 @@ func (e *ExchangeRepository) GetTradeVolumes(kLine model.KLine) (float64, float6
 }
 
 func (e *ExchangeRepository) AddTrade(trade model.Trade) {
-	tradeCacheKey := fmt.Sprintf("trades-%s-%s", trade.Symbol, e.CurrentBot.Id)
+	tradeCacheKey := fmt.Sprintf("trades-%s-%d", trade.Symbol, e.getMarketBotId())
 
 	lastTrades := e.TradeList(trade.Symbol)
 	encoded, _ := json.Marshal(trade)
@@ -751,7 +916,7 @@ func (e *ExchangeRepository) AddTrade(trade model.Trade) {
 }
 
 func (e *ExchangeRepository) TradeList(symbol string) []model.Trade {
-	tradeCacheKey := fmt.Sprintf("trades-%s-%s", symbol, e.CurrentBot.Id)
+	tradeCacheKey := fmt.Sprintf("trades-%s-%d", symbol, e.getMarketBotId())
 	res := e.RDB.LRange(*e.Ctx, tradeCacheKey, 0, 2000).Val()
 	list := make([]model.Trade, 0)
 
@@ -765,8 +930,10 @@ func (e *ExchangeRepository) TradeList(symbol string) []model.Trade {
 }
 
 func (e *ExchangeRepository) SetDecision(decision model.Decision, symbol string) {
+	metrics.DecisionsTotal.WithLabelValues(decision.StrategyName, decision.Operation).Inc()
 	encoded, _ := json.Marshal(decision)
 	e.RDB.Set(*e.Ctx, fmt.Sprintf("decision-%s-%s-bot-%d", decision.StrategyName, symbol, e.CurrentBot.Id), string(encoded), time.Second*model.PriceValidSeconds)
+	e.EventHub.Publish(event.TopicDecision, symbol, decision)
 }
 
 func (e *ExchangeRepository) DeleteDecision(strategy string, symbol string) {
@@ -786,7 +953,7 @@ func (e *ExchangeRepository) GetDecision(strategy string, symbol string) *model.
 }
 
 func (e *ExchangeRepository) getPredictedCacheKey(symbol string) string {
-	return fmt.Sprintf("predicted-price-%s-%d", symbol, e.CurrentBot.Id)
+	return fmt.Sprintf("predicted-price-%s-%d", symbol, e.getMarketBotId())
 }
 
 func (e *ExchangeRepository) GetPredict(symbol string) (float64, error) {
@@ -832,7 +999,7 @@ func (e *ExchangeRepository) SaveKLinePredict(predicted float64, kLine model.KLi
 }
 
 func (e *ExchangeRepository) getInterpolationCacheKey(symbol string) string {
-	return fmt.Sprintf("interpolation-price-%s-%d", symbol, e.CurrentBot.Id)
+	return fmt.Sprintf("interpolation-price-%s-%d", symbol, e.getMarketBotId())
 }
 func (e *ExchangeRepository) GetInterpolation(kLine model.KLine) (model.Interpolation, error) {
 	var interpolation model.Interpolation
@@ -0,0 +1,293 @@
+package repository
+
+import (
+	"database/sql"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"log"
+)
+
+type GridStorageInterface interface {
+	CreateGrid(grid model.Grid) (*int64, error)
+	UpdateGrid(grid model.Grid) error
+	UpdateGridLevel(level model.GridLevel) error
+	GetActiveGrids() []model.Grid
+	GetGrids(symbol string) []model.Grid
+}
+
+type GridRepository struct {
+	DB         *sql.DB
+	CurrentBot *model.Bot
+}
+
+// CreateGrid creates grid with its levels in one transaction
+func (repo *GridRepository) CreateGrid(grid model.Grid) (*int64, error) {
+	tx, err := repo.DB.Begin()
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO grid SET
+			bot_id = ?,
+			symbol = ?,
+			lower_price = ?,
+			upper_price = ?,
+			levels = ?,
+			usdt_limit = ?,
+			status = ?,
+			created_at = NOW()
+	`,
+		repo.CurrentBot.Id,
+		grid.Symbol,
+		grid.LowerPrice,
+		grid.UpperPrice,
+		grid.Levels,
+		grid.USDTLimit,
+		grid.Status,
+	)
+	if err != nil {
+		log.Println(err)
+		_ = tx.Rollback()
+
+		return nil, err
+	}
+
+	gridId, err := res.LastInsertId()
+	if err != nil {
+		_ = tx.Rollback()
+
+		return nil, err
+	}
+
+	for _, level := range grid.GridLevels {
+		_, err = tx.Exec(`
+			INSERT INTO grid_level SET
+				grid_id = ?,
+				level_index = ?,
+				buy_price = ?,
+				sell_price = ?,
+				quantity = ?,
+				status = ?
+		`,
+			gridId,
+			level.Index,
+			level.BuyPrice,
+			level.SellPrice,
+			level.Quantity,
+			level.Status,
+		)
+		if err != nil {
+			log.Println(err)
+			_ = tx.Rollback()
+
+			return nil, err
+		}
+	}
+
+	return &gridId, tx.Commit()
+}
+
+func (repo *GridRepository) UpdateGrid(grid model.Grid) error {
+	_, err := repo.DB.Exec(`
+		UPDATE grid g SET
+			g.status = ?,
+			g.trades = ?,
+			g.fees = ?,
+			g.realized_pnl = ?,
+			g.stopped_at = IF(? = 'stopped', NOW(), NULL)
+		WHERE g.id = ? AND g.bot_id = ?
+	`,
+		grid.Status,
+		grid.Trades,
+		grid.Fees,
+		grid.RealizedPnl,
+		grid.Status,
+		grid.Id,
+		repo.CurrentBot.Id,
+	)
+
+	if err != nil {
+		log.Println(err)
+	}
+
+	return err
+}
+
+func (repo *GridRepository) UpdateGridLevel(level model.GridLevel) error {
+	_, err := repo.DB.Exec(`
+		UPDATE grid_level l SET
+			l.status = ?,
+			l.external_id = ?,
+			l.client_order_id = ?,
+			l.buy_external_id = ?,
+			l.bought_price = ?,
+			l.bought_quantity = ?,
+			l.buy_commission = ?,
+			l.buy_commission_asset = ?,
+			l.bought_at = ?,
+			l.buy_order_id = ?,
+			l.trades = ?,
+			l.realized_pnl = ?
+		WHERE l.id = ?
+	`,
+		level.Status,
+		level.ExternalId,
+		level.ClientOrderId,
+		level.BuyExternalId,
+		level.BoughtPrice,
+		level.BoughtQuantity,
+		level.BuyCommission,
+		level.BuyCommissionAsset,
+		level.BoughtAt,
+		level.BuyOrderId,
+		level.Trades,
+		level.RealizedPnl,
+		level.Id,
+	)
+
+	if err != nil {
+		log.Println(err)
+	}
+
+	return err
+}
+
+// GetActiveGrids returns running grids of all symbols
+func (repo *GridRepository) GetActiveGrids() []model.Grid {
+	return repo.getGrids("g.bot_id = ? AND g.status = ?", repo.CurrentBot.Id, model.GridActive)
+}
+
+// GetGrids returns grids of symbol (all symbols if empty), the latest first
+func (repo *GridRepository) GetGrids(symbol string) []model.Grid {
+	return repo.getGrids("g.bot_id = ? AND (? = '' OR g.symbol = ?)", repo.CurrentBot.Id, symbol, symbol)
+}
+
+func (repo *GridRepository) getGrids(where string, args ...any) []model.Grid {
+	res, err := repo.DB.Query(`
+		SELECT
+			g.id as Id,
+			g.symbol as Symbol,
+			g.lower_price as LowerPrice,
+			g.upper_price as UpperPrice,
+			g.levels as Levels,
+			g.usdt_limit as USDTLimit,
+			g.status as Status,
+			g.trades as Trades,
+			g.fees as Fees,
+			g.realized_pnl as RealizedPnl,
+			g.created_at as CreatedAt,
+			g.stopped_at as StoppedAt
+		FROM grid g
+		WHERE `+where+`
+		ORDER BY g.id DESC
+	`, args...)
+
+	list := make([]model.Grid, 0)
+
+	if err != nil {
+		log.Println(err)
+
+		return list
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var grid model.Grid
+		err := res.Scan(
+			&grid.Id,
+			&grid.Symbol,
+			&grid.LowerPrice,
+			&grid.UpperPrice,
+			&grid.Levels,
+			&grid.USDTLimit,
+			&grid.Status,
+			&grid.Trades,
+			&grid.Fees,
+			&grid.RealizedPnl,
+			&grid.CreatedAt,
+			&grid.StoppedAt,
+		)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		list = append(list, grid)
+	}
+
+	for index := range list {
+		list[index].GridLevels = repo.getGridLevels(list[index].Id)
+	}
+
+	return list
+}
+
+func (repo *GridRepository) getGridLevels(gridId int64) []model.GridLevel {
+	res, err := repo.DB.Query(`
+		SELECT
+			l.id as Id,
+			l.grid_id as GridId,
+			l.level_index as LevelIndex,
+			l.buy_price as BuyPrice,
+			l.sell_price as SellPrice,
+			l.quantity as Quantity,
+			l.status as Status,
+			l.external_id as ExternalId,
+			l.client_order_id as ClientOrderId,
+			l.buy_external_id as BuyExternalId,
+			l.bought_price as BoughtPrice,
+			l.bought_quantity as BoughtQuantity,
+			l.buy_commission as BuyCommission,
+			l.buy_commission_asset as BuyCommissionAsset,
+			l.bought_at as BoughtAt,
+			l.buy_order_id as BuyOrderId,
+			l.trades as Trades,
+			l.realized_pnl as RealizedPnl
+		FROM grid_level l
+		WHERE l.grid_id = ?
+		ORDER BY l.level_index ASC
+	`, gridId)
+
+	list := make([]model.GridLevel, 0)
+
+	if err != nil {
+		log.Println(err)
+
+		return list
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var level model.GridLevel
+		err := res.Scan(
+			&level.Id,
+			&level.GridId,
+			&level.Index,
+			&level.BuyPrice,
+			&level.SellPrice,
+			&level.Quantity,
+			&level.Status,
+			&level.ExternalId,
+			&level.ClientOrderId,
+			&level.BuyExternalId,
+			&level.BoughtPrice,
+			&level.BoughtQuantity,
+			&level.BuyCommission,
+			&level.BuyCommissionAsset,
+			&level.BoughtAt,
+			&level.BuyOrderId,
+			&level.Trades,
+			&level.RealizedPnl,
+		)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		list = append(list, level)
+	}
+
+	return list
+}
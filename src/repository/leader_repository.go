@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"github.com/redis/go-redis/v9"
+	"time"
+)
+
+type LeaderLeaseStorageInterface interface {
+	AcquireLease(key string, identity string, ttl time.Duration) (bool, error)
+	RenewLease(key string, identity string, ttl time.Duration) (bool, error)
+	ReleaseLease(key string, identity string) error
+	GetLeaseOwner(key string) string
+}
+
+// lease is renewed and released only by its owner
+var renewLeaseScript = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+	end
+	return 0
+`)
+
+var releaseLeaseScript = redis.NewScript(`
+	if redis.call("GET", KEYS[1]) == ARGV[1] then
+		return redis.call("DEL", KEYS[1])
+	end
+	return 0
+`)
+
+type LeaderRepository struct {
+	RDB *redis.Client
+	Ctx *context.Context
+}
+
+func (l *LeaderRepository) AcquireLease(key string, identity string, ttl time.Duration) (bool, error) {
+	return l.RDB.SetNX(*l.Ctx, key, identity, ttl).Result()
+}
+
+func (l *LeaderRepository) RenewLease(key string, identity string, ttl time.Duration) (bool, error) {
+	renewed, err := renewLeaseScript.Run(*l.Ctx, l.RDB, []string{key}, identity, ttl.Milliseconds()).Int()
+
+	return renewed == 1, err
+}
+
+func (l *LeaderRepository) ReleaseLease(key string, identity string) error {
+	return releaseLeaseScript.Run(*l.Ctx, l.RDB, []string{key}, identity).Err()
+}
+
+func (l *LeaderRepository) GetLeaseOwner(key string) string {
+	return l.RDB.Get(*l.Ctx, key).Val()
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"database/sql"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"log"
+)
+
+type LotStorageInterface interface {
+	Create(entry model.LotEntry) error
+	GetEntries(to string) []model.LotEntry
+}
+
+type LotRepository struct {
+	DB         *sql.DB
+	CurrentBot *model.Bot
+}
+
+// Create ignores fill which is already recorded (same exchange order, type and asset),
+// entry is dated by fill time or by current time if fill time is unknown
+func (repo *LotRepository) Create(entry model.LotEntry) error {
+	var createdAt any
+	if entry.CreatedAt != "" {
+		createdAt = entry.CreatedAt
+	}
+
+	_, err := repo.DB.Exec(`
+		INSERT IGNORE INTO lot_ledger SET
+			bot_id = ?,
+			asset = ?,
+			symbol = ?,
+			order_id = ?,
+			external_id = ?,
+			type = ?,
+			quantity = ?,
+			price = ?,
+			value = ?,
+			fee = ?,
+			created_at = IFNULL(?, NOW())
+	`,
+		repo.CurrentBot.Id,
+		entry.Asset,
+		entry.Symbol,
+		entry.OrderId,
+		entry.ExternalId,
+		entry.Type,
+		entry.Quantity,
+		entry.Price,
+		entry.Value,
+		entry.Fee,
+		createdAt,
+	)
+
+	if err != nil {
+		log.Println(err)
+	}
+
+	return err
+}
+
+// GetEntries returns fills until date in chronological order, all previous lots are needed to match disposals
+func (repo *LotRepository) GetEntries(to string) []model.LotEntry {
+	res, err := repo.DB.Query(`
+		SELECT
+			l.id as Id,
+			l.asset as Asset,
+			l.symbol as Symbol,
+			l.order_id as OrderId,
+			l.external_id as ExternalId,
+			l.type as Type,
+			l.quantity as Quantity,
+			l.price as Price,
+			l.value as Value,
+			l.fee as Fee,
+			l.created_at as CreatedAt
+		FROM lot_ledger l
+		WHERE l.bot_id = ? AND l.created_at <= ?
+		ORDER BY l.created_at ASC, l.id ASC
+	`,
+		repo.CurrentBot.Id,
+		to,
+	)
+
+	list := make([]model.LotEntry, 0)
+
+	if err != nil {
+		log.Println(err)
+
+		return list
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var entry model.LotEntry
+		err := res.Scan(
+			&entry.Id,
+			&entry.Asset,
+			&entry.Symbol,
+			&entry.OrderId,
+			&entry.ExternalId,
+			&entry.Type,
+			&entry.Quantity,
+			&entry.Price,
+			&entry.Value,
+			&entry.Fee,
+			&entry.CreatedAt,
+		)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		list = append(list, entry)
+	}
+
+	return list
+}
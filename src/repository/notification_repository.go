@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"github.com/google/uuid"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"log"
+	"time"
+)
+
+type NotificationStorageInterface interface {
+	Create(job model.NotificationJob) (*int64, error)
+	GetDue(limit int64) []model.NotificationJob
+	MarkSent(id int64) error
+	MarkFailed(id int64, attempts int64, retryIn time.Duration, status string, lastError string) error
+}
+
+// notificationClaimTimeout is time after which claimed notification can be claimed again (worker is stopped while sending)
+const notificationClaimTimeout = time.Minute * 5
+
+type NotificationRepository struct {
+	DB         *sql.DB
+	CurrentBot *model.Bot
+}
+
+func (repo *NotificationRepository) Create(job model.NotificationJob) (*int64, error) {
+	payload, _ := json.Marshal(job.Notification)
+	res, err := repo.DB.Exec(`
+		INSERT INTO notification_queue SET
+			bot_id = ?,
+			channel = ?,
+			event = ?,
+			payload = ?,
+			status = ?,
+			attempts = 0,
+			next_attempt_at = NOW(),
+			created_at = NOW()
+	`,
+		repo.CurrentBot.Id,
+		job.Channel,
+		job.Notification.Event,
+		string(payload),
+		model.NotificationStatusPending,
+	)
+
+	if err != nil {
+		log.Println(err)
+
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	return &lastId, err
+}
+
+// GetDue claims due notifications, claimed notifications are not returned to other workers until claim timeout
+func (repo *NotificationRepository) GetDue(limit int64) []model.NotificationJob {
+	list := make([]model.NotificationJob, 0)
+	claimId := uuid.New().String()
+
+	_, err := repo.DB.Exec(`
+		UPDATE notification_queue SET
+			status = ?,
+			claim_id = ?,
+			next_attempt_at = DATE_ADD(NOW(), INTERVAL ? SECOND)
+		WHERE bot_id = ? AND status IN (?, ?) AND next_attempt_at <= NOW()
+		ORDER BY id ASC
+		LIMIT ?
+	`,
+		model.NotificationStatusSending,
+		claimId,
+		int64(notificationClaimTimeout.Seconds()),
+		repo.CurrentBot.Id,
+		model.NotificationStatusPending,
+		model.NotificationStatusSending,
+		limit,
+	)
+
+	if err != nil {
+		log.Println(err)
+
+		return list
+	}
+
+	res, err := repo.DB.Query(`
+		SELECT
+			q.id as Id,
+			q.channel as Channel,
+			q.payload as Payload,
+			q.status as Status,
+			q.attempts as Attempts,
+			q.next_attempt_at as NextAttemptAt,
+			q.last_error as LastError
+		FROM notification_queue q
+		WHERE q.bot_id = ? AND q.claim_id = ? AND q.status = ?
+		ORDER BY q.id ASC
+	`,
+		repo.CurrentBot.Id,
+		claimId,
+		model.NotificationStatusSending,
+	)
+
+	if err != nil {
+		log.Println(err)
+
+		return list
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var job model.NotificationJob
+		var payload string
+		err := res.Scan(
+			&job.Id,
+			&job.Channel,
+			&payload,
+			&job.Status,
+			&job.Attempts,
+			&job.NextAttemptAt,
+			&job.LastError,
+		)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		_ = json.Unmarshal([]byte(payload), &job.Notification)
+		list = append(list, job)
+	}
+
+	return list
+}
+
+func (repo *NotificationRepository) MarkSent(id int64) error {
+	_, err := repo.DB.Exec(`
+		UPDATE notification_queue q SET
+			q.status = ?,
+			q.attempts = q.attempts + 1,
+			q.sent_at = NOW()
+		WHERE q.id = ? AND q.bot_id = ?
+	`,
+		model.NotificationStatusSent,
+		id,
+		repo.CurrentBot.Id,
+	)
+
+	return err
+}
+
+func (repo *NotificationRepository) MarkFailed(id int64, attempts int64, retryIn time.Duration, status string, lastError string) error {
+	if len(lastError) > 1024 {
+		lastError = lastError[:1024]
+	}
+
+	_, err := repo.DB.Exec(`
+		UPDATE notification_queue q SET
+			q.status = ?,
+			q.attempts = ?,
+			q.next_attempt_at = DATE_ADD(NOW(), INTERVAL ? SECOND),
+			q.last_error = ?
+		WHERE q.id = ? AND q.bot_id = ?
+	`,
+		status,
+		attempts,
+		int64(retryIn.Seconds()),
+		lastError,
+		id,
+		repo.CurrentBot.Id,
+	)
+
+	return err
+}
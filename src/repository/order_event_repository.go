@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"database/sql"
+	ExchangeModel "gitlab.com/open-soft/go-crypto-bot/src/model"
+	"log"
+)
+
+type OrderEventStorageInterface interface {
+	Create(event ExchangeModel.OrderEvent) (*int64, error)
+	GetOrderEvents(order ExchangeModel.Order) []ExchangeModel.OrderEvent
+}
+
+type OrderEventRepository struct {
+	DB         *sql.DB
+	CurrentBot *ExchangeModel.Bot
+}
+
+func (repo *OrderEventRepository) Create(event ExchangeModel.OrderEvent) (*int64, error) {
+	res, err := repo.DB.Exec(`
+		INSERT INTO order_event SET
+			bot_id = ?,
+			order_id = ?,
+			symbol = ?,
+			external_id = ?,
+			operation = ?,
+			type = ?,
+			reason = ?,
+			price = ?,
+			current_price = ?,
+			quantity = ?,
+			executed_qty = ?,
+			details = ?,
+			created_at = NOW()
+	`,
+		repo.CurrentBot.Id,
+		event.OrderId,
+		event.Symbol,
+		event.ExternalId,
+		event.Operation,
+		event.Type,
+		event.Reason,
+		event.Price,
+		event.CurrentPrice,
+		event.Quantity,
+		event.ExecutedQty,
+		event.Details,
+	)
+
+	if err != nil {
+		log.Println(err)
+
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	return &lastId, err
+}
+
+// Events are attached to order directly or through binance order id (events are written before order is saved)
+func (repo *OrderEventRepository) GetOrderEvents(order ExchangeModel.Order) []ExchangeModel.OrderEvent {
+	res, err := repo.DB.Query(`
+		SELECT
+			e.id as Id,
+			e.order_id as OrderId,
+			e.symbol as Symbol,
+			e.external_id as ExternalId,
+			e.operation as Operation,
+			e.type as Type,
+			e.reason as Reason,
+			e.price as Price,
+			e.current_price as CurrentPrice,
+			e.quantity as Quantity,
+			e.executed_qty as ExecutedQty,
+			e.details as Details,
+			e.created_at as CreatedAt
+		FROM order_event e
+		WHERE e.bot_id = ? AND (
+		    e.order_id = ? OR e.order_id IN (SELECT o.id FROM orders o WHERE o.closes_order = ? AND o.bot_id = ?) OR
+		    (e.symbol = ? AND e.external_id IN (SELECT o.external_id FROM orders o WHERE (o.id = ? OR o.closes_order = ?) AND o.bot_id = ?))
+		)
+		ORDER BY e.id ASC
+	`,
+		repo.CurrentBot.Id,
+		order.Id,
+		order.Id,
+		repo.CurrentBot.Id,
+		order.Symbol,
+		order.Id,
+		order.Id,
+		repo.CurrentBot.Id,
+	)
+	defer res.Close()
+
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	list := make([]ExchangeModel.OrderEvent, 0)
+
+	for res.Next() {
+		var event ExchangeModel.OrderEvent
+		err := res.Scan(
+			&event.Id,
+			&event.OrderId,
+			&event.Symbol,
+			&event.ExternalId,
+			&event.Operation,
+			&event.Type,
+			&event.Reason,
+			&event.Price,
+			&event.CurrentPrice,
+			&event.Quantity,
+			&event.ExecutedQty,
+			&event.Details,
+			&event.CreatedAt,
+		)
+
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		list = append(list, event)
+	}
+
+	return list
+}
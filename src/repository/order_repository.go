@@ -33,6 +33,27 @@ type OrderStorageInterface interface {
 	GetBinanceOrder(symbol string, operation string) *ExchangeModel.BinanceOrder
 	LockBuy(symbol string, seconds int64)
 	HasBuyLock(symbol string) bool
+	GetTakeProfitHighPrice(order ExchangeModel.Order) float64
+	SetTakeProfitHighPrice(order ExchangeModel.Order, price float64)
+}
+
+type OrderReconcileStorageInterface interface {
+	Create(order ExchangeModel.Order) (*int64, error)
+	Update(order ExchangeModel.Order) error
+	GetOpenedOrder(symbol string, operation string) (ExchangeModel.Order, error)
+	FindByExternalId(symbol string, externalId int64) (ExchangeModel.Order, error)
+	GetClosesOrderList(buyOrder ExchangeModel.Order) []ExchangeModel.Order
+	SetBinanceOrder(order ExchangeModel.BinanceOrder)
+	GetBinanceOrder(symbol string, operation string) *ExchangeModel.BinanceOrder
+	DeleteBinanceOrder(order ExchangeModel.BinanceOrder)
+}
+
+type OrderCreatorInterface interface {
+	Create(order ExchangeModel.Order) (*int64, error)
+}
+
+type OrderListReaderInterface interface {
+	GetList() []ExchangeModel.Order
 }
 
 type OrderRepository struct {
@@ -299,6 +320,23 @@ func (repo *OrderRepository) Find(id int64) (ExchangeModel.Order, error) {
 	return order, nil
 }
 
+func (repo *OrderRepository) FindByExternalId(symbol string, externalId int64) (ExchangeModel.Order, error) {
+	var id int64
+
+	err := repo.DB.QueryRow(`
+		SELECT o.id as Id FROM orders o WHERE o.symbol = ? AND o.external_id = ? AND o.bot_id = ?`,
+		symbol,
+		externalId,
+		repo.CurrentBot.Id,
+	).Scan(&id)
+
+	if err != nil {
+		return ExchangeModel.Order{}, err
+	}
+
+	return repo.Find(id)
+}
+
 func (repo *OrderRepository) GetTrades() []ExchangeModel.OrderTrade {
 	res, err := repo.DB.Query(`
 		SELECT
@@ -578,3 +616,26 @@ func (repo *OrderRepository) LockBuy(symbol string, seconds int64) {
 		repo.CurrentBot.Id,
 	), "lock", time.Second*time.Duration(seconds))
 }
+
+// GetTakeProfitHighPrice is the highest price of position after target of trailing tier is reached, 0 before
+func (repo *OrderRepository) GetTakeProfitHighPrice(order ExchangeModel.Order) float64 {
+	price, err := repo.RDB.Get(*repo.Ctx, fmt.Sprintf(
+		"take-profit-high-%d-bot-%d",
+		order.Id,
+		repo.CurrentBot.Id,
+	)).Float64()
+
+	if err != nil {
+		return 0.00
+	}
+
+	return price
+}
+
+func (repo *OrderRepository) SetTakeProfitHighPrice(order ExchangeModel.Order, price float64) {
+	repo.RDB.Set(*repo.Ctx, fmt.Sprintf(
+		"take-profit-high-%d-bot-%d",
+		order.Id,
+		repo.CurrentBot.Id,
+	), price, time.Hour*24*90)
+}
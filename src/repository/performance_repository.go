@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"database/sql"
+	"encoding/json"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"log"
+)
+
+type PerformanceStorageInterface interface {
+	CreateSnapshot(snapshot model.PerformanceSnapshot) (*int64, error)
+	GetSnapshots(symbol string) []model.PerformanceSnapshot
+	CreateOrderStrategies(orderId int64, decisions []model.Decision) error
+	GetOrderStrategies() map[int64][]string
+}
+
+type PerformanceRepository struct {
+	DB         *sql.DB
+	CurrentBot *model.Bot
+}
+
+func (repo *PerformanceRepository) CreateSnapshot(snapshot model.PerformanceSnapshot) (*int64, error) {
+	tradeLimit, _ := json.Marshal(snapshot.TradeLimit)
+	stats, _ := json.Marshal(snapshot.Stats)
+
+	var periodFrom *string
+	if snapshot.Stats.From != "" {
+		periodFrom = &snapshot.Stats.From
+	}
+
+	res, err := repo.DB.Exec(`
+		INSERT INTO performance_snapshot SET
+			bot_id = ?,
+			symbol = ?,
+			reason = ?,
+			trade_limit = ?,
+			stats = ?,
+			period_from = ?,
+			period_to = ?,
+			created_at = NOW()
+	`,
+		repo.CurrentBot.Id,
+		snapshot.Symbol,
+		snapshot.Reason,
+		string(tradeLimit),
+		string(stats),
+		periodFrom,
+		snapshot.Stats.To,
+	)
+
+	if err != nil {
+		log.Println(err)
+
+		return nil, err
+	}
+
+	lastId, err := res.LastInsertId()
+
+	return &lastId, err
+}
+
+// GetSnapshots returns snapshots of symbol, the latest first
+func (repo *PerformanceRepository) GetSnapshots(symbol string) []model.PerformanceSnapshot {
+	res, err := repo.DB.Query(`
+		SELECT
+			s.id as Id,
+			s.symbol as Symbol,
+			s.reason as Reason,
+			s.trade_limit as TradeLimit,
+			s.stats as Stats,
+			s.created_at as CreatedAt
+		FROM performance_snapshot s
+		WHERE s.bot_id = ? AND s.symbol = ?
+		ORDER BY s.period_to DESC, s.id DESC
+	`,
+		repo.CurrentBot.Id,
+		symbol,
+	)
+
+	list := make([]model.PerformanceSnapshot, 0)
+
+	if err != nil {
+		log.Println(err)
+
+		return list
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var snapshot model.PerformanceSnapshot
+		var tradeLimit string
+		var stats string
+		err := res.Scan(
+			&snapshot.Id,
+			&snapshot.Symbol,
+			&snapshot.Reason,
+			&tradeLimit,
+			&stats,
+			&snapshot.CreatedAt,
+		)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		_ = json.Unmarshal([]byte(tradeLimit), &snapshot.TradeLimit)
+		_ = json.Unmarshal([]byte(stats), &snapshot.Stats)
+		list = append(list, snapshot)
+	}
+
+	return list
+}
+
+// CreateOrderStrategies attributes opened position to strategies which decided to buy
+func (repo *PerformanceRepository) CreateOrderStrategies(orderId int64, decisions []model.Decision) error {
+	for _, decision := range decisions {
+		_, err := repo.DB.Exec(`
+			INSERT IGNORE INTO order_strategy SET
+				bot_id = ?,
+				order_id = ?,
+				strategy = ?,
+				score = ?,
+				created_at = NOW()
+		`,
+			repo.CurrentBot.Id,
+			orderId,
+			decision.StrategyName,
+			decision.Score,
+		)
+
+		if err != nil {
+			log.Println(err)
+
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (repo *PerformanceRepository) GetOrderStrategies() map[int64][]string {
+	strategies := make(map[int64][]string)
+
+	res, err := repo.DB.Query(`
+		SELECT
+			os.order_id as OrderId,
+			os.strategy as Strategy
+		FROM order_strategy os
+		WHERE os.bot_id = ?
+		ORDER BY os.order_id ASC, os.strategy ASC
+	`, repo.CurrentBot.Id)
+
+	if err != nil {
+		log.Println(err)
+
+		return strategies
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var orderId int64
+		var strategy string
+		err := res.Scan(&orderId, &strategy)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		strategies[orderId] = append(strategies[orderId], strategy)
+	}
+
+	return strategies
+}
@@ -43,6 +43,10 @@ type SwapRepositoryInterface interface {
 	GetSwapPairBySymbol(symbol string) (model.SwapPair, error)
 }
 
+type SwapGainReaderInterface interface {
+	GetSwapGains() map[int64]float64
+}
+
 type SwapRepository struct {
 	DB         *sql.DB
 	RDB        *redis.Client
@@ -753,11 +757,7 @@ func (s *SwapRepository) UpdateSwapAction(action model.SwapAction) error {
 	return nil
 }
 
-func (s *SwapRepository) GetActiveSwapAction(order model.Order) (model.SwapAction, error) {
-	var action model.SwapAction
-
-	err := s.DB.QueryRow(`
-		SELECT
+const swapActionColumns = `
 		    sa.id as Id,
 		    sa.order_id as OrderId,
 		    sa.bot_id as BotId,
@@ -783,11 +783,16 @@ func (s *SwapRepository) GetActiveSwapAction(order model.Order) (model.SwapActio
 		    sa.swap_three_symbol as SwapThreeSymbol,
 		    sa.swap_three_price as SwapThreePrice,
 		    sa.swap_three_timestamp as SwapThreeTimestamp
-		FROM swap_action sa
-		WHERE sa.order_id = ? AND sa.status IN (?, ?)
-	`,
-		order.Id, model.SwapActionStatusPending, model.SwapActionStatusProcess,
-	).Scan(
+`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanSwapAction(row rowScanner) (model.SwapAction, error) {
+	var action model.SwapAction
+
+	err := row.Scan(
 		&action.Id,
 		&action.OrderId,
 		&action.BotId,
@@ -814,11 +819,58 @@ func (s *SwapRepository) GetActiveSwapAction(order model.Order) (model.SwapActio
 		&action.SwapThreePrice,
 		&action.SwapThreeTimestamp,
 	)
+
+	return action, err
+}
+
+func (s *SwapRepository) GetActiveSwapAction(order model.Order) (model.SwapAction, error) {
+	return scanSwapAction(s.DB.QueryRow(`
+		SELECT`+swapActionColumns+`
+		FROM swap_action sa
+		WHERE sa.order_id = ? AND sa.status IN (?, ?)
+	`,
+		order.Id, model.SwapActionStatusPending, model.SwapActionStatusProcess,
+	))
+}
+
+// GetSwapActions returns the latest swap actions of current bot
+func (s *SwapRepository) GetSwapActions(limit int64) []model.SwapAction {
+	list := make([]model.SwapAction, 0)
+
+	res, err := s.DB.Query(`
+		SELECT`+swapActionColumns+`
+		FROM swap_action sa
+		WHERE sa.bot_id = ?
+		ORDER BY sa.id DESC
+		LIMIT ?
+	`, s.CurrentBot.Id, limit)
+
 	if err != nil {
-		return action, err
+		log.Println(err)
+
+		return list
+	}
+	defer res.Close()
+
+	for res.Next() {
+		action, err := scanSwapAction(res)
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		list = append(list, action)
 	}
 
-	return action, nil
+	return list
+}
+
+func (s *SwapRepository) GetSwapAction(id int64) (model.SwapAction, error) {
+	return scanSwapAction(s.DB.QueryRow(`
+		SELECT`+swapActionColumns+`
+		FROM swap_action sa
+		WHERE sa.id = ? AND sa.bot_id = ?
+	`, id, s.CurrentBot.Id))
 }
 
 func (e *SwapRepository) GetSwapPairBySymbol(symbol string) (model.SwapPair, error) {
@@ -865,3 +917,39 @@ func (e *SwapRepository) GetSwapPairBySymbol(symbol string) (model.SwapPair, err
 
 	return swapPair, nil
 }
+
+// GetSwapGains returns quantity gained by successful swaps per order, swap changes position quantity without order update
+func (s *SwapRepository) GetSwapGains() map[int64]float64 {
+	gains := make(map[int64]float64)
+
+	res, err := s.DB.Query(`
+		SELECT
+			sa.order_id as OrderId,
+			SUM(sa.end_quantity - sa.start_quantity) as Gain
+		FROM swap_action sa
+		WHERE sa.bot_id = ? AND sa.status = ? AND sa.end_quantity IS NOT NULL
+		GROUP BY sa.order_id
+	`, s.CurrentBot.Id, model.SwapActionStatusSuccess)
+
+	if err != nil {
+		log.Println(err)
+
+		return gains
+	}
+	defer res.Close()
+
+	for res.Next() {
+		var orderId int64
+		var gain float64
+		err := res.Scan(&orderId, &gain)
+
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+
+		gains[orderId] = gain
+	}
+
+	return gains
+}
@@ -0,0 +1,148 @@
+package service
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"github.com/google/uuid"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"io"
+	"log"
+	"strings"
+)
+
+// encryptedSecretPrefix marks Binance API secret encrypted by AES-GCM, value without prefix is plaintext (created before encryption)
+const encryptedSecretPrefix = "aes:"
+
+type AccountStorageInterface interface {
+	GetAccounts() []model.Bot
+	CreateAccount(bot model.Bot) error
+	UpdateAccountSecret(bot model.Bot) error
+}
+
+// AccountService creates accounts (bots with own Binance credentials), Binance API secret is stored encrypted by SecretKey
+type AccountService struct {
+	BotRepository AccountStorageInterface
+	SecretKey     string
+}
+
+func (a *AccountService) Create(apiKey string, apiSecret string) (model.Bot, error) {
+	account := model.Bot{
+		BotUuid:       uuid.New().String(),
+		BinanceApiKey: apiKey,
+	}
+
+	encrypted, err := a.EncryptSecret(apiSecret)
+	if err != nil {
+		return account, err
+	}
+	account.BinanceApiSecret = encrypted
+
+	err = a.BotRepository.CreateAccount(account)
+	if err != nil {
+		return account, err
+	}
+
+	account.BinanceApiSecret = apiSecret
+
+	return account, nil
+}
+
+// GetAccounts returns accounts with decrypted secrets, plaintext secret is encrypted in storage,
+// account which secret can't be decrypted (SecretKey is changed) is skipped
+func (a *AccountService) GetAccounts() []model.Bot {
+	list := make([]model.Bot, 0)
+
+	for _, account := range a.BotRepository.GetAccounts() {
+		if !strings.HasPrefix(account.BinanceApiSecret, encryptedSecretPrefix) {
+			a.encryptStored(account)
+			list = append(list, account)
+			continue
+		}
+
+		secret, err := a.DecryptSecret(account.BinanceApiSecret)
+		if err != nil {
+			log.Printf("Account [%s] is skipped, secret can't be decrypted: %s", account.BotUuid, err.Error())
+			continue
+		}
+
+		account.BinanceApiSecret = secret
+		list = append(list, account)
+	}
+
+	return list
+}
+
+func (a *AccountService) EncryptSecret(secret string) (string, error) {
+	gcm, err := a.getCipher()
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return "", err
+	}
+
+	encrypted := gcm.Seal(nonce, nonce, []byte(secret), nil)
+
+	return encryptedSecretPrefix + base64.StdEncoding.EncodeToString(encrypted), nil
+}
+
+func (a *AccountService) DecryptSecret(value string) (string, error) {
+	gcm, err := a.getCipher()
+	if err != nil {
+		return "", err
+	}
+
+	encrypted, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(value, encryptedSecretPrefix))
+	if err != nil {
+		return "", err
+	}
+
+	if len(encrypted) < gcm.NonceSize() {
+		return "", errors.New("Encrypted secret is too short")
+	}
+
+	nonce, encrypted := encrypted[:gcm.NonceSize()], encrypted[gcm.NonceSize():]
+	secret, err := gcm.Open(nil, nonce, encrypted, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return string(secret), nil
+}
+
+func (a *AccountService) encryptStored(account model.Bot) {
+	encrypted, err := a.EncryptSecret(account.BinanceApiSecret)
+	if err == nil {
+		account.BinanceApiSecret = encrypted
+		err = a.BotRepository.UpdateAccountSecret(account)
+	}
+
+	if err != nil {
+		log.Printf("Account [%s] secret is stored as plaintext: %s", account.BotUuid, err.Error())
+		return
+	}
+
+	log.Printf("Account [%s] secret is encrypted", account.BotUuid)
+}
+
+// getCipher uses AES-256 key derived from SecretKey
+func (a *AccountService) getCipher() (cipher.AEAD, error) {
+	if a.SecretKey == "" {
+		return nil, errors.New("bot.accountSecretKey is not set")
+	}
+
+	key := sha256.Sum256([]byte(a.SecretKey))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
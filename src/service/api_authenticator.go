@@ -0,0 +1,131 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/client"
+	ExchangeModel "gitlab.com/open-soft/go-crypto-bot/src/model"
+	ExchangeRepository "gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+type ApiAuthenticatorInterface interface {
+	Authenticate(req *http.Request, scope string) (*ExchangeModel.ApiClient, error)
+}
+
+// ApiAuthenticator checks HMAC-SHA256 signature of: timestamp + method + request uri + body
+type ApiAuthenticator struct {
+	ApiClientRepository ExchangeRepository.ApiClientStorageInterface
+	CurrentBot          *ExchangeModel.Bot
+	RecvWindow          int64  // milliseconds
+	LegacyScope         string // scope for botUuid query authorization, empty disables it
+}
+
+func (a *ApiAuthenticator) Authenticate(req *http.Request, scope string) (*ExchangeModel.ApiClient, error) {
+	apiKey := req.Header.Get(ExchangeModel.ApiHeaderKey)
+
+	if apiKey == "" {
+		return a.authenticateLegacy(req, scope)
+	}
+
+	timestamp, err := strconv.ParseInt(req.Header.Get(ExchangeModel.ApiHeaderTimestamp), 10, 64)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("%s header is invalid", ExchangeModel.ApiHeaderTimestamp))
+	}
+
+	diff := time.Now().UnixMilli() - timestamp
+	if diff > a.RecvWindow || diff < -a.RecvWindow {
+		return nil, errors.New("Request timestamp is outside of recvWindow")
+	}
+
+	apiClient, err := a.ApiClientRepository.FindByApiKey(apiKey)
+	if err != nil || !apiClient.IsEnabled {
+		return nil, errors.New("API key is invalid")
+	}
+
+	body := make([]byte, 0)
+	if req.Body != nil {
+		body, err = io.ReadAll(req.Body)
+		if err != nil {
+			return nil, err
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+
+	signature := req.Header.Get(ExchangeModel.ApiHeaderSignature)
+	expected := client.SignApiRequest(apiClient.ApiSecret, timestamp, req.Method, req.URL.RequestURI(), body)
+	if !hmac.Equal([]byte(signature), []byte(expected)) {
+		return nil, errors.New("Signature is invalid")
+	}
+
+	// signature contains timestamp, so it is enough to remember it for twice recvWindow
+	if !a.ApiClientRepository.RememberSignature(apiKey, signature, time.Millisecond*time.Duration(a.RecvWindow*2)) {
+		return nil, errors.New("Request is already processed")
+	}
+
+	if !apiClient.HasScope(scope) {
+		return nil, errors.New(fmt.Sprintf("Scope '%s' is required", scope))
+	}
+
+	a.ApiClientRepository.Touch(apiClient)
+
+	return &apiClient, nil
+}
+
+func (a *ApiAuthenticator) authenticateLegacy(req *http.Request, scope string) (*ExchangeModel.ApiClient, error) {
+	if a.LegacyScope == "" || req.URL.Query().Get("botUuid") != a.CurrentBot.BotUuid {
+		return nil, errors.New("Forbidden")
+	}
+
+	apiClient := ExchangeModel.ApiClient{
+		Name:      "botUuid",
+		Scope:     a.LegacyScope,
+		IsEnabled: true,
+	}
+
+	if !apiClient.HasScope(scope) {
+		return nil, errors.New(fmt.Sprintf("Scope '%s' is required, use API key", scope))
+	}
+
+	return &apiClient, nil
+}
+
+func GenerateApiClient(name string, scope string) (ExchangeModel.ApiClient, error) {
+	if !ExchangeModel.IsValidApiScope(scope) {
+		return ExchangeModel.ApiClient{}, errors.New(fmt.Sprintf("Scope '%s' is invalid", scope))
+	}
+
+	apiKey, err := randomHex(32)
+	if err != nil {
+		return ExchangeModel.ApiClient{}, err
+	}
+	apiSecret, err := randomHex(32)
+	if err != nil {
+		return ExchangeModel.ApiClient{}, err
+	}
+
+	return ExchangeModel.ApiClient{
+		Name:      name,
+		ApiKey:    apiKey,
+		ApiSecret: apiSecret,
+		Scope:     scope,
+		IsEnabled: true,
+	}, nil
+}
+
+func randomHex(size int) (string, error) {
+	buffer := make([]byte, size)
+	_, err := rand.Read(buffer)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(buffer), nil
+}
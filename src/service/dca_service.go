@@ -0,0 +1,246 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"log"
+	"math"
+	"slices"
+	"time"
+)
+
+const dcaOrderTtlSeconds = 120
+
+type DcaExecutorInterface interface {
+	BuyAccumulation(tradeLimit model.TradeLimit, price float64, quantity float64, ttl int64) (model.Order, error)
+}
+
+// DcaService executes DCA plans by schedule, plan symbol should have trade limit (it can be disabled),
+// it provides exchange filters, price stream and buy price calculation for price ceiling
+type DcaService struct {
+	DcaRepository      repository.DcaStorageInterface
+	OrderExecutor      DcaExecutorInterface
+	ExchangeRepository repository.ExchangeTradeInfoInterface
+	PriceCalculator    PriceCalculatorInterface
+	Formatter          *Formatter
+	TimeService        TimeServiceInterface
+}
+
+func (d *DcaService) Run(ctx context.Context, interval time.Duration) {
+	for ctx.Err() == nil {
+		d.ExecuteAll()
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+// ExecuteAll buys for active plans which are due, plan is retried on the next run if price is above ceiling or buy is failed
+func (d *DcaService) ExecuteAll() {
+	now := d.TimeService.GetNowUnix()
+
+	for _, plan := range d.DcaRepository.GetPlans() {
+		if plan.Status != model.DcaPlanActive || plan.NextBuyAt > now {
+			continue
+		}
+
+		plan = d.execute(plan, now)
+		err := d.DcaRepository.UpdatePlan(plan)
+		if err != nil {
+			log.Printf("[%s] DCA plan %d is not saved: %s", plan.Symbol, plan.Id, err.Error())
+		}
+	}
+}
+
+func (d *DcaService) execute(plan model.DcaPlan, now int64) model.DcaPlan {
+	tradeLimit, err := d.ExchangeRepository.GetTradeLimit(plan.Symbol)
+	if err != nil {
+		plan.LastMessage = fmt.Sprintf("Trade limit is not found: %s", err.Error())
+
+		return plan
+	}
+
+	amount := plan.GetNextAmount()
+	if amount < tradeLimit.MinNotional {
+		plan.Status = model.DcaPlanCompleted
+		plan.LastMessage = "Budget is spent"
+		log.Printf("[%s] DCA plan %d is completed", plan.Symbol, plan.Id)
+
+		return plan
+	}
+
+	kLine := d.ExchangeRepository.GetLastKLine(plan.Symbol)
+	if kLine == nil {
+		plan.LastMessage = "Price is unknown"
+
+		return plan
+	}
+
+	ceiling, err := d.getPriceCeiling(plan, tradeLimit)
+	if err != nil {
+		plan.LastMessage = fmt.Sprintf("Price ceiling is unknown: %s", err.Error())
+
+		return plan
+	}
+
+	if ceiling > 0 && kLine.Close > ceiling {
+		plan.LastMessage = fmt.Sprintf("Price %f is above ceiling %f", kLine.Close, ceiling)
+
+		return plan
+	}
+
+	price := d.Formatter.FormatPrice(tradeLimit, kLine.Close)
+	quantity := d.Formatter.FormatQuantity(tradeLimit, amount/price)
+
+	order, err := d.OrderExecutor.BuyAccumulation(tradeLimit, price, quantity, dcaOrderTtlSeconds)
+	if err != nil {
+		plan.LastMessage = fmt.Sprintf("Buy is failed: %s", err.Error())
+		log.Printf("[%s] DCA plan %d: %s", plan.Symbol, plan.Id, plan.LastMessage)
+
+		return plan
+	}
+
+	dcaOrder := model.DcaOrder{
+		PlanId:     plan.Id,
+		Symbol:     plan.Symbol,
+		ExternalId: *order.ExternalId,
+		Price:      order.Price,
+		Quantity:   order.ExecutedQuantity,
+		AmountUsdt: order.Price * order.ExecutedQuantity,
+	}
+	if order.Commission != nil && order.CommissionAsset != nil {
+		dcaOrder.Commission = *order.Commission
+		dcaOrder.CommissionAsset = *order.CommissionAsset
+	}
+
+	err = d.DcaRepository.CreateOrder(dcaOrder)
+	if err != nil {
+		log.Printf("[%s] DCA order %d is not saved: %s", plan.Symbol, dcaOrder.ExternalId, err.Error())
+	}
+
+	plan.Orders++
+	plan.SpentUsdt += dcaOrder.AmountUsdt
+	plan.Quantity += dcaOrder.Quantity
+	if dcaOrder.CommissionAsset == tradeLimit.GetBaseAsset() {
+		plan.Quantity -= dcaOrder.Commission
+	}
+	plan.NextBuyAt = now + plan.IntervalHours*3600
+	plan.LastMessage = ""
+	log.Printf("[%s] DCA plan %d: bought %f at %f", plan.Symbol, plan.Id, dcaOrder.Quantity, dcaOrder.Price)
+
+	if plan.GetNextAmount() < tradeLimit.MinNotional {
+		plan.Status = model.DcaPlanCompleted
+		log.Printf("[%s] DCA plan %d is completed", plan.Symbol, plan.Id)
+	}
+
+	return plan
+}
+
+// getPriceCeiling is the lowest of fixed max price and calculated buy price of trade limit, 0 means no ceiling
+func (d *DcaService) getPriceCeiling(plan model.DcaPlan, tradeLimit model.TradeLimit) (float64, error) {
+	ceiling := plan.MaxPrice
+
+	if plan.UsePriceCalculator {
+		buyPrice, err := d.PriceCalculator.CalculateBuy(tradeLimit)
+		if err != nil {
+			return 0.00, err
+		}
+
+		if ceiling > 0 {
+			ceiling = math.Min(ceiling, buyPrice)
+		} else {
+			ceiling = buyPrice
+		}
+	}
+
+	return ceiling, nil
+}
+
+func (d *DcaService) GetPlans() []model.DcaPlan {
+	return d.DcaRepository.GetPlans()
+}
+
+func (d *DcaService) GetOrders(planId int64, limit int64) []model.DcaOrder {
+	return d.DcaRepository.GetOrders(planId, limit)
+}
+
+func (d *DcaService) CreatePlan(plan model.DcaPlan) (model.DcaPlan, error) {
+	if plan.Status == "" {
+		plan.Status = model.DcaPlanActive
+	}
+
+	err := d.validate(plan)
+	if err != nil {
+		return plan, err
+	}
+
+	id, err := d.DcaRepository.CreatePlan(plan)
+	if err != nil {
+		return plan, err
+	}
+
+	return d.DcaRepository.GetPlan(*id)
+}
+
+// UpdatePlan changes settings and status of plan, spent budget and bought quantity are kept
+func (d *DcaService) UpdatePlan(plan model.DcaPlan) (model.DcaPlan, error) {
+	entity, err := d.DcaRepository.GetPlan(plan.Id)
+	if err != nil {
+		return plan, err
+	}
+
+	if plan.Symbol != entity.Symbol {
+		return plan, errors.New("symbol can't be changed")
+	}
+
+	entity.AmountUsdt = plan.AmountUsdt
+	entity.IntervalHours = plan.IntervalHours
+	entity.MaxPrice = plan.MaxPrice
+	entity.UsePriceCalculator = plan.UsePriceCalculator
+	entity.BudgetUsdt = plan.BudgetUsdt
+	if plan.Status != "" {
+		entity.Status = plan.Status
+	}
+
+	err = d.validate(entity)
+	if err != nil {
+		return plan, err
+	}
+
+	err = d.DcaRepository.UpdatePlan(entity)
+	if err != nil {
+		return plan, err
+	}
+
+	return entity, nil
+}
+
+func (d *DcaService) validate(plan model.DcaPlan) error {
+	tradeLimit, err := d.ExchangeRepository.GetTradeLimit(plan.Symbol)
+	if err != nil {
+		return errors.New(fmt.Sprintf("trade limit %s is not found, it is required for exchange filters and price", plan.Symbol))
+	}
+
+	if !slices.Contains([]string{model.DcaPlanActive, model.DcaPlanPaused, model.DcaPlanCompleted}, plan.Status) {
+		return errors.New(fmt.Sprintf("status %s is invalid, allowed: active, paused, completed", plan.Status))
+	}
+	if plan.AmountUsdt < tradeLimit.MinNotional {
+		return errors.New(fmt.Sprintf("amount %f is less than exchange min notional %f", plan.AmountUsdt, tradeLimit.MinNotional))
+	}
+	if plan.IntervalHours <= 0 {
+		return errors.New("interval hours should be greater than 0")
+	}
+	if plan.MaxPrice < 0 {
+		return errors.New("max price can't be negative")
+	}
+	if plan.BudgetUsdt < 0 || (plan.BudgetUsdt > 0 && plan.BudgetUsdt < plan.AmountUsdt) {
+		return errors.New(fmt.Sprintf("budget %f should be 0 (unlimited) or not less than amount %f", plan.BudgetUsdt, plan.AmountUsdt))
+	}
+
+	return nil
+}
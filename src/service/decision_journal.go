@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"log"
+	"sync"
+	"time"
+)
+
+type DecisionJournalInterface interface {
+	Record(entry model.DecisionJournalEntry)
+}
+
+// DecisionJournal persists results of MakerService decisions, the same skip of symbol is stored once per RepeatSeconds
+type DecisionJournal struct {
+	DecisionJournalRepository repository.DecisionJournalStorageInterface
+	TimeService               TimeServiceInterface
+	RepeatSeconds             int64
+	RetentionDays             int64
+
+	mu        sync.Mutex
+	lastSkips map[string]decisionJournalSkip
+}
+
+type decisionJournalSkip struct {
+	key       string
+	timestamp int64
+}
+
+func (j *DecisionJournal) Record(entry model.DecisionJournalEntry) {
+	if j == nil {
+		return
+	}
+
+	if !j.isRepeatedSkip(entry) {
+		err := j.DecisionJournalRepository.Create(entry)
+		if err != nil {
+			log.Printf("[%s] Decision journal entry is not saved: %s", entry.Symbol, err.Error())
+		}
+	}
+}
+
+func (j *DecisionJournal) GetList(symbol string, limit int64) []model.DecisionJournalEntry {
+	return j.DecisionJournalRepository.GetList(symbol, limit)
+}
+
+// Run removes entries older than RetentionDays once per hour
+func (j *DecisionJournal) Run(ctx context.Context) {
+	if j == nil || j.RetentionDays <= 0 {
+		return
+	}
+
+	for ctx.Err() == nil {
+		deleted, err := j.DecisionJournalRepository.DeleteOlderThan(j.RetentionDays)
+		if err == nil && deleted > 0 {
+			log.Printf("Decision journal: %d old entries are removed", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Hour):
+		}
+	}
+}
+
+// isRepeatedSkip remembers the last skip of symbol, any other result resets it
+func (j *DecisionJournal) isRepeatedSkip(entry model.DecisionJournalEntry) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.lastSkips == nil {
+		j.lastSkips = make(map[string]decisionJournalSkip)
+	}
+
+	if entry.Result != model.DecisionJournalSkipped {
+		delete(j.lastSkips, entry.Symbol)
+
+		return false
+	}
+
+	now := j.TimeService.GetNowUnix()
+	key := entry.Operation + ":" + entry.Reason
+	lastSkip, ok := j.lastSkips[entry.Symbol]
+	if ok && lastSkip.key == key && now-lastSkip.timestamp < j.RepeatSeconds {
+		return true
+	}
+
+	j.lastSkips[entry.Symbol] = decisionJournalSkip{key: key, timestamp: now}
+
+	return false
+}
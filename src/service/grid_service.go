@@ -0,0 +1,527 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/client"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"log"
+	"math"
+	"strings"
+	"time"
+)
+
+// GridService keeps resting buy and sell limit orders for trade limits in grid mode,
+// each level buys at its price and sells bought quantity at the next level price
+type GridService struct {
+	GridRepository     repository.GridStorageInterface
+	OrderRepository    repository.OrderCreatorInterface
+	ExchangeRepository repository.GridTradeInfoInterface
+	Binance            client.ExchangeGridAPIInterface
+	BalanceService     BalanceServiceInterface
+	Formatter          *Formatter
+	LotLedger          LotLedgerInterface
+	TimeService        TimeServiceInterface
+	ShutdownService    ShutdownServiceInterface
+}
+
+func getGridViolations(limit model.TradeLimit) []string {
+	messages := make([]string, 0)
+	options := limit.GridOptions
+
+	if options.LowerPrice <= 0 {
+		messages = append(messages, "grid lower price should be greater than 0")
+	}
+	if options.UpperPrice <= options.LowerPrice {
+		messages = append(messages, fmt.Sprintf("grid upper price %f should be greater than lower price %f", options.UpperPrice, options.LowerPrice))
+	}
+	if options.Levels < 2 {
+		messages = append(messages, fmt.Sprintf("grid levels %d should be at least 2", options.Levels))
+	}
+	if options.Levels > 0 && limit.USDTLimit/float64(options.Levels) < limit.MinNotional {
+		messages = append(messages, fmt.Sprintf("grid level amount %f is less than exchange min notional %f", limit.USDTLimit/float64(options.Levels), limit.MinNotional))
+	}
+
+	return messages
+}
+
+func (g *GridService) Run(ctx context.Context, interval time.Duration) {
+	for ctx.Err() == nil {
+		g.SyncAll()
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+// SyncAll creates grids for enabled trade limits in grid mode, processes fills of resting orders
+// and places new ones, grid is recreated on options change and stopped when trade limit leaves grid mode
+func (g *GridService) SyncAll() {
+	if !g.ShutdownService.StartOperation() {
+		return
+	}
+	defer g.ShutdownService.FinishOperation()
+
+	active := make(map[string]model.Grid)
+	for _, grid := range g.GridRepository.GetActiveGrids() {
+		active[grid.Symbol] = grid
+	}
+
+	for _, limit := range g.ExchangeRepository.GetTradeLimits() {
+		if !limit.IsEnabled || !limit.IsGrid() {
+			continue
+		}
+
+		grid, exists := active[limit.Symbol]
+		delete(active, limit.Symbol)
+
+		if exists && !grid.IsConfiguredBy(limit) {
+			err := g.Stop(&grid)
+			if err != nil {
+				log.Printf("[%s] Grid %d is not stopped: %s", limit.Symbol, grid.Id, err.Error())
+				continue
+			}
+
+			log.Printf("[%s] Grid %d is stopped, options are changed", limit.Symbol, grid.Id)
+			exists = false
+		}
+
+		if !exists {
+			created, err := g.create(limit)
+			if err != nil {
+				log.Printf("[%s] Grid is not created: %s", limit.Symbol, err.Error())
+				continue
+			}
+
+			log.Printf("[%s] Grid %d is created: %f - %f, %d levels", limit.Symbol, created.Id, created.LowerPrice, created.UpperPrice, created.Levels)
+			grid = created
+		}
+
+		g.sync(&grid, limit)
+	}
+
+	// trade limit is disabled, removed or switched to position mode
+	for _, grid := range active {
+		grid := grid
+		err := g.Stop(&grid)
+		if err != nil {
+			log.Printf("[%s] Grid %d is not stopped: %s", grid.Symbol, grid.Id, err.Error())
+			continue
+		}
+
+		log.Printf("[%s] Grid %d is stopped", grid.Symbol, grid.Id)
+	}
+}
+
+// Stop processes fills and cancels resting orders, bought quantity is left on balance
+func (g *GridService) Stop(grid *model.Grid) error {
+	failed := make([]string, 0)
+
+	for index := range grid.GridLevels {
+		level := &grid.GridLevels[index]
+		err := g.checkLevel(grid, level)
+		if err == nil && level.ExternalId != nil {
+			_, err = g.Binance.CancelOrder(grid.Symbol, *level.ExternalId)
+			if err == nil {
+				err = g.checkLevel(grid, level)
+			}
+		}
+
+		if err != nil {
+			failed = append(failed, fmt.Sprintf("level %d: %s", level.Index, err.Error()))
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.New(strings.Join(failed, ", "))
+	}
+
+	grid.Status = model.GridStopped
+
+	return g.GridRepository.UpdateGrid(*grid)
+}
+
+func (g *GridService) GetGrids(symbol string) []model.Grid {
+	return g.GridRepository.GetGrids(symbol)
+}
+
+func (g *GridService) create(limit model.TradeLimit) (model.Grid, error) {
+	options := limit.GridOptions
+	step := (options.UpperPrice - options.LowerPrice) / float64(options.Levels)
+	levelUsdt := limit.USDTLimit / float64(options.Levels)
+
+	grid := model.Grid{
+		Symbol:     limit.Symbol,
+		LowerPrice: options.LowerPrice,
+		UpperPrice: options.UpperPrice,
+		Levels:     options.Levels,
+		USDTLimit:  limit.USDTLimit,
+		Status:     model.GridActive,
+		GridLevels: make([]model.GridLevel, 0),
+	}
+
+	for index := int64(0); index < options.Levels; index++ {
+		buyPrice := g.Formatter.FormatPrice(limit, options.LowerPrice+step*float64(index))
+		grid.GridLevels = append(grid.GridLevels, model.GridLevel{
+			Index:     index,
+			BuyPrice:  buyPrice,
+			SellPrice: g.Formatter.FormatPrice(limit, options.LowerPrice+step*float64(index+1)),
+			Quantity:  g.Formatter.FormatQuantity(limit, levelUsdt/buyPrice),
+			Status:    model.GridLevelIdle,
+		})
+	}
+
+	gridId, err := g.GridRepository.CreateGrid(grid)
+	if err != nil {
+		return grid, err
+	}
+
+	// reload to get ids of levels
+	for _, created := range g.GridRepository.GetActiveGrids() {
+		if created.Id == *gridId {
+			return created, nil
+		}
+	}
+
+	return grid, errors.New("created grid is not found")
+}
+
+func (g *GridService) sync(grid *model.Grid, limit model.TradeLimit) {
+	kLine := g.ExchangeRepository.GetLastKLine(limit.Symbol)
+
+	for index := range grid.GridLevels {
+		level := &grid.GridLevels[index]
+		err := g.checkLevel(grid, level)
+		if err != nil {
+			log.Printf("[%s] Grid level %d is not checked: %s", grid.Symbol, level.Index, err.Error())
+			continue
+		}
+
+		if kLine == nil || level.ExternalId != nil {
+			continue
+		}
+
+		err = g.placeOrder(grid, level, limit, kLine.Close)
+		if err != nil {
+			log.Printf("[%s] Grid level %d order is not placed: %s", grid.Symbol, level.Index, err.Error())
+		}
+	}
+}
+
+// placeOrder places buy for idle level below the current price and sell for bought level,
+// level is saved with client order id before placing, so the order is recovered by checkLevel after crash
+func (g *GridService) placeOrder(grid *model.Grid, level *model.GridLevel, limit model.TradeLimit, lastPrice float64) error {
+	var operation string
+	var quantity float64
+	var price float64
+	var asset string
+
+	switch level.Status {
+	case model.GridLevelIdle:
+		if lastPrice <= level.BuyPrice {
+			return nil
+		}
+
+		quoteAsset := strings.TrimPrefix(limit.Symbol, limit.GetBaseAsset())
+		balance, err := g.BalanceService.GetAssetBalance(quoteAsset, true)
+		if err != nil {
+			return err
+		}
+		if balance < level.BuyPrice*level.Quantity {
+			return errors.New(fmt.Sprintf("not enough %s balance: %f", quoteAsset, balance))
+		}
+
+		operation, quantity, price, asset = "BUY", level.Quantity, level.BuyPrice, quoteAsset
+		level.Status = model.GridLevelBuyPlaced
+	case model.GridLevelBought:
+		quantity = g.Formatter.FormatQuantity(limit, level.GetHeldQuantity(limit.GetBaseAsset()))
+		// quantity is too small to be sold, it is left on balance
+		if quantity > level.GetHeldQuantity(limit.GetBaseAsset()) || quantity*level.SellPrice < limit.MinNotional {
+			return nil
+		}
+
+		balance, err := g.BalanceService.GetAssetBalance(limit.GetBaseAsset(), true)
+		if err != nil {
+			return err
+		}
+		if balance < quantity {
+			return errors.New(fmt.Sprintf("not enough %s balance: %f", limit.GetBaseAsset(), balance))
+		}
+
+		operation, price, asset = "SELL", level.SellPrice, limit.GetBaseAsset()
+		level.Status = model.GridLevelSellPlaced
+	default:
+		return nil
+	}
+
+	clientOrderId := fmt.Sprintf("grid-%d-%d", level.Id, time.Now().UnixNano())
+	level.ClientOrderId = &clientOrderId
+	err := g.GridRepository.UpdateGridLevel(*level)
+	if err != nil {
+		level.ClientOrderId = nil
+		level.Status = getGridLevelUnplacedStatus(level.Status)
+
+		return err
+	}
+
+	// on error level is kept with client order id, checkLevel finds out whether order was placed
+	binanceOrder, err := g.Binance.LimitOrderWithClientId(limit.Symbol, quantity, price, operation, "GTC", clientOrderId)
+	if err != nil {
+		return err
+	}
+
+	g.BalanceService.InvalidateBalanceCache(asset)
+	level.ExternalId = &binanceOrder.OrderId
+	level.ClientOrderId = nil
+	log.Printf("[%s] Grid level %d: %s order %d is placed at %f", grid.Symbol, level.Index, binanceOrder.Side, binanceOrder.OrderId, binanceOrder.Price)
+
+	return g.GridRepository.UpdateGridLevel(*level)
+}
+
+// recoverOrder finds order of the level saved before placing, level returns to previous status if order was not placed
+func (g *GridService) recoverOrder(grid *model.Grid, level *model.GridLevel) error {
+	binanceOrder, err := g.Binance.QueryOrderByClientId(grid.Symbol, *level.ClientOrderId)
+	if err != nil && !strings.Contains(err.Error(), "Order does not exist") {
+		return err
+	}
+
+	if err != nil {
+		log.Printf("[%s] Grid level %d: order %s was not placed", grid.Symbol, level.Index, *level.ClientOrderId)
+		level.Status = getGridLevelUnplacedStatus(level.Status)
+	} else {
+		log.Printf("[%s] Grid level %d: order %d is recovered by %s", grid.Symbol, level.Index, binanceOrder.OrderId, *level.ClientOrderId)
+		level.ExternalId = &binanceOrder.OrderId
+	}
+
+	level.ClientOrderId = nil
+
+	return g.GridRepository.UpdateGridLevel(*level)
+}
+
+func getGridLevelUnplacedStatus(status string) string {
+	switch status {
+	case model.GridLevelBuyPlaced:
+		return model.GridLevelIdle
+	case model.GridLevelSellPlaced:
+		return model.GridLevelBought
+	}
+
+	return status
+}
+
+// checkLevel moves level to the next state when resting order is filled, canceled or expired
+func (g *GridService) checkLevel(grid *model.Grid, level *model.GridLevel) error {
+	if level.ExternalId == nil && level.ClientOrderId != nil {
+		err := g.recoverOrder(grid, level)
+		if err != nil {
+			return err
+		}
+	}
+
+	if level.ExternalId == nil {
+		return nil
+	}
+
+	binanceOrder, err := g.Binance.QueryOrder(grid.Symbol, *level.ExternalId)
+	if err != nil {
+		return err
+	}
+
+	if !binanceOrder.IsFilled() && !binanceOrder.IsCanceled() && !binanceOrder.IsExpired() {
+		return nil
+	}
+
+	switch level.Status {
+	case model.GridLevelBuyPlaced:
+		if binanceOrder.HasExecutedQuantity() {
+			g.onBuyFilled(grid, level, binanceOrder)
+		} else {
+			level.Status = getGridLevelUnplacedStatus(level.Status)
+		}
+	case model.GridLevelSellPlaced:
+		if binanceOrder.HasExecutedQuantity() {
+			err = g.onSellFilled(grid, level, binanceOrder)
+			if err != nil {
+				return err
+			}
+		} else {
+			level.Status = getGridLevelUnplacedStatus(level.Status)
+		}
+	}
+
+	level.ExternalId = nil
+
+	order := model.Order{Symbol: grid.Symbol}
+	g.BalanceService.InvalidateBalanceCache(strings.TrimPrefix(grid.Symbol, order.GetBaseAsset()))
+	g.BalanceService.InvalidateBalanceCache(order.GetBaseAsset())
+
+	return g.GridRepository.UpdateGridLevel(*level)
+}
+
+func (g *GridService) onBuyFilled(grid *model.Grid, level *model.GridLevel, binanceOrder model.BinanceOrder) {
+	commission, commissionAsset := g.getCommission(binanceOrder)
+	boughtAt := g.TimeService.GetNowDateTimeString()
+
+	level.Status = model.GridLevelBought
+	level.BuyExternalId = &binanceOrder.OrderId
+	level.BoughtPrice = getGridFillPrice(binanceOrder)
+	level.BoughtQuantity = binanceOrder.ExecutedQty
+	level.BuyCommission = commission
+	level.BuyCommissionAsset = commissionAsset
+	level.BoughtAt = &boughtAt
+	level.BuyOrderId = nil
+
+	log.Printf("[%s] Grid level %d: bought %f at %f", grid.Symbol, level.Index, level.BoughtQuantity, level.BoughtPrice)
+
+	if g.LotLedger != nil {
+		g.LotLedger.RecordOrder(g.getOrder(grid, binanceOrder, commission, commissionAsset), model.LotEntryBuy, nil)
+	}
+}
+
+// onSellFilled realizes PnL of sold part, closed buy and sell orders are saved for PnL and performance reports
+func (g *GridService) onSellFilled(grid *model.Grid, level *model.GridLevel, binanceOrder model.BinanceOrder) error {
+	order := model.Order{Symbol: grid.Symbol}
+	baseAsset := order.GetBaseAsset()
+	quoteAsset := strings.TrimPrefix(grid.Symbol, baseAsset)
+
+	soldPart := 1.00
+	if level.GetHeldQuantity(baseAsset) > 0 {
+		soldPart = math.Min(1.00, binanceOrder.ExecutedQty/level.GetHeldQuantity(baseAsset))
+	}
+
+	commission, commissionAsset := g.getCommission(binanceOrder)
+	buyFee := 0.00
+	if level.BuyCommissionAsset == quoteAsset {
+		buyFee = level.BuyCommission * soldPart
+	}
+	sellFee := 0.00
+	if commissionAsset == quoteAsset {
+		sellFee = commission
+	}
+
+	sellPrice := getGridFillPrice(binanceOrder)
+	cost := level.BoughtPrice * level.BoughtQuantity * soldPart
+	pnl := sellPrice*binanceOrder.ExecutedQty - sellFee - cost - buyFee
+
+	if level.BuyOrderId == nil {
+		buyCommission := level.BuyCommission
+		buyOrder := model.Order{
+			Symbol:             grid.Symbol,
+			Price:              level.BoughtPrice,
+			Quantity:           level.BoughtQuantity,
+			ExecutedQuantity:   level.BoughtQuantity,
+			CreatedAt:          *level.BoughtAt,
+			Operation:          "buy",
+			Status:             "closed",
+			ExternalId:         level.BuyExternalId,
+			Commission:         &buyCommission,
+			CommissionAsset:    &level.BuyCommissionAsset,
+			ExtraChargeOptions: make(model.ExtraChargeOptions, 0),
+		}
+
+		buyOrderId, err := g.OrderRepository.Create(buyOrder)
+		if err != nil {
+			return err
+		}
+
+		// sell order is still resting in storage, sell is processed again if it is not saved
+		level.BuyOrderId = buyOrderId
+		err = g.GridRepository.UpdateGridLevel(*level)
+		if err != nil {
+			return err
+		}
+	}
+
+	sellOrder := g.getOrder(grid, binanceOrder, commission, commissionAsset)
+	sellOrder.ClosesOrder = level.BuyOrderId
+	_, err := g.OrderRepository.Create(sellOrder)
+	if err != nil {
+		return err
+	}
+
+	if g.LotLedger != nil {
+		g.LotLedger.RecordOrder(sellOrder, model.LotEntrySell, level.BuyOrderId)
+	}
+
+	level.Trades++
+	level.RealizedPnl += pnl
+	grid.Trades++
+	grid.Fees += buyFee + sellFee
+	grid.RealizedPnl += pnl
+
+	log.Printf("[%s] Grid level %d: sold %f at %f, PnL %f", grid.Symbol, level.Index, binanceOrder.ExecutedQty, sellPrice, pnl)
+
+	// filled sell completes the level, quantity cut by formatting is left on balance
+	level.Status = model.GridLevelBought
+	if binanceOrder.IsFilled() || soldPart >= 1.00 {
+		level.Status = model.GridLevelIdle
+		level.BuyExternalId = nil
+		level.BoughtPrice = 0.00
+		level.BoughtQuantity = 0.00
+		level.BuyCommission = 0.00
+		level.BuyCommissionAsset = ""
+		level.BoughtAt = nil
+		level.BuyOrderId = nil
+	} else {
+		level.BoughtQuantity -= level.BoughtQuantity * soldPart
+		level.BuyCommission -= level.BuyCommission * soldPart
+	}
+
+	return g.GridRepository.UpdateGrid(*grid)
+}
+
+func (g *GridService) getOrder(grid *model.Grid, binanceOrder model.BinanceOrder, commission float64, commissionAsset string) model.Order {
+	createdAt := binanceOrder.GetFilledAt()
+	if createdAt == "" {
+		createdAt = g.TimeService.GetNowDateTimeString()
+	}
+
+	return model.Order{
+		Symbol:             grid.Symbol,
+		Price:              getGridFillPrice(binanceOrder),
+		Quantity:           binanceOrder.OrigQty,
+		ExecutedQuantity:   binanceOrder.ExecutedQty,
+		CreatedAt:          createdAt,
+		Operation:          strings.ToLower(binanceOrder.Side),
+		Status:             "closed",
+		ExternalId:         &binanceOrder.OrderId,
+		Commission:         &commission,
+		CommissionAsset:    &commissionAsset,
+		ExtraChargeOptions: make(model.ExtraChargeOptions, 0),
+	}
+}
+
+// getCommission sums commission of order trades, it is 0 if trades are not loaded
+func (g *GridService) getCommission(binanceOrder model.BinanceOrder) (float64, string) {
+	trades, err := g.Binance.GetTrades(model.Order{Symbol: binanceOrder.Symbol})
+	if err != nil {
+		log.Printf("[%s] Trades of order %d are not loaded: %s", binanceOrder.Symbol, binanceOrder.OrderId, err.Error())
+
+		return 0.00, ""
+	}
+
+	commission := 0.00
+	commissionAsset := ""
+	for _, trade := range trades {
+		if trade.OrderId != binanceOrder.OrderId {
+			continue
+		}
+
+		commission += trade.Commission
+		commissionAsset = trade.CommissionAsset
+	}
+
+	return commission, commissionAsset
+}
+
+func getGridFillPrice(binanceOrder model.BinanceOrder) float64 {
+	if binanceOrder.ExecutedQty > 0 && binanceOrder.CummulativeQuoteQty > 0 {
+		return binanceOrder.CummulativeQuoteQty / binanceOrder.ExecutedQty
+	}
+
+	return binanceOrder.Price
+}
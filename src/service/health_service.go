@@ -12,6 +12,10 @@ import (
 	"time"
 )
 
+type StreamHealthProviderInterface interface {
+	GetStreamHealth() []model.StreamHealth
+}
+
 type HealthService struct {
 	ExchangeRepository *repository.ExchangeRepository
 	PythonMLBridge     *PythonMLBridge
@@ -20,6 +24,8 @@ type HealthService struct {
 	Ctx                *context.Context
 	Binance            *client.Binance
 	CurrentBot         *model.Bot
+	StreamProviders    []StreamHealthProviderInterface
+	LeaderElection     *LeaderElection
 }
 
 func (h *HealthService) HealthCheck() model.BotHealth {
@@ -49,6 +55,21 @@ func (h *HealthService) HealthCheck() model.BotHealth {
 		binanceStatus = model.BinanceStatusApiKeyCheck
 	}
 
+	streams := make([]model.StreamHealth, 0)
+	streamStatus := model.StreamStatusOk
+	for _, provider := range h.StreamProviders {
+		for _, stream := range provider.GetStreamHealth() {
+			streams = append(streams, stream)
+
+			if stream.Status == model.StreamStatusDisconnected {
+				streamStatus = model.StreamStatusDisconnected
+			}
+			if stream.Status == model.StreamStatusStale && streamStatus == model.StreamStatusOk {
+				streamStatus = model.StreamStatusStale
+			}
+		}
+	}
+
 	dbStatus := model.DbStatusOk
 	if h.DB.Ping() != nil {
 		dbStatus = model.DbStatusFail
@@ -66,6 +87,10 @@ func (h *HealthService) HealthCheck() model.BotHealth {
 		Bot:           *h.CurrentBot,
 		DbStatus:      dbStatus,
 		BinanceStatus: binanceStatus,
+		StreamStatus:  streamStatus,
+		Leader:        h.LeaderElection.GetLeader(),
+		IsLeader:      h.LeaderElection.IsLeader(),
+		Streams:       streams,
 		MlStatus:      mlStatus,
 		RedisStatus:   redisStatus,
 		Cores:         runtime.NumCPU(),
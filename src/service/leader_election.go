@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+const LeaderMasterBotKey = "leader-master-bot"
+
+// LeaderElection elects one instance by Redis lease, leader renews lease every third of LeaseTtl
+// and steps down if lease is not renewed (lease is expired or Redis is not available)
+type LeaderElection struct {
+	LeaderLeaseStorage repository.LeaderLeaseStorageInterface
+	Key                string
+	Identity           string
+	LeaseTtl           time.Duration
+	leader             atomic.Bool
+}
+
+// Run campaigns until ctx is cancelled, onElected is called with context which is cancelled on leadership loss.
+// Terms don't overlap: the next term is started when onElected of the previous one has returned.
+// Run returns (and releases lease) when the last term has returned, even if leadership is already lost
+func (l *LeaderElection) Run(ctx context.Context, onElected func(leaderCtx context.Context)) {
+	var resign context.CancelFunc
+	var termDone chan struct{}
+
+	stepDown := func() {
+		if resign == nil {
+			return
+		}
+
+		resign()
+		resign = nil
+		l.leader.Store(false)
+		metrics.Leader.Set(0)
+	}
+
+	for ctx.Err() == nil {
+		isLeader := l.campaign()
+
+		if isLeader && resign == nil {
+			log.Printf("Leader [%s]: %s is elected", l.Key, l.Identity)
+			leaderCtx, cancel := context.WithCancel(ctx)
+			resign = cancel
+			l.leader.Store(true)
+			metrics.Leader.Set(1)
+
+			previousDone, done := termDone, make(chan struct{})
+			termDone = done
+			go func() {
+				defer close(done)
+				if previousDone != nil {
+					<-previousDone
+				}
+				onElected(leaderCtx)
+			}()
+		}
+
+		if !isLeader && resign != nil {
+			log.Printf("Leader [%s]: %s lost leadership", l.Key, l.Identity)
+			stepDown()
+		}
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(l.LeaseTtl / 3):
+		}
+	}
+
+	isLeader := l.IsLeader()
+	if isLeader {
+		stepDown()
+	}
+
+	// the last term could be stopped by lost leadership, but it is still finishing
+	if termDone != nil {
+		<-termDone
+	}
+
+	if isLeader {
+		err := l.LeaderLeaseStorage.ReleaseLease(l.Key, l.Identity)
+		if err != nil {
+			log.Printf("Leader [%s] release: %s", l.Key, err.Error())
+		}
+		log.Printf("Leader [%s]: %s released leadership", l.Key, l.Identity)
+	}
+}
+
+func (l *LeaderElection) IsLeader() bool {
+	return l.leader.Load()
+}
+
+func (l *LeaderElection) GetLeader() string {
+	return l.LeaderLeaseStorage.GetLeaseOwner(l.Key)
+}
+
+func (l *LeaderElection) campaign() bool {
+	if l.IsLeader() {
+		renewed, err := l.LeaderLeaseStorage.RenewLease(l.Key, l.Identity, l.LeaseTtl)
+		if err != nil {
+			log.Printf("Leader [%s] renew: %s", l.Key, err.Error())
+		}
+
+		return err == nil && renewed
+	}
+
+	acquired, err := l.LeaderLeaseStorage.AcquireLease(l.Key, l.Identity, l.LeaseTtl)
+	if err != nil {
+		log.Printf("Leader [%s] acquire: %s", l.Key, err.Error())
+	}
+
+	return err == nil && acquired
+}
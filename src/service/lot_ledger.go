@@ -0,0 +1,279 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"io"
+	"log"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const lotQuantityPrecision = 0.000000001
+
+var capitalGainCsvHeader = []string{
+	"asset", "symbol", "type", "acquiredAt", "disposedAt", "quantity", "proceeds", "costBasis", "gain", "unmatchedLot",
+}
+
+type LotLedgerInterface interface {
+	RecordOrder(order model.Order, entryType string, positionId *int64)
+	RecordSwapLeg(swapAction model.SwapAction, binanceOrder model.BinanceOrder)
+}
+
+type SwapPairReaderInterface interface {
+	GetSwapPairBySymbol(symbol string) (model.SwapPair, error)
+}
+
+// LotLedger records each fill as lot entry, disposals are matched with lots by FIFO, LIFO or average cost on report
+type LotLedger struct {
+	LotRepository      repository.LotStorageInterface
+	SwapRepository     SwapPairReaderInterface
+	ExchangeRepository repository.KLineReaderInterface
+}
+
+type lot struct {
+	quantity   float64
+	cost       float64
+	acquiredAt string
+}
+
+// RecordOrder records filled buy, extra buy or sell order at its creation time (fill time),
+// commission paid in base asset reduces acquired quantity
+func (l *LotLedger) RecordOrder(order model.Order, entryType string, positionId *int64) {
+	if order.ExternalId == nil || order.ExecutedQuantity <= 0 {
+		return
+	}
+
+	baseAsset := order.GetBaseAsset()
+	quoteAsset := strings.TrimPrefix(order.Symbol, baseAsset)
+	entry := model.LotEntry{
+		Asset:      baseAsset,
+		Symbol:     order.Symbol,
+		OrderId:    positionId,
+		ExternalId: *order.ExternalId,
+		Type:       entryType,
+		Quantity:   order.ExecutedQuantity,
+		Price:      order.Price,
+		Value:      order.Price * order.ExecutedQuantity,
+		CreatedAt:  order.CreatedAt,
+	}
+
+	if order.Commission != nil && order.CommissionAsset != nil {
+		switch *order.CommissionAsset {
+		case baseAsset:
+			if entry.IsAcquisition() {
+				entry.Quantity -= *order.Commission
+			}
+		case quoteAsset:
+			entry.Fee = *order.Commission
+		}
+	}
+
+	l.create(entry)
+}
+
+// RecordSwapLeg records filled swap leg as disposal of one asset and acquisition of another one,
+// both are valued in USDT by price of disposed asset (or acquired one if disposed asset price is unknown)
+func (l *LotLedger) RecordSwapLeg(swapAction model.SwapAction, binanceOrder model.BinanceOrder) {
+	swapPair, err := l.SwapRepository.GetSwapPairBySymbol(binanceOrder.Symbol)
+	if err != nil {
+		log.Printf("[%s] Swap leg is not recorded in lot ledger: %s", binanceOrder.Symbol, err.Error())
+		return
+	}
+
+	outAsset, outQuantity := swapPair.BaseAsset, binanceOrder.ExecutedQty
+	inAsset, inQuantity := swapPair.QuoteAsset, binanceOrder.CummulativeQuoteQty
+	if binanceOrder.Side == "BUY" {
+		outAsset, outQuantity, inAsset, inQuantity = inAsset, inQuantity, outAsset, outQuantity
+	}
+	if outQuantity <= 0 || inQuantity <= 0 {
+		return
+	}
+
+	value := outQuantity * l.getUsdtPrice(outAsset)
+	if value == 0.00 {
+		value = inQuantity * l.getUsdtPrice(inAsset)
+	}
+	if value == 0.00 {
+		log.Printf("[%s] Swap leg value is unknown, lots are recorded with zero value", binanceOrder.Symbol)
+	}
+
+	l.create(model.LotEntry{
+		Asset:      outAsset,
+		Symbol:     binanceOrder.Symbol,
+		OrderId:    &swapAction.OrderId,
+		ExternalId: binanceOrder.OrderId,
+		Type:       model.LotEntrySwapOut,
+		Quantity:   outQuantity,
+		Price:      value / outQuantity,
+		Value:      value,
+		CreatedAt:  binanceOrder.GetFilledAt(),
+	})
+	l.create(model.LotEntry{
+		Asset:      inAsset,
+		Symbol:     binanceOrder.Symbol,
+		OrderId:    &swapAction.OrderId,
+		ExternalId: binanceOrder.OrderId,
+		Type:       model.LotEntrySwapIn,
+		Quantity:   inQuantity,
+		Price:      value / inQuantity,
+		Value:      value,
+		CreatedAt:  binanceOrder.GetFilledAt(),
+	})
+}
+
+// GetCapitalGains matches disposals from date range (YYYY-MM-DD, inclusive) with lots acquired before them
+func (l *LotLedger) GetCapitalGains(method string, from string, to string) ([]model.CapitalGain, error) {
+	if !slices.Contains(model.LotMethods, method) {
+		return nil, errors.New(fmt.Sprintf("Method %s is invalid, allowed: %s", method, strings.Join(model.LotMethods, ", ")))
+	}
+
+	fromDate, err := time.Parse("2006-01-02", from)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Date from %s is invalid, format is YYYY-MM-DD", from))
+	}
+	toDate, err := time.Parse("2006-01-02", to)
+	if err != nil {
+		return nil, errors.New(fmt.Sprintf("Date to %s is invalid, format is YYYY-MM-DD", to))
+	}
+	if toDate.Before(fromDate) {
+		return nil, errors.New("Date to must be after date from")
+	}
+
+	periodStart := fromDate.Format("2006-01-02 15:04:05")
+	periodEnd := toDate.Add(time.Hour*24 - time.Second).Format("2006-01-02 15:04:05")
+
+	return l.MatchLots(l.LotRepository.GetEntries(periodEnd), method, periodStart), nil
+}
+
+// MatchLots matches disposals with lots per asset, only disposals since date are returned
+func (l *LotLedger) MatchLots(entries []model.LotEntry, method string, since string) []model.CapitalGain {
+	lots := make(map[string][]lot)
+	gains := make([]model.CapitalGain, 0)
+
+	for _, entry := range entries {
+		assetLots := lots[entry.Asset]
+
+		if entry.IsAcquisition() {
+			if method == model.LotMethodAverage && len(assetLots) > 0 {
+				assetLots[0].quantity += entry.Quantity
+				assetLots[0].cost += entry.Value + entry.Fee
+			} else {
+				assetLots = append(assetLots, lot{
+					quantity:   entry.Quantity,
+					cost:       entry.Value + entry.Fee,
+					acquiredAt: entry.CreatedAt,
+				})
+			}
+			lots[entry.Asset] = assetLots
+			continue
+		}
+
+		proceeds := entry.Value - entry.Fee
+		remaining := entry.Quantity
+		for remaining > lotQuantityPrecision && len(assetLots) > 0 {
+			index := 0
+			if method == model.LotMethodLifo {
+				index = len(assetLots) - 1
+			}
+
+			matched := &assetLots[index]
+			quantity := math.Min(remaining, matched.quantity)
+			cost := matched.cost * quantity / matched.quantity
+			matched.quantity -= quantity
+			matched.cost -= cost
+			remaining -= quantity
+
+			acquiredAt := matched.acquiredAt
+			if method == model.LotMethodAverage {
+				acquiredAt = ""
+			}
+			gains = append(gains, getCapitalGain(entry, acquiredAt, quantity, proceeds*quantity/entry.Quantity, cost, false))
+
+			if matched.quantity <= lotQuantityPrecision {
+				assetLots = slices.Delete(assetLots, index, index+1)
+			}
+		}
+		lots[entry.Asset] = assetLots
+
+		// asset was bought before ledger was started or outside of the bot
+		if remaining > lotQuantityPrecision {
+			gains = append(gains, getCapitalGain(entry, "", remaining, proceeds*remaining/entry.Quantity, 0.00, true))
+		}
+	}
+
+	return slices.DeleteFunc(gains, func(gain model.CapitalGain) bool {
+		return gain.DisposedAt < since
+	})
+}
+
+func (l *LotLedger) WriteCsv(writer io.Writer, gains []model.CapitalGain) error {
+	csvWriter := csv.NewWriter(writer)
+	err := csvWriter.Write(capitalGainCsvHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, gain := range gains {
+		err = csvWriter.Write([]string{
+			gain.Asset,
+			gain.Symbol,
+			gain.Type,
+			gain.AcquiredAt,
+			gain.DisposedAt,
+			strconv.FormatFloat(gain.Quantity, 'f', -1, 64),
+			strconv.FormatFloat(gain.Proceeds, 'f', -1, 64),
+			strconv.FormatFloat(gain.CostBasis, 'f', -1, 64),
+			strconv.FormatFloat(gain.Gain, 'f', -1, 64),
+			strconv.FormatBool(gain.UnmatchedLot),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
+
+func (l *LotLedger) create(entry model.LotEntry) {
+	err := l.LotRepository.Create(entry)
+	if err != nil {
+		log.Printf("[%s] Lot ledger entry [%s] is not saved: %s", entry.Symbol, entry.Type, err.Error())
+	}
+}
+
+func (l *LotLedger) getUsdtPrice(asset string) float64 {
+	if asset == "USDT" {
+		return 1.00
+	}
+
+	kLine := l.ExchangeRepository.GetLastKLine(asset + "USDT")
+	if kLine == nil {
+		return 0.00
+	}
+
+	return kLine.Close
+}
+
+func getCapitalGain(entry model.LotEntry, acquiredAt string, quantity float64, proceeds float64, cost float64, unmatched bool) model.CapitalGain {
+	return model.CapitalGain{
+		Asset:        entry.Asset,
+		Symbol:       entry.Symbol,
+		Type:         entry.Type,
+		AcquiredAt:   acquiredAt,
+		DisposedAt:   entry.CreatedAt,
+		Quantity:     roundPnl(quantity),
+		Proceeds:     roundPnl(proceeds),
+		CostBasis:    roundPnl(cost),
+		Gain:         roundPnl(proceeds - cost),
+		UnmatchedLot: unmatched,
+	}
+}
@@ -11,19 +11,27 @@ import (
 )
 
 type MakerService struct {
-	OrderExecutor      *OrderExecutor
-	OrderRepository    *ExchangeRepository.OrderRepository
-	ExchangeRepository *ExchangeRepository.ExchangeRepository
-	Binance            *ExchangeClient.Binance
-	Formatter          *Formatter
-	MinDecisions       float64
-	HoldScore          float64
-	CurrentBot         *ExchangeModel.Bot
-	PriceCalculator    *PriceCalculator
-	TradeStack         *TradeStack
+	OrderExecutor         *OrderExecutor
+	OrderRepository       *ExchangeRepository.OrderRepository
+	ExchangeRepository    *ExchangeRepository.ExchangeRepository
+	Binance               *ExchangeClient.Binance
+	Formatter             *Formatter
+	MinDecisions          float64
+	HoldScore             float64
+	CurrentBot            *ExchangeModel.Bot
+	PriceCalculator       *PriceCalculator
+	TradeStack            *TradeStack
+	ShutdownService       ShutdownServiceInterface
+	PerformanceRepository ExchangeRepository.PerformanceStorageInterface
+	DecisionJournal       DecisionJournalInterface
 }
 
 func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
+	// new decisions are not accepted during shutdown
+	if m.ShutdownService.IsShuttingDown() {
+		return
+	}
+
 	buyScore := 0.00
 	sellScore := 0.00
 	holdScore := 0.00
@@ -59,18 +67,38 @@ func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
 		return
 	}
 
+	// grid levels are traded by GridService
+	if tradeLimit.IsGrid() {
+		return
+	}
+
+	entry := &ExchangeModel.DecisionJournalEntry{
+		Symbol:      symbol,
+		BuyScore:    buyScore,
+		SellScore:   sellScore,
+		HoldScore:   holdScore,
+		Decisions:   decisions,
+		TradeLimit:  &tradeLimit,
+		ManualOrder: manualOrder,
+	}
+
 	lastKline := m.ExchangeRepository.GetLastKLine(tradeLimit.Symbol)
 
 	if lastKline == nil {
 		log.Printf("[%s] Last price is unknown... skip!", symbol)
+		m.journal(entry, "", ExchangeModel.DecisionJournalSkipped, "Last price is unknown")
 		return
 	}
+	entry.LastPrice = lastKline.Close
 
 	openedOrder, buyOrderErr := m.OrderRepository.GetOpenedOrderCached(symbol, "BUY")
 
 	if m.OrderExecutor.ProcessSwap(openedOrder) {
 		return
 	}
+	if buyOrderErr == nil {
+		entry.OrderId = &openedOrder.Id
+	}
 
 	allowManualOrder := true
 
@@ -104,6 +132,10 @@ func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
 		log.Printf("[%s] Manual order %s", tradeLimit.Symbol, manualOrder.Operation)
 	}
 
+	entry.BuyScore = buyScore
+	entry.SellScore = sellScore
+	entry.HoldScore = holdScore
+
 	// todo: fallback to existing order...
 	// log.Printf("[%s] Maker - H:%f, S:%f, B:%f\n", symbol, holdScore, sellScore, buyScore)
 	if holdScore >= m.HoldScore {
@@ -115,6 +147,7 @@ func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
 
 		if len(marketDepth.Asks) < 3 && manualOrder == nil {
 			log.Printf("[%s] Too small ASKs amount: %d\n", symbol, len(marketDepth.Asks))
+			m.journal(entry, ExchangeModel.DecisionJournalSell, ExchangeModel.DecisionJournalSkipped, "Too small ASKs amount")
 			return
 		}
 
@@ -133,17 +166,33 @@ func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
 				isManual = true
 			}
 
+			quantity := 0.00
+			if price > 0 && !isManual {
+				price, quantity = m.OrderExecutor.CalculateTakeProfitSell(tradeLimit, openedOrder, price)
+			} else if price > 0 {
+				quantity = m.OrderExecutor.CalculateSellQuantity(openedOrder)
+			}
+
+			entry.Price = price
+
 			if price > 0 {
-				quantity := m.Formatter.FormatQuantity(tradeLimit, m.OrderExecutor.CalculateSellQuantity(openedOrder))
+				quantity = m.Formatter.FormatQuantity(tradeLimit, quantity)
+				entry.Quantity = quantity
 
 				if quantity >= tradeLimit.MinQuantity {
 					log.Printf("[%s] SELL QTY = %f", openedOrder.Symbol, quantity)
 					err = m.OrderExecutor.Sell(tradeLimit, openedOrder, symbol, price, quantity, isManual)
 					if err != nil {
 						log.Printf("[%s] SELL error: %s", openedOrder.Symbol, err.Error())
+						m.journal(entry, ExchangeModel.DecisionJournalSell, ExchangeModel.DecisionJournalFailed, err.Error())
+					} else {
+						m.journal(entry, ExchangeModel.DecisionJournalSell, ExchangeModel.DecisionJournalExecuted, "")
 					}
+				} else if !isManual && m.OrderExecutor.IsTakeProfitTrailing(tradeLimit, openedOrder) {
+					m.journal(entry, ExchangeModel.DecisionJournalSell, ExchangeModel.DecisionJournalSkipped, "Take profit trailing stop is not reached")
 				} else {
 					log.Printf("[%s] SELL QTY = %f is too small!", openedOrder.Symbol, quantity)
+					m.journal(entry, ExchangeModel.DecisionJournalSell, ExchangeModel.DecisionJournalSkipped, "SELL quantity is too small")
 				}
 
 				if err != nil {
@@ -151,6 +200,7 @@ func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
 				}
 			} else {
 				log.Printf("[%s] No BIDs on the market", symbol)
+				m.journal(entry, ExchangeModel.DecisionJournalSell, ExchangeModel.DecisionJournalSkipped, "No BIDs on the market")
 			}
 		}
 
@@ -158,13 +208,20 @@ func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
 	}
 
 	if buyScore > sellScore {
+		operation := ExchangeModel.DecisionJournalBuy
+		if buyOrderErr == nil {
+			operation = ExchangeModel.DecisionJournalExtraCharge
+		}
+
 		if !tradeLimit.IsEnabled {
 			log.Printf("[%s] BUY operation is disabled", symbol)
+			m.journal(entry, operation, ExchangeModel.DecisionJournalSkipped, "BUY operation is disabled")
 			return
 		}
 
 		if !m.TradeStack.CanBuy(tradeLimit) {
 			log.Printf("[%s] Trade Stack check is not passed, wait order.", symbol)
+			m.journal(entry, operation, ExchangeModel.DecisionJournalSkipped, "Trade Stack check is not passed")
 
 			// has opened BUY order (extra charge time)
 			if buyOrderErr == nil {
@@ -190,6 +247,7 @@ func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
 
 		if balanceErr != nil {
 			log.Printf("[%s] Min balance check: %s", tradeLimit.Symbol, balanceErr.Error())
+			m.journal(entry, operation, ExchangeModel.DecisionJournalSkipped, "Min balance check: "+balanceErr.Error())
 			time.Sleep(time.Minute)
 			return
 		}
@@ -198,12 +256,17 @@ func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
 
 		if len(marketDepth.Bids) < 3 && manualOrder == nil {
 			log.Printf("[%s] Too small BIDs amount: %d\n", symbol, len(marketDepth.Bids))
+			m.journal(entry, operation, ExchangeModel.DecisionJournalSkipped, "Too small BIDs amount")
 			return
 		}
 
-		price, err := m.PriceCalculator.CalculateBuy(tradeLimit)
+		calculation, err := m.PriceCalculator.CalculateBuyExplained(tradeLimit)
+		price := calculation.Price
+		entry.PriceCalculation = &calculation
 
 		if err != nil {
+			m.journal(entry, operation, ExchangeModel.DecisionJournalSkipped, err.Error())
+
 			lastKline := m.ExchangeRepository.GetLastKLine(symbol)
 			if lastKline == nil {
 				log.Printf("[%s] Last price is unknown", symbol)
@@ -217,33 +280,46 @@ func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
 		if manualOrder != nil && strings.ToUpper(manualOrder.Operation) == "BUY" {
 			price = m.Formatter.FormatPrice(tradeLimit, manualOrder.Price)
 		}
+		entry.Price = price
 
 		if buyOrderErr != nil {
 			if lastKline.IsPriceExpired() {
 				log.Printf("[%s] Price is expired", symbol)
+				m.journal(entry, operation, ExchangeModel.DecisionJournalSkipped, "Price is expired")
 				return
 			}
 
 			if price > 0 {
 				// todo: get buy quantity, buy to all cutlet! check available balance!
 				quantity := m.Formatter.FormatQuantity(tradeLimit, tradeLimit.USDTLimit/price)
+				entry.Quantity = quantity
 
 				if (quantity * price) < tradeLimit.MinNotional {
 					log.Printf("[%s] BUY Notional: %.8f < %.8f", symbol, quantity*price, tradeLimit.MinNotional)
+					m.journal(entry, operation, ExchangeModel.DecisionJournalSkipped, "BUY notional is less than min notional")
 					return
 				}
 
 				err = m.OrderExecutor.Buy(tradeLimit, symbol, price, quantity)
 				if err != nil {
 					log.Printf("[%s] %s", symbol, err)
+					m.journal(entry, operation, ExchangeModel.DecisionJournalFailed, err.Error())
 
 					if strings.Contains(err.Error(), "not enough balance") {
 						log.Printf("[%s] wait 1 minute...", symbol)
 						time.Sleep(time.Minute * 1)
 					}
+				} else {
+					openedOrder, buyOrderErr = m.OrderRepository.GetOpenedOrderCached(symbol, "BUY")
+					if buyOrderErr == nil {
+						entry.OrderId = &openedOrder.Id
+						m.recordStrategies(openedOrder, decisions)
+					}
+					m.journal(entry, operation, ExchangeModel.DecisionJournalExecuted, "")
 				}
 			} else {
 				log.Printf("[%s] No ASKs on the market", symbol)
+				m.journal(entry, operation, ExchangeModel.DecisionJournalSkipped, "No ASKs on the market")
 			}
 		} else {
 			lastKline = m.ExchangeRepository.GetLastKLine(tradeLimit.Symbol)
@@ -257,13 +333,19 @@ func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
 						price = m.Formatter.FormatPrice(tradeLimit, lastKline.Close)
 					}
 
+					entry.Price = price
+
 					err = m.OrderExecutor.BuyExtra(tradeLimit, openedOrder, price)
 					if err != nil {
 						log.Printf("[%s] %s", symbol, err)
+						m.journal(entry, operation, ExchangeModel.DecisionJournalFailed, err.Error())
 
 						m.OrderExecutor.TrySwap(openedOrder)
+					} else {
+						m.journal(entry, operation, ExchangeModel.DecisionJournalExecuted, "")
 					}
 				} else {
+					m.journal(entry, operation, ExchangeModel.DecisionJournalSkipped, "Extra charge is not allowed")
 					log.Printf(
 						"[%s] Extra charge is not allowed: %.2f of %.2f",
 						symbol,
@@ -276,6 +358,37 @@ func (m *MakerService) Make(symbol string, decisions []ExchangeModel.Decision) {
 	}
 }
 
+// journal records result of decision with inputs collected before it
+func (m *MakerService) journal(entry *ExchangeModel.DecisionJournalEntry, operation string, result string, reason string) {
+	if m.DecisionJournal == nil {
+		return
+	}
+
+	entry.Operation = operation
+	entry.Result = result
+	entry.Reason = reason
+	m.DecisionJournal.Record(*entry)
+}
+
+// recordStrategies attributes opened position to strategies which voted for buy
+func (m *MakerService) recordStrategies(openedOrder ExchangeModel.Order, decisions []ExchangeModel.Decision) {
+	if m.PerformanceRepository == nil {
+		return
+	}
+
+	buyDecisions := make([]ExchangeModel.Decision, 0)
+	for _, decision := range decisions {
+		if decision.Operation == "BUY" {
+			buyDecisions = append(buyDecisions, decision)
+		}
+	}
+
+	err := m.PerformanceRepository.CreateOrderStrategies(openedOrder.Id, buyDecisions)
+	if err != nil {
+		log.Printf("[%s] Order strategies are not saved: %s", openedOrder.Symbol, err.Error())
+	}
+}
+
 func (m *MakerService) tradeLimit(symbol string) *ExchangeModel.TradeLimit {
 	tradeLimits := m.ExchangeRepository.GetTradeLimits()
 	for _, tradeLimit := range tradeLimits {
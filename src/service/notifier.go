@@ -0,0 +1,214 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"log"
+	"net/http"
+	"slices"
+	"text/template"
+	"time"
+)
+
+const defaultNotificationTemplate = "{{.Event}}{{if .Symbol}} {{.Symbol}}{{end}}{{if .Operation}} {{.Operation}} {{.Quantity}} x {{.Price}}{{end}}{{if .Code}} [{{.Code}}]{{end}}{{if .Message}}: {{.Message}}{{end}}"
+
+const notificationRetryMin = time.Second * 30
+const notificationRetryMax = time.Hour
+const notificationQueueBatch = 50
+
+type NotifierInterface interface {
+	Send(notification model.Notification, text string) error
+}
+
+// NotifierChannel is configured notifier, notification is routed to channel if event matches (empty events match all)
+type NotifierChannel struct {
+	Name     string
+	Events   []string
+	Template *template.Template
+	Notifier NotifierInterface
+}
+
+func (c *NotifierChannel) Matches(event string) bool {
+	return len(c.Events) == 0 || slices.Contains(c.Events, event)
+}
+
+func (c *NotifierChannel) Render(notification model.Notification) (string, error) {
+	var text bytes.Buffer
+	err := c.Template.Execute(&text, notification)
+
+	return text.String(), err
+}
+
+// NotifierRouter routes notification to matching channels through durable queue,
+// failed delivery is retried with exponential backoff until MaxAttempts is reached
+type NotifierRouter struct {
+	Channels               []NotifierChannel
+	NotificationRepository repository.NotificationStorageInterface
+	CurrentBot             *model.Bot
+	MaxAttempts            int64
+}
+
+func (r *NotifierRouter) Notify(notification model.Notification) {
+	if r == nil {
+		return
+	}
+
+	if r.CurrentBot != nil {
+		notification.BotId = r.CurrentBot.Id
+		notification.BotUuid = r.CurrentBot.BotUuid
+	}
+	if notification.DateTime == "" {
+		notification.DateTime = time.Now().Format("2006-01-02 15:04:05")
+	}
+
+	for _, channel := range r.Channels {
+		if !channel.Matches(notification.Event) {
+			continue
+		}
+
+		if r.NotificationRepository != nil {
+			_, err := r.NotificationRepository.Create(model.NotificationJob{
+				Channel:      channel.Name,
+				Notification: notification,
+			})
+			if err == nil {
+				continue
+			}
+			log.Printf("[%s] Notification is not queued: %s, send directly", channel.Name, err.Error())
+		}
+
+		err := r.deliver(channel, notification)
+		if err != nil {
+			log.Printf("[%s] Notification [%s] failed: %s", channel.Name, notification.Event, err.Error())
+		}
+	}
+}
+
+// ProcessQueue delivers due notifications, returns number of sent notifications
+func (r *NotifierRouter) ProcessQueue() int {
+	if r == nil || r.NotificationRepository == nil {
+		return 0
+	}
+
+	sent := 0
+	for _, job := range r.NotificationRepository.GetDue(notificationQueueBatch) {
+		err := errors.New(fmt.Sprintf("Channel %s is not configured", job.Channel))
+		for _, channel := range r.Channels {
+			if channel.Name == job.Channel {
+				err = r.deliver(channel, job.Notification)
+				break
+			}
+		}
+
+		if err == nil {
+			sent++
+			err = r.NotificationRepository.MarkSent(job.Id)
+			if err != nil {
+				log.Printf("[%s] Notification %d is not marked as sent: %s", job.Channel, job.Id, err.Error())
+			}
+			continue
+		}
+
+		attempts := job.Attempts + 1
+		status := model.NotificationStatusPending
+		if attempts >= r.getMaxAttempts() {
+			status = model.NotificationStatusFailed
+		}
+		log.Printf("[%s] Notification %d attempt %d failed: %s", job.Channel, job.Id, attempts, err.Error())
+
+		err = r.NotificationRepository.MarkFailed(job.Id, attempts, GetNotificationRetryDelay(attempts), status, err.Error())
+		if err != nil {
+			log.Printf("[%s] Notification %d is not marked as failed: %s", job.Channel, job.Id, err.Error())
+		}
+	}
+
+	return sent
+}
+
+// Run processes queue until ctx is cancelled
+func (r *NotifierRouter) Run(ctx context.Context, interval time.Duration) {
+	if r == nil {
+		return
+	}
+
+	for ctx.Err() == nil {
+		r.ProcessQueue()
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (r *NotifierRouter) deliver(channel NotifierChannel, notification model.Notification) error {
+	text, err := channel.Render(notification)
+	if err != nil {
+		return err
+	}
+
+	return channel.Notifier.Send(notification, text)
+}
+
+func (r *NotifierRouter) getMaxAttempts() int64 {
+	if r.MaxAttempts > 0 {
+		return r.MaxAttempts
+	}
+
+	return 1
+}
+
+// GetNotificationRetryDelay is 30s, 1m, 2m, 4m ... 1h
+func GetNotificationRetryDelay(attempts int64) time.Duration {
+	delay := notificationRetryMin
+	for i := int64(1); i < attempts && delay < notificationRetryMax; i++ {
+		delay = delay * 2
+	}
+
+	return min(delay, notificationRetryMax)
+}
+
+func NewNotifierChannel(config model.NotifierChannelConfig, httpClient *http.Client) (*NotifierChannel, error) {
+	text := config.Template
+	if text == "" {
+		text = defaultNotificationTemplate
+	}
+	tpl, err := template.New(config.Name).Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	var notifier NotifierInterface
+	switch config.Type {
+	case model.NotifierTypeTelegram:
+		notifier = &TelegramNotifier{HttpClient: httpClient, BotToken: config.BotToken, ChatId: config.ChatId}
+	case model.NotifierTypeSlack:
+		notifier = &SlackNotifier{HttpClient: httpClient, WebhookUrl: config.Url}
+	case model.NotifierTypeEmail:
+		notifier = &EmailNotifier{
+			Host:     config.SmtpHost,
+			Port:     config.SmtpPort,
+			Username: config.Username,
+			Password: config.Password,
+			From:     config.From,
+			To:       config.To,
+		}
+	case model.NotifierTypeWebhook:
+		notifier = &WebhookNotifier{HttpClient: httpClient, Url: config.Url, Secret: config.Secret}
+	case model.NotifierTypeAutoTrade:
+		notifier = &AutoTradeNotifier{HttpClient: httpClient, AutoTradeHost: config.Url}
+	default:
+		return nil, errors.New(fmt.Sprintf("Notifier type %s is not supported", config.Type))
+	}
+
+	return &NotifierChannel{
+		Name:     config.Name,
+		Events:   config.Events,
+		Template: tpl,
+		Notifier: notifier,
+	}, nil
+}
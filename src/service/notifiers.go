@@ -0,0 +1,173 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"io"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+const telegramApiUrl = "https://api.telegram.org"
+
+// TelegramNotifier sends message through Telegram Bot API
+type TelegramNotifier struct {
+	HttpClient *http.Client
+	ApiUrl     string
+	BotToken   string
+	ChatId     string
+}
+
+func (n *TelegramNotifier) Send(notification model.Notification, text string) error {
+	apiUrl := n.ApiUrl
+	if apiUrl == "" {
+		apiUrl = telegramApiUrl
+	}
+	encoded, _ := json.Marshal(map[string]string{
+		"chat_id": n.ChatId,
+		"text":    text,
+	})
+
+	return postJson(n.HttpClient, fmt.Sprintf("%s/bot%s/sendMessage", apiUrl, n.BotToken), encoded, nil)
+}
+
+// SlackNotifier sends message to Slack incoming webhook
+type SlackNotifier struct {
+	HttpClient *http.Client
+	WebhookUrl string
+}
+
+func (n *SlackNotifier) Send(notification model.Notification, text string) error {
+	encoded, _ := json.Marshal(map[string]string{
+		"text": text,
+	})
+
+	return postJson(n.HttpClient, n.WebhookUrl, encoded, nil)
+}
+
+type EmailNotifier struct {
+	Host     string
+	Port     int64
+	Username string
+	Password string
+	From     string
+	To       []string
+}
+
+func (n *EmailNotifier) Send(notification model.Notification, text string) error {
+	var auth smtp.Auth
+	if n.Username != "" {
+		auth = smtp.PlainAuth("", n.Username, n.Password, n.Host)
+	}
+
+	subject := fmt.Sprintf("[%s] %s %s", notification.BotUuid, strings.ToUpper(notification.Event), notification.Symbol)
+	message := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		n.From,
+		strings.Join(n.To, ", "),
+		strings.TrimSpace(subject),
+		text,
+	)
+
+	return smtp.SendMail(fmt.Sprintf("%s:%d", n.Host, n.Port), auth, n.From, n.To, []byte(message))
+}
+
+// WebhookNotifier posts notification JSON signed by HMAC-SHA256 of "{timestamp}.{body}" with shared secret
+type WebhookNotifier struct {
+	HttpClient *http.Client
+	Url        string
+	Secret     string
+}
+
+func (n *WebhookNotifier) Send(notification model.Notification, text string) error {
+	encoded, _ := json.Marshal(model.WebhookNotification{
+		Notification: notification,
+		Text:         text,
+	})
+	timestamp := fmt.Sprintf("%d", time.Now().Unix())
+
+	return postJson(n.HttpClient, n.Url, encoded, map[string]string{
+		model.NotificationHeaderTimestamp: timestamp,
+		model.NotificationHeaderSignature: SignNotification(n.Secret, timestamp, encoded),
+	})
+}
+
+func SignNotification(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AutoTradeNotifier sends legacy autotrade callbacks (/public/callback/error and /public/callback/telegram)
+type AutoTradeNotifier struct {
+	HttpClient    *http.Client
+	AutoTradeHost string
+}
+
+func (n *AutoTradeNotifier) Send(notification model.Notification, text string) error {
+	if notification.Event == model.NotificationEventError {
+		encoded, _ := json.Marshal(model.ErrorNotification{
+			BotId:        notification.BotId,
+			Stop:         notification.Stop,
+			ErrorCode:    notification.Code,
+			ErrorMessage: notification.Message,
+		})
+
+		return postJson(n.HttpClient, fmt.Sprintf("%s/public/callback/error", n.AutoTradeHost), encoded, nil)
+	}
+
+	details := notification.Message
+	if notification.Event != model.NotificationEventBuy && notification.Event != model.NotificationEventSell {
+		details = text
+	}
+	encoded, _ := json.Marshal(model.TgOrderNotification{
+		BotId:     notification.BotId,
+		Price:     notification.Price,
+		Quantity:  notification.Quantity,
+		Symbol:    notification.Symbol,
+		Operation: strings.ToUpper(notification.Operation),
+		DateTime:  notification.DateTime,
+		Details:   details,
+	})
+
+	return postJson(n.HttpClient, fmt.Sprintf("%s/public/callback/telegram", n.AutoTradeHost), encoded, nil)
+}
+
+func postJson(httpClient *http.Client, url string, body []byte, headers map[string]string) error {
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: time.Second * 10}
+	}
+
+	res, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	_, err = io.ReadAll(res.Body)
+
+	if res.StatusCode >= 400 {
+		return errors.New(fmt.Sprintf("Request failed with error code: %d", res.StatusCode))
+	}
+
+	return err
+}
@@ -0,0 +1,83 @@
+package service
+
+import (
+	"fmt"
+	eventHub "gitlab.com/open-soft/go-crypto-bot/src/event"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"log"
+	"strings"
+)
+
+type OrderEventRecorderInterface interface {
+	Record(event model.OrderEvent)
+}
+
+type OrderEventRecorder struct {
+	OrderEventRepository repository.OrderEventStorageInterface
+	EventHub             *eventHub.Hub
+	NotifierRouter       *NotifierRouter
+}
+
+func (r *OrderEventRecorder) Record(event model.OrderEvent) {
+	switch event.Type {
+	case model.OrderEventBuyPlaced, model.OrderEventSellPlaced:
+		metrics.OrdersTotal.WithLabelValues(strings.ToLower(event.Operation), "placed").Inc()
+	case model.OrderEventFilled:
+		metrics.OrdersTotal.WithLabelValues(strings.ToLower(event.Operation), "filled").Inc()
+	case model.OrderEventCancelled:
+		metrics.OrdersTotal.WithLabelValues(strings.ToLower(event.Operation), "cancelled").Inc()
+	}
+
+	id, err := r.OrderEventRepository.Create(event)
+
+	if err != nil {
+		log.Printf("[%s] Order event [%s] is not saved: %s", event.Symbol, event.Type, err.Error())
+	} else if id != nil {
+		event.Id = *id
+	}
+
+	if strings.HasPrefix(event.Type, "swap_") {
+		r.EventHub.Publish(eventHub.TopicSwap, event.Symbol, event)
+	} else {
+		r.EventHub.Publish(eventHub.TopicOrder, event.Symbol, event)
+	}
+
+	r.notify(event)
+}
+
+// notify sends swap lifecycle and risk (loss security, reconciliation) events, progress is not sent
+func (r *OrderEventRecorder) notify(event model.OrderEvent) {
+	notificationEvent := ""
+	switch {
+	case event.Type == model.OrderEventSwapStarted,
+		event.Type == model.OrderEventSwapFinished,
+		event.Type == model.OrderEventSwapCancelled,
+		event.Type == model.OrderEventSwapRollback:
+		notificationEvent = model.NotificationEventSwap
+	case event.Type == model.OrderEventReconciled,
+		event.Reason != nil && *event.Reason == model.OrderEventReasonLossSecurity:
+		notificationEvent = model.NotificationEventRisk
+	default:
+		return
+	}
+
+	message := event.Type
+	if event.Reason != nil {
+		message = fmt.Sprintf("%s (%s)", message, *event.Reason)
+	}
+	if event.Details != nil && *event.Details != "" {
+		message = fmt.Sprintf("%s: %s", message, *event.Details)
+	}
+
+	r.NotifierRouter.Notify(model.Notification{
+		Event:     notificationEvent,
+		Symbol:    event.Symbol,
+		Operation: strings.ToUpper(event.Operation),
+		Price:     event.Price,
+		Quantity:  event.Quantity,
+		Code:      event.Type,
+		Message:   message,
+	})
+}
@@ -4,13 +4,21 @@ import (
 	"errors"
 	"fmt"
 	ExchangeClient "gitlab.com/open-soft/go-crypto-bot/src/client"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
 	ExchangeModel "gitlab.com/open-soft/go-crypto-bot/src/model"
 	ExchangeRepository "gitlab.com/open-soft/go-crypto-bot/src/repository"
 	"log"
+	"math"
 	"strings"
 	"sync"
+	"time"
 )
 
+type TradeLockInterface interface {
+	TryLock(symbol string) bool
+	Unlock(symbol string)
+}
+
 type OrderExecutor struct {
 	TradeStack             BuyOrderStackInterface
 	CurrentBot             *ExchangeModel.Bot
@@ -25,6 +33,10 @@ type OrderExecutor struct {
 	SwapExecutor           SwapExecutorInterface
 	SwapValidator          SwapValidatorInterface
 	CallbackManager        CallbackManagerInterface
+	OrderEventRecorder     OrderEventRecorderInterface
+	LotLedger              LotLedgerInterface
+	ChildOrderRepository   ExchangeRepository.ChildOrderStorageInterface
+	ShutdownService        ShutdownServiceInterface
 	Formatter              *Formatter
 	SwapSellOrderDays      int64
 	SwapEnabled            bool
@@ -32,7 +44,6 @@ type OrderExecutor struct {
 	TurboSwapProfitPercent float64
 	Lock                   map[string]bool
 	TradeLockMutex         sync.RWMutex
-	LockChannel            *chan ExchangeModel.Lock
 	CancelRequestMap       map[string]bool
 }
 
@@ -64,8 +75,15 @@ func (m *OrderExecutor) BuyExtra(tradeLimit ExchangeModel.TradeLimit, order Exch
 		)
 	}
 
-	m.acquireLock(order.Symbol)
-	defer m.releaseLock(order.Symbol)
+	if !m.ShutdownService.StartOperation() {
+		return errors.New(fmt.Sprintf("[%s] Extra buy is rejected, shutdown in progress", tradeLimit.Symbol))
+	}
+	defer m.ShutdownService.FinishOperation()
+
+	if !m.TryLock(order.Symbol) {
+		return errors.New(fmt.Sprintf("[%s] Extra buy is Locked", order.Symbol))
+	}
+	defer m.Unlock(order.Symbol)
 	// todo: get buy quantity, buy to all cutlet! check available balance!
 	quantity := m.Formatter.FormatQuantity(tradeLimit, order.GetAvailableExtraBudget(*lastKline)/price)
 
@@ -104,7 +122,7 @@ func (m *OrderExecutor) BuyExtra(tradeLimit ExchangeModel.TradeLimit, order Exch
 
 	balanceBefore, balanceErr := m.BalanceService.GetAssetBalance(order.GetBaseAsset(), true)
 
-	binanceOrder, err := m.tryLimitOrder(extraOrder, "BUY", 120)
+	binanceOrder, err := m.tryLimitOrder(tradeLimit, extraOrder, "BUY", 120, false)
 
 	if err != nil {
 		m.BalanceService.InvalidateBalanceCache("USDT")
@@ -134,8 +152,9 @@ func (m *OrderExecutor) BuyExtra(tradeLimit ExchangeModel.TradeLimit, order Exch
 	m.OrderRepository.DeleteManualOrder(order.Symbol)
 
 	if balanceErr == nil {
-		m.UpdateCommission(balanceBefore, extraOrder)
+		extraOrder = m.UpdateCommission(balanceBefore, extraOrder)
 	}
+	m.recordLot(extraOrder, ExchangeModel.LotEntryBuyExtra, &order.Id)
 
 	order.ExecutedQuantity = executedQty + order.ExecutedQuantity
 	order.Price = avgPrice
@@ -163,6 +182,12 @@ func (m *OrderExecutor) BuyExtra(tradeLimit ExchangeModel.TradeLimit, order Exch
 		return err
 	}
 
+	extraChargeEvent := binanceOrder.GetOrderEvent(ExchangeModel.OrderEventExtraCharge, &order.Id)
+	extraChargeDetails := fmt.Sprintf("Average price is %f, used extra budget %f USDT", avgPrice, order.UsedExtraBudget)
+	extraChargeEvent.CurrentPrice = &lastKline.Close
+	extraChargeEvent.Details = &extraChargeDetails
+	m.OrderEventRecorder.Record(extraChargeEvent)
+
 	go func(extraOrder ExchangeModel.Order, tradeLimit ExchangeModel.TradeLimit) {
 		m.CallbackManager.BuyOrder(
 			extraOrder,
@@ -176,14 +201,20 @@ func (m *OrderExecutor) BuyExtra(tradeLimit ExchangeModel.TradeLimit, order Exch
 }
 
 func (m *OrderExecutor) Buy(tradeLimit ExchangeModel.TradeLimit, symbol string, price float64, quantity float64) error {
-	if m.isTradeLocked(symbol) {
+	if !m.TryLock(symbol) {
 		return errors.New(fmt.Sprintf("Operation Buy is Locked %s", symbol))
 	}
+	defer m.Unlock(symbol)
 
 	if quantity <= 0.00 {
 		return errors.New(fmt.Sprintf("Available quantity is %f", quantity))
 	}
 
+	if !m.ShutdownService.StartOperation() {
+		return errors.New(fmt.Sprintf("Operation Buy is rejected, shutdown in progress %s", symbol))
+	}
+	defer m.ShutdownService.FinishOperation()
+
 	balanceErr := m.CheckBalance(symbol, price, quantity)
 
 	if balanceErr != nil {
@@ -197,10 +228,6 @@ func (m *OrderExecutor) Buy(tradeLimit ExchangeModel.TradeLimit, symbol string,
 		return balanceErr
 	}
 
-	// to avoid concurrent map writes
-	m.acquireLock(symbol)
-	defer m.releaseLock(symbol)
-
 	// todo: commission
 	// You place an order to buy 10 ETH for 3,452.55 USDT each:
 	// Trading fee = 10 ETH * 0.1% = 0.01 ETH
@@ -225,7 +252,7 @@ func (m *OrderExecutor) Buy(tradeLimit ExchangeModel.TradeLimit, symbol string,
 
 	balanceBefore, balanceErr := m.BalanceService.GetAssetBalance(order.GetBaseAsset(), true)
 
-	binanceOrder, err := m.tryLimitOrder(order, "BUY", 480)
+	binanceOrder, parentId, err := m.executeLimitOrder(tradeLimit, order, "BUY", 480)
 
 	if err != nil {
 		m.BalanceService.InvalidateBalanceCache("USDT")
@@ -238,7 +265,7 @@ func (m *OrderExecutor) Buy(tradeLimit ExchangeModel.TradeLimit, symbol string,
 	order.Price = binanceOrder.Price
 	order.CreatedAt = m.TimeService.GetNowDateTimeString()
 
-	_, err = m.OrderRepository.Create(order)
+	lastId, err := m.createOrUpdateOrder(&order, parentId)
 	m.BalanceService.InvalidateBalanceCache("USDT")
 	m.BalanceService.InvalidateBalanceCache(order.GetBaseAsset())
 
@@ -256,8 +283,9 @@ func (m *OrderExecutor) Buy(tradeLimit ExchangeModel.TradeLimit, symbol string,
 	m.OrderRepository.DeleteManualOrder(order.Symbol)
 
 	if balanceErr == nil {
-		m.UpdateCommission(balanceBefore, order)
+		order = m.UpdateCommission(balanceBefore, order)
 	}
+	m.recordLot(order, ExchangeModel.LotEntryBuy, lastId)
 
 	go func(order ExchangeModel.Order, tradeLimit ExchangeModel.TradeLimit) {
 		m.CallbackManager.BuyOrder(
@@ -272,12 +300,15 @@ func (m *OrderExecutor) Buy(tradeLimit ExchangeModel.TradeLimit, symbol string,
 }
 
 func (m *OrderExecutor) Sell(tradeLimit ExchangeModel.TradeLimit, opened ExchangeModel.Order, symbol string, price float64, quantity float64, isManual bool) error {
-	if m.isTradeLocked(symbol) {
+	if !m.TryLock(symbol) {
 		return errors.New(fmt.Sprintf("Operation Sell is Locked %s", symbol))
 	}
+	defer m.Unlock(symbol)
 
-	m.acquireLock(symbol)
-	defer m.releaseLock(symbol)
+	if !m.ShutdownService.StartOperation() {
+		return errors.New(fmt.Sprintf("Operation Sell is rejected, shutdown in progress %s", symbol))
+	}
+	defer m.ShutdownService.FinishOperation()
 
 	// todo: commission
 	// Or you place an order to sell 10 ETH for 3,452.55 USDT each:
@@ -327,7 +358,7 @@ func (m *OrderExecutor) Sell(tradeLimit ExchangeModel.TradeLimit, opened Exchang
 		// todo: add commission???
 	}
 
-	binanceOrder, err := m.tryLimitOrder(order, "SELL", 480)
+	binanceOrder, parentId, err := m.executeLimitOrder(tradeLimit, order, "SELL", 480)
 
 	if err != nil {
 		m.BalanceService.InvalidateBalanceCache("USDT")
@@ -341,7 +372,7 @@ func (m *OrderExecutor) Sell(tradeLimit ExchangeModel.TradeLimit, opened Exchang
 	order.Price = binanceOrder.Price
 	order.CreatedAt = m.TimeService.GetNowDateTimeString()
 
-	lastId, err := m.OrderRepository.Create(order)
+	lastId, err := m.createOrUpdateOrder(&order, parentId)
 
 	if err != nil {
 		// todo: test 2024/02/02 08:24:29 [XLMUSDT] Error 1062 (23000): Duplicate entry '207993-XLMUSDT' for key 'order_external_id_symbol'
@@ -356,6 +387,7 @@ func (m *OrderExecutor) Sell(tradeLimit ExchangeModel.TradeLimit, opened Exchang
 	}
 
 	m.OrderRepository.DeleteManualOrder(order.Symbol)
+	m.recordLot(order, ExchangeModel.LotEntrySell, &opened.Id)
 	_, err = m.OrderRepository.Find(*lastId)
 
 	if err != nil {
@@ -401,6 +433,22 @@ func (m *OrderExecutor) Sell(tradeLimit ExchangeModel.TradeLimit, opened Exchang
 	return nil
 }
 
+// createOrUpdateOrder updates order which is already saved with fills of child orders
+func (m *OrderExecutor) createOrUpdateOrder(order *ExchangeModel.Order, parentId *int64) (*int64, error) {
+	if parentId == nil {
+		id, err := m.OrderRepository.Create(*order)
+		if err == nil {
+			order.Id = *id
+		}
+
+		return id, err
+	}
+
+	order.Id = *parentId
+
+	return parentId, m.OrderRepository.Update(*order)
+}
+
 func (m *OrderExecutor) ProcessSwap(order ExchangeModel.Order) bool {
 	if m.SwapEnabled && order.IsSwap() {
 		log.Printf("[%s] Swap Order [%d] Mode: processing...", order.Symbol, order.Id)
@@ -446,10 +494,125 @@ func (m *OrderExecutor) TrySwap(order ExchangeModel.Order) {
 	}
 }
 
+// accumulationCancelAttempts limits cancel requests of DCA order which is not filled in ttl
+const accumulationCancelAttempts = 5
+
 // todo: order has to be Interface
-func (m *OrderExecutor) tryLimitOrder(order ExchangeModel.Order, operation string, ttl int64) (ExchangeModel.BinanceOrder, error) {
+// BuyAccumulation buys quantity for DCA plan, binance order is not cached and is not saved as position,
+// so sell logic never touches it. Order is canceled if it is not filled in ttl seconds
+func (m *OrderExecutor) BuyAccumulation(tradeLimit ExchangeModel.TradeLimit, price float64, quantity float64, ttl int64) (ExchangeModel.Order, error) {
+	symbol := tradeLimit.Symbol
+	order := ExchangeModel.Order{
+		Symbol:             symbol,
+		Quantity:           quantity,
+		Price:              price,
+		Status:             "closed",
+		Operation:          "buy",
+		ExtraChargeOptions: make(ExchangeModel.ExtraChargeOptions, 0),
+	}
+
+	// reconciler and maker skip locked symbol, so accumulation order is not taken as position order
+	if !m.TryLock(symbol) {
+		return order, errors.New(fmt.Sprintf("Operation Buy is Locked %s", symbol))
+	}
+	defer m.Unlock(symbol)
+
+	if quantity <= 0.00 {
+		return order, errors.New(fmt.Sprintf("Available quantity is %f", quantity))
+	}
+
+	if !m.ShutdownService.StartOperation() {
+		return order, errors.New(fmt.Sprintf("Operation Buy is rejected, shutdown in progress %s", symbol))
+	}
+	defer m.ShutdownService.FinishOperation()
+
+	balanceErr := m.checkUsdtBalance(symbol, price*quantity)
+
+	if balanceErr != nil {
+		m.CallbackManager.Error(
+			*m.CurrentBot,
+			"balance_error",
+			balanceErr.Error(),
+			false,
+		)
+
+		return order, balanceErr
+	}
+
+	balanceBefore, balanceErr := m.BalanceService.GetAssetBalance(order.GetBaseAsset(), true)
+
+	binanceOrder, err := m.placeLimitOrder(tradeLimit, symbol, quantity, price, "BUY")
+	if err != nil {
+		m.BalanceService.InvalidateBalanceCache("USDT")
+
+		return order, err
+	}
+	log.Printf("[%s] DCA BUY Order created %d, Price: %.6f", symbol, binanceOrder.OrderId, binanceOrder.Price)
+
+	start := m.TimeService.GetNowUnix()
+	cancelAttempts := 0
+	for binanceOrder.IsNew() || binanceOrder.IsPartiallyFilled() {
+		if m.TimeService.GetNowUnix()-start >= ttl {
+			_, err = m.Binance.CancelOrder(symbol, binanceOrder.OrderId)
+			if err != nil {
+				cancelAttempts++
+				log.Printf("[%s] DCA order %d is not canceled (attempt %d): %s", symbol, binanceOrder.OrderId, cancelAttempts, err.Error())
+
+				if cancelAttempts >= accumulationCancelAttempts {
+					m.BalanceService.InvalidateBalanceCache("USDT")
+					m.BalanceService.InvalidateBalanceCache(order.GetBaseAsset())
+
+					return order, errors.New(fmt.Sprintf("binance order [%d] is not canceled after %d attempts, it is left on exchange: %s", binanceOrder.OrderId, cancelAttempts, err.Error()))
+				}
+
+				m.TimeService.WaitSeconds(5)
+			}
+		} else {
+			m.TimeService.WaitSeconds(5)
+		}
+
+		queried, err := m.Binance.QueryOrder(symbol, binanceOrder.OrderId)
+		if err != nil {
+			log.Printf("[%s] DCA order %d is not loaded: %s", symbol, binanceOrder.OrderId, err.Error())
+			m.TimeService.WaitSeconds(5)
+			continue
+		}
+		binanceOrder = queried
+	}
+
+	m.BalanceService.InvalidateBalanceCache("USDT")
+	m.BalanceService.InvalidateBalanceCache(order.GetBaseAsset())
+
+	if !binanceOrder.HasExecutedQuantity() {
+		return order, errors.New(fmt.Sprintf("binance order [%d] is %s and not executed", binanceOrder.OrderId, strings.ToLower(binanceOrder.Status)))
+	}
+
+	order.ExternalId = &binanceOrder.OrderId
+	order.Quantity = binanceOrder.OrigQty
+	order.ExecutedQuantity = binanceOrder.GetExecutedQuantity()
+	if binanceOrder.CummulativeQuoteQty > 0 {
+		order.Price = binanceOrder.CummulativeQuoteQty / binanceOrder.ExecutedQty
+	}
+	order.CreatedAt = m.TimeService.GetNowDateTimeString()
+
+	if balanceErr == nil {
+		balanceAfter, err := m.BalanceService.GetAssetBalance(order.GetBaseAsset(), true)
+		if err == nil {
+			commission := math.Max(0.00, order.ExecutedQuantity-(balanceAfter-balanceBefore))
+			assetSymbol := order.GetBaseAsset()
+			order.Commission = &commission
+			order.CommissionAsset = &assetSymbol
+		}
+	}
+	m.recordLot(order, ExchangeModel.LotEntryBuy, nil)
+
+	return order, nil
+}
+
+// tryLimitOrder places limit order and waits its execution, order with sliceEnd is cancelled when ttl is reached
+func (m *OrderExecutor) tryLimitOrder(tradeLimit ExchangeModel.TradeLimit, order ExchangeModel.Order, operation string, ttl int64, sliceEnd bool) (ExchangeModel.BinanceOrder, error) {
 	// todo: extra order flag...
-	binanceOrder, err := m.findOrCreateOrder(order, operation)
+	binanceOrder, err := m.findOrCreateOrder(tradeLimit, order, operation)
 
 	if err != nil {
 		return binanceOrder, err
@@ -470,7 +633,7 @@ func (m *OrderExecutor) tryLimitOrder(order ExchangeModel.Order, operation strin
 	}
 
 	// todo: save sell order in buy order to make sure it is saved after processing...
-	binanceOrder, err = m.waitExecution(binanceOrder, ttl)
+	binanceOrder, err = m.waitExecution(binanceOrder, ttl, order.ClosesOrder, sliceEnd)
 
 	if err != nil {
 		return binanceOrder, err
@@ -479,8 +642,320 @@ func (m *OrderExecutor) tryLimitOrder(order ExchangeModel.Order, operation strin
 	return binanceOrder, nil
 }
 
-func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, seconds int64) (ExchangeModel.BinanceOrder, error) {
-	defer m.OrderRepository.DeleteBinanceOrder(binanceOrder)
+// executeLimitOrder places one limit order or child orders by execution algorithm of trade limit,
+// fills of child orders are aggregated to one binance order with average price and id of the first child order.
+// Parent order is saved after every fill of child order and its id is returned, it is nil for one limit order
+func (m *OrderExecutor) executeLimitOrder(tradeLimit ExchangeModel.TradeLimit, order ExchangeModel.Order, operation string, ttl int64) (ExchangeModel.BinanceOrder, *int64, error) {
+	quantities := m.getChildQuantities(tradeLimit, order)
+	pending := make([]ExchangeModel.ChildOrder, 0)
+	if m.ChildOrderRepository != nil {
+		pending = m.ChildOrderRepository.GetPendingChildOrders(order.Symbol, operation)
+	}
+
+	if len(quantities) < 2 && len(pending) == 0 {
+		binanceOrder, err := m.tryLimitOrder(tradeLimit, order, operation, ttl, false)
+
+		return binanceOrder, nil, err
+	}
+
+	algorithm := tradeLimit.ExecutionOptions.Algorithm
+	parent := ExchangeModel.BinanceOrder{
+		Symbol: order.Symbol,
+		Side:   operation,
+		Type:   "LIMIT",
+		Price:  order.Price,
+	}
+	var parentId *int64
+	childIds := make([]int64, 0)
+
+	// fills of child orders executed before restart are saved to the parent order first
+	for _, child := range pending {
+		log.Printf("[%s] %s child order %d is recovered, executed %f", order.Symbol, operation, child.ExternalId, child.ExecutedQuantity)
+		childIds = append(childIds, child.Id)
+		m.addChildFill(&parent, ExchangeModel.BinanceOrder{
+			OrderId:     child.ExternalId,
+			Price:       child.Price,
+			OrigQty:     child.Quantity,
+			ExecutedQty: child.ExecutedQuantity,
+		})
+	}
+	if len(pending) > 0 {
+		parentId = m.saveParentOrder(order, parent, nil, childIds)
+	}
+
+	// TWAP child order is cancelled at the end of its slice, not executed quantity is placed with the next slice
+	sliceEnd := algorithm == ExchangeModel.ExecutionAlgorithmTwap
+
+	// executes child order, returns false if the rest of child orders should not be placed
+	execute := func(child ExchangeModel.Order) bool {
+		binanceOrder, err := m.tryLimitOrder(tradeLimit, child, operation, ttl, sliceEnd)
+		isSliceEnded := err != nil && strings.Contains(err.Error(), "is cancelled at the end of slice")
+
+		if err != nil && !isSliceEnded {
+			log.Printf("[%s] %s child order: %s", order.Symbol, operation, err.Error())
+			return false
+		}
+
+		// filled child order is not recovered from cache, the next child order is placed instead
+		m.OrderRepository.DeleteBinanceOrder(binanceOrder)
+		childId := m.saveChildOrder(algorithm, binanceOrder, parentId)
+		if childId != nil {
+			childIds = append(childIds, *childId)
+		}
+
+		if binanceOrder.ExecutedQty > 0.00 {
+			m.addChildFill(&parent, binanceOrder)
+			parentId = m.saveParentOrder(order, parent, parentId, childIds)
+		}
+
+		if isSliceEnded {
+			log.Printf("[%s] %s child order %d is cancelled at the end of slice, executed %f", order.Symbol, operation, binanceOrder.OrderId, binanceOrder.ExecutedQty)
+			return true
+		}
+
+		// partially filled child order is cancelled by loss security, user or ttl, the rest is not placed
+		if !binanceOrder.IsFilled() {
+			log.Printf("[%s] %s child order %d is %s, the rest is not placed", order.Symbol, operation, binanceOrder.OrderId, binanceOrder.Status)
+			return false
+		}
+
+		return true
+	}
+
+	proceed := true
+
+	// child order placed before restart is finished first
+	existing, _ := m.findBinanceOrder(order.Symbol, operation, false)
+	if existing != nil {
+		child := order
+		child.Quantity = existing.OrigQty
+		child.Price = existing.Price
+		proceed = execute(child)
+	}
+
+	rest := order
+	rest.Quantity = m.Formatter.FormatQuantity(tradeLimit, m.Formatter.ToFixed(order.Quantity-parent.ExecutedQty, 8))
+	if proceed && rest.Quantity >= tradeLimit.MinQuantity && rest.Quantity > 0.00 {
+		quantities = m.getChildQuantities(tradeLimit, rest)
+		interval := int64(0)
+		if algorithm == ExchangeModel.ExecutionAlgorithmTwap {
+			interval = tradeLimit.ExecutionOptions.Minutes * 60 / int64(len(quantities))
+			ttl = interval
+		}
+		start := m.TimeService.GetNowUnix()
+		executedBefore := parent.ExecutedQty
+		planned := 0.00
+
+		for index, quantity := range quantities {
+			child := order
+			child.Quantity = quantity
+			planned += quantity
+
+			// not executed quantity of previous slices is added to the current one
+			if sliceEnd {
+				child.Quantity = m.Formatter.FormatQuantity(tradeLimit, m.Formatter.ToFixed(planned-(parent.ExecutedQty-executedBefore), 8))
+			}
+
+			if index > 0 || existing != nil || len(pending) > 0 {
+				if m.ShutdownService.IsShuttingDown() || m.HasCancelRequest(order.Symbol) {
+					log.Printf("[%s] %s %s is cancelled after %d child orders", order.Symbol, operation, algorithm, len(childIds))
+					break
+				}
+
+				wait := start + int64(index)*interval - m.TimeService.GetNowUnix()
+				if wait > 0 {
+					m.TimeService.WaitSeconds(wait)
+				}
+
+				child.Price = m.getChildPrice(tradeLimit, order, operation)
+			}
+
+			if !execute(child) {
+				break
+			}
+		}
+	}
+
+	if parent.ExecutedQty == 0.00 {
+		return parent, parentId, errors.New(fmt.Sprintf("[%s] %s %s is not executed", order.Symbol, operation, algorithm))
+	}
+
+	parent.Price = parent.CummulativeQuoteQty / parent.ExecutedQty
+	parent.Status = "CANCELED"
+	if order.Quantity-parent.ExecutedQty < tradeLimit.MinQuantity {
+		parent.Status = "FILLED"
+	}
+
+	log.Printf(
+		"[%s] %s %s is finished: %d child orders, executed %f of %f, average price %f",
+		order.Symbol,
+		operation,
+		algorithm,
+		len(childIds),
+		parent.ExecutedQty,
+		order.Quantity,
+		parent.Price,
+	)
+
+	return parent, parentId, nil
+}
+
+func (m *OrderExecutor) addChildFill(parent *ExchangeModel.BinanceOrder, binanceOrder ExchangeModel.BinanceOrder) {
+	if parent.OrderId == 0 {
+		parent.OrderId = binanceOrder.OrderId
+	}
+	quoteQuantity := binanceOrder.CummulativeQuoteQty
+	if quoteQuantity == 0.00 {
+		quoteQuantity = binanceOrder.Price * binanceOrder.ExecutedQty
+	}
+	parent.OrigQty += binanceOrder.OrigQty
+	parent.ExecutedQty += binanceOrder.ExecutedQty
+	parent.CummulativeQuoteQty += quoteQuantity
+}
+
+// saveParentOrder saves fills of child orders, so executed quantity is not lost if bot is stopped during execution
+func (m *OrderExecutor) saveParentOrder(order ExchangeModel.Order, parent ExchangeModel.BinanceOrder, parentId *int64, childIds []int64) *int64 {
+	externalId := parent.OrderId
+	order.ExternalId = &externalId
+	order.ExecutedQuantity = parent.ExecutedQty
+	order.Price = parent.CummulativeQuoteQty / parent.ExecutedQty
+
+	if parentId != nil {
+		order.Id = *parentId
+		err := m.OrderRepository.Update(order)
+		if err != nil {
+			log.Printf("[%s] Parent order [%d] is not updated: %s", order.Symbol, *parentId, err.Error())
+		}
+
+		return parentId
+	}
+
+	id, err := m.OrderRepository.Create(order)
+	if err != nil {
+		log.Printf("[%s] Parent order is not saved: %s", order.Symbol, err.Error())
+
+		return nil
+	}
+	m.attachChildOrders(childIds, *id)
+
+	return id
+}
+
+// getChildPrice re-prices the next child order by market, price of parent order is a limit:
+// BUY child order is not placed above it and SELL child order is not placed below it
+func (m *OrderExecutor) getChildPrice(tradeLimit ExchangeModel.TradeLimit, order ExchangeModel.Order, operation string) float64 {
+	if operation == "BUY" {
+		price, err := m.PriceCalculator.CalculateBuy(tradeLimit)
+		if err != nil || price <= 0.00 {
+			return order.Price
+		}
+
+		return m.Formatter.FormatPrice(tradeLimit, math.Min(price, order.Price))
+	}
+
+	if order.ClosesOrder == nil {
+		return order.Price
+	}
+
+	opened, err := m.OrderRepository.Find(*order.ClosesOrder)
+	if err != nil {
+		return order.Price
+	}
+
+	return m.Formatter.FormatPrice(tradeLimit, math.Max(m.PriceCalculator.CalculateSell(tradeLimit, opened), order.Price))
+}
+
+// getChildQuantities splits order by execution algorithm, every child order passes exchange filters
+func (m *OrderExecutor) getChildQuantities(tradeLimit ExchangeModel.TradeLimit, order ExchangeModel.Order) []float64 {
+	options := tradeLimit.ExecutionOptions
+	count := int64(0)
+
+	switch options.Algorithm {
+	case ExchangeModel.ExecutionAlgorithmTwap:
+		count = options.Slices
+	case ExchangeModel.ExecutionAlgorithmIceberg:
+		if options.ClipUsdt > 0 {
+			count = int64(math.Ceil(order.Quantity * order.Price / options.ClipUsdt))
+		}
+	}
+
+	minQuantity := tradeLimit.MinQuantity
+	if order.Price > 0 {
+		minQuantity = math.Max(minQuantity, tradeLimit.MinNotional/order.Price)
+	}
+	if minQuantity > 0 {
+		count = min(count, int64(order.Quantity/minQuantity))
+	}
+
+	if count < 2 {
+		return []float64{order.Quantity}
+	}
+
+	quantity := m.Formatter.FormatQuantity(tradeLimit, order.Quantity/float64(count))
+	quantities := make([]float64, 0)
+	rest := order.Quantity
+
+	for i := int64(1); i < count; i++ {
+		quantities = append(quantities, quantity)
+		rest -= quantity
+	}
+
+	return append(quantities, m.Formatter.FormatQuantity(tradeLimit, m.Formatter.ToFixed(rest, 8)))
+}
+
+func (m *OrderExecutor) saveChildOrder(algorithm string, binanceOrder ExchangeModel.BinanceOrder, orderId *int64) *int64 {
+	if m.ChildOrderRepository == nil {
+		return nil
+	}
+
+	id, err := m.ChildOrderRepository.CreateChildOrder(ExchangeModel.ChildOrder{
+		OrderId:          orderId,
+		Symbol:           binanceOrder.Symbol,
+		ExternalId:       binanceOrder.OrderId,
+		Operation:        strings.ToLower(binanceOrder.Side),
+		Algorithm:        algorithm,
+		Price:            binanceOrder.Price,
+		Quantity:         binanceOrder.OrigQty,
+		ExecutedQuantity: binanceOrder.ExecutedQty,
+		Status:           binanceOrder.Status,
+	})
+
+	if err != nil {
+		log.Printf("[%s] Child order %d is not saved: %s", binanceOrder.Symbol, binanceOrder.OrderId, err.Error())
+
+		return nil
+	}
+
+	return id
+}
+
+func (m *OrderExecutor) attachChildOrders(childIds []int64, orderId int64) {
+	if m.ChildOrderRepository == nil || len(childIds) == 0 {
+		return
+	}
+
+	err := m.ChildOrderRepository.SetParentOrder(childIds, orderId)
+	if err != nil {
+		log.Printf("Child orders are not attached to order [%d]: %s", orderId, err.Error())
+	}
+}
+
+// waitExecution manages placed order until it is filled or cancelled, sliceEnd order is cancelled unconditionally when ttl (seconds) is reached
+func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, seconds int64, orderId *int64, sliceEnd bool) (ExchangeModel.BinanceOrder, error) {
+	defer func(start time.Time) {
+		metrics.OrderExecutionSeconds.WithLabelValues(
+			strings.ToLower(binanceOrder.Side),
+			strings.ToLower(binanceOrder.Status),
+		).Observe(time.Since(start).Seconds())
+	}(time.Now())
+
+	// parked order stays in cache and will be recovered after restart
+	parked := false
+	defer func(binanceOrder ExchangeModel.BinanceOrder) {
+		if !parked {
+			m.OrderRepository.DeleteBinanceOrder(binanceOrder)
+		}
+	}(binanceOrder)
 
 	if binanceOrder.IsFilled() {
 		return binanceOrder, nil
@@ -506,6 +981,11 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 
 	orderManageChannel := make(chan string)
 	control := make(chan string)
+	// written by handler before "cancel" signal is sent
+	cancelReason := ""
+	// written by handler before "amend" signal is sent
+	amendPrice := 0.00
+	var currentPrice *float64
 	defer close(orderManageChannel)
 	defer close(control)
 
@@ -537,8 +1017,28 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 				continue
 			}
 
+			if m.ShutdownService.IsShuttingDown() && binanceOrder.IsNew() {
+				orderManageChannel <- "status"
+				action := <-control
+				if action == "stop" {
+					return
+				}
+
+				if binanceOrder.IsNew() {
+					log.Printf("[%s] Shutdown, park signal sent!", binanceOrder.Symbol)
+					orderManageChannel <- "park"
+					action := <-control
+					if action == "stop" {
+						return
+					}
+				}
+			}
+
 			end := m.TimeService.GetNowUnix()
 			kline := m.ExchangeRepository.GetLastKLine(tradeLimit.Symbol)
+			if kline != nil {
+				currentPrice = &kline.Close
+			}
 
 			if kline != nil && binanceOrder.IsSell() && binanceOrder.IsNew() && m.SwapEnabled {
 				openedBuyPosition, err := m.OrderRepository.GetOpenedOrderCached(binanceOrder.Symbol, "BUY")
@@ -580,6 +1080,7 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 
 								if binanceOrder.IsNew() {
 									log.Printf("[%s] Cancel signal sent!", binanceOrder.Symbol)
+									cancelReason = ExchangeModel.OrderEventReasonSwap
 									orderManageChannel <- "cancel"
 									action := <-control
 									if action == "stop" {
@@ -603,8 +1104,16 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 				log.Printf("[%s] (LossSecurity) Order status is [%s]", binanceOrder.Symbol, binanceOrder.Status)
 
 				if binanceOrder.IsNew() {
-					log.Printf("[%s] (LossSecurity) Cancel signal sent!", binanceOrder.Symbol)
-					orderManageChannel <- "cancel"
+					cancelReason = ExchangeModel.OrderEventReasonLossSecurity
+					signal := "cancel"
+					// order is moved down to corrected price, it is cancelled if amendment is not possible or failed
+					correctedPrice := m.Formatter.FormatPrice(tradeLimit, m.LossSecurity.BuyPriceCorrection(binanceOrder.Price, tradeLimit))
+					if correctedPrice < binanceOrder.Price && m.canAmend(*binanceOrder, correctedPrice) {
+						amendPrice = correctedPrice
+						signal = "amend"
+					}
+					log.Printf("[%s] (LossSecurity) %s signal sent!", binanceOrder.Symbol, signal)
+					orderManageChannel <- signal
 					action := <-control
 					if action == "stop" {
 						m.OrderRepository.LockBuy(binanceOrder.Symbol, 10)
@@ -619,6 +1128,7 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 					"[%s] Cancel request received from user",
 					binanceOrder.Symbol,
 				)
+				cancelReason = ExchangeModel.OrderEventReasonUserRequest
 				orderManageChannel <- "cancel"
 				action := <-control
 				if action == "stop" {
@@ -635,6 +1145,7 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 					binanceOrder.Side,
 					binanceOrder.OrderId,
 				)
+				cancelReason = ExchangeModel.OrderEventReasonMaxProfit
 				orderManageChannel <- "cancel"
 				action := <-control
 				if action == "stop" {
@@ -651,6 +1162,7 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 						binanceOrder.Symbol,
 						openedBuyPosition.GetProfitPercent(kline.Close).Value(),
 					)
+					cancelReason = ExchangeModel.OrderEventReasonExtraCharge
 					orderManageChannel <- "cancel"
 					action := <-control
 					if action == "stop" {
@@ -681,8 +1193,27 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 				timer = 0
 				m.TimeService.WaitSeconds(1)
 
+				// slice of TWAP is ended, child order is cancelled even if it is partially filled
+				if sliceEnd && end >= (start+*ttl) && (binanceOrder.IsNew() || binanceOrder.IsPartiallyFilled()) {
+					log.Printf(
+						"[%s] %s Order [%d] status [%s] slice is ended, order is cancelled, ExecutedQty: %.6f of %.6f",
+						binanceOrder.Symbol,
+						binanceOrder.Side,
+						binanceOrder.OrderId,
+						binanceOrder.Status,
+						binanceOrder.ExecutedQty,
+						binanceOrder.OrigQty,
+					)
+					cancelReason = ExchangeModel.OrderEventReasonSliceEnd
+					orderManageChannel <- "cancel"
+					action := <-control
+					if action == "stop" {
+						return
+					}
+				}
+
 				// check only new timeout
-				if end >= (start+*ttl) && binanceOrder.IsNew() {
+				if !sliceEnd && end >= (start+*ttl) && binanceOrder.IsNew() {
 					if kline != nil && binanceOrder.IsSell() {
 						openedBuyPosition, err := m.OrderRepository.GetOpenedOrderCached(binanceOrder.Symbol, "BUY")
 						if err == nil {
@@ -699,11 +1230,14 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 									openedBuyPosition.Price,
 									profitPercent.Value(),
 								)
-								orderManageChannel <- "cancel"
+								cancelReason = ExchangeModel.OrderEventReasonTtl
+								amendPrice = m.Formatter.FormatPrice(tradeLimit, m.PriceCalculator.CalculateSell(tradeLimit, openedBuyPosition))
+								orderManageChannel <- m.getRepriceSignal(*binanceOrder, amendPrice)
 								action := <-control
 								if action == "stop" {
 									return
 								}
+								start = m.TimeService.GetNowUnix()
 							} else {
 								log.Printf(
 									"[%s] %s Order [%d] status [%s] ttl ignored, current price is [%.8f], order price [%.8f], open [%.8f], profit: %.2f",
@@ -725,6 +1259,7 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 								binanceOrder.OrderId,
 								err.Error(),
 							)
+							cancelReason = ExchangeModel.OrderEventReasonTtl
 							orderManageChannel <- "cancel"
 							action := <-control
 							if action == "stop" {
@@ -746,11 +1281,18 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 								binanceOrder.Price,
 								positionPercentage.Value(),
 							)
-							orderManageChannel <- "cancel"
+							cancelReason = ExchangeModel.OrderEventReasonTtl
+							amendPrice = 0.00
+							buyPrice, err := m.PriceCalculator.CalculateBuy(tradeLimit)
+							if err == nil {
+								amendPrice = m.Formatter.FormatPrice(tradeLimit, buyPrice)
+							}
+							orderManageChannel <- m.getRepriceSignal(*binanceOrder, amendPrice)
 							action := <-control
 							if action == "stop" {
 								return
 							}
+							start = m.TimeService.GetNowUnix()
 						} else {
 							log.Printf(
 								"[%s] %s Order [%d] status [%s] ttl ignored, current price is [%.8f], order price [%.8f], diff percent: %.2f",
@@ -767,9 +1309,15 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 				}
 			} else {
 				manualOrder := m.OrderRepository.GetManualOrder(binanceOrder.Symbol)
-				// cancel current immediately on new manual order
-				if manualOrder != nil && manualOrder.Price != binanceOrder.Price {
-					orderManageChannel <- "cancel"
+				// move current to manual price immediately, cancel it if amendment is not possible or failed
+				if manualOrder != nil && m.Formatter.FormatPrice(tradeLimit, manualOrder.Price) != binanceOrder.Price {
+					cancelReason = ExchangeModel.OrderEventReasonManualPrice
+					signal := "cancel"
+					if m.canAmend(*binanceOrder, m.Formatter.FormatPrice(tradeLimit, manualOrder.Price)) {
+						amendPrice = m.Formatter.FormatPrice(tradeLimit, manualOrder.Price)
+						signal = "amend"
+					}
+					orderManageChannel <- signal
 					action := <-control
 					if action == "stop" {
 						return
@@ -795,6 +1343,25 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 			continue
 		}
 
+		if action == "amend" {
+			amended, err := m.amendOrder(tradeLimit, binanceOrder, amendPrice, orderId, cancelReason)
+			if err == nil {
+				binanceOrder = amended
+				control <- "continue"
+				continue
+			}
+
+			// state of both orders is unknown, cancel flow queries the current one
+			log.Printf(
+				"[%s] %s Order %d amendment failed, cancel it: %s",
+				binanceOrder.Symbol,
+				binanceOrder.Side,
+				binanceOrder.OrderId,
+				err.Error(),
+			)
+			break
+		}
+
 		if action == "cancel" {
 			log.Printf(
 				"[%s] %s Order %d, cancel signal has received",
@@ -805,6 +1372,20 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 			break
 		}
 
+		if action == "park" {
+			log.Printf(
+				"[%s] %s Order %d is parked until restart",
+				binanceOrder.Symbol,
+				binanceOrder.Side,
+				binanceOrder.OrderId,
+			)
+			parked = true
+			m.OrderRepository.SetBinanceOrder(binanceOrder)
+			control <- "stop"
+
+			return binanceOrder, errors.New(fmt.Sprintf("Order %d is parked, shutdown in progress", binanceOrder.OrderId))
+		}
+
 		queryOrder, err := m.Binance.QueryOrder(binanceOrder.Symbol, binanceOrder.OrderId)
 
 		if err != nil {
@@ -812,6 +1393,7 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 
 			if strings.Contains(err.Error(), "Order was canceled or expired") {
 				control <- "stop"
+				m.recordCancelEvent(binanceOrder, orderId, ExchangeModel.OrderEventReasonExchange, currentPrice)
 				return binanceOrder, err
 			}
 
@@ -831,9 +1413,14 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 		m.OrderRepository.SetBinanceOrder(binanceOrder)
 
 		if binanceOrder.IsPartiallyFilled() {
-			// Add 5 minutes more if ExecutedQty moves up!
+			// Add 5 minutes more if ExecutedQty moves up (slice of TWAP is not extended)!
 			if binanceOrder.GetExecutedQuantity() > executedQty {
-				seconds = seconds + (60 * 5)
+				if !sliceEnd {
+					seconds = seconds + (60 * 5)
+				}
+				partialFillEvent := binanceOrder.GetOrderEvent(ExchangeModel.OrderEventPartialFill, orderId)
+				partialFillEvent.CurrentPrice = currentPrice
+				m.OrderEventRecorder.Record(partialFillEvent)
 			}
 
 			executedQty = binanceOrder.GetExecutedQuantity()
@@ -843,6 +1430,7 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 		}
 
 		if binanceOrder.IsExpired() {
+			m.recordCancelEvent(binanceOrder, orderId, ExchangeModel.OrderEventReasonExchange, currentPrice)
 			if binanceOrder.HasExecutedQuantity() {
 				control <- "stop"
 				return binanceOrder, nil
@@ -852,6 +1440,7 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 		}
 
 		if binanceOrder.IsCanceled() {
+			m.recordCancelEvent(binanceOrder, orderId, ExchangeModel.OrderEventReasonExchange, currentPrice)
 			if binanceOrder.HasExecutedQuantity() {
 				control <- "stop"
 				return binanceOrder, nil
@@ -865,6 +1454,9 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 			log.Printf("[%s] Order [%d] is executed [%s]", binanceOrder.Symbol, binanceOrder.OrderId, binanceOrder.Status)
 
 			control <- "stop"
+			filledEvent := binanceOrder.GetOrderEvent(ExchangeModel.OrderEventFilled, orderId)
+			filledEvent.CurrentPrice = currentPrice
+			m.OrderEventRecorder.Record(filledEvent)
 			return binanceOrder, nil
 		}
 
@@ -905,6 +1497,10 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 			log.Printf("[%s] Order [%d] is recovered [%s]", binanceOrder.Symbol, binanceOrder.OrderId, binanceOrder.Status)
 
 			if binanceOrder.IsFilled() {
+				filledEvent := binanceOrder.GetOrderEvent(ExchangeModel.OrderEventFilled, orderId)
+				filledEvent.CurrentPrice = currentPrice
+				m.OrderEventRecorder.Record(filledEvent)
+
 				return binanceOrder, nil
 			}
 
@@ -917,7 +1513,7 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 					binanceOrder.Status,
 				)
 
-				return m.waitExecution(binanceOrder, 120)
+				return m.waitExecution(binanceOrder, 120, orderId, sliceEnd)
 			}
 
 			// Just in case of bug...
@@ -929,9 +1525,11 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 					binanceOrder.Status,
 				)
 
-				return m.waitExecution(binanceOrder, 120)
+				return m.waitExecution(binanceOrder, 120, orderId, sliceEnd)
 			}
 
+			m.recordCancelEvent(binanceOrder, orderId, cancelReason, currentPrice)
+
 			if binanceOrder.HasExecutedQuantity() {
 				log.Printf(
 					"Order [%d] is [%s], ExecutedQty = %.8f",
@@ -953,6 +1551,11 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 
 	binanceOrder = cancelOrder
 	control <- "stop"
+	m.recordCancelEvent(binanceOrder, orderId, cancelReason, currentPrice)
+
+	if cancelReason == ExchangeModel.OrderEventReasonSliceEnd {
+		return binanceOrder, errors.New(fmt.Sprintf("Order %d is cancelled at the end of slice", binanceOrder.OrderId))
+	}
 
 	// handle cancel error and get again
 
@@ -972,6 +1575,73 @@ func (m *OrderExecutor) waitExecution(binanceOrder ExchangeModel.BinanceOrder, s
 	return binanceOrder, errors.New(fmt.Sprintf("Order %d was CANCELED", binanceOrder.OrderId))
 }
 
+// canAmend allows to move only not executed order, executed part would be lost from the position otherwise
+func (m *OrderExecutor) canAmend(binanceOrder ExchangeModel.BinanceOrder, price float64) bool {
+	return binanceOrder.IsNew() && binanceOrder.ExecutedQty == 0.00 && price > 0.00 && price != binanceOrder.Price
+}
+
+// getRepriceSignal moves order to new price, order is kept if price is not changed and cancelled if price is not calculated
+func (m *OrderExecutor) getRepriceSignal(binanceOrder ExchangeModel.BinanceOrder, price float64) string {
+	if price > 0.00 && price == binanceOrder.Price {
+		return "continue"
+	}
+
+	if m.canAmend(binanceOrder, price) {
+		return "amend"
+	}
+
+	return "cancel"
+}
+
+// amendOrder moves resting order to new price by one cancel-replace request, so there is no time without order
+func (m *OrderExecutor) amendOrder(tradeLimit ExchangeModel.TradeLimit, binanceOrder ExchangeModel.BinanceOrder, price float64, orderId *int64, reason string) (ExchangeModel.BinanceOrder, error) {
+	amended, err := m.Binance.CancelReplaceOrder(
+		binanceOrder.Symbol,
+		binanceOrder.OrderId,
+		binanceOrder.OrigQty,
+		price,
+		binanceOrder.Side,
+		tradeLimit.GetOrderType(),
+	)
+
+	if err != nil {
+		return binanceOrder, err
+	}
+
+	log.Printf(
+		"[%s] %s Order %d is replaced by %d, price %.6f -> %.6f",
+		binanceOrder.Symbol,
+		binanceOrder.Side,
+		binanceOrder.OrderId,
+		amended.OrderId,
+		binanceOrder.Price,
+		amended.Price,
+	)
+	m.OrderRepository.SetBinanceOrder(amended)
+	if amended.IsBuy() {
+		m.BalanceService.InvalidateBalanceCache("USDT")
+	}
+
+	event := amended.GetOrderEvent(ExchangeModel.OrderEventAmended, orderId)
+	details := fmt.Sprintf("order %d price %.8f is replaced", binanceOrder.OrderId, binanceOrder.Price)
+	event.Details = &details
+	if reason != "" {
+		event.Reason = &reason
+	}
+	m.OrderEventRecorder.Record(event)
+
+	return amended, nil
+}
+
+func (m *OrderExecutor) recordCancelEvent(binanceOrder ExchangeModel.BinanceOrder, orderId *int64, reason string, currentPrice *float64) {
+	event := binanceOrder.GetOrderEvent(ExchangeModel.OrderEventCancelled, orderId)
+	event.CurrentPrice = currentPrice
+	if reason != "" {
+		event.Reason = &reason
+	}
+	m.OrderEventRecorder.Record(event)
+}
+
 func (m *OrderExecutor) CalculateSellQuantity(order ExchangeModel.Order) float64 {
 	binanceOrder := m.OrderRepository.GetBinanceOrder(order.Symbol, "SELL")
 
@@ -995,6 +1665,88 @@ func (m *OrderExecutor) CalculateSellQuantity(order ExchangeModel.Order) float64
 	return balance
 }
 
+// CalculateTakeProfitSell applies take-profit ladder of trade limit to sell price and quantity:
+// price is raised to target of the current tier and quantity is limited by the tier remaining quantity.
+// Quantity which is not covered by tiers is sold by trailing tier (quantity is 0 until its stop is reached)
+// or by regular price if there is no trailing tier.
+func (m *OrderExecutor) CalculateTakeProfitSell(tradeLimit ExchangeModel.TradeLimit, order ExchangeModel.Order, price float64) (float64, float64) {
+	quantity := m.CalculateSellQuantity(order)
+
+	// sell order is already placed, it should be finished as is
+	if len(tradeLimit.TakeProfitOptions) == 0 || m.OrderRepository.GetBinanceOrder(order.Symbol, "SELL") != nil {
+		return price, quantity
+	}
+
+	tier, tierQuantity, ok := order.GetTakeProfitTier(tradeLimit.TakeProfitOptions, tradeLimit.MinQuantity)
+	if !ok {
+		trailing, hasTrailing := tradeLimit.TakeProfitOptions.GetTrailing()
+		if hasTrailing {
+			return m.calculateTrailingSell(tradeLimit, order, trailing, price, quantity)
+		}
+
+		return price, quantity
+	}
+
+	price = math.Max(price, m.Formatter.FormatPrice(tradeLimit, order.GetTakeProfitPrice(tier)))
+
+	// the rest of position is sold with the tier if it can't be sold by a separate order
+	rest := quantity - tierQuantity
+	if rest >= tradeLimit.MinQuantity && rest*price >= tradeLimit.MinNotional {
+		quantity = tierQuantity
+	}
+
+	log.Printf("[%s] Take profit tier %d: %.2f%% at %f", order.Symbol, tier.Index, tier.Percent.Value(), price)
+
+	return price, quantity
+}
+
+// calculateTrailingSell follows the highest price after target of trailing tier is reached,
+// the rest of position is sold when price falls by TrailingPercent from the highest price
+func (m *OrderExecutor) calculateTrailingSell(tradeLimit ExchangeModel.TradeLimit, order ExchangeModel.Order, trailing ExchangeModel.TakeProfitOption, price float64, quantity float64) (float64, float64) {
+	kline := m.ExchangeRepository.GetLastKLine(order.Symbol)
+	if kline == nil {
+		return price, 0.00
+	}
+
+	high := m.OrderRepository.GetTakeProfitHighPrice(order)
+	if high == 0.00 && kline.Close < order.GetTakeProfitPrice(trailing) {
+		return price, 0.00
+	}
+
+	if kline.Close > high {
+		high = kline.Close
+		m.OrderRepository.SetTakeProfitHighPrice(order, high)
+	}
+
+	stop := m.Formatter.FormatPrice(tradeLimit, high*(100-trailing.TrailingPercent.Value())/100)
+	if kline.Close > stop {
+		log.Printf("[%s] Take profit trailing tier %d: high %f, stop %f, current %f", order.Symbol, trailing.Index, high, stop, kline.Close)
+
+		return price, 0.00
+	}
+
+	// market is already below the stop, exit is priced at the best bid (or close) to be filled,
+	// but not lower than min close price of position
+	exitPrice := kline.Close
+	depth := m.PriceCalculator.GetDepth(order.Symbol)
+	if bestBid := depth.GetBestBid(); bestBid > 0.00 {
+		exitPrice = bestBid
+	}
+	exitPrice = math.Max(m.Formatter.FormatPrice(tradeLimit, exitPrice), m.Formatter.FormatPrice(tradeLimit, order.GetMinClosePrice(tradeLimit)))
+
+	log.Printf("[%s] Take profit trailing tier %d: stop %f is reached, high %f, exit at %f", order.Symbol, trailing.Index, stop, high, exitPrice)
+
+	return exitPrice, quantity
+}
+
+// IsTakeProfitTrailing is true when the rest of position waits for stop price of trailing tier
+func (m *OrderExecutor) IsTakeProfitTrailing(tradeLimit ExchangeModel.TradeLimit, order ExchangeModel.Order) bool {
+	_, hasTrailing := tradeLimit.TakeProfitOptions.GetTrailing()
+	_, _, hasTier := order.GetTakeProfitTier(tradeLimit.TakeProfitOptions, tradeLimit.MinQuantity)
+
+	return hasTrailing && !hasTier
+}
+
 func (m *OrderExecutor) MakeSwap(order ExchangeModel.Order, swapChain ExchangeModel.SwapChainEntity) {
 	assetBalance, err := m.BalanceService.GetAssetBalance(swapChain.SwapOne.BaseAsset, false)
 
@@ -1002,6 +1754,9 @@ func (m *OrderExecutor) MakeSwap(order ExchangeModel.Order, swapChain ExchangeMo
 		return
 	}
 
+	// balance can hold coins bought by DCA plan or user, only position quantity is swapped
+	startQuantity := min(assetBalance, order.GetRemainingToSellQuantity())
+
 	swapAction, err := m.SwapRepository.GetActiveSwapAction(order)
 
 	if err == nil {
@@ -1020,7 +1775,7 @@ func (m *OrderExecutor) MakeSwap(order ExchangeModel.Order, swapChain ExchangeMo
 		Asset:           swapChain.SwapOne.BaseAsset,
 		Status:          ExchangeModel.SwapActionStatusPending,
 		StartTimestamp:  m.TimeService.GetNowUnix(),
-		StartQuantity:   assetBalance,
+		StartQuantity:   startQuantity,
 		SwapOneSymbol:   swapChain.SwapOne.GetSymbol(),
 		SwapOnePrice:    swapChain.SwapOne.Price,
 		SwapTwoSymbol:   swapChain.SwapTwo.GetSymbol(),
@@ -1045,15 +1800,28 @@ func (m *OrderExecutor) MakeSwap(order ExchangeModel.Order, swapChain ExchangeMo
 	if err == nil {
 		log.Printf("[%s] Swap order mode enabled [%s]", order.Symbol, swapChain.Title)
 	}
+
+	swapDetails := fmt.Sprintf("Swap chain [%s] %.2f%%, start quantity %f %s", swapChain.Title, swapChain.Percent.Value(), startQuantity, swapChain.SwapOne.BaseAsset)
+	m.OrderEventRecorder.Record(ExchangeModel.OrderEvent{
+		OrderId:     &order.Id,
+		Symbol:      order.Symbol,
+		ExternalId:  order.ExternalId,
+		Operation:   strings.ToUpper(order.Operation),
+		Type:        ExchangeModel.OrderEventSwapStarted,
+		Price:       order.Price,
+		Quantity:    order.ExecutedQuantity,
+		ExecutedQty: order.ExecutedQuantity,
+		Details:     &swapDetails,
+	})
 }
 
-func (m *OrderExecutor) UpdateCommission(balanceBefore float64, order ExchangeModel.Order) {
+func (m *OrderExecutor) UpdateCommission(balanceBefore float64, order ExchangeModel.Order) ExchangeModel.Order {
 	assetSymbol := order.GetBaseAsset()
 	balanceAfter, err := m.BalanceService.GetAssetBalance(assetSymbol, true)
 
 	if err != nil {
 		log.Printf("[%s] Can't update commission: %s", order.Status, err.Error())
-		return
+		return order
 	}
 
 	arrived := balanceAfter - balanceBefore
@@ -1071,9 +1839,17 @@ func (m *OrderExecutor) UpdateCommission(balanceBefore float64, order ExchangeMo
 	if err != nil {
 		log.Printf("[%s] Order Commission Update: %s", order.Symbol, err.Error())
 	}
+
+	return order
+}
+
+func (m *OrderExecutor) recordLot(order ExchangeModel.Order, entryType string, positionId *int64) {
+	if m.LotLedger != nil {
+		m.LotLedger.RecordOrder(order, entryType, positionId)
+	}
 }
 
-func (m *OrderExecutor) isTradeLocked(symbol string) bool {
+func (m *OrderExecutor) IsTradeLocked(symbol string) bool {
 	m.TradeLockMutex.Lock()
 	isLocked, _ := m.Lock[symbol]
 	m.TradeLockMutex.Unlock()
@@ -1081,12 +1857,24 @@ func (m *OrderExecutor) isTradeLocked(symbol string) bool {
 	return isLocked
 }
 
-func (m *OrderExecutor) acquireLock(symbol string) {
-	*m.LockChannel <- ExchangeModel.Lock{IsLocked: true, Symbol: symbol}
+// TryLock locks symbol for trade operation or reconciliation, symbol which is already locked is not locked again
+func (m *OrderExecutor) TryLock(symbol string) bool {
+	m.TradeLockMutex.Lock()
+	defer m.TradeLockMutex.Unlock()
+
+	if m.Lock[symbol] {
+		return false
+	}
+
+	m.Lock[symbol] = true
+
+	return true
 }
 
-func (m *OrderExecutor) releaseLock(symbol string) {
-	*m.LockChannel <- ExchangeModel.Lock{IsLocked: false, Symbol: symbol}
+func (m *OrderExecutor) Unlock(symbol string) {
+	m.TradeLockMutex.Lock()
+	m.Lock[symbol] = false
+	m.TradeLockMutex.Unlock()
 }
 
 func (m *OrderExecutor) findBinanceOrder(symbol string, operation string, cachedOnly bool) (*ExchangeModel.BinanceOrder, error) {
@@ -1121,7 +1909,7 @@ func (m *OrderExecutor) findBinanceOrder(symbol string, operation string, cached
 	return nil, errors.New(fmt.Sprintf("[%s] Binance order is not found", symbol))
 }
 
-func (m *OrderExecutor) findOrCreateOrder(order ExchangeModel.Order, operation string) (ExchangeModel.BinanceOrder, error) {
+func (m *OrderExecutor) findOrCreateOrder(tradeLimit ExchangeModel.TradeLimit, order ExchangeModel.Order, operation string) (ExchangeModel.BinanceOrder, error) {
 	// todo: extra order flag...
 	cached, err := m.findBinanceOrder(order.Symbol, operation, false)
 
@@ -1131,7 +1919,11 @@ func (m *OrderExecutor) findOrCreateOrder(order ExchangeModel.Order, operation s
 		return *cached, nil
 	}
 
-	binanceOrder, err := m.Binance.LimitOrder(order.Symbol, order.Quantity, order.Price, operation, "GTC")
+	if m.ShutdownService.IsShuttingDown() {
+		return ExchangeModel.BinanceOrder{}, errors.New(fmt.Sprintf("[%s] New %s order is rejected, shutdown in progress", order.Symbol, operation))
+	}
+
+	binanceOrder, err := m.placeLimitOrder(tradeLimit, order.Symbol, order.Quantity, order.Price, operation)
 
 	if err != nil {
 		log.Printf("[%s] Limit: %s", order.Symbol, err.Error())
@@ -1140,6 +1932,12 @@ func (m *OrderExecutor) findOrCreateOrder(order ExchangeModel.Order, operation s
 
 	log.Printf("[%s] %s Order created %d, Price: %.6f", order.Symbol, operation, binanceOrder.OrderId, binanceOrder.Price)
 	m.OrderRepository.SetBinanceOrder(binanceOrder)
+
+	placedEventType := ExchangeModel.OrderEventBuyPlaced
+	if !order.IsBuy() {
+		placedEventType = ExchangeModel.OrderEventSellPlaced
+	}
+	m.OrderEventRecorder.Record(binanceOrder.GetOrderEvent(placedEventType, order.ClosesOrder))
 	if order.IsBuy() {
 		m.BalanceService.InvalidateBalanceCache("USDT")
 	} else {
@@ -1149,6 +1947,53 @@ func (m *OrderExecutor) findOrCreateOrder(order ExchangeModel.Order, operation s
 	return binanceOrder, nil
 }
 
+// placeLimitOrder places GTC limit order or post-only LIMIT_MAKER order by trade limit order type,
+// maker order which would match immediately is re-priced to the best price of own side of order book
+func (m *OrderExecutor) placeLimitOrder(tradeLimit ExchangeModel.TradeLimit, symbol string, quantity float64, price float64, operation string) (ExchangeModel.BinanceOrder, error) {
+	if tradeLimit.GetOrderType() != ExchangeModel.OrderTypeLimitMaker {
+		return m.Binance.LimitOrder(symbol, quantity, price, operation, ExchangeModel.TimeInForceGtc)
+	}
+
+	var binanceOrder ExchangeModel.BinanceOrder
+	var err error
+
+	for attempt := 1; attempt <= 3; attempt++ {
+		binanceOrder, err = m.Binance.LimitMakerOrder(symbol, quantity, price, operation)
+
+		if err == nil || err.Error() != ExchangeModel.BinanceErrorOrderWouldMatch {
+			return binanceOrder, err
+		}
+
+		makerPrice := m.getMakerPrice(tradeLimit, price, operation)
+		if makerPrice == price {
+			break
+		}
+
+		log.Printf("[%s] %s maker order would match at %f, re-priced to %f", symbol, operation, price, makerPrice)
+		price = makerPrice
+	}
+
+	return binanceOrder, err
+}
+
+func (m *OrderExecutor) getMakerPrice(tradeLimit ExchangeModel.TradeLimit, price float64, operation string) float64 {
+	depth := m.PriceCalculator.GetDepth(tradeLimit.Symbol)
+
+	if operation == "BUY" {
+		asks := depth.GetAsks()
+		if len(asks) > 0 && price >= asks[0][0].Value {
+			return m.Formatter.FormatPrice(tradeLimit, asks[0][0].Value-tradeLimit.MinPrice)
+		}
+	} else {
+		bids := depth.GetBids()
+		if len(bids) > 0 && price <= bids[0][0].Value {
+			return m.Formatter.FormatPrice(tradeLimit, bids[0][0].Value+tradeLimit.MinPrice)
+		}
+	}
+
+	return price
+}
+
 func (m *OrderExecutor) getAvgPrice(opened ExchangeModel.Order, extra ExchangeModel.Order) float64 {
 	return ((opened.ExecutedQuantity * opened.Price) + (extra.ExecutedQuantity * extra.Price)) / (opened.ExecutedQuantity + extra.ExecutedQuantity)
 }
@@ -1184,17 +2029,21 @@ func (m *OrderExecutor) CheckBalance(symbol string, priceUsdt float64, quantity
 
 	// Check balance for new order
 	if cached == nil {
-		usdtAvailableBalance, err := m.BalanceService.GetAssetBalance("USDT", true)
+		return m.checkUsdtBalance(symbol, priceUsdt*quantity)
+	}
 
-		if err != nil {
-			return errors.New(fmt.Sprintf("[%s] BUY balance error: %s", symbol, err.Error()))
-		}
+	return nil
+}
 
-		requiredUsdtAmount := priceUsdt * quantity
+func (m *OrderExecutor) checkUsdtBalance(symbol string, requiredUsdtAmount float64) error {
+	usdtAvailableBalance, err := m.BalanceService.GetAssetBalance("USDT", true)
 
-		if requiredUsdtAmount > usdtAvailableBalance {
-			return errors.New(fmt.Sprintf("[%s] BUY not enough balance: %f/%f", symbol, usdtAvailableBalance, requiredUsdtAmount))
-		}
+	if err != nil {
+		return errors.New(fmt.Sprintf("[%s] BUY balance error: %s", symbol, err.Error()))
+	}
+
+	if requiredUsdtAmount > usdtAvailableBalance {
+		return errors.New(fmt.Sprintf("[%s] BUY not enough balance: %f/%f", symbol, usdtAvailableBalance, requiredUsdtAmount))
 	}
 
 	return nil
@@ -0,0 +1,449 @@
+package service
+
+import (
+	"fmt"
+	ExchangeClient "gitlab.com/open-soft/go-crypto-bot/src/client"
+	ExchangeModel "gitlab.com/open-soft/go-crypto-bot/src/model"
+	ExchangeRepository "gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"log"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+)
+
+type OrderReconciler struct {
+	Binance              ExchangeClient.ExchangeOrderHistoryAPIInterface
+	OrderRepository      ExchangeRepository.OrderReconcileStorageInterface
+	ExchangeRepository   ExchangeRepository.TradeLimitReaderInterface
+	BalanceService       BalanceServiceInterface
+	CallbackManager      CallbackManagerInterface
+	OrderEventRecorder   OrderEventRecorderInterface
+	LotLedger            LotLedgerInterface
+	ChildOrderRepository ExchangeRepository.ChildOrderStorageInterface
+	TradeLock            TradeLockInterface
+	TimeService          TimeServiceInterface
+	ShutdownService      ShutdownServiceInterface
+	CurrentBot           *ExchangeModel.Bot
+	LastReport           *ExchangeModel.ReconciliationReport
+	ReportMutex          sync.RWMutex
+}
+
+func (r *OrderReconciler) GetLastReport() *ExchangeModel.ReconciliationReport {
+	r.ReportMutex.RLock()
+	defer r.ReportMutex.RUnlock()
+
+	return r.LastReport
+}
+
+func (r *OrderReconciler) Reconcile() ExchangeModel.ReconciliationReport {
+	report := ExchangeModel.ReconciliationReport{
+		StartedAt:     r.TimeService.GetNowDateTimeString(),
+		Skipped:       make([]string, 0),
+		Discrepancies: make([]ExchangeModel.ReconciliationDiscrepancy, 0),
+	}
+
+	if !r.ShutdownService.StartOperation() {
+		message := "Reconciliation is skipped, shutdown in progress"
+		report.Error = &message
+		report.FinishedAt = r.TimeService.GetNowDateTimeString()
+
+		return report
+	}
+	defer r.ShutdownService.FinishOperation()
+
+	for _, tradeLimit := range r.ExchangeRepository.GetTradeLimits() {
+		// grid orders are not positions, GridService tracks them by levels
+		if tradeLimit.IsGrid() {
+			continue
+		}
+
+		// order executor is processing symbol right now, check it next time
+		if !r.TradeLock.TryLock(tradeLimit.Symbol) {
+			report.Skipped = append(report.Skipped, tradeLimit.Symbol)
+			continue
+		}
+
+		// opened orders are loaded under lock, order executor can't place or fill order meanwhile
+		openedOrders, err := r.Binance.GetOpenedOrdersBySymbol(tradeLimit.Symbol)
+
+		if err != nil {
+			log.Printf("[%s] Reconciliation opened orders are not loaded: %s", tradeLimit.Symbol, err.Error())
+			report.Skipped = append(report.Skipped, tradeLimit.Symbol)
+			r.TradeLock.Unlock(tradeLimit.Symbol)
+			continue
+		}
+
+		report.Symbols++
+		report.Discrepancies = append(report.Discrepancies, r.reconcileSymbol(tradeLimit, openedOrders)...)
+		r.TradeLock.Unlock(tradeLimit.Symbol)
+	}
+
+	report.FinishedAt = r.TimeService.GetNowDateTimeString()
+	r.saveReport(report)
+
+	if len(report.Discrepancies) > 0 {
+		messages := make([]string, 0)
+		for _, discrepancy := range report.Discrepancies {
+			log.Printf("[%s] Reconciliation [%s]: %s", discrepancy.Symbol, discrepancy.Type, discrepancy.Message)
+			messages = append(messages, fmt.Sprintf("[%s] %s", discrepancy.Symbol, discrepancy.Message))
+		}
+
+		r.CallbackManager.Error(
+			*r.CurrentBot,
+			"reconciliation",
+			strings.Join(messages, "\n"),
+			false,
+		)
+	}
+
+	return report
+}
+
+func (r *OrderReconciler) saveReport(report ExchangeModel.ReconciliationReport) {
+	r.ReportMutex.Lock()
+	r.LastReport = &report
+	r.ReportMutex.Unlock()
+}
+
+func (r *OrderReconciler) reconcileSymbol(tradeLimit ExchangeModel.TradeLimit, openedOrders []ExchangeModel.BinanceOrder) []ExchangeModel.ReconciliationDiscrepancy {
+	discrepancies := make([]ExchangeModel.ReconciliationDiscrepancy, 0)
+	lockedQuantity := 0.00
+
+	for _, side := range []string{"BUY", "SELL"} {
+		cached := r.OrderRepository.GetBinanceOrder(tradeLimit.Symbol, side)
+		isCachedOpened := false
+
+		for _, exchangeOrder := range openedOrders {
+			if exchangeOrder.Side != side {
+				continue
+			}
+
+			if exchangeOrder.IsSell() {
+				lockedQuantity += exchangeOrder.OrigQty - exchangeOrder.ExecutedQty
+			}
+
+			if cached != nil && cached.OrderId == exchangeOrder.OrderId {
+				isCachedOpened = true
+				r.OrderRepository.SetBinanceOrder(exchangeOrder)
+				continue
+			}
+
+			// order is placed outside of bot (manually or by other bot), it is not adopted
+			discrepancies = append(discrepancies, ExchangeModel.ReconciliationDiscrepancy{
+				Type:       ExchangeModel.ReconciliationOrphanedOrder,
+				Symbol:     tradeLimit.Symbol,
+				ExternalId: &exchangeOrder.OrderId,
+				Expected:   exchangeOrder.OrigQty,
+				Actual:     exchangeOrder.ExecutedQty,
+				Fixed:      false,
+				Message:    fmt.Sprintf("%s order %d is opened on exchange, but is not tracked by bot, it is not adopted", side, exchangeOrder.OrderId),
+			})
+		}
+
+		if cached != nil && !isCachedOpened {
+			discrepancy := r.reconcileCachedOrder(tradeLimit, *cached)
+			if discrepancy != nil {
+				discrepancies = append(discrepancies, *discrepancy)
+			}
+		}
+	}
+
+	opened, err := r.OrderRepository.GetOpenedOrder(tradeLimit.Symbol, "BUY")
+
+	if err != nil {
+		return discrepancies
+	}
+
+	trades, err := r.Binance.GetTrades(opened)
+
+	if err != nil {
+		log.Printf("[%s] Reconciliation trades: %s", tradeLimit.Symbol, err.Error())
+		return discrepancies
+	}
+
+	// extra charge changes executed quantity of opened order, it can't be compared with trades
+	if opened.UsedExtraBudget == 0.00 {
+		discrepancy := r.reconcileExecutedQuantity(tradeLimit, opened, trades, ExchangeModel.ReconciliationExecutedQuantity)
+		if discrepancy != nil {
+			discrepancies = append(discrepancies, *discrepancy)
+		}
+	}
+
+	for _, closeOrder := range r.OrderRepository.GetClosesOrderList(opened) {
+		// extra charge BUY orders close position too, they are not sold quantity
+		if strings.ToUpper(closeOrder.Operation) != "SELL" {
+			continue
+		}
+
+		discrepancy := r.reconcileExecutedQuantity(tradeLimit, closeOrder, trades, ExchangeModel.ReconciliationSoldQuantity)
+		if discrepancy != nil {
+			discrepancies = append(discrepancies, *discrepancy)
+		}
+	}
+
+	// reload, sold quantity could be changed
+	opened, err = r.OrderRepository.GetOpenedOrder(tradeLimit.Symbol, "BUY")
+	if err != nil {
+		return discrepancies
+	}
+
+	balance, err := r.BalanceService.GetAssetBalance(opened.GetBaseAsset(), false)
+	if err != nil {
+		return discrepancies
+	}
+
+	expected := opened.GetRemainingToSellQuantity()
+	if opened.Commission != nil {
+		expected -= *opened.Commission
+	}
+
+	if (balance + lockedQuantity) < (expected - tradeLimit.MinQuantity) {
+		discrepancies = append(discrepancies, ExchangeModel.ReconciliationDiscrepancy{
+			Type:     ExchangeModel.ReconciliationBalance,
+			Symbol:   tradeLimit.Symbol,
+			OrderId:  &opened.Id,
+			Expected: expected,
+			Actual:   balance + lockedQuantity,
+			Fixed:    false,
+			Message:  fmt.Sprintf("%s balance %f is less than opened position quantity %f", opened.GetBaseAsset(), balance+lockedQuantity, expected),
+		})
+	}
+
+	return discrepancies
+}
+
+func (r *OrderReconciler) reconcileCachedOrder(tradeLimit ExchangeModel.TradeLimit, cached ExchangeModel.BinanceOrder) *ExchangeModel.ReconciliationDiscrepancy {
+	binanceOrder, err := r.Binance.QueryOrder(cached.Symbol, cached.OrderId)
+
+	if err != nil {
+		if !strings.Contains(err.Error(), "Order does not exist") {
+			log.Printf("[%s] Reconciliation query order: %s", cached.Symbol, err.Error())
+			return nil
+		}
+
+		r.OrderRepository.DeleteBinanceOrder(cached)
+
+		return &ExchangeModel.ReconciliationDiscrepancy{
+			Type:       ExchangeModel.ReconciliationStaleCachedOrder,
+			Symbol:     cached.Symbol,
+			ExternalId: &cached.OrderId,
+			Fixed:      true,
+			Message:    fmt.Sprintf("%s order %d does not exist on exchange, removed from cache", cached.Side, cached.OrderId),
+		}
+	}
+
+	// opened orders list could be outdated
+	if binanceOrder.IsNew() || binanceOrder.IsPartiallyFilled() {
+		r.OrderRepository.SetBinanceOrder(binanceOrder)
+		return nil
+	}
+
+	if !binanceOrder.HasExecutedQuantity() {
+		r.OrderRepository.DeleteBinanceOrder(binanceOrder)
+
+		return &ExchangeModel.ReconciliationDiscrepancy{
+			Type:       ExchangeModel.ReconciliationStaleCachedOrder,
+			Symbol:     binanceOrder.Symbol,
+			ExternalId: &binanceOrder.OrderId,
+			Fixed:      true,
+			Message:    fmt.Sprintf("%s order %d is %s, removed from cache", binanceOrder.Side, binanceOrder.OrderId, binanceOrder.Status),
+		}
+	}
+
+	existing, err := r.OrderRepository.FindByExternalId(binanceOrder.Symbol, binanceOrder.OrderId)
+
+	if err == nil {
+		r.OrderRepository.DeleteBinanceOrder(binanceOrder)
+
+		return &ExchangeModel.ReconciliationDiscrepancy{
+			Type:       ExchangeModel.ReconciliationStaleCachedOrder,
+			Symbol:     binanceOrder.Symbol,
+			OrderId:    &existing.Id,
+			ExternalId: &binanceOrder.OrderId,
+			Fixed:      true,
+			Message:    fmt.Sprintf("%s order %d is already saved, removed from cache", binanceOrder.Side, binanceOrder.OrderId),
+		}
+	}
+
+	return r.recordMissedFill(tradeLimit, binanceOrder)
+}
+
+func (r *OrderReconciler) recordMissedFill(tradeLimit ExchangeModel.TradeLimit, binanceOrder ExchangeModel.BinanceOrder) *ExchangeModel.ReconciliationDiscrepancy {
+	discrepancy := ExchangeModel.ReconciliationDiscrepancy{
+		Type:       ExchangeModel.ReconciliationMissedFill,
+		Symbol:     binanceOrder.Symbol,
+		ExternalId: &binanceOrder.OrderId,
+		Expected:   binanceOrder.GetExecutedQuantity(),
+		Actual:     0.00,
+		Fixed:      false,
+	}
+
+	opened, openedErr := r.OrderRepository.GetOpenedOrder(binanceOrder.Symbol, "BUY")
+
+	if binanceOrder.IsSell() && openedErr != nil {
+		discrepancy.Message = fmt.Sprintf("SELL order %d is %s, but there is no opened position", binanceOrder.OrderId, binanceOrder.Status)
+
+		return &discrepancy
+	}
+
+	createdAt := binanceOrder.GetFilledAt()
+	if createdAt == "" {
+		createdAt = r.TimeService.GetNowDateTimeString()
+	}
+
+	order := ExchangeModel.Order{
+		Symbol:             binanceOrder.Symbol,
+		Quantity:           binanceOrder.OrigQty,
+		ExecutedQuantity:   binanceOrder.GetExecutedQuantity(),
+		Price:              binanceOrder.Price,
+		CreatedAt:          createdAt,
+		Status:             "closed",
+		Operation:          strings.ToLower(binanceOrder.Side),
+		ExternalId:         &binanceOrder.OrderId,
+		ExtraChargeOptions: make(ExchangeModel.ExtraChargeOptions, 0),
+	}
+
+	if openedErr == nil {
+		order.ClosesOrder = &opened.Id
+	} else {
+		order.Status = "opened"
+		order.ExtraChargeOptions = tradeLimit.ExtraChargeOptions
+	}
+
+	lastId, err := r.OrderRepository.Create(order)
+
+	if err != nil {
+		discrepancy.Message = fmt.Sprintf("%s order %d is %s, but can't be saved: %s", binanceOrder.Side, binanceOrder.OrderId, binanceOrder.Status, err.Error())
+
+		return &discrepancy
+	}
+
+	if openedErr == nil {
+		if binanceOrder.IsBuy() {
+			// extra charge
+			executedQuantity := opened.ExecutedQuantity + order.ExecutedQuantity
+			opened.Price = ((opened.ExecutedQuantity * opened.Price) + (order.ExecutedQuantity * order.Price)) / executedQuantity
+			opened.ExecutedQuantity = executedQuantity
+			opened.UsedExtraBudget = opened.UsedExtraBudget + (order.Price * order.ExecutedQuantity)
+		} else {
+			remaining := opened.GetRemainingToSellQuantity() - order.ExecutedQuantity
+			if opened.Commission != nil {
+				remaining -= *opened.Commission
+			}
+
+			if remaining <= tradeLimit.MinQuantity {
+				opened.Status = "closed"
+			}
+		}
+
+		err = r.OrderRepository.Update(opened)
+
+		if err != nil {
+			discrepancy.Message = fmt.Sprintf("%s order %d is saved, but position [%d] is not updated: %s", binanceOrder.Side, binanceOrder.OrderId, opened.Id, err.Error())
+
+			return &discrepancy
+		}
+	}
+
+	r.OrderRepository.DeleteBinanceOrder(binanceOrder)
+	r.BalanceService.InvalidateBalanceCache("USDT")
+	r.BalanceService.InvalidateBalanceCache(order.GetBaseAsset())
+	r.recordLot(order, opened, openedErr == nil, lastId)
+
+	discrepancy.OrderId = lastId
+	discrepancy.Actual = order.ExecutedQuantity
+	discrepancy.Fixed = true
+	discrepancy.Message = fmt.Sprintf("%s order %d was %s while bot was offline, order [%d] is saved", binanceOrder.Side, binanceOrder.OrderId, binanceOrder.Status, *lastId)
+	r.recordEvent(binanceOrder, lastId, discrepancy.Message)
+
+	return &discrepancy
+}
+
+func (r *OrderReconciler) reconcileExecutedQuantity(
+	tradeLimit ExchangeModel.TradeLimit,
+	order ExchangeModel.Order,
+	trades []ExchangeModel.MyTrade,
+	discrepancyType string,
+) *ExchangeModel.ReconciliationDiscrepancy {
+	if order.ExternalId == nil {
+		return nil
+	}
+
+	// order executed by TWAP or iceberg is filled by all its child orders
+	externalIds := []int64{*order.ExternalId}
+	if r.ChildOrderRepository != nil {
+		for _, child := range r.ChildOrderRepository.GetChildOrders(order.Id) {
+			externalIds = append(externalIds, child.ExternalId)
+		}
+	}
+
+	tradeQuantity := 0.00
+	for _, trade := range trades {
+		if slices.Contains(externalIds, trade.OrderId) {
+			tradeQuantity += trade.Quantity
+		}
+	}
+
+	// trade history is limited, old orders are not available
+	if tradeQuantity == 0.00 || math.Abs(tradeQuantity-order.ExecutedQuantity) <= tradeLimit.MinQuantity {
+		return nil
+	}
+
+	discrepancy := ExchangeModel.ReconciliationDiscrepancy{
+		Type:       discrepancyType,
+		Symbol:     order.Symbol,
+		OrderId:    &order.Id,
+		ExternalId: order.ExternalId,
+		Expected:   tradeQuantity,
+		Actual:     order.ExecutedQuantity,
+	}
+
+	order.ExecutedQuantity = tradeQuantity
+	err := r.OrderRepository.Update(order)
+
+	if err != nil {
+		discrepancy.Message = fmt.Sprintf("Order [%d] executed quantity %f != %f, update failed: %s", order.Id, discrepancy.Actual, tradeQuantity, err.Error())
+
+		return &discrepancy
+	}
+
+	discrepancy.Fixed = true
+	discrepancy.Message = fmt.Sprintf("Order [%d] executed quantity %f is fixed to %f", order.Id, discrepancy.Actual, tradeQuantity)
+
+	event := ExchangeModel.OrderEvent{
+		OrderId:     &order.Id,
+		Symbol:      order.Symbol,
+		ExternalId:  order.ExternalId,
+		Operation:   strings.ToUpper(order.Operation),
+		Type:        ExchangeModel.OrderEventReconciled,
+		Price:       order.Price,
+		Quantity:    order.Quantity,
+		ExecutedQty: tradeQuantity,
+		Details:     &discrepancy.Message,
+	}
+	r.OrderEventRecorder.Record(event)
+
+	return &discrepancy
+}
+
+func (r *OrderReconciler) recordEvent(binanceOrder ExchangeModel.BinanceOrder, orderId *int64, details string) {
+	event := binanceOrder.GetOrderEvent(ExchangeModel.OrderEventReconciled, orderId)
+	event.Details = &details
+	r.OrderEventRecorder.Record(event)
+}
+
+func (r *OrderReconciler) recordLot(order ExchangeModel.Order, opened ExchangeModel.Order, hasOpened bool, lastId *int64) {
+	if r.LotLedger == nil {
+		return
+	}
+
+	switch {
+	case strings.ToUpper(order.Operation) == "SELL":
+		r.LotLedger.RecordOrder(order, ExchangeModel.LotEntrySell, &opened.Id)
+	case hasOpened:
+		r.LotLedger.RecordOrder(order, ExchangeModel.LotEntryBuyExtra, &opened.Id)
+	default:
+		r.LotLedger.RecordOrder(order, ExchangeModel.LotEntryBuy, lastId)
+	}
+}
@@ -0,0 +1,407 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"log"
+	"math"
+	"reflect"
+	"slices"
+	"sort"
+	"time"
+)
+
+type TradeLimitChangeListenerInterface interface {
+	OnTradeLimitChange(before model.TradeLimit, after model.TradeLimit)
+}
+
+// PerformanceService calculates stats of realized trades per symbol, per strategy (strategies which voted for buy)
+// and per trade limit configuration period, period is closed by snapshot when configuration is changed
+type PerformanceService struct {
+	OrderRepository       repository.OrderListReaderInterface
+	PnlService            *PnlService
+	ExchangeRepository    repository.TradeLimitFinderInterface
+	PerformanceRepository repository.PerformanceStorageInterface
+}
+
+// GetSummary returns stats of date range (YYYY-MM-DD, inclusive), empty from means since the first order
+func (s *PerformanceService) GetSummary(from string, to string) (model.PerformanceSummary, error) {
+	periodFrom, periodTo, err := parsePerformancePeriod(from, to)
+	if err != nil {
+		return model.PerformanceSummary{}, err
+	}
+
+	orders := s.OrderRepository.GetList()
+	trades := s.PnlService.getRealizedTrades(orders)
+
+	capital := 0.00
+	symbolCapital := make(map[string]float64)
+	for _, limit := range s.ExchangeRepository.GetTradeLimits() {
+		capital += limit.USDTLimit
+		symbolCapital[limit.Symbol] = limit.USDTLimit
+	}
+
+	summary := model.PerformanceSummary{
+		Overall: s.calculate(orders, trades, func(buy model.Order) bool {
+			return true
+		}, capital, periodFrom, periodTo),
+		Symbols:    make([]model.PerformanceStats, 0),
+		Strategies: make([]model.PerformanceStats, 0),
+	}
+
+	symbols := make([]string, 0)
+	for _, order := range orders {
+		if isPnlOperation(order, "BUY") && !slices.Contains(symbols, order.Symbol) {
+			symbols = append(symbols, order.Symbol)
+		}
+	}
+	sort.Strings(symbols)
+
+	for _, symbol := range symbols {
+		symbol := symbol
+		stats := s.calculate(orders, trades, func(buy model.Order) bool {
+			return buy.Symbol == symbol
+		}, symbolCapital[symbol], periodFrom, periodTo)
+
+		if stats.Trades > 0 || stats.Positions > 0 {
+			stats.Symbol = symbol
+			summary.Symbols = append(summary.Symbols, stats)
+		}
+	}
+
+	orderStrategies := s.PerformanceRepository.GetOrderStrategies()
+	strategies := make([]string, 0)
+	for _, names := range orderStrategies {
+		for _, name := range names {
+			if !slices.Contains(strategies, name) {
+				strategies = append(strategies, name)
+			}
+		}
+	}
+	sort.Strings(strategies)
+
+	for _, strategy := range strategies {
+		strategy := strategy
+		stats := s.calculate(orders, trades, func(buy model.Order) bool {
+			return slices.Contains(orderStrategies[buy.Id], strategy)
+		}, capital, periodFrom, periodTo)
+
+		if stats.Trades > 0 || stats.Positions > 0 {
+			stats.Strategy = strategy
+			summary.Strategies = append(summary.Strategies, stats)
+		}
+	}
+
+	return summary, nil
+}
+
+// GetTradeLimitPerformance returns stats of current configuration (since the last snapshot) and previous snapshots
+func (s *PerformanceService) GetTradeLimitPerformance(symbol string) (model.TradeLimitPerformance, error) {
+	limit, err := s.ExchangeRepository.GetTradeLimit(symbol)
+	if err != nil {
+		return model.TradeLimitPerformance{}, err
+	}
+
+	snapshots := s.PerformanceRepository.GetSnapshots(symbol)
+
+	return model.TradeLimitPerformance{
+		TradeLimit: limit,
+		Current:    s.getTradeLimitStats(limit, snapshots),
+		Snapshots:  snapshots,
+	}, nil
+}
+
+func (s *PerformanceService) Snapshot(symbol string, reason string) (model.PerformanceSnapshot, error) {
+	limit, err := s.ExchangeRepository.GetTradeLimit(symbol)
+	if err != nil {
+		return model.PerformanceSnapshot{}, err
+	}
+
+	return s.createSnapshot(limit, reason)
+}
+
+// OnTradeLimitChange closes configuration period of previous trade limit, exchange filters updates are ignored
+func (s *PerformanceService) OnTradeLimitChange(before model.TradeLimit, after model.TradeLimit) {
+	if !IsTradeLimitStrategyChanged(before, after) {
+		return
+	}
+
+	_, err := s.createSnapshot(before, model.PerformanceSnapshotConfigChange)
+	if err != nil {
+		log.Printf("[%s] Performance snapshot is not saved: %s", before.Symbol, err.Error())
+	}
+}
+
+func (s *PerformanceService) createSnapshot(limit model.TradeLimit, reason string) (model.PerformanceSnapshot, error) {
+	snapshot := model.PerformanceSnapshot{
+		Symbol:     limit.Symbol,
+		Reason:     reason,
+		TradeLimit: limit,
+		Stats:      s.getTradeLimitStats(limit, s.PerformanceRepository.GetSnapshots(limit.Symbol)),
+	}
+
+	id, err := s.PerformanceRepository.CreateSnapshot(snapshot)
+	if err != nil {
+		return snapshot, err
+	}
+	snapshot.Id = *id
+
+	return snapshot, nil
+}
+
+func (s *PerformanceService) getTradeLimitStats(limit model.TradeLimit, snapshots []model.PerformanceSnapshot) model.PerformanceStats {
+	from := time.Time{}
+	if len(snapshots) > 0 {
+		from = parsePnlDate(snapshots[0].Stats.To)
+	}
+
+	orders := s.OrderRepository.GetList()
+	stats := s.calculate(orders, s.PnlService.getRealizedTrades(orders), func(buy model.Order) bool {
+		return buy.Symbol == limit.Symbol
+	}, limit.USDTLimit, from, getPerformanceNow())
+	stats.Symbol = limit.Symbol
+
+	return stats
+}
+
+// calculate stats of trades closed within period for positions matched by filter
+func (s *PerformanceService) calculate(
+	orders []model.Order,
+	trades []model.RealizedTrade,
+	match func(buy model.Order) bool,
+	capital float64,
+	from time.Time,
+	to time.Time,
+) model.PerformanceStats {
+	buys := make(map[int64]model.Order)
+	for _, order := range orders {
+		if isPnlOperation(order, "BUY") && match(order) {
+			buys[order.Id] = order
+		}
+	}
+
+	if from.IsZero() {
+		for _, buy := range buys {
+			opened := parsePnlDate(buy.CreatedAt)
+			if from.IsZero() || opened.Before(from) {
+				from = opened
+			}
+		}
+	}
+	if from.IsZero() || from.After(to) {
+		from = to
+	}
+
+	stats := model.PerformanceStats{
+		From:    from.Format("2006-01-02 15:04:05"),
+		To:      to.Format("2006-01-02 15:04:05"),
+		Capital: capital,
+	}
+
+	dailyPnl := make(map[string]float64)
+	closedAt := make(map[int64]time.Time)
+	hoursOpened := 0.00
+	cumulative := 0.00
+	peak := 0.00
+
+	for _, trade := range trades {
+		buy, ok := buys[trade.BuyOrderId]
+		if !ok {
+			continue
+		}
+
+		closed := parsePnlDate(trade.Close)
+		if closed.After(closedAt[buy.Id]) {
+			closedAt[buy.Id] = closed
+		}
+		if closed.Before(from) || closed.After(to) {
+			continue
+		}
+
+		stats.Trades++
+		if trade.RealizedPnl > 0 {
+			stats.Wins++
+			stats.GrossProfit += trade.RealizedPnl
+		} else {
+			stats.Losses++
+			stats.GrossLoss -= trade.RealizedPnl
+		}
+		stats.Fees += trade.Fees
+		stats.NetPnl += trade.RealizedPnl
+		hoursOpened += closed.Sub(parsePnlDate(buy.CreatedAt)).Hours()
+		dailyPnl[closed.Format("2006-01-02")] += trade.RealizedPnl
+
+		cumulative += trade.RealizedPnl
+		peak = math.Max(peak, cumulative)
+		stats.MaxDrawdown = math.Max(stats.MaxDrawdown, peak-cumulative)
+	}
+
+	invested := 0.00
+	for _, buy := range buys {
+		opened := parsePnlDate(buy.CreatedAt)
+		closed := to
+		if buy.IsClosed() {
+			lastTrade, ok := closedAt[buy.Id]
+			if !ok {
+				continue
+			}
+			closed = lastTrade
+		}
+
+		if !opened.Before(from) && !opened.After(to) {
+			stats.Positions++
+			if buy.UsedExtraBudget > 0 {
+				stats.ExtraChargePositions++
+				stats.ExtraChargeBudget += buy.UsedExtraBudget
+			}
+		}
+
+		start, end := opened, closed
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if end.After(start) {
+			invested += buy.Price * buy.ExecutedQuantity * end.Sub(start).Hours()
+		}
+	}
+
+	if stats.Trades > 0 {
+		stats.WinRate = float64(stats.Wins) * 100 / float64(stats.Trades)
+		stats.AvgHoursOpened = hoursOpened / float64(stats.Trades)
+	}
+	if stats.GrossLoss > 0 {
+		profitFactor := roundPnl(stats.GrossProfit / stats.GrossLoss)
+		stats.ProfitFactor = &profitFactor
+	}
+	if stats.Positions > 0 {
+		stats.ExtraChargeRate = float64(stats.ExtraChargePositions) * 100 / float64(stats.Positions)
+	}
+
+	if capital > 0 {
+		stats.MaxDrawdownPercent = stats.MaxDrawdown * 100 / capital
+
+		// days without closed trades are zero returns
+		returns := make([]float64, 0)
+		day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, from.Location())
+		for ; !day.After(to); day = day.AddDate(0, 0, 1) {
+			returns = append(returns, dailyPnl[day.Format("2006-01-02")]/capital)
+		}
+		stats.Sharpe, stats.Sortino = getSharpeSortino(returns)
+
+		periodHours := to.Sub(from).Hours()
+		if periodHours > 0 {
+			stats.CapitalUtilisation = invested * 100 / (capital * periodHours)
+		}
+	}
+
+	return roundPerformanceStats(stats)
+}
+
+// IsTradeLimitStrategyChanged compares trading configuration, exchange filters are synchronized by bot
+func IsTradeLimitStrategyChanged(before model.TradeLimit, after model.TradeLimit) bool {
+	for _, limit := range []*model.TradeLimit{&before, &after} {
+		limit.Id = 0
+		limit.MinPrice = 0
+		limit.MinQuantity = 0
+		limit.MinNotional = 0
+		limit.Mode = limit.GetMode()
+		limit.OrderType = limit.GetOrderType()
+		if len(limit.ExtraChargeOptions) == 0 {
+			limit.ExtraChargeOptions = nil
+		}
+		if len(limit.TakeProfitOptions) == 0 {
+			limit.TakeProfitOptions = nil
+		}
+	}
+
+	return !reflect.DeepEqual(before, after)
+}
+
+// getSharpeSortino returns annualized ratios of daily returns, risk-free rate is zero
+func getSharpeSortino(returns []float64) (float64, float64) {
+	if len(returns) < 2 {
+		return 0.00, 0.00
+	}
+
+	mean := 0.00
+	for _, value := range returns {
+		mean += value
+	}
+	mean = mean / float64(len(returns))
+
+	variance := 0.00
+	downside := 0.00
+	for _, value := range returns {
+		variance += math.Pow(value-mean, 2)
+		downside += math.Pow(math.Min(value, 0), 2)
+	}
+	deviation := math.Sqrt(variance / float64(len(returns)-1))
+	downsideDeviation := math.Sqrt(downside / float64(len(returns)))
+
+	sharpe := 0.00
+	sortino := 0.00
+	if deviation > 0 {
+		sharpe = mean / deviation * math.Sqrt(365)
+	}
+	if downsideDeviation > 0 {
+		sortino = mean / downsideDeviation * math.Sqrt(365)
+	}
+
+	return sharpe, sortino
+}
+
+func roundPerformanceStats(stats model.PerformanceStats) model.PerformanceStats {
+	stats.WinRate = roundPnl(stats.WinRate)
+	stats.AvgHoursOpened = roundPnl(stats.AvgHoursOpened)
+	stats.GrossProfit = roundPnl(stats.GrossProfit)
+	stats.GrossLoss = roundPnl(stats.GrossLoss)
+	stats.Fees = roundPnl(stats.Fees)
+	stats.NetPnl = roundPnl(stats.NetPnl)
+	stats.Sharpe = roundPnl(stats.Sharpe)
+	stats.Sortino = roundPnl(stats.Sortino)
+	stats.MaxDrawdown = roundPnl(stats.MaxDrawdown)
+	stats.MaxDrawdownPercent = roundPnl(stats.MaxDrawdownPercent)
+	stats.ExtraChargeRate = roundPnl(stats.ExtraChargeRate)
+	stats.ExtraChargeBudget = roundPnl(stats.ExtraChargeBudget)
+	stats.CapitalUtilisation = roundPnl(stats.CapitalUtilisation)
+
+	return stats
+}
+
+// parsePerformancePeriod parses dates in format YYYY-MM-DD, date to is inclusive and defaults to now
+func parsePerformancePeriod(from string, to string) (time.Time, time.Time, error) {
+	periodFrom := time.Time{}
+	periodTo := getPerformanceNow()
+
+	if from != "" {
+		value, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return periodFrom, periodTo, errors.New(fmt.Sprintf("Date from %s is invalid, format is YYYY-MM-DD", from))
+		}
+		periodFrom = value
+	}
+
+	if to != "" {
+		value, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return periodFrom, periodTo, errors.New(fmt.Sprintf("Date to %s is invalid, format is YYYY-MM-DD", to))
+		}
+		periodTo = value.Add(time.Hour*24 - time.Second)
+	}
+
+	if !periodFrom.IsZero() && periodTo.Before(periodFrom) {
+		return periodFrom, periodTo, errors.New("Date to must be after date from")
+	}
+
+	return periodFrom, periodTo, nil
+}
+
+// getPerformanceNow is current time in the same (database) format as order dates
+func getPerformanceNow() time.Time {
+	return parsePnlDate(time.Now().Format("2006-01-02 15:04:05"))
+}
@@ -0,0 +1,301 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"math"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// PnlService calculates realized PnL of closed sell orders and unrealized PnL of opened positions,
+// fees are converted to quote asset and quantity gained by swaps lowers cost price of position
+type PnlService struct {
+	OrderRepository    repository.OrderListReaderInterface
+	SwapRepository     repository.SwapGainReaderInterface
+	ExchangeRepository repository.KLineReaderInterface
+}
+
+func (s *PnlService) GetReport(period string) (model.PnlReport, error) {
+	if !slices.Contains([]string{model.PnlPeriodDay, model.PnlPeriodWeek, model.PnlPeriodMonth}, period) {
+		return model.PnlReport{}, errors.New(fmt.Sprintf("Period %s is invalid, allowed: day, week, month", period))
+	}
+
+	orders := s.OrderRepository.GetList()
+	trades := s.getRealizedTrades(orders)
+	unrealized := s.getUnrealized(orders)
+
+	report := model.PnlReport{
+		Period:     period,
+		Overall:    make([]model.PnlPeriodSummary, 0),
+		Symbols:    make([]model.PnlPeriodSummary, 0),
+		Unrealized: unrealized,
+		FeeTotals:  s.getFeeTotals(orders),
+	}
+
+	overall := make(map[string]*model.PnlPeriodSummary)
+	symbols := make(map[string]*model.PnlPeriodSummary)
+	for _, trade := range trades {
+		key := getPnlPeriodKey(trade.Close, period)
+		addPnlSummary(overall, key, model.PnlPeriodSummary{Period: key}, trade)
+		addPnlSummary(symbols, key+trade.Symbol, model.PnlPeriodSummary{Period: key, Symbol: trade.Symbol}, trade)
+
+		report.RealizedPnl += trade.RealizedPnl
+		report.Fees += trade.Fees
+	}
+	for _, position := range unrealized {
+		report.UnrealizedPnl += position.Pnl
+	}
+
+	for _, summary := range overall {
+		report.Overall = append(report.Overall, roundPnlSummary(*summary))
+	}
+	for _, summary := range symbols {
+		report.Symbols = append(report.Symbols, roundPnlSummary(*summary))
+	}
+	sort.SliceStable(report.Overall, func(i int, j int) bool {
+		return report.Overall[i].Period < report.Overall[j].Period
+	})
+	sort.SliceStable(report.Symbols, func(i int, j int) bool {
+		if report.Symbols[i].Period == report.Symbols[j].Period {
+			return report.Symbols[i].Symbol < report.Symbols[j].Symbol
+		}
+
+		return report.Symbols[i].Period < report.Symbols[j].Period
+	})
+
+	report.RealizedPnl = roundPnl(report.RealizedPnl)
+	report.UnrealizedPnl = roundPnl(report.UnrealizedPnl)
+	report.Fees = roundPnl(report.Fees)
+
+	return report, nil
+}
+
+// GetEquityCurve returns cumulative realized PnL after each closed trade and current equity with unrealized PnL
+func (s *PnlService) GetEquityCurve() []model.EquityPoint {
+	orders := s.OrderRepository.GetList()
+	points := make([]model.EquityPoint, 0)
+
+	realized := 0.00
+	for _, trade := range s.getRealizedTrades(orders) {
+		realized += trade.RealizedPnl
+		points = append(points, model.EquityPoint{
+			Timestamp:   parsePnlDate(trade.Close).UnixMilli(),
+			RealizedPnl: roundPnl(realized),
+			Equity:      roundPnl(realized),
+		})
+	}
+
+	unrealized := 0.00
+	for _, position := range s.getUnrealized(orders) {
+		unrealized += position.Pnl
+	}
+	points = append(points, model.EquityPoint{
+		Timestamp:     time.Now().UnixMilli(),
+		RealizedPnl:   roundPnl(realized),
+		UnrealizedPnl: roundPnl(unrealized),
+		Equity:        roundPnl(realized + unrealized),
+	})
+
+	return points
+}
+
+func (s *PnlService) GetRealizedTrades() []model.RealizedTrade {
+	return s.getRealizedTrades(s.OrderRepository.GetList())
+}
+
+func (s *PnlService) getRealizedTrades(orders []model.Order) []model.RealizedTrade {
+	buyOrders := make(map[int64]model.Order)
+	for _, order := range orders {
+		if isPnlOperation(order, "BUY") {
+			buyOrders[order.Id] = order
+		}
+	}
+	swapGains := s.SwapRepository.GetSwapGains()
+
+	trades := make([]model.RealizedTrade, 0)
+	for _, sell := range orders {
+		if !isPnlOperation(sell, "SELL") || !sell.IsClosed() || sell.ClosesOrder == nil || sell.ExecutedQuantity <= 0 {
+			continue
+		}
+
+		buy, ok := buyOrders[*sell.ClosesOrder]
+		if !ok {
+			continue
+		}
+
+		heldQuantity := buy.ExecutedQuantity + swapGains[buy.Id]
+		if heldQuantity <= 0 {
+			continue
+		}
+
+		share := math.Min(sell.ExecutedQuantity/heldQuantity, 1)
+		costPrice := buy.Price * buy.ExecutedQuantity / heldQuantity
+		grossPnl := (sell.Price - costPrice) * sell.ExecutedQuantity
+		fees := s.getQuoteFee(sell) + s.getQuoteFee(buy)*share
+
+		trades = append(trades, model.RealizedTrade{
+			OrderId:      sell.Id,
+			BuyOrderId:   buy.Id,
+			Symbol:       sell.Symbol,
+			Close:        sell.CreatedAt,
+			Quantity:     sell.ExecutedQuantity,
+			CostPrice:    costPrice,
+			SellPrice:    sell.Price,
+			GrossPnl:     roundPnl(grossPnl),
+			Fees:         roundPnl(fees),
+			RealizedPnl:  roundPnl(grossPnl - fees),
+			SwapQuantity: swapGains[buy.Id] * share,
+		})
+	}
+
+	sort.SliceStable(trades, func(i int, j int) bool {
+		return trades[i].Close < trades[j].Close
+	})
+
+	return trades
+}
+
+func (s *PnlService) getUnrealized(orders []model.Order) []model.UnrealizedPnl {
+	swapGains := s.SwapRepository.GetSwapGains()
+	list := make([]model.UnrealizedPnl, 0)
+
+	for _, order := range orders {
+		if !isPnlOperation(order, "BUY") || order.Status != "opened" {
+			continue
+		}
+
+		heldQuantity := order.ExecutedQuantity + swapGains[order.Id]
+		quantity := order.GetRemainingToSellQuantity() + swapGains[order.Id]
+		kLine := s.ExchangeRepository.GetLastKLine(order.Symbol)
+		if kLine == nil || heldQuantity <= 0 || quantity <= 0 {
+			continue
+		}
+
+		costPrice := order.Price * order.ExecutedQuantity / heldQuantity
+		// buy fee of remaining quantity is not realized yet
+		pnl := (kLine.Close-costPrice)*quantity - s.getQuoteFee(order)*math.Min(quantity/heldQuantity, 1)
+
+		list = append(list, model.UnrealizedPnl{
+			OrderId:      order.Id,
+			Symbol:       order.Symbol,
+			Quantity:     quantity,
+			CostPrice:    costPrice,
+			CurrentPrice: kLine.Close,
+			Pnl:          roundPnl(pnl),
+			Percent:      roundPnl(pnl * 100 / (costPrice * quantity)),
+		})
+	}
+
+	return list
+}
+
+func (s *PnlService) getFeeTotals(orders []model.Order) []model.FeeTotal {
+	totals := make(map[string]*model.FeeTotal)
+	for _, order := range orders {
+		if order.Commission == nil || order.CommissionAsset == nil || *order.Commission == 0 {
+			continue
+		}
+
+		total, ok := totals[*order.CommissionAsset]
+		if !ok {
+			total = &model.FeeTotal{Asset: *order.CommissionAsset}
+			totals[*order.CommissionAsset] = total
+		}
+		total.Amount += *order.Commission
+		total.QuoteAmount += s.getQuoteFee(order)
+	}
+
+	list := make([]model.FeeTotal, 0)
+	for _, total := range totals {
+		total.QuoteAmount = roundPnl(total.QuoteAmount)
+		list = append(list, *total)
+	}
+	sort.SliceStable(list, func(i int, j int) bool {
+		return list[i].Asset < list[j].Asset
+	})
+
+	return list
+}
+
+// getQuoteFee converts commission to quote asset, commission in third asset (BNB) is converted by its latest kline
+func (s *PnlService) getQuoteFee(order model.Order) float64 {
+	if order.Commission == nil || order.CommissionAsset == nil {
+		return 0.00
+	}
+
+	baseAsset := order.GetBaseAsset()
+	quoteAsset := strings.TrimPrefix(order.Symbol, baseAsset)
+
+	switch *order.CommissionAsset {
+	case quoteAsset:
+		return *order.Commission
+	case baseAsset:
+		return *order.Commission * order.Price
+	}
+
+	kLine := s.ExchangeRepository.GetLastKLine(*order.CommissionAsset + quoteAsset)
+	if kLine == nil {
+		return 0.00
+	}
+
+	return *order.Commission * kLine.Close
+}
+
+func isPnlOperation(order model.Order, operation string) bool {
+	return strings.ToUpper(order.Operation) == operation
+}
+
+func addPnlSummary(summaries map[string]*model.PnlPeriodSummary, key string, initial model.PnlPeriodSummary, trade model.RealizedTrade) {
+	summary, ok := summaries[key]
+	if !ok {
+		summary = &initial
+		summaries[key] = summary
+	}
+
+	summary.Trades++
+	summary.GrossPnl += trade.GrossPnl
+	summary.Fees += trade.Fees
+	summary.RealizedPnl += trade.RealizedPnl
+}
+
+func roundPnlSummary(summary model.PnlPeriodSummary) model.PnlPeriodSummary {
+	summary.GrossPnl = roundPnl(summary.GrossPnl)
+	summary.Fees = roundPnl(summary.Fees)
+	summary.RealizedPnl = roundPnl(summary.RealizedPnl)
+
+	return summary
+}
+
+// getPnlPeriodKey is 2024-01-31 (day), 2024-W05 (ISO week) or 2024-01 (month)
+func getPnlPeriodKey(date string, period string) string {
+	value := parsePnlDate(date)
+
+	switch period {
+	case model.PnlPeriodWeek:
+		year, week := value.ISOWeek()
+
+		return fmt.Sprintf("%d-W%02d", year, week)
+	case model.PnlPeriodMonth:
+		return value.Format("2006-01")
+	}
+
+	return value.Format("2006-01-02")
+}
+
+func parsePnlDate(date string) time.Time {
+	value, err := time.Parse("2006-01-02 15:04:05", date)
+	if err != nil {
+		value, _ = time.Parse(time.RFC3339, date)
+	}
+
+	return value
+}
+
+func roundPnl(value float64) float64 {
+	return math.Round(value*100000000) / 100000000
+}
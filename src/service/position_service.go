@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/redis/go-redis/v9"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"time"
+)
+
+type PositionService struct {
+	RDB                *redis.Client
+	Ctx                *context.Context
+	OrderRepository    *repository.OrderRepository
+	ExchangeRepository *repository.ExchangeRepository
+	Formatter          *Formatter
+	PriceCalculator    *PriceCalculator
+}
+
+func (p *PositionService) GetPositions() []model.Position {
+	positions := make([]model.Position, 0)
+
+	for _, limit := range p.ExchangeRepository.GetTradeLimits() {
+		position := p.GetPosition(limit)
+		if position != nil {
+			positions = append(positions, *position)
+		}
+	}
+
+	return positions
+}
+
+func (p *PositionService) GetPosition(limit model.TradeLimit) *model.Position {
+	openedOrder, err := p.OrderRepository.GetOpenedOrderCached(limit.Symbol, "BUY")
+	if err != nil {
+		return nil
+	}
+
+	kLine := p.ExchangeRepository.GetLastKLine(limit.Symbol)
+	if kLine == nil {
+		return nil
+	}
+
+	var sellPrice float64
+
+	binanceOrder := p.OrderRepository.GetBinanceOrder(openedOrder.Symbol, "SELL")
+	executedQty := 0.00
+	origQty := openedOrder.ExecutedQuantity
+
+	if binanceOrder != nil {
+		sellPrice = binanceOrder.Price
+		origQty = binanceOrder.OrigQty
+		executedQty = binanceOrder.ExecutedQty
+	} else {
+		sellPriceCacheKey := fmt.Sprintf("sell-price-%d", openedOrder.Id)
+		sellPriceCached := p.RDB.Get(*p.Ctx, sellPriceCacheKey).Val()
+		if len(sellPriceCached) > 0 {
+			_ = json.Unmarshal([]byte(sellPriceCached), &sellPrice)
+		} else {
+			sellPrice = p.PriceCalculator.CalculateSell(limit, openedOrder)
+			encoded, _ := json.Marshal(sellPrice)
+			p.RDB.Set(*p.Ctx, sellPriceCacheKey, string(encoded), time.Hour)
+		}
+	}
+
+	predictedPrice, err := p.ExchangeRepository.GetPredict(limit.Symbol)
+	if predictedPrice > 0.00 {
+		predictedPrice = p.Formatter.FormatPrice(limit, predictedPrice)
+	}
+
+	interpolation := p.PriceCalculator.InterpolatePrice(limit.Symbol)
+	if interpolation.BtcInterpolationUsdt > 0.00 {
+		interpolation.BtcInterpolationUsdt = p.Formatter.FormatPrice(limit, interpolation.BtcInterpolationUsdt)
+	}
+	if interpolation.EthInterpolationUsdt > 0.00 {
+		interpolation.EthInterpolationUsdt = p.Formatter.FormatPrice(limit, interpolation.EthInterpolationUsdt)
+	}
+
+	return &model.Position{
+		Symbol:         limit.Symbol,
+		Order:          openedOrder,
+		KLine:          *kLine,
+		Percent:        openedOrder.GetProfitPercent(kLine.Close),
+		SellPrice:      sellPrice,
+		Profit:         p.Formatter.ToFixed(openedOrder.GetQuoteProfit(kLine.Close), 2),
+		TargetProfit:   p.Formatter.ToFixed(openedOrder.GetQuoteProfit(sellPrice), 2),
+		PredictedPrice: predictedPrice,
+		Interpolation:  interpolation,
+		ExecutedQty:    executedQty,
+		OrigQty:        origQty,
+		ManualOrderConfig: model.ManualOrderConfig{
+			PriceStep:     limit.MinPrice,
+			MinClosePrice: openedOrder.GetManualMinClosePrice(),
+		},
+	}
+}
@@ -26,14 +26,24 @@ type PriceCalculator struct {
 }
 
 func (m *PriceCalculator) CalculateBuy(tradeLimit model.TradeLimit) (float64, error) {
+	calculation, err := m.CalculateBuyExplained(tradeLimit)
+
+	return calculation.Price, err
+}
+
+// CalculateBuyExplained returns buy price with inputs it is calculated from (decision journal)
+func (m *PriceCalculator) CalculateBuyExplained(tradeLimit model.TradeLimit) (model.BuyPriceCalculation, error) {
+	calculation := model.BuyPriceCalculation{Corrections: make([]model.PriceCorrection, 0)}
 	marketDepth := m.GetDepth(tradeLimit.Symbol)
 	lastKline := m.ExchangeRepository.GetLastKLine(tradeLimit.Symbol)
 
 	if lastKline == nil {
-		return 0.00, errors.New(fmt.Sprintf("[%s] Current price is unknown, wait...", tradeLimit.Symbol))
+		return calculation, errors.New(fmt.Sprintf("[%s] Current price is unknown, wait...", tradeLimit.Symbol))
 	}
 
 	minPrice := m.ExchangeRepository.GetPeriodMinPrice(tradeLimit.Symbol, tradeLimit.MinPriceMinutesPeriod)
+	calculation.LastPrice = lastKline.Close
+	calculation.MinPrice = minPrice
 	order, err := m.OrderRepository.GetOpenedOrderCached(tradeLimit.Symbol, "BUY")
 
 	// Extra charge by current price
@@ -61,18 +71,24 @@ func (m *PriceCalculator) CalculateBuy(tradeLimit model.TradeLimit) (float64, er
 			extraBuyPrice = lastKline.Close
 		}
 
-		return m.LossSecurity.BuyPriceCorrection(extraBuyPrice, tradeLimit), nil
+		calculation.ExtraCharge = true
+		calculation.Price = calculation.Correct("buy_price_correction", extraBuyPrice, m.LossSecurity.BuyPriceCorrection(extraBuyPrice, tradeLimit))
+
+		return calculation, nil
 	}
 
 	frame := m.FrameService.GetFrame(tradeLimit.Symbol, tradeLimit.FrameInterval, tradeLimit.FramePeriod)
 	bestFramePrice, err := m.GetBestFrameBuy(tradeLimit, marketDepth, frame)
 	buyPrice := minPrice
+	calculation.Frame = &frame
 
 	if err == nil {
+		calculation.FramePrice = bestFramePrice[1]
 		if buyPrice > bestFramePrice[1] {
 			buyPrice = bestFramePrice[1]
 		}
 	} else {
+		calculation.FrameError = err.Error()
 		log.Printf("[%s] Buy Frame Error: %s, current = %f", tradeLimit.Symbol, err.Error(), lastKline.Close)
 		potentialOpenPrice := lastKline.Close
 		for {
@@ -96,7 +112,7 @@ func (m *PriceCalculator) CalculateBuy(tradeLimit model.TradeLimit) (float64, er
 	}
 
 	log.Printf("[%s] buy price history check", tradeLimit.Symbol)
-	buyPrice = m.LossSecurity.CheckBuyPriceOnHistory(tradeLimit, buyPrice)
+	buyPrice = calculation.Correct("history_check", buyPrice, m.LossSecurity.CheckBuyPriceOnHistory(tradeLimit, buyPrice))
 	closePrice := tradeLimit.GetClosePrice(buyPrice)
 
 	log.Printf(
@@ -114,7 +130,9 @@ func (m *PriceCalculator) CalculateBuy(tradeLimit model.TradeLimit) (float64, er
 		closePrice,
 	)
 
-	return m.LossSecurity.BuyPriceCorrection(buyPrice, tradeLimit), nil
+	calculation.Price = calculation.Correct("buy_price_correction", buyPrice, m.LossSecurity.BuyPriceCorrection(buyPrice, tradeLimit))
+
+	return calculation, nil
 }
 
 func (m *PriceCalculator) CalculateSell(tradeLimit model.TradeLimit, order model.Order) float64 {
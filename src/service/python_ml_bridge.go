@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"github.com/redis/go-redis/v9"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
 	ExchangeModel "gitlab.com/open-soft/go-crypto-bot/src/model"
 	ExchangeRepository "gitlab.com/open-soft/go-crypto-bot/src/repository"
 	"log"
@@ -16,6 +17,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 	"unsafe"
 )
 
@@ -176,6 +178,7 @@ with open(result_path, 'w') as out:
 func (p *PythonMLBridge) LearnModel(symbol string) error {
 	p.setLearning(true)
 	defer p.setLearning(false)
+	defer metrics.ObserveSince(metrics.MLDurationSeconds.WithLabelValues("learn"), time.Now())
 
 	datasetPath, err := p.DataSetBuilder.PrepareDataset(symbol)
 	if err != nil {
@@ -286,6 +289,7 @@ func (p *PythonMLBridge) Predict(symbol string) (float64, error) {
 	if p.Learning {
 		return 0.00, errors.New("learning in the process")
 	}
+	defer metrics.ObserveSince(metrics.MLDurationSeconds.WithLabelValues("predict"), time.Now())
 
 	modelFilePath := p.getModelFilePath(symbol)
 	_, err := os.Stat(modelFilePath)
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"sync"
+	"time"
+)
+
+type ShutdownServiceInterface interface {
+	IsShuttingDown() bool
+	StartOperation() bool
+	FinishOperation()
+}
+
+// ShutdownService tracks in-flight exchange operations, new operations are rejected after Shutdown() call
+type ShutdownService struct {
+	mutex      sync.Mutex
+	shutdown   bool
+	operations int64
+}
+
+func (s *ShutdownService) Shutdown() {
+	s.mutex.Lock()
+	s.shutdown = true
+	s.mutex.Unlock()
+}
+
+func (s *ShutdownService) IsShuttingDown() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.shutdown
+}
+
+func (s *ShutdownService) StartOperation() bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.shutdown {
+		return false
+	}
+
+	s.operations++
+
+	return true
+}
+
+func (s *ShutdownService) FinishOperation() {
+	s.mutex.Lock()
+	s.operations--
+	s.mutex.Unlock()
+}
+
+func (s *ShutdownService) GetOperations() int64 {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.operations
+}
+
+// Wait returns false if operations are not finished in time
+func (s *ShutdownService) Wait(timeout time.Duration) bool {
+	deadline := time.Now().Add(timeout)
+
+	for s.GetOperations() > 0 {
+		if time.Now().After(deadline) {
+			return false
+		}
+
+		time.Sleep(time.Millisecond * 100)
+	}
+
+	return true
+}
@@ -0,0 +1,239 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/client"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StreamSymbol is symbol with price streams, worker (decisions, learning) is started only for trade limits
+type StreamSymbol struct {
+	Symbol    string
+	HasWorker bool
+}
+
+type streamSubscription struct {
+	connection client.StreamConnectionInterface
+	cancel     context.CancelFunc
+}
+
+// SubscriptionManager keeps stream subscriptions and per-symbol workers in sync with symbol provider
+type SubscriptionManager struct {
+	Name           string
+	Events         []string
+	MaxStreams     int // per connection
+	BatchOffset    int64
+	SymbolProvider func() []StreamSymbol
+	StartWorker    func(ctx context.Context, symbol string) // returns when worker is stopped
+	Connect        func(batch int64) client.StreamConnectionInterface
+	subscriptions  map[string]*streamSubscription
+	connections    []client.StreamConnectionInterface
+	workers        map[string]chan struct{} // closed when worker of symbol is stopped
+	mu             sync.Mutex
+}
+
+// Watch syncs subscriptions until ctx is cancelled, workers are stopped with ctx
+func (m *SubscriptionManager) Watch(ctx context.Context, interval time.Duration) {
+	for ctx.Err() == nil {
+		m.Sync(ctx)
+
+		select {
+		case <-ctx.Done():
+		case <-time.After(interval):
+		}
+	}
+}
+
+func (m *SubscriptionManager) Sync(ctx context.Context) (added []string, removed []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.subscriptions == nil {
+		m.subscriptions = make(map[string]*streamSubscription)
+	}
+	if m.workers == nil {
+		m.workers = make(map[string]chan struct{})
+	}
+
+	added = make([]string, 0)
+	removed = make([]string, 0)
+	symbols := make(map[string]StreamSymbol)
+	for _, streamSymbol := range m.SymbolProvider() {
+		existing, ok := symbols[streamSymbol.Symbol]
+		streamSymbol.HasWorker = streamSymbol.HasWorker || (ok && existing.HasWorker)
+		symbols[streamSymbol.Symbol] = streamSymbol
+	}
+
+	for symbol, subscription := range m.subscriptions {
+		if _, ok := symbols[symbol]; ok {
+			continue
+		}
+
+		if subscription.cancel != nil {
+			subscription.cancel()
+		}
+		err := subscription.connection.Unsubscribe(m.getStreams(symbol))
+		if err != nil {
+			log.Printf("[%s] %s stream unsubscribe: %s", symbol, m.Name, err.Error())
+		}
+		delete(m.subscriptions, symbol)
+		removed = append(removed, symbol)
+	}
+
+	for symbol, streamSymbol := range symbols {
+		subscription, ok := m.subscriptions[symbol]
+
+		if !ok {
+			streams := m.getStreams(symbol)
+			connection := m.getConnection(len(streams))
+			err := connection.Subscribe(streams)
+			if err != nil {
+				log.Printf("[%s] %s stream subscribe: %s", symbol, m.Name, err.Error())
+			}
+
+			subscription = &streamSubscription{connection: connection}
+			m.subscriptions[symbol] = subscription
+			added = append(added, symbol)
+		}
+
+		if streamSymbol.HasWorker && subscription.cancel == nil && m.StartWorker != nil {
+			subscription.cancel = m.startWorker(ctx, symbol)
+		}
+
+		if !streamSymbol.HasWorker && subscription.cancel != nil {
+			subscription.cancel()
+			subscription.cancel = nil
+		}
+	}
+
+	slices.Sort(added)
+	slices.Sort(removed)
+	if len(added) > 0 {
+		log.Printf("%s streams subscribed: %s", m.Name, strings.Join(added, ", "))
+	}
+	if len(removed) > 0 {
+		log.Printf("%s streams unsubscribed: %s", m.Name, strings.Join(removed, ", "))
+	}
+	metrics.StreamSymbols.WithLabelValues(m.Name).Set(float64(len(m.subscriptions)))
+
+	return added, removed
+}
+
+// Close stops workers and closes connections, manager can be synced again
+func (m *SubscriptionManager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, subscription := range m.subscriptions {
+		if subscription.cancel != nil {
+			subscription.cancel()
+		}
+	}
+	for _, connection := range m.connections {
+		connection.Close()
+	}
+
+	m.subscriptions = make(map[string]*streamSubscription)
+	m.connections = make([]client.StreamConnectionInterface, 0)
+	metrics.StreamSymbols.WithLabelValues(m.Name).Set(0)
+	log.Printf("%s streams are closed", m.Name)
+}
+
+func (m *SubscriptionManager) GetSymbols() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	symbols := make([]string, 0)
+	for symbol := range m.subscriptions {
+		symbols = append(symbols, symbol)
+	}
+	slices.Sort(symbols)
+
+	return symbols
+}
+
+// GetWorkerSymbols returns symbols with running or stopping worker
+func (m *SubscriptionManager) GetWorkerSymbols() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	symbols := make([]string, 0)
+	for symbol := range m.workers {
+		symbols = append(symbols, symbol)
+	}
+	slices.Sort(symbols)
+
+	return symbols
+}
+
+func (m *SubscriptionManager) GetStreamHealth() []model.StreamHealth {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	health := make([]model.StreamHealth, 0)
+	for _, connection := range m.connections {
+		health = append(health, connection.GetHealth())
+	}
+
+	return health
+}
+
+// startWorker starts worker of symbol when the previous worker of the same symbol is stopped, so they don't overlap
+func (m *SubscriptionManager) startWorker(ctx context.Context, symbol string) context.CancelFunc {
+	workerCtx, cancel := context.WithCancel(ctx)
+	previousDone, done := m.workers[symbol], make(chan struct{})
+	m.workers[symbol] = done
+
+	go func() {
+		defer m.removeWorker(symbol, done)
+		defer close(done)
+		if previousDone != nil {
+			<-previousDone
+		}
+		if workerCtx.Err() == nil {
+			m.StartWorker(workerCtx, symbol)
+		}
+	}()
+
+	return cancel
+}
+
+// removeWorker forgets stopped worker of symbol (unsubscribed or disabled), worker started after it is kept
+func (m *SubscriptionManager) removeWorker(symbol string, done chan struct{}) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.workers[symbol] == done {
+		delete(m.workers, symbol)
+	}
+}
+
+func (m *SubscriptionManager) getStreams(symbol string) []string {
+	streams := make([]string, 0)
+	for _, event := range m.Events {
+		streams = append(streams, fmt.Sprintf("%s%s", strings.ToLower(symbol), event))
+	}
+
+	return streams
+}
+
+// getConnection returns connection with enough free streams or opens new one
+func (m *SubscriptionManager) getConnection(streams int) client.StreamConnectionInterface {
+	for _, connection := range m.connections {
+		if len(connection.GetStreams())+streams <= m.MaxStreams {
+			return connection
+		}
+	}
+
+	connection := m.Connect(m.BatchOffset + int64(len(m.connections)))
+	m.connections = append(m.connections, connection)
+
+	return connection
+}
@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"gitlab.com/open-soft/go-crypto-bot/src/client"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
 	ExchangeModel "gitlab.com/open-soft/go-crypto-bot/src/model"
 	"gitlab.com/open-soft/go-crypto-bot/src/repository"
 	"log"
@@ -16,15 +17,33 @@ type SwapExecutorInterface interface {
 }
 
 type SwapExecutor struct {
-	SwapRepository  repository.SwapBasicRepositoryInterface
-	OrderRepository repository.OrderUpdaterInterface
-	BalanceService  BalanceServiceInterface
-	Binance         client.ExchangeOrderAPIInterface
-	TimeService     TimeServiceInterface
-	Formatter       *Formatter
+	SwapRepository     repository.SwapBasicRepositoryInterface
+	OrderRepository    repository.OrderUpdaterInterface
+	BalanceService     BalanceServiceInterface
+	Binance            client.ExchangeOrderAPIInterface
+	TimeService        TimeServiceInterface
+	Formatter          *Formatter
+	OrderEventRecorder OrderEventRecorderInterface
+	ShutdownService    ShutdownServiceInterface
+	LotLedger          LotLedgerInterface
+	TimeInForce        string // time in force of swap legs (GTC or FOK), rollback and force legs are always IOC
+}
+
+func (s *SwapExecutor) GetTimeInForce() string {
+	if s.TimeInForce == "" {
+		return ExchangeModel.TimeInForceGtc
+	}
+
+	return s.TimeInForce
 }
 
 func (s *SwapExecutor) Execute(order ExchangeModel.Order) {
+	if !s.ShutdownService.StartOperation() {
+		log.Printf("[%s] Swap processing is postponed, shutdown in progress", order.Symbol)
+		return
+	}
+	defer s.ShutdownService.FinishOperation()
+
 	swapAction, err := s.SwapRepository.GetActiveSwapAction(order)
 
 	if err != nil {
@@ -57,12 +76,16 @@ func (s *SwapExecutor) Execute(order ExchangeModel.Order) {
 	if swapOneOrder == nil {
 		return
 	}
+	metrics.SwapLegsTotal.WithLabelValues("one", "filled").Inc()
+	s.recordLot(swapAction, *swapOneOrder)
 
 	swapTwoOrder := s.ExecuteSwapTwo(&swapAction, swapChain, *swapOneOrder)
 
 	if swapTwoOrder == nil {
 		return
 	}
+	metrics.SwapLegsTotal.WithLabelValues("two", "filled").Inc()
+	s.recordLot(swapAction, *swapTwoOrder)
 
 	assetTwo := strings.ReplaceAll(swapOneOrder.Symbol, swapAction.Asset, "")
 
@@ -72,6 +95,8 @@ func (s *SwapExecutor) Execute(order ExchangeModel.Order) {
 	if swapThreeOrder == nil {
 		return
 	}
+	metrics.SwapLegsTotal.WithLabelValues("three", "filled").Inc()
+	s.recordLot(swapAction, *swapThreeOrder)
 
 	endQuantity := swapThreeOrder.ExecutedQty
 	if swapChain.IsSBS() {
@@ -97,6 +122,44 @@ func (s *SwapExecutor) Execute(order ExchangeModel.Order) {
 		balanceBefore,
 		balanceAfter,
 	)
+	s.recordSwapEvent(&swapAction, *swapThreeOrder, ExchangeModel.OrderEventSwapFinished, fmt.Sprintf(
+		"Swap [%d] finished, %s %f -> %f",
+		swapAction.Id,
+		swapAction.Asset,
+		swapAction.StartQuantity,
+		endQuantity,
+	))
+}
+
+func (s *SwapExecutor) recordSwapEvent(swapAction *ExchangeModel.SwapAction, binanceOrder ExchangeModel.BinanceOrder, eventType string, details string) {
+	event := binanceOrder.GetOrderEvent(eventType, &swapAction.OrderId)
+	event.Details = &details
+	s.OrderEventRecorder.Record(event)
+
+	switch eventType {
+	case ExchangeModel.OrderEventSwapProgress:
+		metrics.SwapLegsTotal.WithLabelValues(s.getSwapLeg(swapAction, binanceOrder), "placed").Inc()
+	case ExchangeModel.OrderEventSwapCancelled:
+		metrics.SwapLegsTotal.WithLabelValues(s.getSwapLeg(swapAction, binanceOrder), "cancelled").Inc()
+	}
+}
+
+// recordLot records filled leg, leg is recorded once even if swap is processed again after restart
+func (s *SwapExecutor) recordLot(swapAction ExchangeModel.SwapAction, binanceOrder ExchangeModel.BinanceOrder) {
+	if s.LotLedger != nil {
+		s.LotLedger.RecordSwapLeg(swapAction, binanceOrder)
+	}
+}
+
+func (s *SwapExecutor) getSwapLeg(swapAction *ExchangeModel.SwapAction, binanceOrder ExchangeModel.BinanceOrder) string {
+	switch binanceOrder.Symbol {
+	case swapAction.SwapThreeSymbol:
+		return "three"
+	case swapAction.SwapTwoSymbol:
+		return "two"
+	default:
+		return "one"
+	}
 }
 
 func (s *SwapExecutor) ExecuteSwapOne(swapAction *ExchangeModel.SwapAction, order ExchangeModel.Order) *ExchangeModel.BinanceOrder {
@@ -109,7 +172,7 @@ func (s *SwapExecutor) ExecuteSwapOne(swapAction *ExchangeModel.SwapAction, orde
 			s.Formatter.FormatQuantity(swapPair, swapAction.StartQuantity),
 			s.Formatter.FormatPrice(swapPair, swapAction.SwapOnePrice),
 			"SELL",
-			"GTC",
+			s.GetTimeInForce(),
 		)
 
 		if err != nil {
@@ -131,6 +194,12 @@ func (s *SwapExecutor) ExecuteSwapOne(swapAction *ExchangeModel.SwapAction, orde
 			_ = s.OrderRepository.Update(order)
 			// invalidate balance cache
 			s.BalanceService.InvalidateBalanceCache(swapAction.Asset)
+			s.recordSwapEvent(swapAction, binanceOrder, ExchangeModel.OrderEventSwapCancelled, fmt.Sprintf(
+				"Swap [%d] one %s failed: %s",
+				swapAction.Id,
+				swapAction.SwapOneSymbol,
+				err.Error(),
+			))
 			return nil
 		}
 
@@ -140,6 +209,12 @@ func (s *SwapExecutor) ExecuteSwapOne(swapAction *ExchangeModel.SwapAction, orde
 		swapAction.SwapOneTimestamp = &nowTimestamp
 		swapAction.SwapOneExternalStatus = &binanceOrder.Status
 		_ = s.SwapRepository.UpdateSwapAction(*swapAction)
+		s.recordSwapEvent(swapAction, binanceOrder, ExchangeModel.OrderEventSwapProgress, fmt.Sprintf(
+			"Swap [%d] one %s %s placed",
+			swapAction.Id,
+			binanceOrder.Side,
+			binanceOrder.Symbol,
+		))
 	} else {
 		binanceOrder, err := s.Binance.QueryOrder(swapAction.SwapOneSymbol, *swapAction.SwapOneExternalId)
 		if err != nil {
@@ -202,6 +277,12 @@ func (s *SwapExecutor) ExecuteSwapOne(swapAction *ExchangeModel.SwapAction, orde
 				break
 			}
 
+			// swap action state is saved, processing will be continued after restart
+			if s.ShutdownService.IsShuttingDown() {
+				log.Printf("[%s] Swap [%d] one is parked, shutdown in progress", binanceOrder.Symbol, swapAction.Id)
+				return nil
+			}
+
 			// todo: timeout... cancel and remove swap action...
 
 			if binanceOrder.IsCanceled() || binanceOrder.IsExpired() {
@@ -216,6 +297,12 @@ func (s *SwapExecutor) ExecuteSwapOne(swapAction *ExchangeModel.SwapAction, orde
 				// invalidate balance cache
 				s.BalanceService.InvalidateBalanceCache(swapAction.Asset)
 				log.Printf("[%s] Swap one process cancelled, cancel all the operation!", order.Symbol)
+				s.recordSwapEvent(swapAction, binanceOrder, ExchangeModel.OrderEventSwapCancelled, fmt.Sprintf(
+					"Swap [%d] one %s is %s",
+					swapAction.Id,
+					binanceOrder.Symbol,
+					binanceOrder.Status,
+				))
 
 				return nil
 			}
@@ -235,6 +322,11 @@ func (s *SwapExecutor) ExecuteSwapOne(swapAction *ExchangeModel.SwapAction, orde
 					// invalidate balance cache
 					s.BalanceService.InvalidateBalanceCache(swapAction.Asset)
 					log.Printf("[%s] Swap process cancelled, couldn't be processed more than 60 seconds", order.Symbol)
+					s.recordSwapEvent(swapAction, cancelOrder, ExchangeModel.OrderEventSwapCancelled, fmt.Sprintf(
+						"Swap [%d] one %s is not processed more than 60 seconds",
+						swapAction.Id,
+						cancelOrder.Symbol,
+					))
 
 					return nil
 				}
@@ -287,7 +379,7 @@ func (s *SwapExecutor) ExecuteSwapTwo(
 				s.Formatter.FormatQuantity(swapPair, quantity),
 				s.Formatter.FormatPrice(swapPair, swapAction.SwapTwoPrice),
 				"SELL",
-				"GTC",
+				s.GetTimeInForce(),
 			)
 		}
 
@@ -297,7 +389,7 @@ func (s *SwapExecutor) ExecuteSwapTwo(
 				s.Formatter.FormatQuantity(swapPair, quantity/swapAction.SwapTwoPrice),
 				s.Formatter.FormatPrice(swapPair, swapAction.SwapTwoPrice),
 				"BUY",
-				"GTC",
+				s.GetTimeInForce(),
 			)
 		}
 
@@ -317,6 +409,12 @@ func (s *SwapExecutor) ExecuteSwapTwo(
 		swapAction.SwapTwoTimestamp = &nowTimestamp
 		swapAction.SwapTwoExternalStatus = &binanceOrder.Status
 		_ = s.SwapRepository.UpdateSwapAction(*swapAction)
+		s.recordSwapEvent(swapAction, binanceOrder, ExchangeModel.OrderEventSwapProgress, fmt.Sprintf(
+			"Swap [%d] two %s %s placed",
+			swapAction.Id,
+			binanceOrder.Side,
+			binanceOrder.Symbol,
+		))
 	} else {
 		binanceOrder, err := s.Binance.QueryOrder(swapAction.SwapTwoSymbol, *swapAction.SwapTwoExternalId)
 		if err != nil {
@@ -376,6 +474,12 @@ func (s *SwapExecutor) ExecuteSwapTwo(
 				break
 			}
 
+			// swap action state is saved, processing will be continued after restart
+			if s.ShutdownService.IsShuttingDown() {
+				log.Printf("[%s] Swap [%d] two is parked, shutdown in progress", binanceOrder.Symbol, swapAction.Id)
+				return nil
+			}
+
 			if binanceOrder.IsCanceled() || binanceOrder.IsExpired() {
 				swapAction.SwapTwoExternalId = nil
 				swapAction.SwapTwoTimestamp = nil
@@ -447,7 +551,7 @@ func (s *SwapExecutor) ExecuteSwapThree(
 				s.Formatter.FormatQuantity(swapPair, quantity/swapAction.SwapThreePrice),
 				s.Formatter.FormatPrice(swapPair, swapAction.SwapThreePrice),
 				"BUY",
-				"GTC",
+				s.GetTimeInForce(),
 			)
 		}
 
@@ -457,7 +561,7 @@ func (s *SwapExecutor) ExecuteSwapThree(
 				s.Formatter.FormatQuantity(swapPair, quantity),
 				s.Formatter.FormatPrice(swapPair, swapAction.SwapThreePrice),
 				"SELL",
-				"GTC",
+				s.GetTimeInForce(),
 			)
 		}
 
@@ -477,6 +581,12 @@ func (s *SwapExecutor) ExecuteSwapThree(
 		swapAction.SwapThreeTimestamp = &nowTimestamp
 		swapAction.SwapThreeExternalStatus = &binanceOrder.Status
 		_ = s.SwapRepository.UpdateSwapAction(*swapAction)
+		s.recordSwapEvent(swapAction, binanceOrder, ExchangeModel.OrderEventSwapProgress, fmt.Sprintf(
+			"Swap [%d] three %s %s placed",
+			swapAction.Id,
+			binanceOrder.Side,
+			binanceOrder.Symbol,
+		))
 	} else {
 		binanceOrder, err := s.Binance.QueryOrder(swapAction.SwapThreeSymbol, *swapAction.SwapThreeExternalId)
 		if err != nil {
@@ -537,6 +647,12 @@ func (s *SwapExecutor) ExecuteSwapThree(
 				break
 			}
 
+			// swap action state is saved, processing will be continued after restart
+			if s.ShutdownService.IsShuttingDown() {
+				log.Printf("[%s] Swap [%d] three is parked, shutdown in progress", binanceOrder.Symbol, swapAction.Id)
+				return nil
+			}
+
 			if binanceOrder.IsCanceled() || binanceOrder.IsExpired() {
 				swapAction.SwapThreeExternalId = nil
 				swapAction.SwapThreeTimestamp = nil
@@ -692,6 +808,15 @@ func (s *SwapExecutor) TryRollbackSwapTwo(
 			if err != nil {
 				panic(err)
 			}
+			metrics.SwapRollbacksTotal.WithLabelValues("rollback").Inc()
+			s.recordLot(*action, binanceOrder)
+			s.recordSwapEvent(action, binanceOrder, ExchangeModel.OrderEventSwapRollback, fmt.Sprintf(
+				"Swap [%d] two rolled back, %s %f -> %f",
+				action.Id,
+				action.Asset,
+				action.StartQuantity,
+				binanceOrder.ExecutedQty,
+			))
 			return nil
 		} else {
 			return errors.New(fmt.Sprintf("Can't rollback swap, percent is too low: %.2f%s", percent, "%"))
@@ -843,6 +968,15 @@ func (s *SwapExecutor) TryForceSwapThree(
 			if err != nil {
 				panic(err)
 			}
+			metrics.SwapRollbacksTotal.WithLabelValues("force").Inc()
+			s.recordLot(*swapAction, binanceOrder)
+			s.recordSwapEvent(swapAction, binanceOrder, ExchangeModel.OrderEventSwapFinished, fmt.Sprintf(
+				"Swap [%d] three forced, %s %f -> %f",
+				swapAction.Id,
+				swapAction.Asset,
+				swapAction.StartQuantity,
+				*swapAction.EndQuantity,
+			))
 			return nil
 		} else {
 			return errors.New(fmt.Sprintf("Can't force swap, percent is too low: %.2f%s", percent, "%"))
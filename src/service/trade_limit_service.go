@@ -0,0 +1,541 @@
+package service
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gitlab.com/open-soft/go-crypto-bot/src/client"
+	ExchangeModel "gitlab.com/open-soft/go-crypto-bot/src/model"
+	ExchangeRepository "gitlab.com/open-soft/go-crypto-bot/src/repository"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+var tradeLimitCsvHeader = []string{
+	"symbol",
+	"USDTLimit",
+	"minPrice",
+	"minQuantity",
+	"minNotional",
+	"minProfitPercent",
+	"isEnabled",
+	"minPriceMinutesPeriod",
+	"frameInterval",
+	"framePeriod",
+	"buyPriceHistoryCheckInterval",
+	"buyPriceHistoryCheckPeriod",
+	"extraChargeOptions",
+	"mode",
+	"gridOptions",
+	"takeProfitOptions",
+	"executionOptions",
+	"orderType",
+}
+
+var tradeLimitIntervals = []string{"1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w"}
+
+type TradeLimitService struct {
+	ExchangeRepository ExchangeRepository.TradeLimitStorageInterface
+	OrderRepository    ExchangeRepository.OrderStorageInterface
+	Binance            client.ExchangeTradeLimitAPIInterface
+	ChangeListener     TradeLimitChangeListenerInterface
+}
+
+// CheckRemovable refuses removing symbol with opened position or order on exchange
+func (t *TradeLimitService) CheckRemovable(limit ExchangeModel.TradeLimit) error {
+	opened := t.getOpenedTrade(limit.Symbol)
+	if opened != "" {
+		return errors.New(fmt.Sprintf("Trade limit %s has %s", limit.Symbol, opened))
+	}
+
+	return nil
+}
+
+// CheckModeChange refuses switching to grid mode while position or order on exchange is opened,
+// they are traded by maker only and would be left without sell in grid mode.
+// Grid is stopped by GridService when trade limit leaves grid mode
+func (t *TradeLimitService) CheckModeChange(before ExchangeModel.TradeLimit, after ExchangeModel.TradeLimit) error {
+	if before.IsGrid() || !after.IsGrid() {
+		return nil
+	}
+
+	opened := t.getOpenedTrade(before.Symbol)
+	if opened != "" {
+		return errors.New(fmt.Sprintf("Trade limit %s can not be switched to grid mode, it has %s", before.Symbol, opened))
+	}
+
+	return nil
+}
+
+func (t *TradeLimitService) getOpenedTrade(symbol string) string {
+	_, err := t.OrderRepository.GetOpenedOrderCached(symbol, "BUY")
+	if err == nil {
+		return "opened position"
+	}
+
+	for _, operation := range []string{"BUY", "SELL"} {
+		if t.OrderRepository.GetBinanceOrder(symbol, operation) != nil {
+			return fmt.Sprintf("opened %s order on exchange", operation)
+		}
+	}
+
+	// cache could be lost or order could be placed manually
+	openedOrders, err := t.Binance.GetOpenedOrders()
+	if err != nil {
+		return fmt.Sprintf("orders on exchange which are not loaded: %s", err.Error())
+	}
+	for _, openedOrder := range openedOrders {
+		if openedOrder.Symbol == symbol {
+			return fmt.Sprintf("opened %s order on exchange", openedOrder.Side)
+		}
+	}
+
+	return ""
+}
+
+func (t *TradeLimitService) Delete(symbol string) error {
+	limit, err := t.ExchangeRepository.GetTradeLimit(symbol)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Trade limit %s is not found", symbol))
+	}
+
+	err = t.CheckRemovable(limit)
+	if err != nil {
+		return err
+	}
+
+	return t.ExchangeRepository.DeleteTradeLimit(limit)
+}
+
+func (t *TradeLimitService) Archive(symbol string) error {
+	limit, err := t.ExchangeRepository.GetTradeLimit(symbol)
+	if err != nil {
+		return errors.New(fmt.Sprintf("Trade limit %s is not found", symbol))
+	}
+
+	err = t.CheckRemovable(limit)
+	if err != nil {
+		return err
+	}
+
+	return t.ExchangeRepository.ArchiveTradeLimit(limit)
+}
+
+// Validate checks limits and takes min price, quantity and notional from exchange filters
+func (t *TradeLimitService) Validate(limits []ExchangeModel.TradeLimit) ([]ExchangeModel.TradeLimit, []ExchangeModel.TradeLimitViolation) {
+	violations := make([]ExchangeModel.TradeLimitViolation, 0)
+	symbols := make([]string, 0)
+
+	for _, limit := range limits {
+		if limit.Symbol == "" {
+			violations = append(violations, ExchangeModel.TradeLimitViolation{Message: "symbol is required"})
+			continue
+		}
+		if slices.Contains(symbols, limit.Symbol) {
+			violations = append(violations, ExchangeModel.TradeLimitViolation{Symbol: limit.Symbol, Message: "symbol is duplicated"})
+			continue
+		}
+		symbols = append(symbols, limit.Symbol)
+	}
+
+	if len(violations) > 0 || len(symbols) == 0 {
+		return limits, violations
+	}
+
+	exchangeInfo, err := t.Binance.GetExchangeData(symbols)
+	if err != nil {
+		return limits, append(violations, ExchangeModel.TradeLimitViolation{Message: fmt.Sprintf("Exchange info: %s", err.Error())})
+	}
+
+	exchangeSymbols := make(map[string]ExchangeModel.ExchangeSymbol)
+	for _, exchangeSymbol := range exchangeInfo.Symbols {
+		exchangeSymbols[exchangeSymbol.Symbol] = exchangeSymbol
+	}
+
+	validated := make([]ExchangeModel.TradeLimit, 0)
+	for _, limit := range limits {
+		exchangeSymbol, ok := exchangeSymbols[limit.Symbol]
+		if !ok {
+			violations = append(violations, ExchangeModel.TradeLimitViolation{Symbol: limit.Symbol, Message: "symbol is not found on exchange"})
+			continue
+		}
+		if !exchangeSymbol.IsTrading() {
+			violations = append(violations, ExchangeModel.TradeLimitViolation{Symbol: limit.Symbol, Message: fmt.Sprintf("symbol status is %s", exchangeSymbol.Status)})
+			continue
+		}
+
+		for _, filter := range exchangeSymbol.Filters {
+			if filter.FilterType == "PRICE_FILTER" && filter.MinPrice != nil {
+				limit.MinPrice = *filter.MinPrice
+			}
+			if filter.FilterType == "LOT_SIZE" && filter.MinQuantity != nil {
+				limit.MinQuantity = *filter.MinQuantity
+			}
+			if filter.FilterType == "NOTIONAL" && filter.MinNotional != nil {
+				limit.MinNotional = *filter.MinNotional
+			}
+		}
+
+		for _, message := range t.getViolations(limit) {
+			violations = append(violations, ExchangeModel.TradeLimitViolation{Symbol: limit.Symbol, Message: message})
+		}
+
+		validated = append(validated, limit)
+	}
+
+	return validated, violations
+}
+
+func (t *TradeLimitService) getViolations(limit ExchangeModel.TradeLimit) []string {
+	messages := make([]string, 0)
+
+	if limit.USDTLimit < limit.MinNotional {
+		messages = append(messages, fmt.Sprintf("USDTLimit %f is less than exchange min notional %f", limit.USDTLimit, limit.MinNotional))
+	}
+	if limit.MinProfitPercent <= 0 {
+		messages = append(messages, "minProfitPercent must be positive")
+	}
+	if limit.FrameInterval != "" && !slices.Contains(tradeLimitIntervals, limit.FrameInterval) {
+		messages = append(messages, fmt.Sprintf("frameInterval %s is invalid", limit.FrameInterval))
+	}
+	if limit.BuyPriceHistoryCheckInterval != "" && !slices.Contains(tradeLimitIntervals, limit.BuyPriceHistoryCheckInterval) {
+		messages = append(messages, fmt.Sprintf("buyPriceHistoryCheckInterval %s is invalid", limit.BuyPriceHistoryCheckInterval))
+	}
+	for _, option := range limit.ExtraChargeOptions {
+		if option.Percent >= 0 {
+			messages = append(messages, fmt.Sprintf("extra charge %d percent must be negative", option.Index))
+		}
+		if option.AmountUsdt < limit.MinNotional {
+			messages = append(messages, fmt.Sprintf("extra charge %d amount %f is less than exchange min notional %f", option.Index, option.AmountUsdt, limit.MinNotional))
+		}
+	}
+	if !slices.Contains([]string{ExchangeModel.TradeLimitModePosition, ExchangeModel.TradeLimitModeGrid}, limit.GetMode()) {
+		messages = append(messages, fmt.Sprintf("mode %s is invalid, allowed: position, grid", limit.Mode))
+	}
+	if !slices.Contains([]string{ExchangeModel.OrderTypeLimit, ExchangeModel.OrderTypeLimitMaker}, limit.GetOrderType()) {
+		messages = append(messages, fmt.Sprintf("order type %s is invalid, allowed: LIMIT, LIMIT_MAKER", limit.OrderType))
+	}
+	if limit.IsGrid() {
+		messages = append(messages, getGridViolations(limit)...)
+	}
+	messages = append(messages, getTakeProfitViolations(limit)...)
+	messages = append(messages, getExecutionViolations(limit)...)
+
+	return messages
+}
+
+func (t *TradeLimitService) getModeChangeViolations(limits []ExchangeModel.TradeLimit) []ExchangeModel.TradeLimitViolation {
+	violations := make([]ExchangeModel.TradeLimitViolation, 0)
+
+	for _, limit := range limits {
+		existing, err := t.ExchangeRepository.GetTradeLimit(limit.Symbol)
+		if err != nil {
+			continue
+		}
+
+		err = t.CheckModeChange(existing, limit)
+		if err != nil {
+			violations = append(violations, ExchangeModel.TradeLimitViolation{Symbol: limit.Symbol, Message: err.Error()})
+		}
+	}
+
+	return violations
+}
+
+// Import creates new and updates existing limits by symbol (archived limit is restored),
+// limits are saved in one transaction, nothing is saved if any limit is invalid
+func (t *TradeLimitService) Import(limits []ExchangeModel.TradeLimit, dryRun bool) ExchangeModel.TradeLimitImportReport {
+	report := ExchangeModel.TradeLimitImportReport{
+		DryRun:     dryRun,
+		Created:    make([]string, 0),
+		Updated:    make([]string, 0),
+		Violations: make([]ExchangeModel.TradeLimitViolation, 0),
+	}
+
+	validated, violations := t.Validate(limits)
+	if len(violations) == 0 {
+		violations = t.getModeChangeViolations(validated)
+	}
+	if len(violations) > 0 {
+		report.Violations = violations
+
+		return report
+	}
+
+	existingLimits := make(map[string]ExchangeModel.TradeLimit)
+	for index, limit := range validated {
+		existing, err := t.ExchangeRepository.GetTradeLimit(limit.Symbol)
+		if err == nil {
+			validated[index].Id = existing.Id
+			existingLimits[limit.Symbol] = existing
+			report.Updated = append(report.Updated, limit.Symbol)
+		} else {
+			report.Created = append(report.Created, limit.Symbol)
+		}
+	}
+
+	if dryRun {
+		return report
+	}
+
+	err := t.ExchangeRepository.ImportTradeLimits(validated)
+	if err != nil {
+		report.Created = make([]string, 0)
+		report.Updated = make([]string, 0)
+		report.Violations = append(report.Violations, ExchangeModel.TradeLimitViolation{Message: fmt.Sprintf("Import is rolled back: %s", err.Error())})
+
+		return report
+	}
+
+	if t.ChangeListener != nil {
+		for _, limit := range validated {
+			existing, exists := existingLimits[limit.Symbol]
+			if exists {
+				t.ChangeListener.OnTradeLimitChange(existing, limit)
+			}
+		}
+	}
+
+	return report
+}
+
+func (t *TradeLimitService) WriteCsv(writer io.Writer, limits []ExchangeModel.TradeLimit) error {
+	csvWriter := csv.NewWriter(writer)
+	err := csvWriter.Write(tradeLimitCsvHeader)
+	if err != nil {
+		return err
+	}
+
+	for _, limit := range limits {
+		extraChargeOptions := getCsvOptions(limit.ExtraChargeOptions, len(limit.ExtraChargeOptions) == 0)
+		gridOptions := getCsvOptions(limit.GridOptions, limit.GridOptions == ExchangeModel.GridOptions{})
+		takeProfitOptions := getCsvOptions(limit.TakeProfitOptions, len(limit.TakeProfitOptions) == 0)
+		executionOptions := getCsvOptions(limit.ExecutionOptions, limit.ExecutionOptions.Algorithm == "")
+		err = csvWriter.Write([]string{
+			limit.Symbol,
+			strconv.FormatFloat(limit.USDTLimit, 'f', -1, 64),
+			strconv.FormatFloat(limit.MinPrice, 'f', -1, 64),
+			strconv.FormatFloat(limit.MinQuantity, 'f', -1, 64),
+			strconv.FormatFloat(limit.MinNotional, 'f', -1, 64),
+			strconv.FormatFloat(limit.MinProfitPercent, 'f', -1, 64),
+			strconv.FormatBool(limit.IsEnabled),
+			strconv.FormatInt(limit.MinPriceMinutesPeriod, 10),
+			limit.FrameInterval,
+			strconv.FormatInt(limit.FramePeriod, 10),
+			limit.BuyPriceHistoryCheckInterval,
+			strconv.FormatInt(limit.BuyPriceHistoryCheckPeriod, 10),
+			extraChargeOptions,
+			limit.Mode,
+			gridOptions,
+			takeProfitOptions,
+			executionOptions,
+			limit.OrderType,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	csvWriter.Flush()
+
+	return csvWriter.Error()
+}
+
+// getCsvOptions is JSON of options column, empty options are written as empty value
+func getCsvOptions(options any, isEmpty bool) string {
+	if isEmpty {
+		return ""
+	}
+
+	encoded, _ := json.Marshal(options)
+
+	return string(encoded)
+}
+
+// ReadCsv reads columns by header name, columns with options are JSON
+func (t *TradeLimitService) ReadCsv(reader io.Reader) ([]ExchangeModel.TradeLimit, error) {
+	records, err := csv.NewReader(reader).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, errors.New("CSV header is required")
+	}
+
+	columns := make(map[string]int)
+	for index, name := range records[0] {
+		columns[strings.TrimSpace(name)] = index
+	}
+	if _, ok := columns["symbol"]; !ok {
+		return nil, errors.New("CSV column symbol is required")
+	}
+
+	limits := make([]ExchangeModel.TradeLimit, 0)
+	for line, record := range records[1:] {
+		value := func(name string) string {
+			index, ok := columns[name]
+			if !ok || index >= len(record) {
+				return ""
+			}
+
+			return strings.TrimSpace(record[index])
+		}
+		limit := ExchangeModel.TradeLimit{
+			Symbol:                       strings.ToUpper(value("symbol")),
+			FrameInterval:                value("frameInterval"),
+			BuyPriceHistoryCheckInterval: value("buyPriceHistoryCheckInterval"),
+			ExtraChargeOptions:           make(ExchangeModel.ExtraChargeOptions, 0),
+			Mode:                         value("mode"),
+			OrderType:                    value("orderType"),
+		}
+
+		floats := map[string]*float64{
+			"USDTLimit":        &limit.USDTLimit,
+			"minPrice":         &limit.MinPrice,
+			"minQuantity":      &limit.MinQuantity,
+			"minNotional":      &limit.MinNotional,
+			"minProfitPercent": &limit.MinProfitPercent,
+		}
+		for name, target := range floats {
+			if value(name) == "" {
+				continue
+			}
+			*target, err = strconv.ParseFloat(value(name), 64)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("CSV line %d: %s is invalid", line+2, name))
+			}
+		}
+
+		integers := map[string]*int64{
+			"minPriceMinutesPeriod":      &limit.MinPriceMinutesPeriod,
+			"framePeriod":                &limit.FramePeriod,
+			"buyPriceHistoryCheckPeriod": &limit.BuyPriceHistoryCheckPeriod,
+		}
+		for name, target := range integers {
+			if value(name) == "" {
+				continue
+			}
+			*target, err = strconv.ParseInt(value(name), 10, 64)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("CSV line %d: %s is invalid", line+2, name))
+			}
+		}
+
+		if value("isEnabled") != "" {
+			limit.IsEnabled, err = strconv.ParseBool(value("isEnabled"))
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("CSV line %d: isEnabled is invalid", line+2))
+			}
+		}
+
+		if value("extraChargeOptions") != "" {
+			err = json.Unmarshal([]byte(value("extraChargeOptions")), &limit.ExtraChargeOptions)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("CSV line %d: extraChargeOptions is invalid", line+2))
+			}
+		}
+
+		if value("gridOptions") != "" {
+			err = json.Unmarshal([]byte(value("gridOptions")), &limit.GridOptions)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("CSV line %d: gridOptions is invalid", line+2))
+			}
+		}
+
+		if value("takeProfitOptions") != "" {
+			err = json.Unmarshal([]byte(value("takeProfitOptions")), &limit.TakeProfitOptions)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("CSV line %d: takeProfitOptions is invalid", line+2))
+			}
+		}
+
+		if value("executionOptions") != "" {
+			err = json.Unmarshal([]byte(value("executionOptions")), &limit.ExecutionOptions)
+			if err != nil {
+				return nil, errors.New(fmt.Sprintf("CSV line %d: executionOptions is invalid", line+2))
+			}
+		}
+
+		limits = append(limits, limit)
+	}
+
+	return limits, nil
+}
+
+// getTakeProfitViolations checks tiers of take-profit ladder, tiers can't sell more than position quantity,
+// the only trailing tier sells the rest after the last target
+func getTakeProfitViolations(limit ExchangeModel.TradeLimit) []string {
+	messages := make([]string, 0)
+	percents := make([]ExchangeModel.Percent, 0)
+	quantityPercent := 0.00
+	trailingTiers := 0
+
+	for _, option := range limit.TakeProfitOptions {
+		if option.Percent <= 0 {
+			messages = append(messages, fmt.Sprintf("take profit %d percent must be positive", option.Index))
+		}
+		if slices.Contains(percents, option.Percent) {
+			messages = append(messages, fmt.Sprintf("take profit %d percent %.2f is duplicated", option.Index, option.Percent.Value()))
+		}
+		if option.TrailingPercent < 0 || option.TrailingPercent >= 100 {
+			messages = append(messages, fmt.Sprintf("take profit %d trailing percent must be between 0 and 100", option.Index))
+		}
+		if option.IsTrailing() && option.QuantityPercent != 0 {
+			messages = append(messages, fmt.Sprintf("take profit %d trailing tier sells the rest of position, quantity percent must be 0", option.Index))
+		}
+		if !option.IsTrailing() && option.QuantityPercent <= 0 {
+			messages = append(messages, fmt.Sprintf("take profit %d quantity percent must be positive", option.Index))
+		}
+		percents = append(percents, option.Percent)
+		quantityPercent += option.QuantityPercent
+	}
+
+	trailing, hasTrailing := limit.TakeProfitOptions.GetTrailing()
+	for _, option := range limit.TakeProfitOptions {
+		if option.IsTrailing() {
+			trailingTiers++
+		} else if hasTrailing && option.Percent >= trailing.Percent {
+			messages = append(messages, fmt.Sprintf("take profit %d trailing tier percent must be the highest", trailing.Index))
+		}
+	}
+	if trailingTiers > 1 {
+		messages = append(messages, "take profit can have only one trailing tier")
+	}
+
+	if quantityPercent > 100 {
+		messages = append(messages, fmt.Sprintf("take profit quantity percent sum %.2f is greater than 100", quantityPercent))
+	}
+
+	return messages
+}
+
+// getExecutionViolations checks that every child order of execution algorithm can pass exchange filters
+func getExecutionViolations(limit ExchangeModel.TradeLimit) []string {
+	messages := make([]string, 0)
+	options := limit.ExecutionOptions
+
+	switch options.Algorithm {
+	case "":
+	case ExchangeModel.ExecutionAlgorithmTwap:
+		if options.Minutes <= 0 {
+			messages = append(messages, "twap minutes must be positive")
+		}
+		if options.Slices < 2 {
+			messages = append(messages, "twap slices must be at least 2")
+		}
+		if options.Slices > 0 && limit.USDTLimit/float64(options.Slices) < limit.MinNotional {
+			messages = append(messages, fmt.Sprintf("twap slice amount %f is less than exchange min notional %f", limit.USDTLimit/float64(options.Slices), limit.MinNotional))
+		}
+	case ExchangeModel.ExecutionAlgorithmIceberg:
+		if options.ClipUsdt < limit.MinNotional {
+			messages = append(messages, fmt.Sprintf("iceberg clip %f is less than exchange min notional %f", options.ClipUsdt, limit.MinNotional))
+		}
+	default:
+		messages = append(messages, fmt.Sprintf("execution algorithm %s is invalid, allowed: twap, iceberg", options.Algorithm))
+	}
+
+	return messages
+}
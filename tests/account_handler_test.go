@@ -0,0 +1,128 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/config"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestContainerBotHandler(t *testing.T) {
+	assertion := assert.New(t)
+
+	container := config.Container{CurrentBot: &model.Bot{Id: 1, BotUuid: "main-uuid"}}
+	handler := container.GetBotHandler(map[string]http.Handler{
+		"main-uuid": http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte("main"))
+		}),
+		"account-uuid": http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			_, _ = w.Write([]byte("account"))
+		}),
+	})
+
+	request := func(uri string, botUuid string) string {
+		req := httptest.NewRequest("GET", uri, nil)
+		if botUuid != "" {
+			req.Header.Set(model.ApiHeaderBotUuid, botUuid)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, req)
+
+		return recorder.Body.String()
+	}
+
+	assertion.Equal("main", request("/health/check", ""))
+	assertion.Equal("account", request("/health/check", "account-uuid"))
+	assertion.Equal("account", request("/health/check?botUuid=account-uuid", ""))
+	// header has priority, unknown bot is handled (and rejected) by main bot
+	assertion.Equal("main", request("/health/check?botUuid=account-uuid", "main-uuid"))
+	assertion.Equal("main", request("/health/check", "unknown-uuid"))
+}
+
+func TestAccountSecretIsStoredEncrypted(t *testing.T) {
+	assertion := assert.New(t)
+
+	storage := &AccountStorageMock{}
+	accountService := service.AccountService{BotRepository: storage, SecretKey: "account-key"}
+
+	account, err := accountService.Create("api-key", "api-secret")
+	assertion.Nil(err)
+	assertion.Equal("api-secret", account.BinanceApiSecret)
+	assertion.Len(storage.Accounts, 1)
+	assertion.Equal(account.BotUuid, storage.Accounts[0].BotUuid)
+	assertion.Equal("api-key", storage.Accounts[0].BinanceApiKey)
+	assertion.True(strings.HasPrefix(storage.Accounts[0].BinanceApiSecret, "aes:"))
+	assertion.NotContains(storage.Accounts[0].BinanceApiSecret, "api-secret")
+
+	accounts := accountService.GetAccounts()
+	assertion.Len(accounts, 1)
+	assertion.Equal("api-secret", accounts[0].BinanceApiSecret)
+
+	// secret can't be decrypted by another key, account is skipped
+	otherService := service.AccountService{BotRepository: storage, SecretKey: "other-key"}
+	assertion.Len(otherService.GetAccounts(), 0)
+
+	// account can't be created without key
+	_, err = (&service.AccountService{BotRepository: storage}).Create("api-key", "api-secret")
+	assertion.NotNil(err)
+	assertion.Len(storage.Accounts, 1)
+}
+
+func TestAccountPlaintextSecretIsEncrypted(t *testing.T) {
+	assertion := assert.New(t)
+
+	storage := &AccountStorageMock{Accounts: []model.Bot{
+		{Id: 2, BotUuid: "account-uuid", BinanceApiKey: "api-key", BinanceApiSecret: "api-secret"},
+	}}
+	accountService := service.AccountService{BotRepository: storage, SecretKey: "account-key"}
+
+	accounts := accountService.GetAccounts()
+	assertion.Len(accounts, 1)
+	assertion.Equal("api-secret", accounts[0].BinanceApiSecret)
+	assertion.True(strings.HasPrefix(storage.Accounts[0].BinanceApiSecret, "aes:"))
+
+	accounts = accountService.GetAccounts()
+	assertion.Equal("api-secret", accounts[0].BinanceApiSecret)
+}
+
+func TestAccountsAreIsolated(t *testing.T) {
+	assertion := assert.New(t)
+
+	storage := &AccountStorageMock{}
+	accountService := service.AccountService{BotRepository: storage, SecretKey: "account-key"}
+	first, _ := accountService.Create("first-key", "first-secret")
+	second, _ := accountService.Create("second-key", "second-secret")
+
+	botConfig := config.DefaultConfig()
+	botConfig.Bot.Uuid = "main-uuid"
+	botConfig.Bot.IsMasterBot = true
+	botConfig.Binance.ApiKey = "main-key"
+	botConfig.Binance.ApiSecret = "main-secret"
+	mainBot := &model.Bot{Id: 1, BotUuid: "main-uuid"}
+	container := config.Container{CurrentBot: mainBot, Config: &botConfig, AccountService: &accountService}
+
+	container.InitAccounts()
+
+	assertion.Len(container.GetBots(), 3)
+	for index, account := range []model.Bot{first, second} {
+		accountContainer := container.Accounts[index]
+		assertion.Equal(account.BotUuid, accountContainer.CurrentBot.BotUuid)
+		assertion.Equal(account.BinanceApiKey, accountContainer.Binance.ApiKey)
+		assertion.Equal(account.BinanceApiSecret, accountContainer.Binance.ApiSecret)
+		assertion.False(accountContainer.IsMasterBot)
+		// orders and balances are stored by account, market data is read from main bot
+		assertion.Equal(accountContainer.CurrentBot, accountContainer.OrderRepository.CurrentBot)
+		assertion.Equal(accountContainer.CurrentBot, accountContainer.BalanceService.CurrentBot)
+		assertion.Equal(mainBot, accountContainer.ExchangeRepository.MarketBot)
+	}
+	assertion.NotEqual(container.Accounts[0].CurrentBot.Id, container.Accounts[1].CurrentBot.Id)
+	assertion.NotSame(container.Accounts[0].OrderExecutor, container.Accounts[1].OrderExecutor)
+	assertion.NotSame(container.Accounts[0].Binance, container.Accounts[1].Binance)
+	// main config is not changed by accounts
+	assertion.Equal("main-secret", botConfig.Binance.ApiSecret)
+	assertion.True(botConfig.Bot.IsMasterBot)
+}
@@ -0,0 +1,109 @@
+package tests
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/client"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestApiAuthenticatorSignedRequest(t *testing.T) {
+	assertion := assert.New(t)
+
+	apiClientRepository := new(ApiClientStorageMock)
+	authenticator := service.ApiAuthenticator{
+		ApiClientRepository: apiClientRepository,
+		CurrentBot:          &model.Bot{Id: 999, BotUuid: "bot-uuid"},
+		RecvWindow:          5000,
+		LegacyScope:         model.ApiScopeRead,
+	}
+
+	apiClient := model.ApiClient{
+		Id:        1,
+		Name:      "trader",
+		ApiKey:    "key",
+		ApiSecret: "secret",
+		Scope:     model.ApiScopeTrade,
+		IsEnabled: true,
+	}
+	apiClientRepository.On("FindByApiKey", "key").Return(apiClient, nil)
+	apiClientRepository.On("Touch", apiClient)
+
+	body := `{"symbol":"ETHUSDT"}`
+	timestamp := time.Now().UnixMilli()
+	signature := client.SignApiRequest("secret", timestamp, "POST", "/order", []byte(body))
+
+	request := httptest.NewRequest("POST", "/order", strings.NewReader(body))
+	request.Header.Set(model.ApiHeaderKey, "key")
+	request.Header.Set(model.ApiHeaderTimestamp, fmt.Sprintf("%d", timestamp))
+	request.Header.Set(model.ApiHeaderSignature, signature)
+	apiClientRepository.On("RememberSignature", "key", signature, mock.Anything).Once().Return(true)
+
+	authorized, err := authenticator.Authenticate(request, model.ApiScopeTrade)
+	assertion.Nil(err)
+	assertion.Equal(int64(1), authorized.Id)
+
+	// replay of the same request
+	replayed := httptest.NewRequest("POST", "/order", strings.NewReader(body))
+	replayed.Header = request.Header.Clone()
+	apiClientRepository.On("RememberSignature", "key", signature, mock.Anything).Once().Return(false)
+	_, err = authenticator.Authenticate(replayed, model.ApiScopeTrade)
+	assertion.EqualError(err, "Request is already processed")
+
+	// body is changed
+	tampered := httptest.NewRequest("POST", "/order", strings.NewReader(`{"symbol":"BTCUSDT"}`))
+	tampered.Header = request.Header.Clone()
+	_, err = authenticator.Authenticate(tampered, model.ApiScopeTrade)
+	assertion.EqualError(err, "Signature is invalid")
+
+	// timestamp is outside of recvWindow
+	stale := httptest.NewRequest("POST", "/order", strings.NewReader(body))
+	stale.Header = request.Header.Clone()
+	staleTimestamp := timestamp - 60000
+	stale.Header.Set(model.ApiHeaderTimestamp, fmt.Sprintf("%d", staleTimestamp))
+	stale.Header.Set(model.ApiHeaderSignature, client.SignApiRequest("secret", staleTimestamp, "POST", "/order", []byte(body)))
+	_, err = authenticator.Authenticate(stale, model.ApiScopeTrade)
+	assertion.EqualError(err, "Request timestamp is outside of recvWindow")
+
+	// trade scope is not enough for admin endpoint
+	adminTimestamp := time.Now().UnixMilli()
+	adminSignature := client.SignApiRequest("secret", adminTimestamp, "POST", "/trade/limit/create", []byte(body))
+	admin := httptest.NewRequest("POST", "/trade/limit/create", strings.NewReader(body))
+	admin.Header.Set(model.ApiHeaderKey, "key")
+	admin.Header.Set(model.ApiHeaderTimestamp, fmt.Sprintf("%d", adminTimestamp))
+	admin.Header.Set(model.ApiHeaderSignature, adminSignature)
+	apiClientRepository.On("RememberSignature", "key", adminSignature, mock.Anything).Once().Return(true)
+	_, err = authenticator.Authenticate(admin, model.ApiScopeAdmin)
+	assertion.EqualError(err, "Scope 'admin' is required")
+}
+
+func TestApiAuthenticatorLegacyBotUuid(t *testing.T) {
+	assertion := assert.New(t)
+
+	authenticator := service.ApiAuthenticator{
+		ApiClientRepository: new(ApiClientStorageMock),
+		CurrentBot:          &model.Bot{Id: 999, BotUuid: "bot-uuid"},
+		RecvWindow:          5000,
+		LegacyScope:         model.ApiScopeRead,
+	}
+
+	apiClient, err := authenticator.Authenticate(httptest.NewRequest("GET", "/order/list?botUuid=bot-uuid", nil), model.ApiScopeRead)
+	assertion.Nil(err)
+	assertion.True(apiClient.IsLegacy())
+
+	_, err = authenticator.Authenticate(httptest.NewRequest("POST", "/order?botUuid=bot-uuid", nil), model.ApiScopeTrade)
+	assertion.NotNil(err)
+
+	_, err = authenticator.Authenticate(httptest.NewRequest("GET", "/order/list?botUuid=wrong", nil), model.ApiScopeRead)
+	assertion.EqualError(err, "Forbidden")
+
+	authenticator.LegacyScope = ""
+	_, err = authenticator.Authenticate(httptest.NewRequest("GET", "/order/list?botUuid=bot-uuid", nil), model.ApiScopeRead)
+	assertion.EqualError(err, "Forbidden")
+}
@@ -0,0 +1,139 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/client"
+	"gitlab.com/open-soft/go-crypto-bot/src/controller"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestBotApiClientSignedRequest(t *testing.T) {
+	assertion := assert.New(t)
+
+	apiClientRepository := new(ApiClientStorageMock)
+	apiAuditRepository := new(ApiAuditStorageMock)
+	apiClient := model.ApiClient{
+		Id:        1,
+		Name:      "botctl",
+		ApiKey:    "key",
+		ApiSecret: "secret",
+		Scope:     model.ApiScopeAdmin,
+		IsEnabled: true,
+	}
+	apiClientRepository.On("FindByApiKey", "key").Return(apiClient, nil)
+	apiClientRepository.On("Touch", apiClient)
+	apiClientRepository.On("RememberSignature", "key", mock.Anything, mock.Anything).Return(true)
+	auditId := int64(1)
+	apiAuditRepository.On("CreateAudit", mock.Anything).Return(&auditId, nil)
+
+	var updated model.TradeLimit
+	router := &controller.Router{
+		Prefix: controller.ApiV1Prefix,
+		ApiGuard: &controller.ApiGuard{
+			ApiAuthenticator: &service.ApiAuthenticator{
+				ApiClientRepository: apiClientRepository,
+				CurrentBot:          &model.Bot{Id: 999, BotUuid: "bot-uuid"},
+				RecvWindow:          5000,
+			},
+			ApiAuditRepository: apiAuditRepository,
+			CorsOrigins:        []string{"*"},
+		},
+		Routes: []controller.Route{
+			{Method: "GET", Path: "/trade/limit/list", Scope: model.ApiScopeRead, Handler: func(w http.ResponseWriter, req *http.Request) {
+				encoded, _ := json.Marshal([]model.TradeLimit{{Id: 1, Symbol: "ETHUSDT", IsEnabled: true}})
+				_, _ = w.Write(encoded)
+			}},
+			{Method: "PUT", Path: "/trade/limit/update", Scope: model.ApiScopeAdmin, Handler: func(w http.ResponseWriter, req *http.Request) {
+				_ = json.NewDecoder(req.Body).Decode(&updated)
+				encoded, _ := json.Marshal(updated)
+				_, _ = w.Write(encoded)
+			}},
+		},
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	botApiClient := client.BotApiClient{Host: server.URL, ApiKey: "key", ApiSecret: "secret"}
+
+	limit, err := botApiClient.GetTradeLimit("ETHUSDT")
+	assertion.Nil(err)
+	limit.IsEnabled = false
+	result, err := botApiClient.UpdateTradeLimit(*limit)
+	assertion.Nil(err)
+	assertion.False(result.IsEnabled)
+	assertion.Equal("ETHUSDT", updated.Symbol)
+	apiAuditRepository.AssertNumberOfCalls(t, "CreateAudit", 1)
+
+	_, err = botApiClient.GetTradeLimit("BTCUSDT")
+	assertion.EqualError(err, "Trade limit BTCUSDT is not found")
+
+	// error envelope is returned as error
+	invalid := client.BotApiClient{Host: server.URL, ApiKey: "key", ApiSecret: "wrong"}
+	_, err = invalid.GetTradeLimits()
+	assertion.EqualError(err, "GET /trade/limit/list: 403 Signature is invalid")
+}
+
+func TestBotApiClientSwapActions(t *testing.T) {
+	assertion := assert.New(t)
+
+	status := "FILLED"
+	externalId := int64(1001)
+	requested := make([]string, 0)
+	router := &controller.Router{
+		Prefix: controller.ApiV1Prefix,
+		ApiGuard: &controller.ApiGuard{
+			ApiAuthenticator: &service.ApiAuthenticator{
+				CurrentBot:  &model.Bot{Id: 999, BotUuid: "bot-uuid"},
+				RecvWindow:  5000,
+				LegacyScope: model.ApiScopeRead,
+			},
+			ApiAuditRepository: new(ApiAuditStorageMock),
+			CorsOrigins:        []string{"*"},
+		},
+		Routes: []controller.Route{
+			{Method: "GET", Path: "/swap/action/list", Scope: model.ApiScopeRead, Handler: func(w http.ResponseWriter, req *http.Request) {
+				requested = append(requested, req.URL.Query().Get("limit"))
+				encoded, _ := json.Marshal([]model.SwapAction{{Id: 15, Status: model.SwapActionStatusProcess}})
+				_, _ = w.Write(encoded)
+			}},
+			{Method: "GET", Path: "/swap/action", Scope: model.ApiScopeRead, Handler: func(w http.ResponseWriter, req *http.Request) {
+				if req.URL.Query().Get("id") != "15" {
+					http.Error(w, "Swap action is not found", http.StatusNotFound)
+					return
+				}
+				encoded, _ := json.Marshal(model.SwapAction{
+					Id:                    15,
+					Status:                model.SwapActionStatusProcess,
+					SwapOneSymbol:         "ETHBTC",
+					SwapOneExternalId:     &externalId,
+					SwapOneExternalStatus: &status,
+					SwapTwoSymbol:         "XRPBTC",
+				})
+				_, _ = w.Write(encoded)
+			}},
+		},
+	}
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	botApiClient := client.BotApiClient{Host: server.URL, BotUuid: "bot-uuid"}
+
+	actions, err := botApiClient.GetSwapActions(20)
+	assertion.Nil(err)
+	assertion.Len(actions, 1)
+	assertion.Equal([]string{"20"}, requested)
+
+	action, err := botApiClient.GetSwapAction(15)
+	assertion.Nil(err)
+	assertion.Equal("FILLED", *action.SwapOneExternalStatus)
+	assertion.Nil(action.SwapTwoExternalStatus)
+
+	_, err = botApiClient.GetSwapAction(16)
+	assertion.EqualError(err, "GET /swap/action: 404 Swap action is not found")
+}
@@ -0,0 +1,78 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/config"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"os"
+	"testing"
+)
+
+func TestLoadConfigWithEnvOverride(t *testing.T) {
+	assertion := assert.New(t)
+
+	path := t.TempDir() + "/config.yaml"
+	_ = os.WriteFile(path, []byte(`
+bot:
+  uuid: 'file-uuid'
+  isMasterBot: false
+http:
+  port: 9090
+database:
+  dsn: 'root:secret@tcp(mysql:3306)/go_crypto_bot'
+redis:
+  dsn: 'redis:6379'
+binance:
+  apiKey: 'key'
+  apiSecret: 'secret'
+  wsDsn: 'wss://testnet.binance.vision/ws-api/v3'
+  streamDsn: 'wss://stream.binance.com'
+maker:
+  holdScore: 80
+`), 0644)
+
+	t.Setenv("BOT_UUID", "env-uuid")
+	t.Setenv("SHUTDOWN_TIMEOUT", "30")
+
+	botConfig, err := config.LoadConfig(path)
+
+	assertion.Nil(err)
+	assertion.Equal("env-uuid", botConfig.Bot.Uuid)
+	assertion.False(botConfig.Bot.IsMasterBot)
+	assertion.Equal(int64(9090), botConfig.Http.Port)
+	assertion.Equal(80.00, botConfig.Maker.HoldScore)
+	assertion.Equal(4.00, botConfig.Maker.MinDecisions)
+	assertion.Equal(64, botConfig.Database.MaxOpenConns)
+	assertion.Equal(int64(30), botConfig.Shutdown.Timeout)
+
+	redacted := botConfig.Redacted()
+	assertion.Equal(model.ConfigRedacted, redacted.Database.Dsn)
+	assertion.Equal(model.ConfigRedacted, redacted.Binance.ApiKey)
+	assertion.Equal(model.ConfigRedacted, redacted.Binance.ApiSecret)
+	assertion.Equal("", redacted.Redis.Password)
+	assertion.Equal("key", botConfig.Binance.ApiKey)
+}
+
+func TestValidateConfig(t *testing.T) {
+	assertion := assert.New(t)
+
+	botConfig := config.DefaultConfig()
+	botConfig.Http.Port = 0
+	botConfig.Maker.HoldScore = 120
+	botConfig.Swap.LegTimeInForce = "DAY"
+	botConfig.Bot.MultiAccount = true
+
+	err := config.ValidateConfig(botConfig)
+
+	assertion.NotNil(err)
+	assertion.Contains(err.Error(), "bot.uuid is required")
+	assertion.Contains(err.Error(), "binance.apiKey is required")
+	assertion.Contains(err.Error(), "http.port 0 is invalid")
+	assertion.Contains(err.Error(), "maker.holdScore must be between 0 and 100")
+	assertion.Contains(err.Error(), "swap.legTimeInForce must be one of: GTC, FOK")
+	assertion.Contains(err.Error(), "bot.accountSecretKey is required for multi account")
+
+	botConfig.Swap.LegTimeInForce = model.TimeInForceIoc
+	err = config.ValidateConfig(botConfig)
+	assertion.Contains(err.Error(), "swap.legTimeInForce must be one of: GTC, FOK")
+}
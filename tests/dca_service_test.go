@@ -0,0 +1,224 @@
+package tests
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"sync"
+	"testing"
+)
+
+func TestDcaPlanIsExecutedBelowPriceCeilingUntilBudgetIsSpent(t *testing.T) {
+	assertion := assert.New(t)
+
+	dcaRepository := &DcaStorageMock{}
+	orderExecutor := new(DcaExecutorMock)
+	exchangeRepository := new(ExchangeTradeInfoMock)
+	priceCalculator := new(PriceCalculatorMock)
+	timeService := new(TimeServiceMock)
+
+	dcaService := service.DcaService{
+		DcaRepository:      dcaRepository,
+		OrderExecutor:      orderExecutor,
+		ExchangeRepository: exchangeRepository,
+		PriceCalculator:    priceCalculator,
+		Formatter:          &service.Formatter{},
+		TimeService:        timeService,
+	}
+
+	tradeLimit := model.TradeLimit{
+		Symbol:      "ETHUSDT",
+		MinPrice:    0.01,
+		MinQuantity: 0.0001,
+		MinNotional: 5,
+	}
+	exchangeRepository.On("GetTradeLimit", "ETHUSDT").Return(tradeLimit, nil)
+	exchangeRepository.On("GetTradeLimit", "BTCUSDT").Return(model.TradeLimit{}, errors.New("sql: no rows in result set"))
+	priceCalculator.On("CalculateBuy", tradeLimit).Return(2100.00, nil)
+
+	_, err := dcaService.CreatePlan(model.DcaPlan{Symbol: "BTCUSDT", AmountUsdt: 100, IntervalHours: 24})
+	assertion.Equal("trade limit BTCUSDT is not found, it is required for exchange filters and price", err.Error())
+	_, err = dcaService.CreatePlan(model.DcaPlan{Symbol: "ETHUSDT", AmountUsdt: 100, IntervalHours: 24, BudgetUsdt: 50})
+	assertion.Equal("budget 50.000000 should be 0 (unlimited) or not less than amount 100.000000", err.Error())
+
+	plan, err := dcaService.CreatePlan(model.DcaPlan{
+		Symbol:             "ETHUSDT",
+		AmountUsdt:         100,
+		IntervalHours:      24,
+		MaxPrice:           2500,
+		UsePriceCalculator: true,
+		BudgetUsdt:         250,
+	})
+	assertion.Nil(err)
+	assertion.Equal(model.DcaPlanActive, plan.Status)
+
+	// price is above calculated buy price
+	timeService.On("GetNowUnix").Return(1000).Once()
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 2200}).Once()
+	dcaService.ExecuteAll()
+
+	plan, _ = dcaRepository.GetPlan(plan.Id)
+	assertion.Equal("Price 2200.000000 is above ceiling 2100.000000", plan.LastMessage)
+	assertion.Equal(int64(0), plan.NextBuyAt)
+	orderExecutor.AssertNotCalled(t, "BuyAccumulation")
+
+	// plan is retried on the next run
+	externalId := int64(11)
+	commission := 0.00005
+	commissionAsset := "ETH"
+	timeService.On("GetNowUnix").Return(1300).Once()
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 2000}).Times(3)
+	orderExecutor.On("BuyAccumulation", tradeLimit, 2000.00, 0.05, int64(120)).Return(model.Order{
+		Symbol:           "ETHUSDT",
+		Price:            2000,
+		ExecutedQuantity: 0.05,
+		ExternalId:       &externalId,
+		Commission:       &commission,
+		CommissionAsset:  &commissionAsset,
+	}, nil).Twice()
+	dcaService.ExecuteAll()
+
+	plan, _ = dcaRepository.GetPlan(plan.Id)
+	assertion.Equal("", plan.LastMessage)
+	assertion.Equal(int64(1), plan.Orders)
+	assertion.Equal(100.00, plan.SpentUsdt)
+	assertion.InDelta(0.04995, plan.Quantity, 0.0000001)
+	assertion.Equal(int64(1300+24*3600), plan.NextBuyAt)
+	assertion.Len(dcaRepository.Orders, 1)
+	assertion.Equal(int64(11), dcaRepository.Orders[0].ExternalId)
+
+	// plan is not due
+	timeService.On("GetNowUnix").Return(2000).Once()
+	dcaService.ExecuteAll()
+	assertion.Len(dcaRepository.Orders, 1)
+
+	// the last order is limited by remaining budget, then plan is completed
+	timeService.On("GetNowUnix").Return(1300 + 24*3600).Once()
+	dcaService.ExecuteAll()
+	timeService.On("GetNowUnix").Return(1300 + 48*3600).Once()
+	orderExecutor.On("BuyAccumulation", tradeLimit, 2000.00, 0.025, int64(120)).Return(model.Order{
+		Symbol:           "ETHUSDT",
+		Price:            2000,
+		ExecutedQuantity: 0.025,
+		ExternalId:       &externalId,
+	}, nil).Once()
+	dcaService.ExecuteAll()
+
+	plan, _ = dcaRepository.GetPlan(plan.Id)
+	assertion.Equal(model.DcaPlanCompleted, plan.Status)
+	assertion.Equal(int64(3), plan.Orders)
+	assertion.Equal(250.00, plan.SpentUsdt)
+	assertion.InDelta(2001.6, plan.GetAveragePrice(), 0.1)
+
+	// paused plan is kept
+	plan.Status = model.DcaPlanPaused
+	plan, err = dcaService.UpdatePlan(plan)
+	assertion.Nil(err)
+	assertion.Equal(250.00, plan.SpentUsdt)
+	timeService.On("GetNowUnix").Return(1300 + 96*3600).Once()
+	dcaService.ExecuteAll()
+	orderExecutor.AssertNumberOfCalls(t, "BuyAccumulation", 3)
+}
+
+func TestBuyAccumulationIsNotSavedAsPosition(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	balanceService := new(BalanceServiceMock)
+	timeService := new(TimeServiceMock)
+
+	orderExecutor := service.OrderExecutor{
+		CurrentBot: &model.Bot{
+			Id:      999,
+			BotUuid: uuid.New().String(),
+		},
+		TimeService:     timeService,
+		BalanceService:  balanceService,
+		Binance:         binance,
+		OrderRepository: new(OrderStorageMock),
+		CallbackManager: new(TelegramNotificatorMock),
+		Lock:            make(map[string]bool),
+		TradeLockMutex:  sync.RWMutex{},
+		ShutdownService: &service.ShutdownService{},
+	}
+
+	balanceService.On("GetAssetBalance", "USDT", true).Return(1000.00, nil)
+	balanceService.On("GetAssetBalance", "ETH", true).Return(1.00, nil).Once()
+	balanceService.On("GetAssetBalance", "ETH", true).Return(1.02997, nil).Once()
+	balanceService.On("InvalidateBalanceCache", mock.Anything)
+	timeService.On("GetNowDateTimeString").Return("2024-02-01 00:00:00")
+	timeService.On("GetNowUnix").Return(1000).Once()
+	timeService.On("GetNowUnix").Return(1060).Once()
+	timeService.On("GetNowUnix").Return(1130).Once()
+	timeService.On("WaitSeconds", int64(5)).Once()
+
+	// order is partially filled and canceled after ttl
+	binance.On("LimitOrder", "ETHUSDT", 0.05, 2000.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 21, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2000, OrigQty: 0.05,
+	}, nil)
+	binance.On("QueryOrder", "ETHUSDT", int64(21)).Return(model.BinanceOrder{
+		OrderId: 21, Symbol: "ETHUSDT", Side: "BUY", Status: "PARTIALLY_FILLED", Price: 2000, OrigQty: 0.05, ExecutedQty: 0.03, CummulativeQuoteQty: 60,
+	}, nil).Once()
+	binance.On("CancelOrder", "ETHUSDT", int64(21)).Return(model.BinanceOrder{}, nil).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(21)).Return(model.BinanceOrder{
+		OrderId: 21, Symbol: "ETHUSDT", Side: "BUY", Status: "CANCELED", Price: 2000, OrigQty: 0.05, ExecutedQty: 0.03, CummulativeQuoteQty: 60,
+	}, nil).Once()
+
+	order, err := orderExecutor.BuyAccumulation(model.TradeLimit{Symbol: "ETHUSDT"}, 2000.00, 0.05, 120)
+	assertion.Nil(err)
+	assertion.Equal(int64(21), *order.ExternalId)
+	assertion.Equal(0.03, order.ExecutedQuantity)
+	assertion.Equal(2000.00, order.Price)
+	assertion.InDelta(0.00003, *order.Commission, 0.0000001)
+	assertion.Equal("ETH", *order.CommissionAsset)
+	binance.AssertNumberOfCalls(t, "CancelOrder", 1)
+}
+
+func TestBuyAccumulationGivesUpWhenOrderIsNotCanceled(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	balanceService := new(BalanceServiceMock)
+	timeService := new(TimeServiceMock)
+
+	orderExecutor := service.OrderExecutor{
+		CurrentBot: &model.Bot{
+			Id:      999,
+			BotUuid: uuid.New().String(),
+		},
+		TimeService:     timeService,
+		BalanceService:  balanceService,
+		Binance:         binance,
+		OrderRepository: new(OrderStorageMock),
+		CallbackManager: new(TelegramNotificatorMock),
+		Lock:            make(map[string]bool),
+		TradeLockMutex:  sync.RWMutex{},
+		ShutdownService: &service.ShutdownService{},
+	}
+
+	balanceService.On("GetAssetBalance", "USDT", true).Return(1000.00, nil)
+	balanceService.On("GetAssetBalance", "ETH", true).Return(1.00, nil)
+	balanceService.On("InvalidateBalanceCache", mock.Anything)
+	timeService.On("GetNowUnix").Return(1000).Once()
+	timeService.On("GetNowUnix").Return(1130)
+	timeService.On("WaitSeconds", int64(5))
+
+	// ttl is reached, cancel is rejected and order stays NEW
+	binance.On("LimitOrder", "ETHUSDT", 0.05, 2000.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 22, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2000, OrigQty: 0.05,
+	}, nil)
+	binance.On("CancelOrder", "ETHUSDT", int64(22)).Return(model.BinanceOrder{}, errors.New("Order was not canceled due to cancel restrictions."))
+	binance.On("QueryOrder", "ETHUSDT", int64(22)).Return(model.BinanceOrder{
+		OrderId: 22, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2000, OrigQty: 0.05,
+	}, nil)
+
+	_, err := orderExecutor.BuyAccumulation(model.TradeLimit{Symbol: "ETHUSDT"}, 2000.00, 0.05, 120)
+	assertion.EqualError(err, "binance order [22] is not canceled after 5 attempts, it is left on exchange: Order was not canceled due to cancel restrictions.")
+	binance.AssertNumberOfCalls(t, "CancelOrder", 5)
+	binance.AssertNumberOfCalls(t, "QueryOrder", 4)
+	timeService.AssertNumberOfCalls(t, "WaitSeconds", 4)
+	assertion.False(orderExecutor.IsTradeLocked("ETHUSDT"))
+}
@@ -0,0 +1,125 @@
+package tests
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"io/ioutil"
+	"testing"
+)
+
+func TestDecisionJournalRepeatedSkip(t *testing.T) {
+	assertion := assert.New(t)
+
+	timeService := new(TimeServiceMock)
+	storage := &DecisionJournalStorageMock{}
+	decisionJournal := service.DecisionJournal{
+		DecisionJournalRepository: storage,
+		TimeService:               timeService,
+		RepeatSeconds:             300,
+	}
+
+	skip := model.DecisionJournalEntry{
+		Symbol:    "BTCUSDT",
+		Operation: model.DecisionJournalBuy,
+		Result:    model.DecisionJournalSkipped,
+		Reason:    "Trade Stack check is not passed",
+	}
+
+	timeService.On("GetNowUnix").Return(1000).Times(2)
+	decisionJournal.Record(skip)
+	decisionJournal.Record(skip)
+	assertion.Len(storage.Entries, 1)
+
+	// another symbol and another reason are not repeated
+	ethSkip := skip
+	ethSkip.Symbol = "ETHUSDT"
+	timeService.On("GetNowUnix").Return(1100).Times(2)
+	decisionJournal.Record(ethSkip)
+	otherSkip := skip
+	otherSkip.Reason = "Too small BIDs amount"
+	decisionJournal.Record(otherSkip)
+	assertion.Len(storage.Entries, 3)
+
+	timeService.On("GetNowUnix").Return(1500).Once()
+	decisionJournal.Record(otherSkip)
+	assertion.Len(storage.Entries, 4)
+
+	// executed decision is always recorded and resets the last skip
+	decisionJournal.Record(model.DecisionJournalEntry{
+		Symbol:    "BTCUSDT",
+		Operation: model.DecisionJournalBuy,
+		Result:    model.DecisionJournalExecuted,
+	})
+	timeService.On("GetNowUnix").Return(1510).Once()
+	decisionJournal.Record(otherSkip)
+	assertion.Len(storage.Entries, 6)
+
+	// nil journal is disabled
+	var disabled *service.DecisionJournal
+	disabled.Record(skip)
+}
+
+func TestCalculateBuyExplained(t *testing.T) {
+	assertion := assert.New(t)
+
+	content, _ := ioutil.ReadFile("example/ethusdt@depth.json")
+	var depth model.DepthEvent
+	json.Unmarshal(content, &depth)
+
+	exchangeRepoMock := new(ExchangePriceStorageMock)
+	orderRepositoryMock := new(OrderCachedReaderMock)
+	frameServiceMock := new(FrameServiceMock)
+	lossSecurityMock := new(LossSecurityMock)
+
+	priceCalculator := service.PriceCalculator{
+		LossSecurity:       lossSecurityMock,
+		ExchangeRepository: exchangeRepoMock,
+		OrderRepository:    orderRepositoryMock,
+		FrameService:       frameServiceMock,
+		Binance:            new(ExchangePriceAPIMock),
+		Formatter:          &service.Formatter{},
+	}
+
+	tradeLimit := model.TradeLimit{
+		Symbol:                "ETHUSDT",
+		MinPrice:              0.01,
+		MinQuantity:           0.0001,
+		MinProfitPercent:      2.50,
+		MinPriceMinutesPeriod: 200,
+		FrameInterval:         "2h",
+		FramePeriod:           20,
+	}
+	exchangeRepoMock.On("GetDepth", "ETHUSDT").Return(depth.Depth)
+	exchangeRepoMock.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{
+		Close: 1474.64,
+	})
+	exchangeRepoMock.On("GetPeriodMinPrice", "ETHUSDT", int64(200)).Return(900.00)
+	orderRepositoryMock.On("GetOpenedOrderCached", "ETHUSDT", "BUY").Return(model.Order{}, errors.New("Order is not found"))
+	frameServiceMock.On("GetFrame", "ETHUSDT", "2h", int64(20)).Return(model.Frame{
+		High:    1480.00,
+		Low:     1250.30,
+		AvgHigh: 1400.00,
+		AvgLow:  1300.00,
+	})
+	lossSecurityMock.On("CheckBuyPriceOnHistory", tradeLimit, 900.00).Return(880.00)
+	lossSecurityMock.On("BuyPriceCorrection", 880.00, tradeLimit).Return(870.00)
+
+	calculation, err := priceCalculator.CalculateBuyExplained(tradeLimit)
+	assertion.Nil(err)
+	assertion.Equal(870.00, calculation.Price)
+	assertion.False(calculation.ExtraCharge)
+	assertion.Equal(1474.64, calculation.LastPrice)
+	assertion.Equal(900.00, calculation.MinPrice)
+	assertion.Equal(1400.00, calculation.Frame.AvgHigh)
+	assertion.Equal([]model.PriceCorrection{
+		{Name: "history_check", From: 900.00, To: 880.00},
+		{Name: "buy_price_correction", From: 880.00, To: 870.00},
+	}, calculation.Corrections)
+
+	price, err := priceCalculator.CalculateBuy(tradeLimit)
+	assertion.Nil(err)
+	assertion.Equal(870.00, price)
+}
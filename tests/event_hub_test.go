@@ -0,0 +1,111 @@
+package tests
+
+import (
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/controller"
+	"gitlab.com/open-soft/go-crypto-bot/src/event"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventHubSymbolSubscription(t *testing.T) {
+	assertion := assert.New(t)
+
+	hub := event.Hub{}
+	subscriber := hub.Subscribe(1)
+	subscriber.Subscribe(event.TopicSwap, []string{})
+	subscriber.Subscribe(event.TopicOrder, []string{"ETHUSDT"})
+	assertion.True(hub.HasSubscribers(event.TopicOrder))
+	assertion.False(hub.HasSubscribers(event.TopicPosition))
+
+	orderEventRepository := new(OrderEventStorageMock)
+	eventId := int64(10)
+	orderEventRepository.On("Create", mock.Anything).Return(&eventId, nil)
+	recorder := service.OrderEventRecorder{
+		OrderEventRepository: orderEventRepository,
+		EventHub:             &hub,
+	}
+
+	recorder.Record(model.OrderEvent{Symbol: "BTCUSDT", Operation: "BUY", Type: model.OrderEventBuyPlaced})
+	assertion.Len(subscriber.Messages, 0)
+
+	recorder.Record(model.OrderEvent{Symbol: "ETHUSDT", Operation: "BUY", Type: model.OrderEventFilled})
+	assertion.Len(subscriber.Messages, 1)
+
+	// buffer is full, message is dropped instead of blocking
+	recorder.Record(model.OrderEvent{Symbol: "BTCUSDT", Operation: "SELL", Type: model.OrderEventSwapProgress})
+	assertion.Len(subscriber.Messages, 1)
+
+	message := <-subscriber.Messages
+	assertion.Equal(event.TopicOrder, message.Topic)
+	assertion.Equal("ETHUSDT", message.Symbol)
+	assertion.Equal(int64(10), message.Payload.(model.OrderEvent).Id)
+
+	recorder.Record(model.OrderEvent{Symbol: "BTCUSDT", Operation: "SELL", Type: model.OrderEventSwapProgress})
+	message = <-subscriber.Messages
+	assertion.Equal(event.TopicSwap, message.Topic)
+
+	hub.Unsubscribe(subscriber)
+	assertion.False(hub.HasSubscribers(event.TopicSwap))
+	hub.Publish(event.TopicSwap, "BTCUSDT", nil)
+
+	var nilHub *event.Hub
+	nilHub.Publish(event.TopicDecision, "BTCUSDT", nil)
+}
+
+func TestWebsocketControllerStream(t *testing.T) {
+	assertion := assert.New(t)
+
+	hub := event.Hub{}
+	websocketController := controller.WebsocketController{
+		EventHub:    &hub,
+		CorsOrigins: []string{"*"},
+	}
+	router := getTestRouter()
+	router.Routes = append(router.Routes, controller.Route{
+		Method:  "GET",
+		Path:    "/ws",
+		Scope:   model.ApiScopeRead,
+		Handler: websocketController.GetStreamAction,
+	})
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	connection, _, err := websocket.DefaultDialer.Dial(
+		"ws"+strings.TrimPrefix(server.URL, "http")+"/api/v1/ws?botUuid=bot-uuid&topics=decision&symbols=ethusdt",
+		nil,
+	)
+	assertion.Nil(err)
+	defer connection.Close()
+	_ = connection.SetReadDeadline(time.Now().Add(time.Second * 5))
+
+	var reply controller.WebsocketReply
+	assertion.Nil(connection.WriteJSON(controller.WebsocketCommand{Action: "subscribe", Topic: "unknown"}))
+	assertion.Nil(connection.ReadJSON(&reply))
+	assertion.Equal("Topic 'unknown' is invalid", reply.Error)
+
+	var subscribed controller.WebsocketReply
+	assertion.Nil(connection.WriteJSON(controller.WebsocketCommand{Action: "subscribe", Topic: event.TopicOrder}))
+	assertion.Nil(connection.ReadJSON(&subscribed))
+	assertion.Equal("", subscribed.Error)
+	assertion.Equal(event.TopicOrder, subscribed.Topic)
+
+	hub.Publish(event.TopicDecision, "BTCUSDT", model.Decision{StrategyName: model.SmaTradeStrategyName})
+	hub.Publish(event.TopicDecision, "ETHUSDT", model.Decision{StrategyName: model.BaseKlineStrategyName, Operation: "BUY"})
+
+	var message struct {
+		Topic   string         `json:"topic"`
+		Symbol  string         `json:"symbol"`
+		Payload model.Decision `json:"payload"`
+	}
+	assertion.Nil(connection.ReadJSON(&message))
+	assertion.Equal(event.TopicDecision, message.Topic)
+	assertion.Equal("ETHUSDT", message.Symbol)
+	assertion.Equal(model.BaseKlineStrategyName, message.Payload.StrategyName)
+}
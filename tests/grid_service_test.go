@@ -0,0 +1,276 @@
+package tests
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"testing"
+)
+
+func TestGridLevelsAreFilledAndReplaced(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	exchangeRepository := new(GridTradeInfoMock)
+	balanceService := new(BalanceServiceMock)
+	timeService := new(TimeServiceMock)
+	gridRepository := &GridStorageMock{}
+	orderRepository := &OrderCreatorMock{}
+
+	gridService := service.GridService{
+		GridRepository:     gridRepository,
+		OrderRepository:    orderRepository,
+		ExchangeRepository: exchangeRepository,
+		Binance:            binance,
+		BalanceService:     balanceService,
+		Formatter:          &service.Formatter{},
+		TimeService:        timeService,
+		ShutdownService:    &service.ShutdownService{},
+	}
+
+	tradeLimit := model.TradeLimit{
+		Symbol:      "ETHUSDT",
+		USDTLimit:   400,
+		IsEnabled:   true,
+		MinPrice:    0.01,
+		MinQuantity: 0.0001,
+		MinNotional: 5,
+		Mode:        model.TradeLimitModeGrid,
+		GridOptions: model.GridOptions{LowerPrice: 2000, UpperPrice: 2400, Levels: 4},
+	}
+	exchangeRepository.On("GetTradeLimits").Return([]model.TradeLimit{tradeLimit}).Times(3)
+	balanceService.On("GetAssetBalance", "USDT", true).Return(1000.00, nil)
+	balanceService.On("GetAssetBalance", "ETH", true).Return(1.00, nil)
+	balanceService.On("InvalidateBalanceCache", mock.Anything)
+	timeService.On("GetNowDateTimeString").Return("2024-02-01 00:00:00")
+	binance.On("GetTrades", mock.Anything).Return([]model.MyTrade{
+		{OrderId: 1, Commission: 0.00005, CommissionAsset: "ETH"},
+		{OrderId: 3, Commission: 0.1, CommissionAsset: "USDT"},
+	}, nil)
+
+	// buy orders are placed only for levels below the current price
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 2150}).Once()
+	binance.On("LimitOrderWithClientId", "ETHUSDT", 0.05, 2000.00, "BUY", "GTC", mock.Anything).Return(model.BinanceOrder{OrderId: 1, Side: "BUY", Price: 2000}, nil).Once()
+	binance.On("LimitOrderWithClientId", "ETHUSDT", 0.0476, 2100.00, "BUY", "GTC", mock.Anything).Return(model.BinanceOrder{OrderId: 2, Side: "BUY", Price: 2100}, nil).Once()
+
+	gridService.SyncAll()
+
+	grids := gridRepository.GetActiveGrids()
+	assertion.Len(grids, 1)
+	assertion.Len(grids[0].GridLevels, 4)
+	assertion.Equal(2300.00, grids[0].GridLevels[3].BuyPrice)
+	assertion.Equal(2400.00, grids[0].GridLevels[3].SellPrice)
+	assertion.Equal(model.GridLevelBuyPlaced, grids[0].GridLevels[0].Status)
+	assertion.Equal(model.GridLevelBuyPlaced, grids[0].GridLevels[1].Status)
+	assertion.Equal(model.GridLevelIdle, grids[0].GridLevels[2].Status)
+
+	// filled buy is replaced by sell at the next level price, base asset commission is not sold
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 2050}).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(1)).Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "FILLED", Price: 2000, OrigQty: 0.05, ExecutedQty: 0.05, CummulativeQuoteQty: 100,
+	}, nil).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(2)).Return(model.BinanceOrder{
+		OrderId: 2, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2100, OrigQty: 0.0476,
+	}, nil).Times(3)
+	binance.On("LimitOrderWithClientId", "ETHUSDT", 0.0499, 2100.00, "SELL", "GTC", mock.Anything).Return(model.BinanceOrder{OrderId: 3, Side: "SELL", Price: 2100}, nil).Once()
+
+	gridService.SyncAll()
+
+	level := gridRepository.GetActiveGrids()[0].GridLevels[0]
+	assertion.Equal(model.GridLevelSellPlaced, level.Status)
+	assertion.Equal(int64(3), *level.ExternalId)
+	assertion.Equal(2000.00, level.BoughtPrice)
+	assertion.Equal(0.00005, level.BuyCommission)
+
+	// filled sell realizes PnL and level buys again
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 2150}).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(3)).Return(model.BinanceOrder{
+		OrderId: 3, Symbol: "ETHUSDT", Side: "SELL", Status: "FILLED", Price: 2100, OrigQty: 0.0499, ExecutedQty: 0.0499, CummulativeQuoteQty: 104.79,
+	}, nil).Once()
+	binance.On("LimitOrderWithClientId", "ETHUSDT", 0.05, 2000.00, "BUY", "GTC", mock.Anything).Return(model.BinanceOrder{OrderId: 4, Side: "BUY", Price: 2000}, nil).Once()
+
+	gridService.SyncAll()
+
+	grid := gridRepository.GetActiveGrids()[0]
+	level = grid.GridLevels[0]
+	assertion.Equal(model.GridLevelBuyPlaced, level.Status)
+	assertion.Equal(int64(4), *level.ExternalId)
+	assertion.Nil(level.BuyOrderId)
+	assertion.Equal(int64(1), level.Trades)
+	assertion.Equal(int64(1), grid.Trades)
+	assertion.Equal(0.1, grid.Fees)
+	assertion.InDelta(104.79-0.1-100*0.0499/0.04995, grid.RealizedPnl, 0.000001)
+
+	assertion.Len(orderRepository.Orders, 2)
+	assertion.Equal("buy", orderRepository.Orders[0].Operation)
+	assertion.Equal("closed", orderRepository.Orders[0].Status)
+	assertion.Equal(int64(1), *orderRepository.Orders[0].ExternalId)
+	assertion.Equal("sell", orderRepository.Orders[1].Operation)
+	assertion.Equal(int64(1), *orderRepository.Orders[1].ClosesOrder)
+	binance.AssertNumberOfCalls(t, "LimitOrderWithClientId", 4)
+
+	// trade limit is switched to position mode: resting orders are canceled, bought quantity is kept
+	positionLimit := tradeLimit
+	positionLimit.Mode = model.TradeLimitModePosition
+	exchangeRepository.On("GetTradeLimits").Return([]model.TradeLimit{positionLimit}).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(4)).Return(model.BinanceOrder{
+		OrderId: 4, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2000, OrigQty: 0.05,
+	}, nil).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(4)).Return(model.BinanceOrder{
+		OrderId: 4, Symbol: "ETHUSDT", Side: "BUY", Status: "CANCELED", Price: 2000, OrigQty: 0.05,
+	}, nil).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(2)).Return(model.BinanceOrder{
+		OrderId: 2, Symbol: "ETHUSDT", Side: "BUY", Status: "CANCELED", Price: 2100, OrigQty: 0.0476, ExecutedQty: 0.02, CummulativeQuoteQty: 42,
+	}, nil).Once()
+	binance.On("CancelOrder", "ETHUSDT", int64(4)).Return(model.BinanceOrder{}, nil).Once()
+	binance.On("CancelOrder", "ETHUSDT", int64(2)).Return(model.BinanceOrder{}, nil).Once()
+
+	gridService.SyncAll()
+
+	assertion.Len(gridRepository.GetActiveGrids(), 0)
+	grid = gridRepository.GetGrids("ETHUSDT")[0]
+	assertion.Equal(model.GridStopped, grid.Status)
+	assertion.Equal(model.GridLevelIdle, grid.GridLevels[0].Status)
+	assertion.Nil(grid.GridLevels[0].ExternalId)
+	assertion.Equal(model.GridLevelBought, grid.GridLevels[1].Status)
+	assertion.Equal(0.02, grid.GridLevels[1].BoughtQuantity)
+	assertion.Equal(2100.00, grid.GridLevels[1].BoughtPrice)
+}
+
+func TestGridTradeLimitIsValidated(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeInfoAPIMock)
+	tradeLimitService := service.TradeLimitService{
+		ExchangeRepository: new(TradeLimitStorageMock),
+		OrderRepository:    new(OrderStorageMock),
+		Binance:            binance,
+	}
+
+	minPrice := 0.01
+	minQuantity := 0.0001
+	minNotional := 5.00
+	binance.On("GetExchangeData", mock.Anything).Return(&model.ExchangeInfo{Symbols: []model.ExchangeSymbol{
+		{Symbol: "ETHUSDT", Status: "TRADING", Filters: []model.ExchangeFilter{
+			{FilterType: "PRICE_FILTER", MinPrice: &minPrice},
+			{FilterType: "LOT_SIZE", MinQuantity: &minQuantity},
+			{FilterType: "NOTIONAL", MinNotional: &minNotional},
+		}},
+	}}, nil)
+
+	report := tradeLimitService.Import([]model.TradeLimit{{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        40,
+		MinProfitPercent: 1,
+		Mode:             model.TradeLimitModeGrid,
+		GridOptions:      model.GridOptions{LowerPrice: 2400, UpperPrice: 2000, Levels: 10},
+	}}, false)
+
+	assertion.False(report.IsValid())
+	assertion.Len(report.Violations, 2)
+	assertion.Equal("grid upper price 2000.000000 should be greater than lower price 2400.000000", report.Violations[0].Message)
+	assertion.Equal("grid level amount 4.000000 is less than exchange min notional 5.000000", report.Violations[1].Message)
+}
+
+func TestTradeLimitWithPositionIsNotSwitchedToGrid(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeInfoAPIMock)
+	exchangeRepository := new(TradeLimitStorageMock)
+	orderRepository := new(OrderStorageMock)
+	tradeLimitService := service.TradeLimitService{
+		ExchangeRepository: exchangeRepository,
+		OrderRepository:    orderRepository,
+		Binance:            binance,
+	}
+
+	minNotional := 5.00
+	binance.On("GetExchangeData", mock.Anything).Return(&model.ExchangeInfo{Symbols: []model.ExchangeSymbol{
+		{Symbol: "ETHUSDT", Status: "TRADING", Filters: []model.ExchangeFilter{
+			{FilterType: "NOTIONAL", MinNotional: &minNotional},
+		}},
+	}}, nil)
+	exchangeRepository.On("GetTradeLimit", "ETHUSDT").Return(model.TradeLimit{Id: 1, Symbol: "ETHUSDT", USDTLimit: 100, MinProfitPercent: 1}, nil)
+	orderRepository.On("GetOpenedOrderCached", "ETHUSDT", "BUY").Return(model.Order{Id: 10, Symbol: "ETHUSDT"}, nil)
+
+	report := tradeLimitService.Import([]model.TradeLimit{{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        100,
+		MinProfitPercent: 1,
+		Mode:             model.TradeLimitModeGrid,
+		GridOptions:      model.GridOptions{LowerPrice: 2000, UpperPrice: 2400, Levels: 10},
+	}}, false)
+
+	assertion.False(report.IsValid())
+	assertion.Len(report.Violations, 1)
+	assertion.Equal("Trade limit ETHUSDT can not be switched to grid mode, it has opened position", report.Violations[0].Message)
+	exchangeRepository.AssertNotCalled(t, "UpdateTradeLimit", mock.Anything)
+}
+
+func TestGridLevelOrderIsRecoveredByClientOrderId(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	exchangeRepository := new(GridTradeInfoMock)
+	balanceService := new(BalanceServiceMock)
+	gridRepository := &GridStorageMock{}
+
+	gridService := service.GridService{
+		GridRepository:     gridRepository,
+		OrderRepository:    &OrderCreatorMock{},
+		ExchangeRepository: exchangeRepository,
+		Binance:            binance,
+		BalanceService:     balanceService,
+		Formatter:          &service.Formatter{},
+		TimeService:        new(TimeServiceMock),
+		ShutdownService:    &service.ShutdownService{},
+	}
+
+	tradeLimit := model.TradeLimit{
+		Symbol:      "ETHUSDT",
+		USDTLimit:   200,
+		IsEnabled:   true,
+		MinPrice:    0.01,
+		MinQuantity: 0.0001,
+		MinNotional: 5,
+		Mode:        model.TradeLimitModeGrid,
+		GridOptions: model.GridOptions{LowerPrice: 2000, UpperPrice: 2200, Levels: 2},
+	}
+	exchangeRepository.On("GetTradeLimits").Return([]model.TradeLimit{tradeLimit})
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 2250})
+	balanceService.On("GetAssetBalance", "USDT", true).Return(1000.00, nil)
+	balanceService.On("InvalidateBalanceCache", mock.Anything)
+
+	// exchange response is lost, levels are kept with client order id
+	binance.On("LimitOrderWithClientId", "ETHUSDT", mock.Anything, mock.Anything, "BUY", "GTC", mock.Anything).Return(model.BinanceOrder{}, errors.New("timeout")).Twice()
+
+	gridService.SyncAll()
+
+	levels := gridRepository.GetActiveGrids()[0].GridLevels
+	assertion.Equal(model.GridLevelBuyPlaced, levels[0].Status)
+	assertion.NotNil(levels[0].ClientOrderId)
+	assertion.Nil(levels[0].ExternalId)
+
+	// first order was placed and is recovered, second was not placed and is placed again
+	binance.On("QueryOrderByClientId", "ETHUSDT", *levels[0].ClientOrderId).Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2000, OrigQty: 0.05,
+	}, nil).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(1)).Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2000, OrigQty: 0.05,
+	}, nil).Once()
+	binance.On("QueryOrderByClientId", "ETHUSDT", *levels[1].ClientOrderId).Return(model.BinanceOrder{}, errors.New("Order does not exist.")).Once()
+	binance.On("LimitOrderWithClientId", "ETHUSDT", 0.0476, 2100.00, "BUY", "GTC", mock.Anything).Return(model.BinanceOrder{OrderId: 2, Side: "BUY", Price: 2100}, nil).Once()
+
+	gridService.SyncAll()
+
+	levels = gridRepository.GetActiveGrids()[0].GridLevels
+	assertion.Equal(model.GridLevelBuyPlaced, levels[0].Status)
+	assertion.Equal(int64(1), *levels[0].ExternalId)
+	assertion.Nil(levels[0].ClientOrderId)
+	assertion.Equal(model.GridLevelBuyPlaced, levels[1].Status)
+	assertion.Equal(int64(2), *levels[1].ExternalId)
+	assertion.Nil(levels[1].ClientOrderId)
+	binance.AssertNumberOfCalls(t, "LimitOrderWithClientId", 3)
+}
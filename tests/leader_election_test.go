@@ -0,0 +1,133 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestLeaderElectionFailover(t *testing.T) {
+	assertion := assert.New(t)
+
+	storage := &LeaderLeaseStorageMock{}
+	first := &service.LeaderElection{LeaderLeaseStorage: storage, Key: "leader", Identity: "first", LeaseTtl: time.Millisecond * 60}
+	second := &service.LeaderElection{LeaderLeaseStorage: storage, Key: "leader", Identity: "second", LeaseTtl: time.Millisecond * 60}
+
+	firstCtx, stopFirst := context.WithCancel(context.Background())
+	secondCtx, stopSecond := context.WithCancel(context.Background())
+	defer stopSecond()
+
+	firstElected := atomic.Int64{}
+	firstResigned := atomic.Bool{}
+	go first.Run(firstCtx, func(leaderCtx context.Context) {
+		firstElected.Add(1)
+		<-leaderCtx.Done()
+		firstResigned.Store(true)
+	})
+	assertion.Eventually(first.IsLeader, time.Second, time.Millisecond*5)
+
+	secondElected := atomic.Int64{}
+	go second.Run(secondCtx, func(leaderCtx context.Context) {
+		secondElected.Add(1)
+		<-leaderCtx.Done()
+	})
+
+	// lease is renewed, second instance is waiting
+	time.Sleep(time.Millisecond * 150)
+	assertion.True(first.IsLeader())
+	assertion.False(second.IsLeader())
+	assertion.Equal("first", second.GetLeader())
+	assertion.Equal(int64(1), firstElected.Load())
+	assertion.Equal(int64(0), secondElected.Load())
+
+	// leader is stopped, lease is released and second instance takes over
+	stopFirst()
+	assertion.Eventually(second.IsLeader, time.Second, time.Millisecond*5)
+	assertion.True(firstResigned.Load())
+	assertion.False(first.IsLeader())
+	assertion.Equal("second", first.GetLeader())
+	assertion.Equal(int64(1), secondElected.Load())
+
+	// lease is lost (for example expired while Redis was not available), leader steps down
+	storage.Steal("third", time.Minute)
+	assertion.Eventually(func() bool {
+		return !second.IsLeader()
+	}, time.Second, time.Millisecond*5)
+	assertion.Equal("third", second.GetLeader())
+}
+
+func TestLeaderElectionTermsDoNotOverlap(t *testing.T) {
+	assertion := assert.New(t)
+
+	storage := &LeaderLeaseStorageMock{}
+	election := &service.LeaderElection{LeaderLeaseStorage: storage, Key: "leader", Identity: "first", LeaseTtl: time.Millisecond * 60}
+
+	ctx, stop := context.WithCancel(context.Background())
+
+	elected := atomic.Int64{}
+	running := atomic.Int64{}
+	overlapped := atomic.Bool{}
+	finished := make(chan struct{})
+	go func() {
+		election.Run(ctx, func(leaderCtx context.Context) {
+			elected.Add(1)
+			if running.Add(1) > 1 {
+				overlapped.Store(true)
+			}
+			<-leaderCtx.Done()
+			// previous term is still stopping when leadership is taken again
+			time.Sleep(time.Millisecond * 150)
+			running.Add(-1)
+		})
+		close(finished)
+	}()
+	assertion.Eventually(election.IsLeader, time.Second, time.Millisecond*5)
+
+	// lease is lost for a short time and taken again
+	storage.Steal("second", time.Millisecond*30)
+	assertion.Eventually(func() bool {
+		return elected.Load() == 2
+	}, time.Second, time.Millisecond*5)
+	assertion.False(overlapped.Load())
+
+	// lease is released when the last term is finished
+	stop()
+	<-finished
+	assertion.Equal(int64(0), running.Load())
+	assertion.Equal("", election.GetLeader())
+}
+
+func TestLeaderElectionWaitsLastTermAfterLostLeadership(t *testing.T) {
+	assertion := assert.New(t)
+
+	storage := &LeaderLeaseStorageMock{}
+	election := &service.LeaderElection{LeaderLeaseStorage: storage, Key: "leader", Identity: "first", LeaseTtl: time.Millisecond * 60}
+
+	ctx, stop := context.WithCancel(context.Background())
+
+	running := atomic.Int64{}
+	finished := make(chan struct{})
+	go func() {
+		election.Run(ctx, func(leaderCtx context.Context) {
+			running.Add(1)
+			<-leaderCtx.Done()
+			time.Sleep(time.Millisecond * 150)
+			running.Add(-1)
+		})
+		close(finished)
+	}()
+	assertion.Eventually(election.IsLeader, time.Second, time.Millisecond*5)
+
+	// leadership is lost and instance is stopped while the term is still finishing
+	storage.Steal("second", time.Minute)
+	assertion.Eventually(func() bool {
+		return !election.IsLeader()
+	}, time.Second, time.Millisecond*5)
+	stop()
+	<-finished
+	assertion.Equal(int64(0), running.Load())
+	assertion.Equal("second", election.GetLeader())
+}
@@ -0,0 +1,145 @@
+package tests
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"strings"
+	"testing"
+	"time"
+)
+
+func getLotEntries() []model.LotEntry {
+	return []model.LotEntry{
+		{Asset: "ETH", Symbol: "ETHUSDT", Type: model.LotEntryBuy, Quantity: 1, Price: 100, Value: 100, CreatedAt: "2024-01-01 10:00:00"},
+		{Asset: "ETH", Symbol: "ETHUSDT", Type: model.LotEntryBuyExtra, Quantity: 1, Price: 80, Value: 80, CreatedAt: "2024-01-02 10:00:00"},
+		{Asset: "ETH", Symbol: "ETHUSDT", Type: model.LotEntrySell, Quantity: 1.5, Price: 120, Value: 180, CreatedAt: "2024-02-01 10:00:00"},
+		{Asset: "ETH", Symbol: "ETHUSDT", Type: model.LotEntrySell, Quantity: 1, Price: 100, Value: 100, CreatedAt: "2024-03-01 10:00:00"},
+	}
+}
+
+func TestLotMatchingFifo(t *testing.T) {
+	assertion := assert.New(t)
+
+	gains := (&service.LotLedger{}).MatchLots(getLotEntries(), model.LotMethodFifo, "")
+
+	assertion.Len(gains, 4)
+	assertion.Equal("2024-01-01 10:00:00", gains[0].AcquiredAt)
+	assertion.Equal(1.0, gains[0].Quantity)
+	assertion.Equal(20.0, gains[0].Gain)
+	assertion.Equal("2024-01-02 10:00:00", gains[1].AcquiredAt)
+	assertion.Equal(0.5, gains[1].Quantity)
+	assertion.Equal(20.0, gains[1].Gain)
+	assertion.Equal(10.0, gains[2].Gain)
+
+	// 0.5 ETH is sold, but not bought by recorded fills
+	assertion.True(gains[3].UnmatchedLot)
+	assertion.Equal(0.5, gains[3].Quantity)
+	assertion.Equal(0.0, gains[3].CostBasis)
+	assertion.Equal(50.0, gains[3].Gain)
+}
+
+func TestLotMatchingLifo(t *testing.T) {
+	assertion := assert.New(t)
+
+	gains := (&service.LotLedger{}).MatchLots(getLotEntries(), model.LotMethodLifo, "")
+
+	assertion.Equal("2024-01-02 10:00:00", gains[0].AcquiredAt)
+	assertion.Equal(40.0, gains[0].Gain)
+	assertion.Equal("2024-01-01 10:00:00", gains[1].AcquiredAt)
+	assertion.Equal(0.5, gains[1].Quantity)
+	assertion.Equal(10.0, gains[1].Gain)
+}
+
+func TestLotMatchingAverage(t *testing.T) {
+	assertion := assert.New(t)
+
+	gains := (&service.LotLedger{}).MatchLots(getLotEntries(), model.LotMethodAverage, "2024-01-15 00:00:00")
+
+	// average cost is 90
+	assertion.Equal(1.5, gains[0].Quantity)
+	assertion.Equal(135.0, gains[0].CostBasis)
+	assertion.Equal(45.0, gains[0].Gain)
+	assertion.Equal("", gains[0].AcquiredAt)
+	assertion.Equal(0.5, gains[1].Quantity)
+	assertion.Equal(5.0, gains[1].Gain)
+	assertion.True(gains[2].UnmatchedLot)
+}
+
+func TestCapitalGainsCsvForDateRange(t *testing.T) {
+	assertion := assert.New(t)
+
+	ledger := &service.LotLedger{LotRepository: &LotStorageMock{Entries: getLotEntries()}}
+	gains, err := ledger.GetCapitalGains(model.LotMethodFifo, "2024-02-01", "2024-02-29")
+	assertion.Nil(err)
+	assertion.Len(gains, 2)
+
+	var buffer bytes.Buffer
+	assertion.Nil(ledger.WriteCsv(&buffer, gains))
+	lines := strings.Split(strings.TrimSpace(buffer.String()), "\n")
+	assertion.Equal("asset,symbol,type,acquiredAt,disposedAt,quantity,proceeds,costBasis,gain,unmatchedLot", lines[0])
+	assertion.Equal("ETH,ETHUSDT,sell,2024-01-01 10:00:00,2024-02-01 10:00:00,1,120,100,20,false", lines[1])
+
+	_, err = ledger.GetCapitalGains("hifo", "2024-02-01", "2024-02-29")
+	assertion.NotNil(err)
+	_, err = ledger.GetCapitalGains(model.LotMethodFifo, "2024-03-01", "2024-02-29")
+	assertion.NotNil(err)
+}
+
+func TestLotLedgerRecordsFills(t *testing.T) {
+	assertion := assert.New(t)
+
+	storage := &LotStorageMock{}
+	swapRepository := new(SwapRepositoryMock)
+	swapRepository.On("GetSwapPairBySymbol", "XRPBTC").Return(model.SwapPair{Symbol: "XRPBTC", BaseAsset: "XRP", QuoteAsset: "BTC"}, nil)
+	exchangeRepository := new(ExchangePriceStorageMock)
+	exchangeRepository.On("GetLastKLine", "XRPUSDT").Return(&model.KLine{Symbol: "XRPUSDT", Close: 0.5})
+	ledger := &service.LotLedger{
+		LotRepository:      storage,
+		SwapRepository:     swapRepository,
+		ExchangeRepository: exchangeRepository,
+	}
+
+	externalId := int64(1001)
+	positionId := int64(5)
+	commission := 0.001
+	commissionAsset := "ETH"
+	ledger.RecordOrder(model.Order{
+		Symbol:           "ETHUSDT",
+		Price:            100,
+		ExecutedQuantity: 1,
+		CreatedAt:        "2024-03-01 10:00:00",
+		ExternalId:       &externalId,
+		Commission:       &commission,
+		CommissionAsset:  &commissionAsset,
+	}, model.LotEntryBuy, &positionId)
+
+	// order without exchange order is not recorded
+	ledger.RecordOrder(model.Order{Symbol: "ETHUSDT", Price: 100, ExecutedQuantity: 1}, model.LotEntryBuy, &positionId)
+
+	ledger.RecordSwapLeg(model.SwapAction{OrderId: positionId}, model.BinanceOrder{
+		OrderId:             2002,
+		Symbol:              "XRPBTC",
+		Side:                "SELL",
+		ExecutedQty:         100,
+		CummulativeQuoteQty: 0.001,
+		UpdateTime:          1709287200000,
+	})
+
+	assertion.Len(storage.Entries, 3)
+	assertion.Equal(0.999, storage.Entries[0].Quantity)
+	assertion.Equal(100.0, storage.Entries[0].Value)
+	assertion.Equal(&positionId, storage.Entries[0].OrderId)
+	assertion.Equal("2024-03-01 10:00:00", storage.Entries[0].CreatedAt)
+
+	assertion.Equal(model.LotEntrySwapOut, storage.Entries[1].Type)
+	assertion.Equal("XRP", storage.Entries[1].Asset)
+	assertion.Equal(50.0, storage.Entries[1].Value)
+	assertion.Equal(model.LotEntrySwapIn, storage.Entries[2].Type)
+	assertion.Equal("BTC", storage.Entries[2].Asset)
+	assertion.Equal(0.001, storage.Entries[2].Quantity)
+	assertion.Equal(50000.0, storage.Entries[2].Price)
+	// swap leg is dated by fill time on exchange
+	assertion.Equal(time.UnixMilli(1709287200000).Format("2006-01-02 15:04:05"), storage.Entries[2].CreatedAt)
+}
@@ -0,0 +1,112 @@
+package tests
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"testing"
+)
+
+func TestMakerBuyIsRepricedWhenOrderWouldMatch(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	orderRepository := new(OrderStorageMock)
+	balanceService := new(BalanceServiceMock)
+	timeService := new(TimeServiceMock)
+	orderExecutor := newExecutionOrderExecutor(binance, orderRepository, balanceService, timeService, &ChildOrderStorageMock{})
+
+	priceCalculator := new(PriceCalculatorMock)
+	priceCalculator.On("CalculateSell", mock.Anything, mock.Anything).Return(2100.00).Maybe()
+	priceCalculator.On("GetDepth", "ETHUSDT").Return(model.Depth{
+		Symbol: "ETHUSDT",
+		Bids:   [][2]model.Number{{{Value: 1999.50}, {Value: 1.00}}},
+		Asks:   [][2]model.Number{{{Value: 2000.20}, {Value: 1.00}}, {{Value: 1999.90}, {Value: 1.00}}},
+	})
+	orderExecutor.PriceCalculator = priceCalculator
+
+	tradeLimit := model.TradeLimit{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        100,
+		MinPrice:         0.01,
+		MinQuantity:      0.0001,
+		MinNotional:      5,
+		MinProfitPercent: 1,
+		OrderType:        model.OrderTypeLimitMaker,
+	}
+
+	balanceService.On("GetAssetBalance", "ETH", true).Return(0.00, nil)
+	timeService.On("GetNowUnix").Return(0)
+	orderId := int64(12)
+	orderRepository.On("Create", mock.Anything).Return(&orderId, nil)
+
+	// the ask moved below order price, post-only order is placed one tick below the best ask
+	binance.On("LimitMakerOrder", "ETHUSDT", 0.01, 2000.00, "BUY").Return(model.BinanceOrder{}, errors.New(model.BinanceErrorOrderWouldMatch)).Once()
+	binance.On("LimitMakerOrder", "ETHUSDT", 0.01, 1999.89, "BUY").Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Type: model.OrderTypeLimitMaker, Status: "FILLED", Price: 1999.89, OrigQty: 0.01, ExecutedQty: 0.01, CummulativeQuoteQty: 19.9989,
+	}, nil).Once()
+
+	err := orderExecutor.Buy(tradeLimit, "ETHUSDT", 2000.00, 0.01)
+	assertion.Nil(err)
+
+	binance.AssertNumberOfCalls(t, "LimitMakerOrder", 2)
+	binance.AssertNotCalled(t, "LimitOrder", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	assertion.Equal(1999.89, orderRepository.Created.Price)
+}
+
+func TestMakerBuyIsNotRepricedForOtherErrors(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	orderRepository := new(OrderStorageMock)
+	balanceService := new(BalanceServiceMock)
+	timeService := new(TimeServiceMock)
+	orderExecutor := newExecutionOrderExecutor(binance, orderRepository, balanceService, timeService, &ChildOrderStorageMock{})
+
+	tradeLimit := model.TradeLimit{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        100,
+		MinPrice:         0.01,
+		MinQuantity:      0.0001,
+		MinNotional:      5,
+		MinProfitPercent: 1,
+		OrderType:        model.OrderTypeLimitMaker,
+	}
+
+	balanceService.On("GetAssetBalance", "ETH", true).Return(0.00, nil)
+	timeService.On("GetNowUnix").Return(0)
+	binance.On("LimitMakerOrder", "ETHUSDT", 0.01, 2000.00, "BUY").Return(model.BinanceOrder{}, errors.New("Account has insufficient balance for requested action.")).Once()
+
+	err := orderExecutor.Buy(tradeLimit, "ETHUSDT", 2000.00, 0.01)
+	assertion.NotNil(err)
+
+	binance.AssertNumberOfCalls(t, "LimitMakerOrder", 1)
+}
+
+func TestOrderTypeTradeLimitIsValidated(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeInfoAPIMock)
+	tradeLimitService := service.TradeLimitService{
+		ExchangeRepository: new(TradeLimitStorageMock),
+		OrderRepository:    new(OrderStorageMock),
+		Binance:            binance,
+	}
+
+	binance.On("GetExchangeData", mock.Anything).Return(&model.ExchangeInfo{Symbols: []model.ExchangeSymbol{
+		{Symbol: "ETHUSDT", Status: "TRADING"},
+	}}, nil)
+
+	report := tradeLimitService.Import([]model.TradeLimit{{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        40,
+		MinProfitPercent: 1,
+		OrderType:        "MARKET",
+	}}, false)
+
+	assertion.False(report.IsValid())
+	assertion.Len(report.Violations, 1)
+	assertion.Equal("order type MARKET is invalid, allowed: LIMIT, LIMIT_MAKER", report.Violations[0].Message)
+}
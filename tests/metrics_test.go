@@ -0,0 +1,37 @@
+package tests
+
+import (
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/metrics"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"testing"
+)
+
+func TestOrderEventRecorderMetrics(t *testing.T) {
+	assertion := assert.New(t)
+
+	orderEventRepository := new(OrderEventStorageMock)
+	eventId := int64(1)
+	orderEventRepository.On("Create", mock.Anything).Return(&eventId, nil)
+
+	recorder := service.OrderEventRecorder{
+		OrderEventRepository: orderEventRepository,
+	}
+
+	placed := testutil.ToFloat64(metrics.OrdersTotal.WithLabelValues("buy", "placed"))
+	filled := testutil.ToFloat64(metrics.OrdersTotal.WithLabelValues("buy", "filled"))
+	cancelled := testutil.ToFloat64(metrics.OrdersTotal.WithLabelValues("sell", "cancelled"))
+
+	recorder.Record(model.OrderEvent{Symbol: "ETHUSDT", Operation: "BUY", Type: model.OrderEventBuyPlaced})
+	recorder.Record(model.OrderEvent{Symbol: "ETHUSDT", Operation: "BUY", Type: model.OrderEventFilled})
+	recorder.Record(model.OrderEvent{Symbol: "ETHUSDT", Operation: "SELL", Type: model.OrderEventCancelled})
+	recorder.Record(model.OrderEvent{Symbol: "ETHUSDT", Operation: "SELL", Type: model.OrderEventManualOverride})
+
+	assertion.Equal(placed+1, testutil.ToFloat64(metrics.OrdersTotal.WithLabelValues("buy", "placed")))
+	assertion.Equal(filled+1, testutil.ToFloat64(metrics.OrdersTotal.WithLabelValues("buy", "filled")))
+	assertion.Equal(cancelled+1, testutil.ToFloat64(metrics.OrdersTotal.WithLabelValues("sell", "cancelled")))
+	orderEventRepository.AssertNumberOfCalls(t, "Create", 4)
+}
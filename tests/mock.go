@@ -1,8 +1,13 @@
 package tests
 
 import (
+	"errors"
 	"github.com/stretchr/testify/mock"
 	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"slices"
+	"strings"
+	"sync"
+	"time"
 )
 
 type ExchangeRepositoryMock struct {
@@ -126,18 +131,42 @@ func (b *ExchangeOrderAPIMock) GetOpenedOrders() ([]model.BinanceOrder, error) {
 	args := b.Called()
 	return args.Get(0).([]model.BinanceOrder), args.Error(1)
 }
+func (b *ExchangeOrderAPIMock) GetOpenedOrdersBySymbol(symbol string) ([]model.BinanceOrder, error) {
+	args := b.Called(symbol)
+	return args.Get(0).([]model.BinanceOrder), args.Error(1)
+}
 func (b *ExchangeOrderAPIMock) LimitOrder(symbol string, quantity float64, price float64, operation string, timeInForce string) (model.BinanceOrder, error) {
 	args := b.Called(symbol, quantity, price, operation, timeInForce)
 	return args.Get(0).(model.BinanceOrder), args.Error(1)
 }
+func (b *ExchangeOrderAPIMock) LimitOrderWithClientId(symbol string, quantity float64, price float64, operation string, timeInForce string, clientOrderId string) (model.BinanceOrder, error) {
+	args := b.Called(symbol, quantity, price, operation, timeInForce, clientOrderId)
+	return args.Get(0).(model.BinanceOrder), args.Error(1)
+}
+func (b *ExchangeOrderAPIMock) LimitMakerOrder(symbol string, quantity float64, price float64, operation string) (model.BinanceOrder, error) {
+	args := b.Called(symbol, quantity, price, operation)
+	return args.Get(0).(model.BinanceOrder), args.Error(1)
+}
+func (b *ExchangeOrderAPIMock) CancelReplaceOrder(symbol string, orderId int64, quantity float64, price float64, operation string, orderType string) (model.BinanceOrder, error) {
+	args := b.Called(symbol, orderId, quantity, price, operation, orderType)
+	return args.Get(0).(model.BinanceOrder), args.Error(1)
+}
 func (b *ExchangeOrderAPIMock) QueryOrder(symbol string, orderId int64) (model.BinanceOrder, error) {
 	args := b.Called(symbol, orderId)
 	return args.Get(0).(model.BinanceOrder), args.Error(1)
 }
+func (b *ExchangeOrderAPIMock) QueryOrderByClientId(symbol string, clientOrderId string) (model.BinanceOrder, error) {
+	args := b.Called(symbol, clientOrderId)
+	return args.Get(0).(model.BinanceOrder), args.Error(1)
+}
 func (b *ExchangeOrderAPIMock) CancelOrder(symbol string, orderId int64) (model.BinanceOrder, error) {
 	args := b.Called(symbol, orderId)
 	return args.Get(0).(model.BinanceOrder), args.Error(1)
 }
+func (b *ExchangeOrderAPIMock) GetTrades(order model.Order) ([]model.MyTrade, error) {
+	args := b.Called(order)
+	return args.Get(0).([]model.MyTrade), args.Error(1)
+}
 
 type TimeServiceMock struct {
 	mock.Mock
@@ -250,6 +279,13 @@ func (e *OrderStorageMock) Update(order model.Order) error {
 	args := e.Called(order)
 	return args.Error(0)
 }
+func (e *OrderStorageMock) GetTakeProfitHighPrice(order model.Order) float64 {
+	args := e.Called(order)
+	return args.Get(0).(float64)
+}
+func (e *OrderStorageMock) SetTakeProfitHighPrice(order model.Order, price float64) {
+	_ = e.Called(order, price)
+}
 func (e *OrderStorageMock) DeleteManualOrder(symbol string) {
 	_ = e.Called(symbol)
 }
@@ -257,6 +293,14 @@ func (e *OrderStorageMock) Find(id int64) (model.Order, error) {
 	args := e.Called(id)
 	return args.Get(0).(model.Order), args.Error(1)
 }
+func (e *OrderStorageMock) FindByExternalId(symbol string, externalId int64) (model.Order, error) {
+	args := e.Called(symbol, externalId)
+	return args.Get(0).(model.Order), args.Error(1)
+}
+func (e *OrderStorageMock) GetOpenedOrder(symbol string, operation string) (model.Order, error) {
+	args := e.Called(symbol, operation)
+	return args.Get(0).(model.Order), args.Error(1)
+}
 func (e *OrderStorageMock) GetClosesOrderList(buyOrder model.Order) []model.Order {
 	args := e.Called(buyOrder)
 	return args.Get(0).([]model.Order)
@@ -300,6 +344,27 @@ func (e *OrderStorageMock) HasBuyLock(symbol string) bool {
 	return args.Get(0).(bool)
 }
 
+type TradeLimitReaderMock struct {
+	mock.Mock
+}
+
+func (t *TradeLimitReaderMock) GetTradeLimits() []model.TradeLimit {
+	args := t.Called()
+	return args.Get(0).([]model.TradeLimit)
+}
+
+type TradeLockMock struct {
+	mock.Mock
+}
+
+func (t *TradeLockMock) TryLock(symbol string) bool {
+	args := t.Called(symbol)
+	return args.Get(0).(bool)
+}
+func (t *TradeLockMock) Unlock(symbol string) {
+	_ = t.Called(symbol)
+}
+
 type ExchangeTradeInfoMock struct {
 	mock.Mock
 }
@@ -377,6 +442,28 @@ func (s *TelegramNotificatorMock) BuyOrder(order model.Order, bot model.Bot, det
 	_ = s.Called(order, bot, details)
 }
 
+type OrderEventRecorderMock struct {
+	mock.Mock
+}
+
+func (r *OrderEventRecorderMock) Record(event model.OrderEvent) {
+	_ = r.Called(event)
+}
+
+type OrderEventStorageMock struct {
+	mock.Mock
+}
+
+func (r *OrderEventStorageMock) Create(event model.OrderEvent) (*int64, error) {
+	args := r.Called(event)
+	return args.Get(0).(*int64), args.Error(1)
+}
+
+func (r *OrderEventStorageMock) GetOrderEvents(order model.Order) []model.OrderEvent {
+	args := r.Called(order)
+	return args.Get(0).([]model.OrderEvent)
+}
+
 type LossSecurityMock struct {
 	mock.Mock
 }
@@ -393,3 +480,466 @@ func (l *LossSecurityMock) CheckBuyPriceOnHistory(limit model.TradeLimit, buyPri
 	args := l.Called(limit, buyPrice)
 	return args.Get(0).(float64)
 }
+
+type ApiClientStorageMock struct {
+	mock.Mock
+}
+
+func (a *ApiClientStorageMock) FindByApiKey(apiKey string) (model.ApiClient, error) {
+	args := a.Called(apiKey)
+	return args.Get(0).(model.ApiClient), args.Error(1)
+}
+
+func (a *ApiClientStorageMock) Touch(apiClient model.ApiClient) {
+	_ = a.Called(apiClient)
+}
+
+func (a *ApiClientStorageMock) RememberSignature(apiKey string, signature string, ttl time.Duration) bool {
+	args := a.Called(apiKey, signature, ttl)
+	return args.Bool(0)
+}
+
+type ApiAuditStorageMock struct {
+	mock.Mock
+}
+
+func (a *ApiAuditStorageMock) CreateAudit(audit model.ApiAudit) (*int64, error) {
+	args := a.Called(audit)
+	return args.Get(0).(*int64), args.Error(1)
+}
+
+type TradeLimitStorageMock struct {
+	mock.Mock
+}
+
+func (t *TradeLimitStorageMock) GetTradeLimits() []model.TradeLimit {
+	args := t.Called()
+	return args.Get(0).([]model.TradeLimit)
+}
+func (t *TradeLimitStorageMock) GetTradeLimit(symbol string) (model.TradeLimit, error) {
+	args := t.Called(symbol)
+	return args.Get(0).(model.TradeLimit), args.Error(1)
+}
+func (t *TradeLimitStorageMock) CreateTradeLimit(limit model.TradeLimit) (*int64, error) {
+	args := t.Called(limit)
+	return args.Get(0).(*int64), args.Error(1)
+}
+func (t *TradeLimitStorageMock) UpdateTradeLimit(limit model.TradeLimit) error {
+	args := t.Called(limit)
+	return args.Error(0)
+}
+func (t *TradeLimitStorageMock) DeleteTradeLimit(limit model.TradeLimit) error {
+	args := t.Called(limit)
+	return args.Error(0)
+}
+func (t *TradeLimitStorageMock) ArchiveTradeLimit(limit model.TradeLimit) error {
+	args := t.Called(limit)
+	return args.Error(0)
+}
+func (t *TradeLimitStorageMock) ImportTradeLimits(limits []model.TradeLimit) error {
+	args := t.Called(limits)
+	return args.Error(0)
+}
+
+type ExchangeInfoAPIMock struct {
+	mock.Mock
+}
+
+func (e *ExchangeInfoAPIMock) GetExchangeData(symbols []string) (*model.ExchangeInfo, error) {
+	args := e.Called(symbols)
+	return args.Get(0).(*model.ExchangeInfo), args.Error(1)
+}
+func (e *ExchangeInfoAPIMock) GetOpenedOrders() ([]model.BinanceOrder, error) {
+	args := e.Called()
+	return args.Get(0).([]model.BinanceOrder), args.Error(1)
+}
+
+type StreamConnectionMock struct {
+	Streams []string
+	Closed  bool
+}
+
+func (s *StreamConnectionMock) Subscribe(streams []string) error {
+	s.Streams = append(s.Streams, streams...)
+	return nil
+}
+func (s *StreamConnectionMock) Unsubscribe(streams []string) error {
+	s.Streams = slices.DeleteFunc(s.Streams, func(stream string) bool {
+		return slices.Contains(streams, stream)
+	})
+	return nil
+}
+func (s *StreamConnectionMock) GetStreams() []string {
+	return s.Streams
+}
+func (s *StreamConnectionMock) Close() {
+	s.Closed = true
+}
+func (s *StreamConnectionMock) GetHealth() model.StreamHealth {
+	return model.StreamHealth{Status: model.StreamStatusOk, Streams: len(s.Streams)}
+}
+
+type LeaderLeaseStorageMock struct {
+	owner     string
+	expiresAt time.Time
+	mu        sync.Mutex
+}
+
+func (l *LeaderLeaseStorageMock) AcquireLease(key string, identity string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.owner != "" && time.Now().Before(l.expiresAt) {
+		return false, nil
+	}
+	l.owner = identity
+	l.expiresAt = time.Now().Add(ttl)
+	return true, nil
+}
+func (l *LeaderLeaseStorageMock) RenewLease(key string, identity string, ttl time.Duration) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.owner != identity || time.Now().After(l.expiresAt) {
+		return false, nil
+	}
+	l.expiresAt = time.Now().Add(ttl)
+	return true, nil
+}
+func (l *LeaderLeaseStorageMock) ReleaseLease(key string, identity string) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.owner == identity {
+		l.owner = ""
+	}
+	return nil
+}
+func (l *LeaderLeaseStorageMock) GetLeaseOwner(key string) string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if time.Now().After(l.expiresAt) {
+		return ""
+	}
+	return l.owner
+}
+
+// Steal takes lease as another instance would do after expiration
+func (l *LeaderLeaseStorageMock) Steal(identity string, ttl time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.owner = identity
+	l.expiresAt = time.Now().Add(ttl)
+}
+
+type NotificationStorageMock struct {
+	Jobs []model.NotificationJob
+	mu   sync.Mutex
+}
+
+func (n *NotificationStorageMock) Create(job model.NotificationJob) (*int64, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	job.Id = int64(len(n.Jobs) + 1)
+	job.Status = model.NotificationStatusPending
+	n.Jobs = append(n.Jobs, job)
+	return &job.Id, nil
+}
+func (n *NotificationStorageMock) GetDue(limit int64) []model.NotificationJob {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	due := make([]model.NotificationJob, 0)
+	for _, job := range n.Jobs {
+		if job.Status == model.NotificationStatusPending && int64(len(due)) < limit {
+			due = append(due, job)
+		}
+	}
+	return due
+}
+func (n *NotificationStorageMock) MarkSent(id int64) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.Jobs[id-1].Status = model.NotificationStatusSent
+	n.Jobs[id-1].Attempts++
+	return nil
+}
+func (n *NotificationStorageMock) MarkFailed(id int64, attempts int64, retryIn time.Duration, status string, lastError string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.Jobs[id-1].Status = status
+	n.Jobs[id-1].Attempts = attempts
+	n.Jobs[id-1].LastError = &lastError
+	return nil
+}
+
+type OrderListReaderMock struct {
+	mock.Mock
+}
+
+func (o *OrderListReaderMock) GetList() []model.Order {
+	args := o.Called()
+	return args.Get(0).([]model.Order)
+}
+
+type SwapGainReaderMock struct {
+	mock.Mock
+}
+
+func (s *SwapGainReaderMock) GetSwapGains() map[int64]float64 {
+	args := s.Called()
+	return args.Get(0).(map[int64]float64)
+}
+
+type AccountStorageMock struct {
+	Accounts []model.Bot
+}
+
+func (a *AccountStorageMock) GetAccounts() []model.Bot {
+	return append(make([]model.Bot, 0), a.Accounts...)
+}
+func (a *AccountStorageMock) CreateAccount(bot model.Bot) error {
+	bot.Id = int64(len(a.Accounts) + 2)
+	a.Accounts = append(a.Accounts, bot)
+	return nil
+}
+func (a *AccountStorageMock) UpdateAccountSecret(bot model.Bot) error {
+	for index, account := range a.Accounts {
+		if account.Id == bot.Id {
+			a.Accounts[index].BinanceApiSecret = bot.BinanceApiSecret
+		}
+	}
+	return nil
+}
+
+type LotStorageMock struct {
+	Entries []model.LotEntry
+}
+
+func (l *LotStorageMock) Create(entry model.LotEntry) error {
+	l.Entries = append(l.Entries, entry)
+	return nil
+}
+func (l *LotStorageMock) GetEntries(to string) []model.LotEntry {
+	list := make([]model.LotEntry, 0)
+	for _, entry := range l.Entries {
+		if entry.CreatedAt <= to {
+			list = append(list, entry)
+		}
+	}
+	return list
+}
+
+type TradeLimitFinderMock struct {
+	mock.Mock
+}
+
+func (t *TradeLimitFinderMock) GetTradeLimits() []model.TradeLimit {
+	args := t.Called()
+	return args.Get(0).([]model.TradeLimit)
+}
+func (t *TradeLimitFinderMock) GetTradeLimit(symbol string) (model.TradeLimit, error) {
+	args := t.Called(symbol)
+	return args.Get(0).(model.TradeLimit), args.Error(1)
+}
+
+type PerformanceStorageMock struct {
+	Snapshots       []model.PerformanceSnapshot
+	OrderStrategies map[int64][]string
+}
+
+func (p *PerformanceStorageMock) CreateSnapshot(snapshot model.PerformanceSnapshot) (*int64, error) {
+	id := int64(len(p.Snapshots) + 1)
+	snapshot.Id = id
+	p.Snapshots = append([]model.PerformanceSnapshot{snapshot}, p.Snapshots...)
+	return &id, nil
+}
+func (p *PerformanceStorageMock) GetSnapshots(symbol string) []model.PerformanceSnapshot {
+	list := make([]model.PerformanceSnapshot, 0)
+	for _, snapshot := range p.Snapshots {
+		if snapshot.Symbol == symbol {
+			list = append(list, snapshot)
+		}
+	}
+	return list
+}
+func (p *PerformanceStorageMock) CreateOrderStrategies(orderId int64, decisions []model.Decision) error {
+	for _, decision := range decisions {
+		p.OrderStrategies[orderId] = append(p.OrderStrategies[orderId], decision.StrategyName)
+	}
+	return nil
+}
+func (p *PerformanceStorageMock) GetOrderStrategies() map[int64][]string {
+	return p.OrderStrategies
+}
+
+type DecisionJournalStorageMock struct {
+	Entries []model.DecisionJournalEntry
+}
+
+func (d *DecisionJournalStorageMock) Create(entry model.DecisionJournalEntry) error {
+	entry.Id = int64(len(d.Entries) + 1)
+	d.Entries = append(d.Entries, entry)
+	return nil
+}
+func (d *DecisionJournalStorageMock) GetList(symbol string, limit int64) []model.DecisionJournalEntry {
+	return d.Entries
+}
+func (d *DecisionJournalStorageMock) DeleteOlderThan(days int64) (int64, error) {
+	return 0, nil
+}
+
+type GridTradeInfoMock struct {
+	mock.Mock
+}
+
+func (g *GridTradeInfoMock) GetTradeLimits() []model.TradeLimit {
+	args := g.Called()
+	return args.Get(0).([]model.TradeLimit)
+}
+func (g *GridTradeInfoMock) GetLastKLine(symbol string) *model.KLine {
+	args := g.Called(symbol)
+	return args.Get(0).(*model.KLine)
+}
+
+type GridStorageMock struct {
+	Grids []model.Grid
+}
+
+func (g *GridStorageMock) CreateGrid(grid model.Grid) (*int64, error) {
+	grid.Id = int64(len(g.Grids) + 1)
+	for index := range grid.GridLevels {
+		grid.GridLevels[index].Id = grid.Id*100 + int64(index)
+		grid.GridLevels[index].GridId = grid.Id
+	}
+	g.Grids = append(g.Grids, grid)
+	return &grid.Id, nil
+}
+func (g *GridStorageMock) UpdateGrid(grid model.Grid) error {
+	for index := range g.Grids {
+		if g.Grids[index].Id == grid.Id {
+			levels := g.Grids[index].GridLevels
+			g.Grids[index] = grid
+			g.Grids[index].GridLevels = levels
+		}
+	}
+	return nil
+}
+func (g *GridStorageMock) UpdateGridLevel(level model.GridLevel) error {
+	for index := range g.Grids {
+		for levelIndex := range g.Grids[index].GridLevels {
+			if g.Grids[index].GridLevels[levelIndex].Id == level.Id {
+				g.Grids[index].GridLevels[levelIndex] = level
+			}
+		}
+	}
+	return nil
+}
+func (g *GridStorageMock) GetActiveGrids() []model.Grid {
+	list := make([]model.Grid, 0)
+	for _, grid := range g.GetGrids("") {
+		if grid.Status == model.GridActive {
+			list = append(list, grid)
+		}
+	}
+	return list
+}
+func (g *GridStorageMock) GetGrids(symbol string) []model.Grid {
+	list := make([]model.Grid, 0)
+	for _, grid := range g.Grids {
+		if symbol == "" || grid.Symbol == symbol {
+			grid.GridLevels = append([]model.GridLevel{}, grid.GridLevels...)
+			list = append(list, grid)
+		}
+	}
+	return list
+}
+
+type OrderCreatorMock struct {
+	Orders []model.Order
+}
+
+func (o *OrderCreatorMock) Create(order model.Order) (*int64, error) {
+	order.Id = int64(len(o.Orders) + 1)
+	o.Orders = append(o.Orders, order)
+	return &order.Id, nil
+}
+
+type DcaStorageMock struct {
+	Plans  []model.DcaPlan
+	Orders []model.DcaOrder
+}
+
+func (d *DcaStorageMock) CreatePlan(plan model.DcaPlan) (*int64, error) {
+	plan.Id = int64(len(d.Plans) + 1)
+	d.Plans = append(d.Plans, plan)
+	return &plan.Id, nil
+}
+func (d *DcaStorageMock) UpdatePlan(plan model.DcaPlan) error {
+	for index := range d.Plans {
+		if d.Plans[index].Id == plan.Id {
+			d.Plans[index] = plan
+		}
+	}
+	return nil
+}
+func (d *DcaStorageMock) GetPlan(id int64) (model.DcaPlan, error) {
+	for _, plan := range d.Plans {
+		if plan.Id == id {
+			return plan, nil
+		}
+	}
+	return model.DcaPlan{}, errors.New("sql: no rows in result set")
+}
+func (d *DcaStorageMock) GetPlans() []model.DcaPlan {
+	return append([]model.DcaPlan{}, d.Plans...)
+}
+func (d *DcaStorageMock) CreateOrder(order model.DcaOrder) error {
+	order.Id = int64(len(d.Orders) + 1)
+	d.Orders = append(d.Orders, order)
+	return nil
+}
+func (d *DcaStorageMock) GetOrders(planId int64, limit int64) []model.DcaOrder {
+	return d.Orders
+}
+
+type DcaExecutorMock struct {
+	mock.Mock
+}
+
+func (d *DcaExecutorMock) BuyAccumulation(tradeLimit model.TradeLimit, price float64, quantity float64, ttl int64) (model.Order, error) {
+	args := d.Called(tradeLimit, price, quantity, ttl)
+	return args.Get(0).(model.Order), args.Error(1)
+}
+
+type ChildOrderStorageMock struct {
+	Children []model.ChildOrder
+}
+
+func (c *ChildOrderStorageMock) CreateChildOrder(child model.ChildOrder) (*int64, error) {
+	child.Id = int64(len(c.Children) + 1)
+	c.Children = append(c.Children, child)
+	return &child.Id, nil
+}
+func (c *ChildOrderStorageMock) SetParentOrder(childIds []int64, orderId int64) error {
+	for index := range c.Children {
+		if slices.Contains(childIds, c.Children[index].Id) {
+			c.Children[index].OrderId = &orderId
+		}
+	}
+	return nil
+}
+func (c *ChildOrderStorageMock) GetPendingChildOrders(symbol string, operation string) []model.ChildOrder {
+	list := make([]model.ChildOrder, 0)
+	for _, child := range c.Children {
+		if child.OrderId == nil && child.Symbol == symbol && child.Operation == strings.ToLower(operation) && child.ExecutedQuantity > 0 {
+			list = append(list, child)
+		}
+	}
+	return list
+}
+func (c *ChildOrderStorageMock) GetChildOrders(orderId int64) []model.ChildOrder {
+	list := make([]model.ChildOrder, 0)
+	for _, child := range c.Children {
+		if child.OrderId != nil && *child.OrderId == orderId {
+			list = append(list, child)
+		}
+	}
+	return list
+}
@@ -0,0 +1,135 @@
+package tests
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/config"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNotifierRouterSignedWebhookRetry(t *testing.T) {
+	assertion := assert.New(t)
+
+	// target is down for the first request
+	requests := atomic.Int64{}
+	payloads := make(chan model.WebhookNotification, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		timestamp := r.Header.Get(model.NotificationHeaderTimestamp)
+		if r.Header.Get(model.NotificationHeaderSignature) != service.SignNotification("secret", timestamp, body) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var payload model.WebhookNotification
+		_ = json.Unmarshal(body, &payload)
+		payloads <- payload
+	}))
+	defer server.Close()
+
+	webhook, err := service.NewNotifierChannel(model.NotifierChannelConfig{
+		Name:     "webhook",
+		Type:     model.NotifierTypeWebhook,
+		Events:   []string{model.NotificationEventSell},
+		Template: "{{.Operation}} {{.Symbol}} {{.Price}}",
+		Url:      server.URL,
+		Secret:   "secret",
+	}, nil)
+	assertion.Nil(err)
+
+	storage := &NotificationStorageMock{}
+	router := service.NotifierRouter{
+		Channels:               []service.NotifierChannel{*webhook},
+		NotificationRepository: storage,
+		CurrentBot:             &model.Bot{Id: 1, BotUuid: "uuid"},
+		MaxAttempts:            3,
+	}
+
+	// buy is not routed to webhook channel
+	router.Notify(model.Notification{Event: model.NotificationEventBuy, Symbol: "BTCUSDT"})
+	router.Notify(model.Notification{Event: model.NotificationEventSell, Symbol: "BTCUSDT", Operation: "SELL", Price: 42000})
+	assertion.Len(storage.Jobs, 1)
+
+	assertion.Equal(0, router.ProcessQueue())
+	assertion.Equal(model.NotificationStatusPending, storage.Jobs[0].Status)
+	assertion.Equal(int64(1), storage.Jobs[0].Attempts)
+	assertion.Contains(*storage.Jobs[0].LastError, "503")
+
+	assertion.Equal(1, router.ProcessQueue())
+	assertion.Equal(model.NotificationStatusSent, storage.Jobs[0].Status)
+
+	payload := <-payloads
+	assertion.Equal("SELL BTCUSDT 42000", payload.Text)
+	assertion.Equal("uuid", payload.Notification.BotUuid)
+	assertion.Equal(int64(1), payload.Notification.BotId)
+}
+
+func TestNotifierRouterMaxAttempts(t *testing.T) {
+	assertion := assert.New(t)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	slack, err := service.NewNotifierChannel(model.NotifierChannelConfig{
+		Name: "slack",
+		Type: model.NotifierTypeSlack,
+		Url:  server.URL,
+	}, nil)
+	assertion.Nil(err)
+
+	storage := &NotificationStorageMock{}
+	router := service.NotifierRouter{
+		Channels:               []service.NotifierChannel{*slack},
+		NotificationRepository: storage,
+		MaxAttempts:            2,
+	}
+	router.Notify(model.Notification{Event: model.NotificationEventError, Code: "api", Message: "down"})
+
+	router.ProcessQueue()
+	router.ProcessQueue()
+	router.ProcessQueue()
+	assertion.Equal(model.NotificationStatusFailed, storage.Jobs[0].Status)
+	assertion.Equal(int64(2), storage.Jobs[0].Attempts)
+}
+
+func TestNotificationRetryDelay(t *testing.T) {
+	assertion := assert.New(t)
+
+	assertion.Equal(time.Second*30, service.GetNotificationRetryDelay(1))
+	assertion.Equal(time.Minute, service.GetNotificationRetryDelay(2))
+	assertion.Equal(time.Minute*4, service.GetNotificationRetryDelay(4))
+	assertion.Equal(time.Hour, service.GetNotificationRetryDelay(20))
+}
+
+func TestValidateNotifierConfig(t *testing.T) {
+	assertion := assert.New(t)
+
+	botConfig := config.DefaultConfig()
+	botConfig.Notifier.Channels = []model.NotifierChannelConfig{
+		{Name: "tg", Type: model.NotifierTypeTelegram, Events: []string{"buy", "unknown"}},
+		{Name: "tg", Type: "pager", Template: "{{.Symbol"},
+	}
+
+	err := config.ValidateConfig(botConfig)
+
+	assertion.NotNil(err)
+	assertion.Contains(err.Error(), "notifier.channels[0].botToken is required")
+	assertion.Contains(err.Error(), "notifier.channels[0].events 'unknown' is invalid")
+	assertion.Contains(err.Error(), "notifier.channels[1].name 'tg' is duplicated")
+	assertion.Contains(err.Error(), "notifier.channels[1].type 'pager' is invalid")
+	assertion.Contains(err.Error(), "notifier.channels[1].template is invalid")
+}
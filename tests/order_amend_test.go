@@ -0,0 +1,177 @@
+package tests
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"testing"
+)
+
+func newAmendOrderExecutor(
+	binance *ExchangeOrderAPIMock,
+	orderRepository *OrderStorageMock,
+	lossSecurity *LossSecurityMock,
+	tradeLimit model.TradeLimit,
+) *service.OrderExecutor {
+	balanceService := new(BalanceServiceMock)
+	timeService := new(TimeServiceMock)
+	orderExecutor := newExecutionOrderExecutor(binance, orderRepository, balanceService, timeService, &ChildOrderStorageMock{})
+
+	exchangeRepository := new(ExchangeTradeInfoMock)
+	exchangeRepository.On("GetTradeLimit", "ETHUSDT").Return(tradeLimit, nil)
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Symbol: "ETHUSDT", Close: 2000.00})
+	priceCalculator := new(PriceCalculatorMock)
+	priceCalculator.On("CalculateSell", mock.Anything, mock.Anything).Return(2100.00).Maybe()
+	priceCalculator.On("GetDepth", "ETHUSDT").Return(model.Depth{Symbol: "ETHUSDT"})
+	orderExecutor.ExchangeRepository = exchangeRepository
+	orderExecutor.PriceCalculator = priceCalculator
+	orderExecutor.LossSecurity = lossSecurity
+	orderExecutor.TradeStack = &service.TradeStack{}
+
+	balanceService.On("GetAssetBalance", "ETH", true).Return(0.00, nil)
+	timeService.On("GetNowUnix").Return(0)
+	timeService.On("WaitMilliseconds", int64(20)).Maybe()
+	timeService.On("WaitSeconds", mock.Anything).Maybe()
+
+	return orderExecutor
+}
+
+func TestBuyOrderIsAmendedToManualPrice(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	orderRepository := new(OrderStorageMock)
+	lossSecurity := new(LossSecurityMock)
+	tradeLimit := model.TradeLimit{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        100,
+		MinPrice:         0.01,
+		MinQuantity:      0.0001,
+		MinNotional:      5,
+		MinProfitPercent: 1,
+	}
+	orderExecutor := newAmendOrderExecutor(binance, orderRepository, lossSecurity, tradeLimit)
+
+	lossSecurity.On("IsRiskyBuy", mock.Anything, tradeLimit).Return(false)
+	orderRepository.On("GetManualOrder", "ETHUSDT").Return(&model.ManualOrder{Operation: "BUY", Price: 1990.00, Symbol: "ETHUSDT"})
+	orderId := int64(13)
+	orderRepository.On("Create", mock.Anything).Return(&orderId, nil)
+
+	binance.On("LimitOrder", "ETHUSDT", 0.01, 2000.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2000, OrigQty: 0.01,
+	}, nil).Once()
+	binance.On("CancelReplaceOrder", "ETHUSDT", int64(1), 0.01, 1990.00, "BUY", model.OrderTypeLimit).Return(model.BinanceOrder{
+		OrderId: 2, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 1990, OrigQty: 0.01,
+	}, nil).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(2)).Return(model.BinanceOrder{
+		OrderId: 2, Symbol: "ETHUSDT", Side: "BUY", Status: "FILLED", Price: 1990, OrigQty: 0.01, ExecutedQty: 0.01, CummulativeQuoteQty: 19.9,
+	}, nil)
+
+	err := orderExecutor.Buy(tradeLimit, "ETHUSDT", 2000.00, 0.01)
+	assertion.Nil(err)
+
+	binance.AssertNumberOfCalls(t, "CancelReplaceOrder", 1)
+	binance.AssertNotCalled(t, "CancelOrder", mock.Anything, mock.Anything)
+	assertion.Equal(int64(2), *orderRepository.Created.ExternalId)
+	assertion.Equal(1990.00, orderRepository.Created.Price)
+}
+
+func TestRiskyBuyOrderIsCancelledWhenAmendmentFailed(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	orderRepository := new(OrderStorageMock)
+	lossSecurity := new(LossSecurityMock)
+	tradeLimit := model.TradeLimit{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        100,
+		MinPrice:         0.01,
+		MinQuantity:      0.0001,
+		MinNotional:      5,
+		MinProfitPercent: 1,
+	}
+	orderExecutor := newAmendOrderExecutor(binance, orderRepository, lossSecurity, tradeLimit)
+
+	lossSecurity.On("IsRiskyBuy", mock.Anything, tradeLimit).Return(true)
+	lossSecurity.On("BuyPriceCorrection", 2000.00, tradeLimit).Return(1980.00)
+	orderRepository.On("LockBuy", "ETHUSDT", int64(10)).Maybe()
+
+	binance.On("LimitOrder", "ETHUSDT", 0.01, 2000.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2000, OrigQty: 0.01,
+	}, nil).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(1)).Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2000, OrigQty: 0.01,
+	}, nil)
+	binance.On("CancelReplaceOrder", "ETHUSDT", int64(1), 0.01, 1980.00, "BUY", model.OrderTypeLimit).
+		Return(model.BinanceOrder{}, errors.New("Order cancel-replace failed.")).Once()
+	binance.On("CancelOrder", "ETHUSDT", int64(1)).Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "CANCELED", Price: 2000, OrigQty: 0.01,
+	}, nil).Once()
+
+	err := orderExecutor.Buy(tradeLimit, "ETHUSDT", 2000.00, 0.01)
+	assertion.NotNil(err)
+	assertion.Equal("Order 1 was CANCELED", err.Error())
+
+	binance.AssertNumberOfCalls(t, "CancelReplaceOrder", 1)
+	binance.AssertNumberOfCalls(t, "CancelOrder", 1)
+}
+
+func TestBuyOrderIsAmendedWhenTtlIsReached(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	orderRepository := new(OrderStorageMock)
+	lossSecurity := new(LossSecurityMock)
+	tradeLimit := model.TradeLimit{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        100,
+		MinPrice:         0.01,
+		MinQuantity:      0.0001,
+		MinNotional:      5,
+		MinProfitPercent: 1,
+	}
+	orderExecutor := newAmendOrderExecutor(binance, orderRepository, lossSecurity, tradeLimit)
+
+	// ttl is reached on the first status check
+	timeService := new(TimeServiceMock)
+	timeService.On("GetNowDateTimeString").Return("2024-02-01 00:00:00")
+	timeService.On("GetNowUnix").Return(0).Once()
+	timeService.On("GetNowUnix").Return(600)
+	timeService.On("WaitMilliseconds", int64(20)).Maybe()
+	timeService.On("WaitSeconds", mock.Anything).Maybe()
+	orderExecutor.TimeService = timeService
+	priceCalculator := new(PriceCalculatorMock)
+	priceCalculator.On("CalculateBuy", tradeLimit).Return(1990.00, nil)
+	priceCalculator.On("CalculateSell", mock.Anything, mock.Anything).Return(2100.00).Maybe()
+	priceCalculator.On("GetDepth", "ETHUSDT").Return(model.Depth{Symbol: "ETHUSDT"})
+	orderExecutor.PriceCalculator = priceCalculator
+
+	lossSecurity.On("IsRiskyBuy", mock.Anything, tradeLimit).Return(false)
+	orderRepository.On("GetManualOrder", "ETHUSDT").Return(nil)
+	orderId := int64(13)
+	orderRepository.On("Create", mock.Anything).Return(&orderId, nil)
+
+	// price ran 5% away from order
+	binance.On("LimitOrder", "ETHUSDT", 0.01, 1900.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 1900, OrigQty: 0.01,
+	}, nil).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(1)).Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 1900, OrigQty: 0.01,
+	}, nil)
+	binance.On("CancelReplaceOrder", "ETHUSDT", int64(1), 0.01, 1990.00, "BUY", model.OrderTypeLimit).Return(model.BinanceOrder{
+		OrderId: 2, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 1990, OrigQty: 0.01,
+	}, nil).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(2)).Return(model.BinanceOrder{
+		OrderId: 2, Symbol: "ETHUSDT", Side: "BUY", Status: "FILLED", Price: 1990, OrigQty: 0.01, ExecutedQty: 0.01, CummulativeQuoteQty: 19.9,
+	}, nil)
+
+	err := orderExecutor.Buy(tradeLimit, "ETHUSDT", 1900.00, 0.01)
+	assertion.Nil(err)
+
+	binance.AssertNumberOfCalls(t, "CancelReplaceOrder", 1)
+	binance.AssertNotCalled(t, "CancelOrder", mock.Anything, mock.Anything)
+	assertion.Equal(int64(2), *orderRepository.Created.ExternalId)
+	assertion.Equal(1990.00, orderRepository.Created.Price)
+}
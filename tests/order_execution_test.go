@@ -0,0 +1,312 @@
+package tests
+
+import (
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"sync"
+	"testing"
+)
+
+func newExecutionOrderExecutor(
+	binance *ExchangeOrderAPIMock,
+	orderRepository *OrderStorageMock,
+	balanceService *BalanceServiceMock,
+	timeService *TimeServiceMock,
+	childOrderRepository *ChildOrderStorageMock,
+) *service.OrderExecutor {
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
+	callbackManager := new(TelegramNotificatorMock)
+	callbackManager.On("BuyOrder", mock.Anything, mock.Anything, mock.Anything).Maybe()
+	priceCalculator := new(PriceCalculatorMock)
+	priceCalculator.On("CalculateSell", mock.Anything, mock.Anything).Return(2100.00).Maybe()
+	priceCalculator.On("CalculateBuy", mock.Anything).Return(2000.00, nil).Maybe()
+
+	orderExecutor := &service.OrderExecutor{
+		CurrentBot: &model.Bot{
+			Id:      999,
+			BotUuid: uuid.New().String(),
+		},
+		TimeService:          timeService,
+		BalanceService:       balanceService,
+		Binance:              binance,
+		OrderRepository:      orderRepository,
+		PriceCalculator:      priceCalculator,
+		CallbackManager:      callbackManager,
+		OrderEventRecorder:   orderEventRecorder,
+		ChildOrderRepository: childOrderRepository,
+		Formatter:            &service.Formatter{},
+		Lock:                 make(map[string]bool),
+		TradeLockMutex:       sync.RWMutex{},
+		ShutdownService:      &service.ShutdownService{},
+	}
+
+	binance.On("GetOpenedOrders").Return([]model.BinanceOrder{}, nil)
+	orderRepository.On("GetBinanceOrder", "ETHUSDT", "BUY").Return(nil)
+	orderRepository.On("SetBinanceOrder", mock.Anything)
+	orderRepository.On("DeleteBinanceOrder", mock.Anything)
+	orderRepository.On("DeleteManualOrder", "ETHUSDT")
+	orderRepository.On("Update", mock.Anything).Return(nil)
+	balanceService.On("GetAssetBalance", "USDT", true).Return(1000.00, nil)
+	balanceService.On("InvalidateBalanceCache", mock.Anything)
+	timeService.On("GetNowDateTimeString").Return("2024-02-01 00:00:00")
+
+	return orderExecutor
+}
+
+func TestIcebergBuyIsAggregatedFromChildOrders(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	orderRepository := new(OrderStorageMock)
+	balanceService := new(BalanceServiceMock)
+	timeService := new(TimeServiceMock)
+	childOrderRepository := &ChildOrderStorageMock{}
+	orderExecutor := newExecutionOrderExecutor(binance, orderRepository, balanceService, timeService, childOrderRepository)
+
+	tradeLimit := model.TradeLimit{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        60,
+		MinPrice:         0.01,
+		MinQuantity:      0.0001,
+		MinNotional:      5,
+		MinProfitPercent: 1,
+		ExecutionOptions: model.ExecutionOptions{Algorithm: model.ExecutionAlgorithmIceberg, ClipUsdt: 20},
+	}
+
+	balanceService.On("GetAssetBalance", "ETH", true).Return(0.00, nil).Once()
+	balanceService.On("GetAssetBalance", "ETH", true).Return(0.023976, nil).Once()
+	timeService.On("GetNowUnix").Return(0)
+	orderId := int64(10)
+	orderRepository.On("Create", mock.Anything).Return(&orderId, nil)
+
+	// the last child order is partially filled and canceled, nothing is placed after it
+	binance.On("LimitOrder", "ETHUSDT", 0.01, 2000.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "FILLED", Price: 2000, OrigQty: 0.01, ExecutedQty: 0.01, CummulativeQuoteQty: 20,
+	}, nil).Once()
+	binance.On("LimitOrder", "ETHUSDT", 0.01, 2000.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 2, Symbol: "ETHUSDT", Side: "BUY", Status: "FILLED", Price: 2000, OrigQty: 0.01, ExecutedQty: 0.01, CummulativeQuoteQty: 19.9,
+	}, nil).Once()
+	binance.On("LimitOrder", "ETHUSDT", 0.01, 2000.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 3, Symbol: "ETHUSDT", Side: "BUY", Status: "CANCELED", Price: 2000, OrigQty: 0.01, ExecutedQty: 0.004, CummulativeQuoteQty: 8,
+	}, nil).Once()
+
+	err := orderExecutor.Buy(tradeLimit, "ETHUSDT", 2000.00, 0.03)
+	assertion.Nil(err)
+
+	binance.AssertNumberOfCalls(t, "LimitOrder", 3)
+	// parent order is saved after the first fill and updated by the next fills
+	orderRepository.AssertNumberOfCalls(t, "Create", 1)
+	assertion.InDelta(0.01, orderRepository.Created.ExecutedQuantity, 0.0000001)
+	assertion.Equal(orderId, orderRepository.Updated.Id)
+	assertion.Equal(int64(1), *orderRepository.Updated.ExternalId)
+	assertion.Equal(0.03, orderRepository.Updated.Quantity)
+	assertion.InDelta(0.024, orderRepository.Updated.ExecutedQuantity, 0.0000001)
+	assertion.InDelta(47.9/0.024, orderRepository.Updated.Price, 0.0000001)
+	assertion.InDelta(0.000024, *orderRepository.Updated.Commission, 0.0000001)
+
+	children := childOrderRepository.GetChildOrders(orderId)
+	assertion.Len(children, 3)
+	assertion.Equal(int64(3), children[2].ExternalId)
+	assertion.Equal("CANCELED", children[2].Status)
+	assertion.Equal(model.ExecutionAlgorithmIceberg, children[0].Algorithm)
+}
+
+func TestTwapBuyChildOrdersAreSpreadInTime(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	orderRepository := new(OrderStorageMock)
+	balanceService := new(BalanceServiceMock)
+	timeService := new(TimeServiceMock)
+	childOrderRepository := &ChildOrderStorageMock{}
+	orderExecutor := newExecutionOrderExecutor(binance, orderRepository, balanceService, timeService, childOrderRepository)
+
+	tradeLimit := model.TradeLimit{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        100,
+		MinPrice:         0.01,
+		MinQuantity:      0.0001,
+		MinNotional:      5,
+		MinProfitPercent: 1,
+		ExecutionOptions: model.ExecutionOptions{Algorithm: model.ExecutionAlgorithmTwap, Minutes: 3, Slices: 3},
+	}
+
+	balanceService.On("GetAssetBalance", "ETH", true).Return(0.00, nil)
+	timeService.On("GetNowUnix").Return(0).Once()
+	timeService.On("GetNowUnix").Return(10).Once()
+	timeService.On("GetNowUnix").Return(70).Once()
+	timeService.On("WaitSeconds", int64(50)).Twice()
+	orderId := int64(11)
+	orderRepository.On("Create", mock.Anything).Return(&orderId, nil)
+
+	binance.On("LimitOrder", "ETHUSDT", 0.0166, 2000.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "FILLED", Price: 2000, OrigQty: 0.0166, ExecutedQty: 0.0166, CummulativeQuoteQty: 33.2,
+	}, nil).Twice()
+	binance.On("LimitOrder", "ETHUSDT", 0.0168, 2000.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 3, Symbol: "ETHUSDT", Side: "BUY", Status: "FILLED", Price: 2000, OrigQty: 0.0168, ExecutedQty: 0.0168, CummulativeQuoteQty: 33.6,
+	}, nil).Once()
+
+	err := orderExecutor.Buy(tradeLimit, "ETHUSDT", 2000.00, 0.05)
+	assertion.Nil(err)
+
+	binance.AssertNumberOfCalls(t, "LimitOrder", 3)
+	timeService.AssertNumberOfCalls(t, "WaitSeconds", 2)
+	assertion.InDelta(0.05, orderRepository.Updated.ExecutedQuantity, 0.0000001)
+	assertion.InDelta(2000.00, orderRepository.Updated.Price, 0.0000001)
+	assertion.Len(childOrderRepository.GetChildOrders(orderId), 3)
+}
+
+func TestIcebergBuyIsResumedFromPendingChildOrders(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	orderRepository := new(OrderStorageMock)
+	balanceService := new(BalanceServiceMock)
+	timeService := new(TimeServiceMock)
+	// child order was filled before restart, parent order was not saved
+	childOrderRepository := &ChildOrderStorageMock{Children: []model.ChildOrder{{
+		Id: 1, Symbol: "ETHUSDT", ExternalId: 1, Operation: "buy", Algorithm: model.ExecutionAlgorithmIceberg,
+		Price: 2000, Quantity: 0.01, ExecutedQuantity: 0.01, Status: "FILLED",
+	}}}
+	orderExecutor := newExecutionOrderExecutor(binance, orderRepository, balanceService, timeService, childOrderRepository)
+
+	priceCalculator := new(PriceCalculatorMock)
+	priceCalculator.On("CalculateBuy", mock.Anything).Return(1990.00, nil)
+	priceCalculator.On("CalculateSell", mock.Anything, mock.Anything).Return(2100.00).Maybe()
+	orderExecutor.PriceCalculator = priceCalculator
+
+	tradeLimit := model.TradeLimit{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        60,
+		MinPrice:         0.01,
+		MinQuantity:      0.0001,
+		MinNotional:      5,
+		MinProfitPercent: 1,
+		ExecutionOptions: model.ExecutionOptions{Algorithm: model.ExecutionAlgorithmIceberg, ClipUsdt: 20},
+	}
+
+	balanceService.On("GetAssetBalance", "ETH", true).Return(0.01, nil).Once()
+	balanceService.On("GetAssetBalance", "ETH", true).Return(0.03, nil).Once()
+	timeService.On("GetNowUnix").Return(0)
+	orderId := int64(14)
+	orderRepository.On("Create", mock.Anything).Return(&orderId, nil)
+
+	// the rest is placed by re-calculated price
+	binance.On("LimitOrder", "ETHUSDT", 0.01, 1990.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 2, Symbol: "ETHUSDT", Side: "BUY", Status: "FILLED", Price: 1990, OrigQty: 0.01, ExecutedQty: 0.01, CummulativeQuoteQty: 19.9,
+	}, nil).Once()
+	binance.On("LimitOrder", "ETHUSDT", 0.01, 1990.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 3, Symbol: "ETHUSDT", Side: "BUY", Status: "FILLED", Price: 1990, OrigQty: 0.01, ExecutedQty: 0.01, CummulativeQuoteQty: 19.9,
+	}, nil).Once()
+
+	err := orderExecutor.Buy(tradeLimit, "ETHUSDT", 2000.00, 0.03)
+	assertion.Nil(err)
+
+	binance.AssertNumberOfCalls(t, "LimitOrder", 2)
+	orderRepository.AssertNumberOfCalls(t, "Create", 1)
+	assertion.Equal(int64(1), *orderRepository.Updated.ExternalId)
+	assertion.InDelta(0.03, orderRepository.Updated.ExecutedQuantity, 0.0000001)
+	assertion.InDelta(59.8/0.03, orderRepository.Updated.Price, 0.0000001)
+	assertion.Len(childOrderRepository.GetChildOrders(orderId), 3)
+	assertion.Len(childOrderRepository.GetPendingChildOrders("ETHUSDT", "BUY"), 0)
+}
+
+func TestTwapTradeLimitIsValidated(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeInfoAPIMock)
+	tradeLimitService := service.TradeLimitService{
+		ExchangeRepository: new(TradeLimitStorageMock),
+		OrderRepository:    new(OrderStorageMock),
+		Binance:            binance,
+	}
+
+	minNotional := 5.00
+	binance.On("GetExchangeData", mock.Anything).Return(&model.ExchangeInfo{Symbols: []model.ExchangeSymbol{
+		{Symbol: "ETHUSDT", Status: "TRADING", Filters: []model.ExchangeFilter{
+			{FilterType: "NOTIONAL", MinNotional: &minNotional},
+		}},
+	}}, nil)
+
+	report := tradeLimitService.Import([]model.TradeLimit{{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        40,
+		MinProfitPercent: 1,
+		ExecutionOptions: model.ExecutionOptions{Algorithm: model.ExecutionAlgorithmTwap, Slices: 10},
+	}}, false)
+
+	assertion.False(report.IsValid())
+	assertion.Len(report.Violations, 2)
+	assertion.Equal("twap minutes must be positive", report.Violations[0].Message)
+	assertion.Equal("twap slice amount 4.000000 is less than exchange min notional 5.000000", report.Violations[1].Message)
+}
+
+func TestTwapChildOrderIsCancelledAtTheEndOfSlice(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	orderRepository := new(OrderStorageMock)
+	lossSecurity := new(LossSecurityMock)
+	tradeLimit := model.TradeLimit{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        100,
+		MinPrice:         0.01,
+		MinQuantity:      0.0001,
+		MinNotional:      5,
+		MinProfitPercent: 1,
+		ExecutionOptions: model.ExecutionOptions{Algorithm: model.ExecutionAlgorithmTwap, Minutes: 2, Slices: 2},
+	}
+	orderExecutor := newAmendOrderExecutor(binance, orderRepository, lossSecurity, tradeLimit)
+	childOrderRepository := &ChildOrderStorageMock{}
+	orderExecutor.ChildOrderRepository = childOrderRepository
+
+	// the first slice is ended on the first status check
+	timeService := new(TimeServiceMock)
+	timeService.On("GetNowDateTimeString").Return("2024-02-01 00:00:00")
+	timeService.On("GetNowUnix").Return(0).Twice()
+	timeService.On("GetNowUnix").Return(600)
+	timeService.On("WaitMilliseconds", int64(20)).Maybe()
+	timeService.On("WaitSeconds", mock.Anything).Maybe()
+	orderExecutor.TimeService = timeService
+	priceCalculator := new(PriceCalculatorMock)
+	priceCalculator.On("CalculateBuy", tradeLimit).Return(2000.00, nil)
+	priceCalculator.On("CalculateSell", mock.Anything, mock.Anything).Return(2100.00).Maybe()
+	priceCalculator.On("GetDepth", "ETHUSDT").Return(model.Depth{Symbol: "ETHUSDT"})
+	orderExecutor.PriceCalculator = priceCalculator
+
+	lossSecurity.On("IsRiskyBuy", mock.Anything, tradeLimit).Return(false)
+	orderRepository.On("GetManualOrder", "ETHUSDT").Return(nil)
+	orderId := int64(15)
+	orderRepository.On("Create", mock.Anything).Return(&orderId, nil)
+
+	// child order of the first slice never fills, price is not moved away, so ttl of regular order would be ignored
+	binance.On("LimitOrder", "ETHUSDT", 0.025, 2000.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2000, OrigQty: 0.025,
+	}, nil).Once()
+	binance.On("QueryOrder", "ETHUSDT", int64(1)).Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "NEW", Price: 2000, OrigQty: 0.025,
+	}, nil)
+	binance.On("CancelOrder", "ETHUSDT", int64(1)).Return(model.BinanceOrder{
+		OrderId: 1, Symbol: "ETHUSDT", Side: "BUY", Status: "CANCELED", Price: 2000, OrigQty: 0.025,
+	}, nil).Once()
+	// not executed quantity of the first slice is placed with the second one
+	binance.On("LimitOrder", "ETHUSDT", 0.05, 2000.00, "BUY", "GTC").Return(model.BinanceOrder{
+		OrderId: 2, Symbol: "ETHUSDT", Side: "BUY", Status: "FILLED", Price: 2000, OrigQty: 0.05, ExecutedQty: 0.05, CummulativeQuoteQty: 100,
+	}, nil).Once()
+
+	err := orderExecutor.Buy(tradeLimit, "ETHUSDT", 2000.00, 0.05)
+	assertion.Nil(err)
+
+	binance.AssertNumberOfCalls(t, "LimitOrder", 2)
+	binance.AssertNumberOfCalls(t, "CancelOrder", 1)
+	assertion.Equal(int64(2), *orderRepository.Created.ExternalId)
+	assertion.InDelta(0.05, orderRepository.Created.ExecutedQuantity, 0.0000001)
+	children := childOrderRepository.GetChildOrders(orderId)
+	assertion.Len(children, 2)
+	assertion.Equal("CANCELED", children[0].Status)
+	assertion.Equal("FILLED", children[1].Status)
+}
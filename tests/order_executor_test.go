@@ -25,11 +25,11 @@ func TestSellAction(t *testing.T) {
 	swapValidator := new(SwapValidatorMock)
 	timeService := new(TimeServiceMock)
 	telegramNotificatorMock := new(TelegramNotificatorMock)
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
 
 	swapRepository.On("GetSwapChainCache", "ETH").Return(nil)
 
-	lockChannel := make(chan model.Lock)
-
 	lossSecurityMock := new(LossSecurityMock)
 	tradeLimit := model.TradeLimit{
 		Symbol:           "ETHUSDT",
@@ -59,21 +59,13 @@ func TestSellAction(t *testing.T) {
 		SwapSellOrderDays:  10,
 		SwapEnabled:        true,
 		SwapProfitPercent:  1.50,
-		LockChannel:        &lockChannel,
 		Lock:               make(map[string]bool),
 		TradeLockMutex:     sync.RWMutex{},
 		CallbackManager:    telegramNotificatorMock,
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
 	}
 
-	go func(orderExecutor *service.OrderExecutor) {
-		for {
-			lock := <-lockChannel
-			orderExecutor.TradeLockMutex.Lock()
-			orderExecutor.Lock[lock.Symbol] = lock.IsLocked
-			orderExecutor.TradeLockMutex.Unlock()
-		}
-	}(&orderExecutor)
-
 	initialBinanceOrder := model.BinanceOrder{
 		OrderId:     999,
 		Symbol:      "ETHUSDT",
@@ -182,10 +174,11 @@ func TestSellFoundFilled(t *testing.T) {
 	swapValidator := new(SwapValidatorMock)
 	timeService := new(TimeServiceMock)
 	telegramNotificatorMock := new(TelegramNotificatorMock)
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
 
 	swapRepository.On("GetSwapChainCache", "ETH").Return(nil)
 
-	lockChannel := make(chan model.Lock)
 	lossSecurityMock := new(LossSecurityMock)
 	tradeLimit := model.TradeLimit{
 		Symbol:           "ETHUSDT",
@@ -215,21 +208,13 @@ func TestSellFoundFilled(t *testing.T) {
 		SwapSellOrderDays:  10,
 		SwapEnabled:        true,
 		SwapProfitPercent:  1.50,
-		LockChannel:        &lockChannel,
 		Lock:               make(map[string]bool),
 		TradeLockMutex:     sync.RWMutex{},
 		CallbackManager:    telegramNotificatorMock,
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
 	}
 
-	go func(orderExecutor *service.OrderExecutor) {
-		for {
-			lock := <-lockChannel
-			orderExecutor.TradeLockMutex.Lock()
-			orderExecutor.Lock[lock.Symbol] = lock.IsLocked
-			orderExecutor.TradeLockMutex.Unlock()
-		}
-	}(&orderExecutor)
-
 	initialBinanceOrder := model.BinanceOrder{
 		OrderId:             999,
 		Symbol:              "ETHUSDT",
@@ -330,10 +315,11 @@ func TestSellCancelledInProcess(t *testing.T) {
 	swapValidator := new(SwapValidatorMock)
 	timeService := new(TimeServiceMock)
 	telegramNotificatorMock := new(TelegramNotificatorMock)
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
 
 	swapRepository.On("GetSwapChainCache", "ETH").Return(nil)
 
-	lockChannel := make(chan model.Lock)
 	lossSecurityMock := new(LossSecurityMock)
 	tradeLimit := model.TradeLimit{
 		Symbol:           "ETHUSDT",
@@ -363,21 +349,13 @@ func TestSellCancelledInProcess(t *testing.T) {
 		SwapSellOrderDays:  10,
 		SwapEnabled:        true,
 		SwapProfitPercent:  1.50,
-		LockChannel:        &lockChannel,
 		Lock:               make(map[string]bool),
 		TradeLockMutex:     sync.RWMutex{},
 		CallbackManager:    telegramNotificatorMock,
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
 	}
 
-	go func(orderExecutor *service.OrderExecutor) {
-		for {
-			lock := <-lockChannel
-			orderExecutor.TradeLockMutex.Lock()
-			orderExecutor.Lock[lock.Symbol] = lock.IsLocked
-			orderExecutor.TradeLockMutex.Unlock()
-		}
-	}(&orderExecutor)
-
 	initialBinanceOrder := model.BinanceOrder{
 		OrderId:     999,
 		Symbol:      "ETHUSDT",
@@ -457,6 +435,9 @@ func TestSellCancelledInProcess(t *testing.T) {
 
 	err := orderExecutor.Sell(tradeLimit, openedOrder, "ETHUSDT", 2281.52, 0.0089, false)
 	assertion.Error(errors.New("Order is cancelled"), err)
+	orderEventRecorder.AssertCalled(t, "Record", mock.MatchedBy(func(event model.OrderEvent) bool {
+		return event.Type == model.OrderEventCancelled && *event.Reason == model.OrderEventReasonExchange
+	}))
 }
 
 func TestSellQueryFail(t *testing.T) {
@@ -472,10 +453,11 @@ func TestSellQueryFail(t *testing.T) {
 	swapValidator := new(SwapValidatorMock)
 	timeService := new(TimeServiceMock)
 	telegramNotificatorMock := new(TelegramNotificatorMock)
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
 
 	swapRepository.On("GetSwapChainCache", "ETH").Return(nil)
 
-	lockChannel := make(chan model.Lock)
 	lossSecurityMock := new(LossSecurityMock)
 	tradeLimit := model.TradeLimit{
 		Symbol:           "ETHUSDT",
@@ -505,21 +487,13 @@ func TestSellQueryFail(t *testing.T) {
 		SwapSellOrderDays:  10,
 		SwapEnabled:        true,
 		SwapProfitPercent:  1.50,
-		LockChannel:        &lockChannel,
 		Lock:               make(map[string]bool),
 		TradeLockMutex:     sync.RWMutex{},
 		CallbackManager:    telegramNotificatorMock,
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
 	}
 
-	go func(orderExecutor *service.OrderExecutor) {
-		for {
-			lock := <-lockChannel
-			orderExecutor.TradeLockMutex.Lock()
-			orderExecutor.Lock[lock.Symbol] = lock.IsLocked
-			orderExecutor.TradeLockMutex.Unlock()
-		}
-	}(&orderExecutor)
-
 	initialBinanceOrder := model.BinanceOrder{
 		OrderId:     999,
 		Symbol:      "ETHUSDT",
@@ -590,6 +564,9 @@ func TestSellQueryFail(t *testing.T) {
 
 	err := orderExecutor.Sell(tradeLimit, openedOrder, "ETHUSDT", 2281.52, 0.0089, false)
 	assertion.Equal(errors.New("Order was canceled or expired"), err)
+	orderEventRecorder.AssertCalled(t, "Record", mock.MatchedBy(func(event model.OrderEvent) bool {
+		return event.Type == model.OrderEventCancelled && *event.Reason == model.OrderEventReasonExchange
+	}))
 }
 
 func TestSellClosingAction(t *testing.T) {
@@ -605,10 +582,11 @@ func TestSellClosingAction(t *testing.T) {
 	swapValidator := new(SwapValidatorMock)
 	timeService := new(TimeServiceMock)
 	telegramNotificatorMock := new(TelegramNotificatorMock)
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
 
 	swapRepository.On("GetSwapChainCache", "BTC").Return(nil)
 
-	lockChannel := make(chan model.Lock)
 	lossSecurityMock := new(LossSecurityMock)
 	tradeLimit := model.TradeLimit{
 		Symbol:           "BTCUSDT",
@@ -638,21 +616,13 @@ func TestSellClosingAction(t *testing.T) {
 		SwapSellOrderDays:  10,
 		SwapEnabled:        true,
 		SwapProfitPercent:  1.50,
-		LockChannel:        &lockChannel,
 		Lock:               make(map[string]bool),
 		TradeLockMutex:     sync.RWMutex{},
 		CallbackManager:    telegramNotificatorMock,
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
 	}
 
-	go func(orderExecutor *service.OrderExecutor) {
-		for {
-			lock := <-lockChannel
-			orderExecutor.TradeLockMutex.Lock()
-			orderExecutor.Lock[lock.Symbol] = lock.IsLocked
-			orderExecutor.TradeLockMutex.Unlock()
-		}
-	}(&orderExecutor)
-
 	initialBinanceOrder := model.BinanceOrder{
 		OrderId:     999,
 		Symbol:      "BTCUSDT",
@@ -761,10 +731,11 @@ func TestSellClosingTrxAction(t *testing.T) {
 	swapValidator := new(SwapValidatorMock)
 	timeService := new(TimeServiceMock)
 	telegramNotificatorMock := new(TelegramNotificatorMock)
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
 
 	swapRepository.On("GetSwapChainCache", "TRX").Return(nil)
 
-	lockChannel := make(chan model.Lock)
 	lossSecurityMock := new(LossSecurityMock)
 	tradeLimit := model.TradeLimit{
 		Symbol:           "TRXUSDT",
@@ -794,21 +765,13 @@ func TestSellClosingTrxAction(t *testing.T) {
 		SwapSellOrderDays:  10,
 		SwapEnabled:        true,
 		SwapProfitPercent:  1.50,
-		LockChannel:        &lockChannel,
 		Lock:               make(map[string]bool),
 		TradeLockMutex:     sync.RWMutex{},
 		CallbackManager:    telegramNotificatorMock,
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
 	}
 
-	go func(orderExecutor *service.OrderExecutor) {
-		for {
-			lock := <-lockChannel
-			orderExecutor.TradeLockMutex.Lock()
-			orderExecutor.Lock[lock.Symbol] = lock.IsLocked
-			orderExecutor.TradeLockMutex.Unlock()
-		}
-	}(&orderExecutor)
-
 	initialBinanceOrder := model.BinanceOrder{
 		OrderId:     999,
 		Symbol:      "TRXUSDT",
@@ -903,3 +866,48 @@ func TestSellClosingTrxAction(t *testing.T) {
 	assertion.Equal(0.10457, orderRepository.Updated.Price)
 	assertion.Equal(openedExternalId, *orderRepository.Updated.ExternalId)
 }
+
+func TestSwapStartQuantityIsLimitedByPosition(t *testing.T) {
+	assertion := assert.New(t)
+
+	balanceService := new(BalanceServiceMock)
+	orderRepository := new(OrderStorageMock)
+	swapRepository := new(SwapRepositoryMock)
+	timeService := new(TimeServiceMock)
+	eventRecorder := new(OrderEventRecorderMock)
+
+	orderExecutor := service.OrderExecutor{
+		CurrentBot:         &model.Bot{Id: 1},
+		BalanceService:     balanceService,
+		OrderRepository:    orderRepository,
+		SwapRepository:     swapRepository,
+		TimeService:        timeService,
+		OrderEventRecorder: eventRecorder,
+	}
+
+	soldQuantity := 10.00
+	order := model.Order{Id: 5, Symbol: "ETHBTC", Operation: "buy", Status: "opened", ExecutedQuantity: 100, SoldQuantity: &soldQuantity}
+	swapChain := model.SwapChainEntity{
+		Id:        3,
+		Title:     "BTC > ETH > XRP > BTC",
+		SwapOne:   &model.SwapTransitionEntity{BaseAsset: "ETH", QuoteAsset: "BTC"},
+		SwapTwo:   &model.SwapTransitionEntity{BaseAsset: "XRP", QuoteAsset: "ETH"},
+		SwapThree: &model.SwapTransitionEntity{BaseAsset: "XRP", QuoteAsset: "BTC"},
+	}
+
+	// 30 ETH are bought by DCA plan and are not swapped
+	balanceService.On("GetAssetBalance", "ETH", false).Return(120.00, nil)
+	swapRepository.On("GetActiveSwapAction", order).Return(model.SwapAction{}, errors.New("not found"))
+	timeService.On("GetNowUnix").Return(1000)
+	swapActionId := int64(1)
+	swapRepository.On("CreateSwapAction", mock.MatchedBy(func(action model.SwapAction) bool {
+		return action.StartQuantity == 90.00 && action.OrderId == 5
+	})).Return(&swapActionId, nil).Once()
+	orderRepository.On("Update", mock.Anything).Return(nil)
+	eventRecorder.On("Record", mock.Anything)
+
+	orderExecutor.MakeSwap(order, swapChain)
+
+	swapRepository.AssertExpectations(t)
+	assertion.True(orderRepository.Updated.Swap)
+}
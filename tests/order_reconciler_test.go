@@ -0,0 +1,257 @@
+package tests
+
+import (
+	"errors"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"sync"
+	"testing"
+)
+
+func TestReconcileOrphanedOrderAndMissedFill(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	orderRepository := new(OrderStorageMock)
+	tradeLimitReader := new(TradeLimitReaderMock)
+	tradeLock := new(TradeLockMock)
+	balanceService := new(BalanceServiceMock)
+	timeService := new(TimeServiceMock)
+	callbackManager := new(TelegramNotificatorMock)
+	orderEventRecorder := new(OrderEventRecorderMock)
+
+	reconciler := service.OrderReconciler{
+		Binance:            binance,
+		OrderRepository:    orderRepository,
+		ExchangeRepository: tradeLimitReader,
+		BalanceService:     balanceService,
+		CallbackManager:    callbackManager,
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
+		TradeLock:          tradeLock,
+		TimeService:        timeService,
+		CurrentBot: &model.Bot{
+			Id:      999,
+			BotUuid: uuid.New().String(),
+		},
+	}
+
+	tradeLimit := model.TradeLimit{
+		Symbol:      "ETHUSDT",
+		MinQuantity: 0.0001,
+	}
+	tradeLimitReader.On("GetTradeLimits").Return([]model.TradeLimit{tradeLimit})
+	tradeLock.On("TryLock", "ETHUSDT").Return(true)
+	tradeLock.On("Unlock", "ETHUSDT").Once()
+	timeService.On("GetNowDateTimeString").Return("2024-02-01 00:00:00")
+
+	// BUY order is opened on exchange, but cache is empty
+	orphaned := model.BinanceOrder{
+		OrderId: 1001,
+		Symbol:  "ETHUSDT",
+		Side:    "BUY",
+		Status:  "NEW",
+		Price:   2200.00,
+		OrigQty: 0.01,
+	}
+	binance.On("GetOpenedOrdersBySymbol", "ETHUSDT").Return([]model.BinanceOrder{orphaned}, nil)
+	orderRepository.On("GetBinanceOrder", "ETHUSDT", "BUY").Return(nil)
+
+	// SELL order was filled while bot was offline
+	cachedSell := model.BinanceOrder{
+		OrderId: 1002,
+		Symbol:  "ETHUSDT",
+		Side:    "SELL",
+		Status:  "NEW",
+		Price:   2300.00,
+		OrigQty: 0.01,
+	}
+	filledSell := cachedSell
+	filledSell.Status = "FILLED"
+	filledSell.ExecutedQty = 0.01
+	orderRepository.On("GetBinanceOrder", "ETHUSDT", "SELL").Return(&cachedSell)
+	binance.On("QueryOrder", "ETHUSDT", int64(1002)).Return(filledSell, nil)
+	orderRepository.On("FindByExternalId", "ETHUSDT", int64(1002)).Return(model.Order{}, errors.New("sql: no rows in result set"))
+
+	openedExternalId := int64(900)
+	opened := model.Order{
+		Id:               50,
+		Symbol:           "ETHUSDT",
+		Operation:        "buy",
+		Status:           "opened",
+		Price:            2100.00,
+		Quantity:         0.01,
+		ExecutedQuantity: 0.01,
+		ExternalId:       &openedExternalId,
+	}
+	orderRepository.On("GetOpenedOrder", "ETHUSDT", "BUY").Return(opened, nil)
+	createdId := int64(51)
+	orderRepository.On("Create", mock.Anything).Once().Return(&createdId, nil)
+	orderRepository.On("Update", mock.Anything).Once().Return(nil)
+	orderRepository.On("DeleteBinanceOrder", filledSell).Once()
+	balanceService.On("InvalidateBalanceCache", "USDT").Once()
+	balanceService.On("InvalidateBalanceCache", "ETH").Once()
+	orderEventRecorder.On("Record", mock.Anything)
+
+	binance.On("GetTrades", opened).Return([]model.MyTrade{
+		{OrderId: 900, Quantity: 0.01, Price: 2100.00, IsBuyer: true},
+	}, nil)
+	orderRepository.On("GetClosesOrderList", opened).Return([]model.Order{})
+	balanceService.On("GetAssetBalance", "ETH", false).Return(0.01, nil)
+	callbackManager.On("Error", mock.Anything, "reconciliation", mock.Anything, false).Once()
+
+	report := reconciler.Reconcile()
+
+	assertion.Equal(1, report.Symbols)
+	assertion.Len(report.Discrepancies, 2)
+	assertion.Equal(model.ReconciliationOrphanedOrder, report.Discrepancies[0].Type)
+	assertion.False(report.Discrepancies[0].Fixed)
+	assertion.Equal(model.ReconciliationMissedFill, report.Discrepancies[1].Type)
+	assertion.True(report.Discrepancies[1].Fixed)
+	assertion.Equal(createdId, *report.Discrepancies[1].OrderId)
+
+	assertion.Equal("sell", orderRepository.Created.Operation)
+	assertion.Equal("closed", orderRepository.Created.Status)
+	assertion.Equal(opened.Id, *orderRepository.Created.ClosesOrder)
+	assertion.Equal(0.01, orderRepository.Created.ExecutedQuantity)
+	assertion.Equal("closed", orderRepository.Updated.Status)
+	assertion.Equal(&report, reconciler.GetLastReport())
+	orderRepository.AssertExpectations(t)
+	orderRepository.AssertNotCalled(t, "SetBinanceOrder", orphaned)
+	callbackManager.AssertExpectations(t)
+	tradeLock.AssertExpectations(t)
+}
+
+func TestReconcileSoldQuantity(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	orderRepository := new(OrderStorageMock)
+	tradeLimitReader := new(TradeLimitReaderMock)
+	tradeLock := new(TradeLockMock)
+	balanceService := new(BalanceServiceMock)
+	timeService := new(TimeServiceMock)
+	callbackManager := new(TelegramNotificatorMock)
+	orderEventRecorder := new(OrderEventRecorderMock)
+
+	reconciler := service.OrderReconciler{
+		Binance:            binance,
+		OrderRepository:    orderRepository,
+		ExchangeRepository: tradeLimitReader,
+		BalanceService:     balanceService,
+		CallbackManager:    callbackManager,
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
+		TradeLock:          tradeLock,
+		TimeService:        timeService,
+		CurrentBot: &model.Bot{
+			Id:      999,
+			BotUuid: uuid.New().String(),
+		},
+	}
+
+	tradeLimitReader.On("GetTradeLimits").Return([]model.TradeLimit{
+		{Symbol: "ETHUSDT", MinQuantity: 0.0001},
+		{Symbol: "BTCUSDT", MinQuantity: 0.00001},
+	})
+	tradeLock.On("TryLock", "ETHUSDT").Return(true)
+	tradeLock.On("Unlock", "ETHUSDT").Once()
+	tradeLock.On("TryLock", "BTCUSDT").Return(false)
+	timeService.On("GetNowDateTimeString").Return("2024-02-01 00:00:00")
+	binance.On("GetOpenedOrdersBySymbol", "ETHUSDT").Return([]model.BinanceOrder{}, nil)
+	orderRepository.On("GetBinanceOrder", "ETHUSDT", "BUY").Return(nil)
+	orderRepository.On("GetBinanceOrder", "ETHUSDT", "SELL").Return(nil)
+
+	openedExternalId := int64(900)
+	soldQuantity := 0.005
+	opened := model.Order{
+		Id:               50,
+		Symbol:           "ETHUSDT",
+		Operation:        "buy",
+		Status:           "opened",
+		Price:            2100.00,
+		Quantity:         0.02,
+		ExecutedQuantity: 0.02,
+		ExternalId:       &openedExternalId,
+		SoldQuantity:     &soldQuantity,
+	}
+	sellExternalId := int64(901)
+	sell := model.Order{
+		Id:               51,
+		Symbol:           "ETHUSDT",
+		Operation:        "sell",
+		Status:           "closed",
+		Price:            2300.00,
+		Quantity:         0.01,
+		ExecutedQuantity: 0.005,
+		ExternalId:       &sellExternalId,
+		ClosesOrder:      &opened.Id,
+	}
+	orderRepository.On("GetOpenedOrder", "ETHUSDT", "BUY").Return(opened, nil)
+	binance.On("GetTrades", opened).Return([]model.MyTrade{
+		{OrderId: 900, Quantity: 0.02, Price: 2100.00, IsBuyer: true},
+		{OrderId: 901, Quantity: 0.005, Price: 2300.00, IsBuyer: false},
+		{OrderId: 901, Quantity: 0.005, Price: 2300.00, IsBuyer: false},
+		{OrderId: 902, Quantity: 0.01, Price: 2000.00, IsBuyer: true},
+	}, nil)
+	// extra charge BUY closes the same position, but it is not a sell
+	extraExternalId := int64(902)
+	extraBuy := model.Order{
+		Id:               52,
+		Symbol:           "ETHUSDT",
+		Operation:        "buy",
+		Status:           "closed",
+		Price:            2000.00,
+		Quantity:         0.01,
+		ExecutedQuantity: 0.005,
+		ExternalId:       &extraExternalId,
+		ClosesOrder:      &opened.Id,
+	}
+	orderRepository.On("GetClosesOrderList", opened).Return([]model.Order{sell, extraBuy})
+	orderRepository.On("Update", mock.Anything).Once().Return(nil)
+	orderEventRecorder.On("Record", mock.Anything).Once()
+	balanceService.On("GetAssetBalance", "ETH", false).Return(0.015, nil)
+	callbackManager.On("Error", mock.Anything, "reconciliation", mock.Anything, false).Once()
+
+	report := reconciler.Reconcile()
+
+	assertion.Equal(1, report.Symbols)
+	assertion.Equal([]string{"BTCUSDT"}, report.Skipped)
+	assertion.Len(report.Discrepancies, 1)
+	assertion.Equal(model.ReconciliationSoldQuantity, report.Discrepancies[0].Type)
+	assertion.Equal(0.01, report.Discrepancies[0].Expected)
+	assertion.Equal(0.005, report.Discrepancies[0].Actual)
+	assertion.True(report.Discrepancies[0].Fixed)
+	assertion.Equal(sell.Id, orderRepository.Updated.Id)
+	assertion.Equal(0.01, orderRepository.Updated.ExecutedQuantity)
+	binance.AssertNotCalled(t, "GetOpenedOrdersBySymbol", "BTCUSDT")
+}
+
+func TestOrderExecutorLockIsTakenOnce(t *testing.T) {
+	assertion := assert.New(t)
+
+	orderExecutor := service.OrderExecutor{
+		Lock:           make(map[string]bool),
+		TradeLockMutex: sync.RWMutex{},
+	}
+
+	assertion.True(orderExecutor.TryLock("ETHUSDT"))
+	assertion.False(orderExecutor.TryLock("ETHUSDT"))
+	assertion.True(orderExecutor.IsTradeLocked("ETHUSDT"))
+	assertion.True(orderExecutor.TryLock("BTCUSDT"))
+
+	// trade operations are rejected while symbol is reconciled and don't release reconciler lock
+	tradeLimit := model.TradeLimit{Symbol: "ETHUSDT"}
+	assertion.EqualError(orderExecutor.Buy(tradeLimit, "ETHUSDT", 2000, 0.01), "Operation Buy is Locked ETHUSDT")
+	assertion.EqualError(orderExecutor.Sell(tradeLimit, model.Order{Symbol: "ETHUSDT"}, "ETHUSDT", 2000, 0.01, false), "Operation Sell is Locked ETHUSDT")
+	_, err := orderExecutor.BuyAccumulation(tradeLimit, 2000, 0.01, 60)
+	assertion.EqualError(err, "Operation Buy is Locked ETHUSDT")
+	assertion.True(orderExecutor.IsTradeLocked("ETHUSDT"))
+
+	orderExecutor.Unlock("ETHUSDT")
+	assertion.False(orderExecutor.IsTradeLocked("ETHUSDT"))
+	assertion.True(orderExecutor.TryLock("ETHUSDT"))
+}
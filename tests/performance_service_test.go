@@ -0,0 +1,138 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"testing"
+)
+
+func getPerformanceService() (*service.PerformanceService, *PerformanceStorageMock) {
+	buyBtcId := int64(1)
+	extraBtcId := int64(3)
+	buyEthId := int64(5)
+
+	orderRepository := new(OrderListReaderMock)
+	orderRepository.On("GetList").Return([]model.Order{
+		{Id: 1, Symbol: "BTCUSDT", Operation: "buy", Status: "closed", Price: 100, ExecutedQuantity: 1, CreatedAt: "2024-01-01 00:00:00"},
+		{Id: 2, Symbol: "BTCUSDT", Operation: "sell", Status: "closed", Price: 110, ExecutedQuantity: 1, ClosesOrder: &buyBtcId, CreatedAt: "2024-01-02 00:00:00"},
+		{Id: 3, Symbol: "BTCUSDT", Operation: "buy", Status: "closed", Price: 100, ExecutedQuantity: 1, UsedExtraBudget: 50, CreatedAt: "2024-01-03 00:00:00"},
+		{Id: 4, Symbol: "BTCUSDT", Operation: "sell", Status: "closed", Price: 95, ExecutedQuantity: 1, ClosesOrder: &extraBtcId, CreatedAt: "2024-01-04 00:00:00"},
+		{Id: 5, Symbol: "ETHUSDT", Operation: "buy", Status: "closed", Price: 50, ExecutedQuantity: 2, CreatedAt: "2024-01-02 00:00:00"},
+		{Id: 6, Symbol: "ETHUSDT", Operation: "sell", Status: "closed", Price: 60, ExecutedQuantity: 2, ClosesOrder: &buyEthId, CreatedAt: "2024-01-03 12:00:00"},
+	})
+
+	swapRepository := new(SwapGainReaderMock)
+	swapRepository.On("GetSwapGains").Return(map[int64]float64{})
+
+	btcLimit := model.TradeLimit{Id: 1, Symbol: "BTCUSDT", USDTLimit: 100, MinProfitPercent: 2, IsEnabled: true}
+	exchangeRepository := new(TradeLimitFinderMock)
+	exchangeRepository.On("GetTradeLimits").Return([]model.TradeLimit{
+		btcLimit,
+		{Id: 2, Symbol: "ETHUSDT", USDTLimit: 100, MinProfitPercent: 2, IsEnabled: true},
+	})
+	exchangeRepository.On("GetTradeLimit", "BTCUSDT").Return(btcLimit, nil)
+
+	performanceRepository := &PerformanceStorageMock{
+		OrderStrategies: map[int64][]string{1: {"rsi"}, 3: {"ma", "rsi"}, 5: {"ma"}},
+	}
+
+	return &service.PerformanceService{
+		OrderRepository: orderRepository,
+		PnlService: &service.PnlService{
+			OrderRepository:    orderRepository,
+			SwapRepository:     swapRepository,
+			ExchangeRepository: new(ExchangePriceStorageMock),
+		},
+		ExchangeRepository:    exchangeRepository,
+		PerformanceRepository: performanceRepository,
+	}, performanceRepository
+}
+
+func TestPerformanceSummary(t *testing.T) {
+	assertion := assert.New(t)
+
+	performanceService, _ := getPerformanceService()
+	summary, err := performanceService.GetSummary("2024-01-01", "2024-01-04")
+	assertion.Nil(err)
+
+	overall := summary.Overall
+	assertion.Equal(200.00, overall.Capital)
+	assertion.Equal(int64(3), overall.Trades)
+	assertion.Equal(int64(2), overall.Wins)
+	assertion.Equal(int64(1), overall.Losses)
+	assertion.Equal(66.66666667, overall.WinRate)
+	// (24 + 24 + 36) / 3
+	assertion.Equal(28.00, overall.AvgHoursOpened)
+	assertion.Equal(30.00, overall.GrossProfit)
+	assertion.Equal(5.00, overall.GrossLoss)
+	assertion.Equal(6.00, *overall.ProfitFactor)
+	assertion.Equal(25.00, overall.NetPnl)
+	// cumulative 10 -> 30 -> 25
+	assertion.Equal(5.00, overall.MaxDrawdown)
+	assertion.Equal(2.5, overall.MaxDrawdownPercent)
+	assertion.Equal(int64(3), overall.Positions)
+	assertion.Equal(int64(1), overall.ExtraChargePositions)
+	assertion.Equal(33.33333333, overall.ExtraChargeRate)
+	assertion.Equal(50.00, overall.ExtraChargeBudget)
+	assertion.Greater(overall.Sharpe, 0.00)
+	assertion.Greater(overall.Sortino, 0.00)
+	// 8400 USDT hours invested of 200 USDT * 96 hours
+	assertion.InDelta(43.75, overall.CapitalUtilisation, 0.001)
+
+	assertion.Len(summary.Symbols, 2)
+	assertion.Equal("BTCUSDT", summary.Symbols[0].Symbol)
+	assertion.Equal(int64(2), summary.Symbols[0].Trades)
+	assertion.Equal(5.00, summary.Symbols[0].NetPnl)
+	assertion.Equal(100.00, summary.Symbols[0].Capital)
+	assertion.Equal("ETHUSDT", summary.Symbols[1].Symbol)
+	assertion.Nil(summary.Symbols[1].ProfitFactor)
+
+	assertion.Len(summary.Strategies, 2)
+	assertion.Equal("ma", summary.Strategies[0].Strategy)
+	assertion.Equal(15.00, summary.Strategies[0].NetPnl)
+	assertion.Equal("rsi", summary.Strategies[1].Strategy)
+	assertion.Equal(5.00, summary.Strategies[1].NetPnl)
+
+	// only trades closed within period
+	summary, err = performanceService.GetSummary("2024-01-03", "2024-01-03")
+	assertion.Nil(err)
+	assertion.Equal(int64(1), summary.Overall.Trades)
+	assertion.Equal(20.00, summary.Overall.NetPnl)
+
+	_, err = performanceService.GetSummary("2024-01-05", "2024-01-01")
+	assertion.Equal("Date to must be after date from", err.Error())
+}
+
+func TestPerformanceSnapshotOnTradeLimitChange(t *testing.T) {
+	assertion := assert.New(t)
+
+	performanceService, performanceRepository := getPerformanceService()
+	before := model.TradeLimit{Id: 1, Symbol: "BTCUSDT", USDTLimit: 100, MinProfitPercent: 2, IsEnabled: true}
+
+	performance, err := performanceService.GetTradeLimitPerformance("BTCUSDT")
+	assertion.Nil(err)
+	assertion.Equal(int64(2), performance.Current.Trades)
+	assertion.Equal("2024-01-01 00:00:00", performance.Current.From)
+	assertion.Len(performance.Snapshots, 0)
+
+	// exchange filters are synchronized by bot, it is not configuration change
+	after := before
+	after.MinNotional = 5
+	after.ExtraChargeOptions = model.ExtraChargeOptions{}
+	performanceService.OnTradeLimitChange(before, after)
+	assertion.Len(performanceRepository.Snapshots, 0)
+
+	after.USDTLimit = 200
+	performanceService.OnTradeLimitChange(before, after)
+	assertion.Len(performanceRepository.Snapshots, 1)
+	assertion.Equal(model.PerformanceSnapshotConfigChange, performanceRepository.Snapshots[0].Reason)
+	assertion.Equal(100.00, performanceRepository.Snapshots[0].TradeLimit.USDTLimit)
+	assertion.Equal(int64(2), performanceRepository.Snapshots[0].Stats.Trades)
+
+	// new configuration period is started by snapshot
+	performance, err = performanceService.GetTradeLimitPerformance("BTCUSDT")
+	assertion.Nil(err)
+	assertion.Equal(int64(0), performance.Current.Trades)
+	assertion.Len(performance.Snapshots, 1)
+}
@@ -0,0 +1,93 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"testing"
+)
+
+func getPnlService() *service.PnlService {
+	btcCommission := 0.001
+	btcAsset := "BTC"
+	sellCommission := 0.1375
+	usdtAsset := "USDT"
+	bnbCommission := 0.01
+	bnbAsset := "BNB"
+	buyBtcId := int64(1)
+	buyEthId := int64(5)
+
+	orderRepository := new(OrderListReaderMock)
+	orderRepository.On("GetList").Return([]model.Order{
+		{Id: 1, Symbol: "BTCUSDT", Operation: "BUY", Status: "closed", Price: 100, ExecutedQuantity: 1, Commission: &btcCommission, CommissionAsset: &btcAsset, CreatedAt: "2024-01-30 10:00:00"},
+		{Id: 2, Symbol: "BTCUSDT", Operation: "SELL", Status: "closed", Price: 110, ExecutedQuantity: 1.25, Commission: &sellCommission, CommissionAsset: &usdtAsset, ClosesOrder: &buyBtcId, CreatedAt: "2024-01-31 10:00:00"},
+		{Id: 3, Symbol: "ETHUSDT", Operation: "BUY", Status: "opened", Price: 50, ExecutedQuantity: 2, Commission: &bnbCommission, CommissionAsset: &bnbAsset, CreatedAt: "2024-02-02 10:00:00"},
+		{Id: 4, Symbol: "ETHUSDT", Operation: "SELL", Status: "closed", Price: 30, ExecutedQuantity: 1, ClosesOrder: &buyEthId, CreatedAt: "2024-02-01 10:00:00"},
+		{Id: 5, Symbol: "ETHUSDT", Operation: "BUY", Status: "closed", Price: 40, ExecutedQuantity: 1, CreatedAt: "2024-01-15 10:00:00"},
+	})
+
+	// swap added 0.25 BTC to position of order 1
+	swapRepository := new(SwapGainReaderMock)
+	swapRepository.On("GetSwapGains").Return(map[int64]float64{1: 0.25})
+
+	exchangeRepository := new(ExchangePriceStorageMock)
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Symbol: "ETHUSDT", Close: 55})
+	exchangeRepository.On("GetLastKLine", "BNBUSDT").Return(&model.KLine{Symbol: "BNBUSDT", Close: 300})
+
+	return &service.PnlService{
+		OrderRepository:    orderRepository,
+		SwapRepository:     swapRepository,
+		ExchangeRepository: exchangeRepository,
+	}
+}
+
+func TestPnlReport(t *testing.T) {
+	assertion := assert.New(t)
+
+	report, err := getPnlService().GetReport(model.PnlPeriodMonth)
+	assertion.Nil(err)
+
+	// cost price 100 / 1.25 = 80, gross (110 - 80) * 1.25 = 37.5, fees 0.1375 USDT + 0.001 BTC * 100
+	assertion.Equal([]model.PnlPeriodSummary{
+		{Period: "2024-01", Trades: 1, GrossPnl: 37.5, Fees: 0.2375, RealizedPnl: 37.2625},
+		{Period: "2024-02", Trades: 1, GrossPnl: -10, Fees: 0, RealizedPnl: -10},
+	}, report.Overall)
+	assertion.Equal("BTCUSDT", report.Symbols[0].Symbol)
+	assertion.Equal("ETHUSDT", report.Symbols[1].Symbol)
+	assertion.Equal(27.2625, report.RealizedPnl)
+
+	// (55 - 50) * 2 - 0.01 BNB * 300
+	assertion.Len(report.Unrealized, 1)
+	assertion.Equal(7.0, report.Unrealized[0].Pnl)
+	assertion.Equal(7.0, report.UnrealizedPnl)
+
+	assertion.Equal([]model.FeeTotal{
+		{Asset: "BNB", Amount: 0.01, QuoteAmount: 3},
+		{Asset: "BTC", Amount: 0.001, QuoteAmount: 0.1},
+		{Asset: "USDT", Amount: 0.1375, QuoteAmount: 0.1375},
+	}, report.FeeTotals)
+
+	_, err = getPnlService().GetReport("year")
+	assertion.NotNil(err)
+}
+
+func TestPnlWeeklyReport(t *testing.T) {
+	assertion := assert.New(t)
+
+	report, err := getPnlService().GetReport(model.PnlPeriodWeek)
+	assertion.Nil(err)
+	assertion.Equal("2024-W05", report.Overall[0].Period)
+	assertion.Equal(int64(2), report.Overall[0].Trades)
+}
+
+func TestPnlEquityCurve(t *testing.T) {
+	assertion := assert.New(t)
+
+	points := getPnlService().GetEquityCurve()
+
+	assertion.Len(points, 3)
+	assertion.Equal(37.2625, points[0].Equity)
+	assertion.Equal(27.2625, points[1].Equity)
+	assertion.Equal(34.2625, points[2].Equity)
+	assertion.Equal(7.0, points[2].UnrealizedPnl)
+}
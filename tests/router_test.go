@@ -0,0 +1,127 @@
+package tests
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/controller"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func getTestRouter() *controller.Router {
+	return &controller.Router{
+		Prefix: controller.ApiV1Prefix,
+		ApiGuard: &controller.ApiGuard{
+			ApiAuthenticator: &service.ApiAuthenticator{
+				ApiClientRepository: new(ApiClientStorageMock),
+				CurrentBot:          &model.Bot{Id: 999, BotUuid: "bot-uuid"},
+				RecvWindow:          5000,
+				LegacyScope:         model.ApiScopeRead,
+			},
+			ApiAuditRepository: new(ApiAuditStorageMock),
+			CorsOrigins:        []string{"*"},
+		},
+		Routes: []controller.Route{
+			{Method: "GET", Path: "/depth/", Scope: model.ApiScopeRead, Tag: "exchange", Handler: func(w http.ResponseWriter, req *http.Request) {
+				fmt.Fprintf(w, req.URL.Path)
+			}},
+			{Method: "POST", Path: "/order", Scope: model.ApiScopeTrade, Tag: "order", Handler: func(w http.ResponseWriter, req *http.Request) {
+				fmt.Fprintf(w, "OK")
+			}},
+			{Method: "GET", Path: "/order/reconciliation", Scope: model.ApiScopeRead, Tag: "order", Handler: func(w http.ResponseWriter, req *http.Request) {
+				http.Error(w, "Reconciliation is not finished yet", http.StatusNotFound)
+			}},
+		},
+	}
+}
+
+func TestRouterRoutesAndErrorEnvelope(t *testing.T) {
+	assertion := assert.New(t)
+	router := getTestRouter()
+
+	// controller receives path without version prefix
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("GET", "/api/v1/depth/ETHUSDT?botUuid=bot-uuid", nil))
+	assertion.Equal(http.StatusOK, response.Code)
+	assertion.Equal("/depth/ETHUSDT", response.Body.String())
+	assertion.Equal("*", response.Header().Get("Access-Control-Allow-Origin"))
+
+	var envelope controller.ApiErrorEnvelope
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("GET", "/api/v1/order?botUuid=bot-uuid", nil))
+	assertion.Equal(http.StatusMethodNotAllowed, response.Code)
+	assertion.Equal("POST", response.Header().Get("Allow"))
+	assertion.Equal("application/json", response.Header().Get("Content-Type"))
+	assertion.Nil(json.Unmarshal(response.Body.Bytes(), &envelope))
+	assertion.Equal(http.StatusMethodNotAllowed, envelope.Error.Code)
+	assertion.Equal("Method is not allowed", envelope.Error.Message)
+
+	// legacy botUuid has read scope only
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("POST", "/api/v1/order?botUuid=bot-uuid", nil))
+	assertion.Nil(json.Unmarshal(response.Body.Bytes(), &envelope))
+	assertion.Equal(http.StatusForbidden, envelope.Error.Code)
+	assertion.Equal("Scope 'trade' is required, use API key", envelope.Error.Message)
+
+	// controller errors are wrapped as well
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("GET", "/api/v1/order/reconciliation?botUuid=bot-uuid", nil))
+	assertion.Nil(json.Unmarshal(response.Body.Bytes(), &envelope))
+	assertion.Equal(http.StatusNotFound, envelope.Error.Code)
+	assertion.Equal("Reconciliation is not finished yet", envelope.Error.Message)
+
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("GET", "/api/v1/unknown", nil))
+	assertion.Equal(http.StatusNotFound, response.Code)
+
+	// preflight does not require authorization
+	response = httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("OPTIONS", "/api/v1/order", nil))
+	assertion.Equal(http.StatusOK, response.Code)
+}
+
+func TestRouterOpenApi(t *testing.T) {
+	assertion := assert.New(t)
+	router := getTestRouter()
+
+	response := httptest.NewRecorder()
+	router.ServeHTTP(response, httptest.NewRequest("GET", "/api/v1/openapi.json", nil))
+	assertion.Equal(http.StatusOK, response.Code)
+
+	var document struct {
+		OpenApi string                               `json:"openapi"`
+		Paths   map[string]map[string]map[string]any `json:"paths"`
+	}
+	assertion.Nil(json.Unmarshal(response.Body.Bytes(), &document))
+	assertion.Equal("3.0.3", document.OpenApi)
+	assertion.Len(document.Paths, 3)
+	assertion.Equal("trade", document.Paths["/api/v1/order"]["post"]["x-scope"])
+	assertion.Equal("get_depth", document.Paths["/api/v1/depth/{symbol}"]["get"]["operationId"])
+}
+
+func TestLegacyRoutesAreRoutedByMethod(t *testing.T) {
+	assertion := assert.New(t)
+	router := getTestRouter()
+
+	// legacy path is served without version prefix and error envelope
+	handler := controller.MethodHandler(router.ApiGuard, router.Routes[1:2])
+
+	response := httptest.NewRecorder()
+	handler(response, httptest.NewRequest("GET", "/order?botUuid=bot-uuid", nil))
+	assertion.Equal(http.StatusMethodNotAllowed, response.Code)
+	assertion.Equal("POST", response.Header().Get("Allow"))
+	assertion.Equal("Method is not allowed\n", response.Body.String())
+
+	response = httptest.NewRecorder()
+	handler(response, httptest.NewRequest("POST", "/order?botUuid=bot-uuid", nil))
+	assertion.Equal(http.StatusForbidden, response.Code)
+
+	response = httptest.NewRecorder()
+	handler(response, httptest.NewRequest("OPTIONS", "/order", nil))
+	assertion.Equal(http.StatusOK, response.Code)
+}
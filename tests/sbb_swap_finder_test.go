@@ -297,18 +297,26 @@ func TestSwapSellBuyBuy(t *testing.T) {
 	timeServiceMock.On("WaitSeconds", int64(7)).Times(3)
 	timeServiceMock.On("GetNowDiffMinutes", mock.Anything).Return(0.50)
 
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
+
 	executor := service.SwapExecutor{
-		SwapRepository:  swapRepoMock,
-		OrderRepository: orderRepositoryMock,
-		BalanceService:  balanceServiceMock,
-		Binance:         binanceMock,
-		TimeService:     timeServiceMock,
-		Formatter:       &service.Formatter{},
+		SwapRepository:     swapRepoMock,
+		OrderRepository:    orderRepositoryMock,
+		BalanceService:     balanceServiceMock,
+		Binance:            binanceMock,
+		TimeService:        timeServiceMock,
+		Formatter:          &service.Formatter{},
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
 	}
 
 	executor.Execute(order)
 
 	assertion.Equal(model.SwapActionStatusSuccess, swapRepoMock.swapAction.Status)
+	orderEventRecorder.AssertCalled(t, "Record", mock.MatchedBy(func(event model.OrderEvent) bool {
+		return event.Type == model.OrderEventSwapFinished && *event.OrderId == order.Id
+	}))
 	assertion.Equal(104.755, *swapRepoMock.swapAction.EndQuantity)
 	assertion.Equal(int64(19), *swapRepoMock.swapAction.SwapOneExternalId)
 	assertion.Equal("SOLGBP", swapRepoMock.swapAction.SwapOneSymbol)
@@ -610,13 +618,18 @@ func TestSwapSellBuyBuyRollback(t *testing.T) {
 		Price:       57.39,
 	}, nil)
 
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
+
 	executor := service.SwapExecutor{
-		SwapRepository:  swapRepoMock,
-		OrderRepository: orderRepositoryMock,
-		BalanceService:  balanceServiceMock,
-		Binance:         binanceMock,
-		TimeService:     timeServiceMock,
-		Formatter:       &service.Formatter{},
+		SwapRepository:     swapRepoMock,
+		OrderRepository:    orderRepositoryMock,
+		BalanceService:     balanceServiceMock,
+		Binance:            binanceMock,
+		TimeService:        timeServiceMock,
+		Formatter:          &service.Formatter{},
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
 	}
 
 	executor.Execute(order)
@@ -946,13 +959,18 @@ func TestSwapSellBuyBuyForceSwap(t *testing.T) {
 		Price:       0.03234,
 	}, nil)
 
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
+
 	executor := service.SwapExecutor{
-		SwapRepository:  swapRepoMock,
-		OrderRepository: orderRepositoryMock,
-		BalanceService:  balanceServiceMock,
-		Binance:         binanceMock,
-		TimeService:     timeServiceMock,
-		Formatter:       &service.Formatter{},
+		SwapRepository:     swapRepoMock,
+		OrderRepository:    orderRepositoryMock,
+		BalanceService:     balanceServiceMock,
+		Binance:            binanceMock,
+		TimeService:        timeServiceMock,
+		Formatter:          &service.Formatter{},
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
 	}
 
 	executor.Execute(order)
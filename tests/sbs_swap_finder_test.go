@@ -305,13 +305,18 @@ func TestSwapSellBuySell(t *testing.T) {
 	timeServiceMock.On("WaitSeconds", int64(7)).Times(3)
 	timeServiceMock.On("GetNowDiffMinutes", mock.Anything).Return(0.50)
 
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
+
 	executor := service.SwapExecutor{
-		SwapRepository:  swapRepoMock,
-		OrderRepository: orderRepositoryMock,
-		BalanceService:  balanceServiceMock,
-		Binance:         binanceMock,
-		TimeService:     timeServiceMock,
-		Formatter:       &service.Formatter{},
+		SwapRepository:     swapRepoMock,
+		OrderRepository:    orderRepositoryMock,
+		BalanceService:     balanceServiceMock,
+		Binance:            binanceMock,
+		TimeService:        timeServiceMock,
+		Formatter:          &service.Formatter{},
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
 	}
 
 	executor.Execute(order)
@@ -654,13 +659,18 @@ func TestSwapSellBuySellForceSwap(t *testing.T) {
 	timeServiceMock.On("WaitSeconds", int64(7)).Times(3)
 	timeServiceMock.On("GetNowDiffMinutes", mock.Anything).Return(50.00)
 
+	orderEventRecorder := new(OrderEventRecorderMock)
+	orderEventRecorder.On("Record", mock.Anything)
+
 	executor := service.SwapExecutor{
-		SwapRepository:  swapRepoMock,
-		OrderRepository: orderRepositoryMock,
-		BalanceService:  balanceServiceMock,
-		Binance:         binanceMock,
-		TimeService:     timeServiceMock,
-		Formatter:       &service.Formatter{},
+		SwapRepository:     swapRepoMock,
+		OrderRepository:    orderRepositoryMock,
+		BalanceService:     balanceServiceMock,
+		Binance:            binanceMock,
+		TimeService:        timeServiceMock,
+		Formatter:          &service.Formatter{},
+		OrderEventRecorder: orderEventRecorder,
+		ShutdownService:    &service.ShutdownService{},
 	}
 
 	executor.Execute(order)
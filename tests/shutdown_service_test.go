@@ -0,0 +1,50 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"testing"
+	"time"
+)
+
+func TestShutdownDrainsOperations(t *testing.T) {
+	assertion := assert.New(t)
+
+	shutdownService := service.ShutdownService{}
+
+	assertion.True(shutdownService.StartOperation())
+	assertion.Equal(int64(1), shutdownService.GetOperations())
+
+	shutdownService.Shutdown()
+	assertion.True(shutdownService.IsShuttingDown())
+	assertion.False(shutdownService.StartOperation())
+	assertion.False(shutdownService.Wait(time.Millisecond * 150))
+
+	shutdownService.FinishOperation()
+	assertion.True(shutdownService.Wait(time.Millisecond * 150))
+	assertion.Equal(int64(0), shutdownService.GetOperations())
+}
+
+func TestReconcileSkippedOnShutdown(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeOrderAPIMock)
+	timeService := new(TimeServiceMock)
+	timeService.On("GetNowDateTimeString").Return("2024-02-01 00:00:00")
+
+	shutdownService := service.ShutdownService{}
+	shutdownService.Shutdown()
+
+	reconciler := service.OrderReconciler{
+		Binance:         binance,
+		TimeService:     timeService,
+		ShutdownService: &shutdownService,
+	}
+
+	report := reconciler.Reconcile()
+
+	assertion.NotNil(report.Error)
+	assertion.Equal(0, report.Symbols)
+	assertion.Nil(reconciler.GetLastReport())
+	binance.AssertNotCalled(t, "GetOpenedOrders")
+}
@@ -0,0 +1,204 @@
+package tests
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/client"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type streamServer struct {
+	requests    chan model.SocketStreamsRequest
+	connections chan *websocket.Conn
+}
+
+func newStreamServer() (*httptest.Server, *streamServer) {
+	server := &streamServer{
+		requests:    make(chan model.SocketStreamsRequest, 100),
+		connections: make(chan *websocket.Conn, 10),
+	}
+	upgrader := websocket.Upgrader{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		server.connections <- connection
+
+		for {
+			_, message, err := connection.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var request model.SocketStreamsRequest
+			_ = json.Unmarshal(message, &request)
+			server.requests <- request
+		}
+	})), server
+}
+
+func waitStreamRequest(t *testing.T, server *streamServer) model.SocketStreamsRequest {
+	select {
+	case request := <-server.requests:
+		return request
+	case <-time.After(time.Second * 3):
+		t.Fatal("stream request is not received")
+	}
+
+	return model.SocketStreamsRequest{}
+}
+
+func waitStreamConnection(t *testing.T, server *streamServer) *websocket.Conn {
+	select {
+	case connection := <-server.connections:
+		return connection
+	case <-time.After(time.Second * 3):
+		t.Fatal("stream connection is not opened")
+	}
+
+	return nil
+}
+
+func TestStreamConnectionResubscribeOnReconnect(t *testing.T) {
+	assertion := assert.New(t)
+	httpServer, server := newStreamServer()
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	channel := make(chan []byte, 10)
+	connection := &client.StreamConnection{
+		Address:        "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/stream",
+		Channel:        channel,
+		Batch:          "test",
+		ReconnectDelay: time.Millisecond * 10,
+	}
+	_ = connection.Subscribe([]string{"btcusdt@aggTrade"})
+	connection.Start(ctx)
+
+	serverConnection := waitStreamConnection(t, server)
+	request := waitStreamRequest(t, server)
+	assertion.Equal("SUBSCRIBE", request.Method)
+	assertion.Equal([]string{"btcusdt@aggTrade"}, request.Params)
+
+	_ = serverConnection.WriteMessage(websocket.TextMessage, []byte(`{"stream":"btcusdt@aggTrade","data":{}}`))
+	assertion.Equal(`{"stream":"btcusdt@aggTrade","data":{}}`, string(<-channel))
+
+	assertion.Eventually(func() bool {
+		return connection.Subscribe([]string{"ethusdt@aggTrade"}) == nil && connection.GetHealth().Streams == 2
+	}, time.Second, time.Millisecond*10)
+	request = waitStreamRequest(t, server)
+	assertion.Equal([]string{"ethusdt@aggTrade"}, request.Params)
+
+	// connection is lost, all streams are subscribed again
+	_ = serverConnection.Close()
+	waitStreamConnection(t, server)
+	request = waitStreamRequest(t, server)
+	assertion.Equal("SUBSCRIBE", request.Method)
+	assertion.Equal([]string{"btcusdt@aggTrade", "ethusdt@aggTrade"}, request.Params)
+
+	health := connection.GetHealth()
+	assertion.Equal(model.StreamStatusOk, health.Status)
+	assertion.Equal(int64(1), health.Reconnects)
+	assertion.Equal("test", health.Batch)
+}
+
+func TestStreamConnectionStaleDetection(t *testing.T) {
+	assertion := assert.New(t)
+	httpServer, server := newStreamServer()
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	channel := make(chan []byte, 100)
+	connection := &client.StreamConnection{
+		Address:        "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/stream",
+		Channel:        channel,
+		Batch:          "test",
+		PingInterval:   time.Millisecond * 20,
+		StaleAfter:     time.Millisecond * 100,
+		ReconnectDelay: time.Millisecond * 10,
+	}
+	_ = connection.Subscribe([]string{"btcusdt@aggTrade", "ethusdt@aggTrade"})
+	connection.Start(ctx)
+
+	serverConnection := waitStreamConnection(t, server)
+	waitStreamRequest(t, server)
+
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(time.Millisecond * 10):
+				_ = serverConnection.WriteMessage(websocket.TextMessage, []byte(`{"stream":"btcusdt@aggTrade","data":{}}`))
+			}
+		}
+	}()
+
+	// only stream without messages is subscribed again
+	request := waitStreamRequest(t, server)
+	assertion.Equal("UNSUBSCRIBE", request.Method)
+	assertion.Equal([]string{"ethusdt@aggTrade"}, request.Params)
+	request = waitStreamRequest(t, server)
+	assertion.Equal("SUBSCRIBE", request.Method)
+	assertion.Equal([]string{"ethusdt@aggTrade"}, request.Params)
+
+	health := connection.GetHealth()
+	assertion.Equal(model.StreamStatusStale, health.Status)
+	assertion.Equal([]string{"ethusdt@aggTrade"}, health.StaleStreams)
+}
+
+func TestStreamConnectionReadTimeout(t *testing.T) {
+	assertion := assert.New(t)
+	upgrader := websocket.Upgrader{}
+	connections := make(chan bool, 10)
+
+	// server neither writes messages nor reads pings (silently stalled connection)
+	httpServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		connection, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		connections <- true
+		time.Sleep(time.Second)
+		_ = connection.Close()
+	}))
+	defer httpServer.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	connection := &client.StreamConnection{
+		Address:        "ws" + strings.TrimPrefix(httpServer.URL, "http") + "/stream",
+		Channel:        make(chan []byte),
+		Batch:          "test",
+		PingInterval:   time.Millisecond * 20,
+		ReadTimeout:    time.Millisecond * 100,
+		ReconnectDelay: time.Millisecond * 10,
+	}
+	connection.Start(ctx)
+
+	<-connections
+	select {
+	case <-connections:
+	case <-time.After(time.Millisecond * 800):
+		t.Fatal("stalled connection is not reconnected")
+	}
+	assertion.Eventually(func() bool {
+		return connection.GetHealth().Reconnects >= 1
+	}, time.Second, time.Millisecond*10)
+}
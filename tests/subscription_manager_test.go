@@ -0,0 +1,157 @@
+package tests
+
+import (
+	"context"
+	"github.com/stretchr/testify/assert"
+	"gitlab.com/open-soft/go-crypto-bot/src/client"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSubscriptionManagerSync(t *testing.T) {
+	assertion := assert.New(t)
+
+	symbols := []service.StreamSymbol{{Symbol: "BTCUSDT"}, {Symbol: "SOLUSDT", HasWorker: true}}
+	connections := make([]*StreamConnectionMock, 0)
+	batches := make([]int64, 0)
+	workers := sync.Map{}
+
+	manager := &service.SubscriptionManager{
+		Name:        "test",
+		Events:      []string{"@aggTrade", "@kline_1m"},
+		MaxStreams:  4,
+		BatchOffset: 100,
+		SymbolProvider: func() []service.StreamSymbol {
+			return symbols
+		},
+		StartWorker: func(ctx context.Context, symbol string) {
+			workers.Store(symbol, true)
+			<-ctx.Done()
+			workers.Store(symbol, false)
+		},
+		Connect: func(batch int64) client.StreamConnectionInterface {
+			connection := &StreamConnectionMock{}
+			connections = append(connections, connection)
+			batches = append(batches, batch)
+
+			return connection
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	added, removed := manager.Sync(ctx)
+	assertion.Equal([]string{"BTCUSDT", "SOLUSDT"}, added)
+	assertion.Empty(removed)
+	assertion.Len(connections, 1)
+	assertion.ElementsMatch([]string{"btcusdt@aggTrade", "btcusdt@kline_1m", "solusdt@aggTrade", "solusdt@kline_1m"}, connections[0].Streams)
+	assertion.Eventually(func() bool {
+		running, ok := workers.Load("SOLUSDT")
+		return ok && running.(bool)
+	}, time.Second, time.Millisecond*10)
+	_, ok := workers.Load("BTCUSDT")
+	assertion.False(ok)
+
+	// removed symbol frees streams for the new one
+	symbols = []service.StreamSymbol{{Symbol: "BTCUSDT"}, {Symbol: "XRPUSDT", HasWorker: true}}
+	added, removed = manager.Sync(ctx)
+	assertion.Equal([]string{"XRPUSDT"}, added)
+	assertion.Equal([]string{"SOLUSDT"}, removed)
+	assertion.Len(connections, 1)
+	assertion.ElementsMatch([]string{"btcusdt@aggTrade", "btcusdt@kline_1m", "xrpusdt@aggTrade", "xrpusdt@kline_1m"}, connections[0].Streams)
+	assertion.Eventually(func() bool {
+		running, _ := workers.Load("SOLUSDT")
+		return !running.(bool)
+	}, time.Second, time.Millisecond*10)
+
+	// first connection is full, new one is opened
+	symbols = append(symbols, service.StreamSymbol{Symbol: "ETHUSDT"})
+	added, removed = manager.Sync(ctx)
+	assertion.Equal([]string{"ETHUSDT"}, added)
+	assertion.Empty(removed)
+	assertion.Len(connections, 2)
+	assertion.Equal([]int64{100, 101}, batches)
+	assertion.ElementsMatch([]string{"ethusdt@aggTrade", "ethusdt@kline_1m"}, connections[1].Streams)
+	assertion.Equal([]string{"BTCUSDT", "ETHUSDT", "XRPUSDT"}, manager.GetSymbols())
+
+	// nothing is changed
+	added, removed = manager.Sync(ctx)
+	assertion.Empty(added)
+	assertion.Empty(removed)
+
+	// closed manager (leadership is lost) stops workers and connections
+	manager.Close()
+	assertion.True(connections[0].Closed)
+	assertion.True(connections[1].Closed)
+	assertion.Empty(manager.GetSymbols())
+	assertion.Eventually(func() bool {
+		running, _ := workers.Load("XRPUSDT")
+		return !running.(bool)
+	}, time.Second, time.Millisecond*10)
+}
+
+func TestSubscriptionManagerRestartedWorkerDoesNotOverlap(t *testing.T) {
+	assertion := assert.New(t)
+
+	symbols := []service.StreamSymbol{{Symbol: "SOLUSDT", HasWorker: true}}
+	started := atomic.Int64{}
+	running := atomic.Int64{}
+	overlapped := atomic.Bool{}
+
+	manager := &service.SubscriptionManager{
+		Name:        "test",
+		Events:      []string{"@kline_1m"},
+		MaxStreams:  4,
+		BatchOffset: 100,
+		SymbolProvider: func() []service.StreamSymbol {
+			return symbols
+		},
+		StartWorker: func(ctx context.Context, symbol string) {
+			started.Add(1)
+			if running.Add(1) > 1 {
+				overlapped.Store(true)
+			}
+			<-ctx.Done()
+			// cancelled worker is still stopping (model learning) when symbol is added again
+			time.Sleep(time.Millisecond * 100)
+			running.Add(-1)
+		},
+		Connect: func(batch int64) client.StreamConnectionInterface {
+			return &StreamConnectionMock{}
+		},
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	manager.Sync(ctx)
+	assertion.Eventually(func() bool {
+		return started.Load() == 1
+	}, time.Second, time.Millisecond*5)
+
+	// symbol is removed and quickly added again
+	symbols = []service.StreamSymbol{}
+	manager.Sync(ctx)
+	symbols = []service.StreamSymbol{{Symbol: "SOLUSDT", HasWorker: true}}
+	manager.Sync(ctx)
+
+	time.Sleep(time.Millisecond * 50)
+	assertion.Equal(int64(1), started.Load())
+	assertion.Eventually(func() bool {
+		return started.Load() == 2
+	}, time.Second, time.Millisecond*5)
+	assertion.False(overlapped.Load())
+	assertion.Equal([]string{"SOLUSDT"}, manager.GetWorkerSymbols())
+
+	// worker of unsubscribed symbol is forgotten when it is stopped
+	symbols = []service.StreamSymbol{}
+	manager.Sync(ctx)
+	assertion.Eventually(func() bool {
+		return len(manager.GetWorkerSymbols()) == 0
+	}, time.Second, time.Millisecond*5)
+	assertion.Equal(int64(0), running.Load())
+}
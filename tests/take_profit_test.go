@@ -0,0 +1,308 @@
+package tests
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"testing"
+)
+
+func TestTakeProfitTiersAreSoldInOrder(t *testing.T) {
+	assertion := assert.New(t)
+
+	balanceService := new(BalanceServiceMock)
+	orderRepository := new(OrderStorageMock)
+	orderExecutor := service.OrderExecutor{
+		BalanceService:  balanceService,
+		OrderRepository: orderRepository,
+		Formatter:       &service.Formatter{},
+	}
+
+	tradeLimit := model.TradeLimit{
+		Symbol:      "ETHUSDT",
+		MinPrice:    0.01,
+		MinQuantity: 0.0001,
+		MinNotional: 5,
+		TakeProfitOptions: model.TakeProfitOptions{
+			{Index: 1, Percent: 4.00, QuantityPercent: 30},
+			{Index: 0, Percent: 2.00, QuantityPercent: 40},
+		},
+	}
+	commission := 0.00
+	sold := 0.00
+	position := model.Order{
+		Symbol:           "ETHUSDT",
+		Price:            2000,
+		ExecutedQuantity: 1.00,
+		Commission:       &commission,
+		SoldQuantity:     &sold,
+	}
+	orderRepository.On("GetBinanceOrder", "ETHUSDT", "SELL").Return(nil)
+	balanceService.On("GetAssetBalance", "ETH", true).Return(1.00, nil)
+
+	// the first tier is sold at the best of calculated and target price
+	price, quantity := orderExecutor.CalculateTakeProfitSell(tradeLimit, position, 2050.00)
+	assertion.Equal(2050.00, price)
+	assertion.Equal(0.4, quantity)
+
+	// the second tier is placed at its target
+	sold = 0.4
+	price, quantity = orderExecutor.CalculateTakeProfitSell(tradeLimit, position, 2050.00)
+	assertion.Equal(2080.00, price)
+	assertion.InDelta(0.3, quantity, 0.0000001)
+
+	// partially sold tier is completed first
+	sold = 0.5
+	price, quantity = orderExecutor.CalculateTakeProfitSell(tradeLimit, position, 2050.00)
+	assertion.Equal(2080.00, price)
+	assertion.InDelta(0.2, quantity, 0.0000001)
+
+	// rest of position is not covered by tiers and is sold by regular price
+	sold = 0.7
+	price, quantity = orderExecutor.CalculateTakeProfitSell(tradeLimit, position, 2050.00)
+	assertion.Equal(2050.00, price)
+	assertion.InDelta(0.3, quantity, 0.0000001)
+
+	// rest which is less than min notional is sold with the tier
+	sold = 0.00
+	tradeLimit.TakeProfitOptions = model.TakeProfitOptions{{Index: 0, Percent: 2.00, QuantityPercent: 99.9}}
+	price, quantity = orderExecutor.CalculateTakeProfitSell(tradeLimit, position, 2050.00)
+	assertion.Equal(2050.00, price)
+	assertion.Equal(1.00, quantity)
+}
+
+func TestTakeProfitTrailingTierSellsRestOfPosition(t *testing.T) {
+	assertion := assert.New(t)
+
+	balanceService := new(BalanceServiceMock)
+	orderRepository := new(OrderStorageMock)
+	exchangeRepository := new(ExchangeTradeInfoMock)
+	priceCalculator := new(PriceCalculatorMock)
+	orderExecutor := service.OrderExecutor{
+		BalanceService:     balanceService,
+		OrderRepository:    orderRepository,
+		ExchangeRepository: exchangeRepository,
+		PriceCalculator:    priceCalculator,
+		Formatter:          &service.Formatter{},
+	}
+
+	tradeLimit := model.TradeLimit{
+		Symbol:      "ETHUSDT",
+		MinPrice:    0.01,
+		MinQuantity: 0.0001,
+		MinNotional: 5,
+		TakeProfitOptions: model.TakeProfitOptions{
+			{Index: 0, Percent: 2.00, QuantityPercent: 40},
+			{Index: 1, Percent: 4.00, QuantityPercent: 0, TrailingPercent: 2.00},
+		},
+	}
+	commission := 0.00
+	sold := 0.4
+	position := model.Order{
+		Id:               10,
+		Symbol:           "ETHUSDT",
+		Price:            2000,
+		ExecutedQuantity: 1.00,
+		Commission:       &commission,
+		SoldQuantity:     &sold,
+	}
+	orderRepository.On("GetBinanceOrder", "ETHUSDT", "SELL").Return(nil)
+	balanceService.On("GetAssetBalance", "ETH", true).Return(1.00, nil)
+	orderRepository.On("GetTakeProfitHighPrice", position).Return(0.00).Twice()
+	orderRepository.On("GetTakeProfitHighPrice", position).Return(2100.00)
+	orderRepository.On("SetTakeProfitHighPrice", position, 2100.00).Return().Once()
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 2050.00}).Once()
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 2100.00}).Once()
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 2055.00}).Once()
+	priceCalculator.On("GetDepth", "ETHUSDT").Return(model.Depth{
+		Symbol: "ETHUSDT",
+		Bids:   [][2]model.Number{{{Value: 2054.50}, {Value: 1.00}}},
+	}).Once()
+
+	// target of trailing tier is not reached
+	price, quantity := orderExecutor.CalculateTakeProfitSell(tradeLimit, position, 2050.00)
+	assertion.Equal(2050.00, price)
+	assertion.Equal(0.00, quantity)
+	assertion.True(orderExecutor.IsTakeProfitTrailing(tradeLimit, position))
+
+	// target is reached, the high is followed and stop is not reached
+	price, quantity = orderExecutor.CalculateTakeProfitSell(tradeLimit, position, 2050.00)
+	assertion.Equal(0.00, quantity)
+
+	// price falls by trailing percent from the high (stop 2058), the rest is sold at the best bid
+	price, quantity = orderExecutor.CalculateTakeProfitSell(tradeLimit, position, 2050.00)
+	assertion.Equal(2054.50, price)
+	assertion.InDelta(0.6, quantity, 0.0000001)
+
+	orderRepository.AssertExpectations(t)
+	exchangeRepository.AssertExpectations(t)
+	priceCalculator.AssertExpectations(t)
+}
+
+func TestTakeProfitTrailingTierSellsWhenPriceKeepsFalling(t *testing.T) {
+	assertion := assert.New(t)
+
+	balanceService := new(BalanceServiceMock)
+	orderRepository := new(OrderStorageMock)
+	exchangeRepository := new(ExchangeTradeInfoMock)
+	priceCalculator := new(PriceCalculatorMock)
+	orderExecutor := service.OrderExecutor{
+		BalanceService:     balanceService,
+		OrderRepository:    orderRepository,
+		ExchangeRepository: exchangeRepository,
+		PriceCalculator:    priceCalculator,
+		Formatter:          &service.Formatter{},
+	}
+
+	tradeLimit := model.TradeLimit{
+		Symbol:           "ETHUSDT",
+		MinPrice:         0.01,
+		MinQuantity:      0.0001,
+		MinNotional:      5,
+		MinProfitPercent: 1.00,
+		TakeProfitOptions: model.TakeProfitOptions{
+			{Index: 0, Percent: 2.00, QuantityPercent: 40},
+			{Index: 1, Percent: 4.00, QuantityPercent: 0, TrailingPercent: 2.00},
+		},
+	}
+	commission := 0.00
+	sold := 0.4
+	position := model.Order{
+		Id:               10,
+		Symbol:           "ETHUSDT",
+		Price:            2000,
+		ExecutedQuantity: 1.00,
+		Commission:       &commission,
+		SoldQuantity:     &sold,
+	}
+	orderRepository.On("GetBinanceOrder", "ETHUSDT", "SELL").Return(nil)
+	balanceService.On("GetAssetBalance", "ETH", true).Return(1.00, nil)
+	// the high is 2100, stop is 2058
+	orderRepository.On("GetTakeProfitHighPrice", position).Return(2100.00)
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 2040.00}).Once()
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 2030.00}).Once()
+	exchangeRepository.On("GetLastKLine", "ETHUSDT").Return(&model.KLine{Close: 1990.00}).Once()
+	priceCalculator.On("GetDepth", "ETHUSDT").Return(model.Depth{
+		Symbol: "ETHUSDT",
+		Bids:   [][2]model.Number{{{Value: 2039.50}, {Value: 1.00}}},
+	}).Once()
+	priceCalculator.On("GetDepth", "ETHUSDT").Return(model.Depth{Symbol: "ETHUSDT"}).Once()
+	priceCalculator.On("GetDepth", "ETHUSDT").Return(model.Depth{
+		Symbol: "ETHUSDT",
+		Bids:   [][2]model.Number{{{Value: 1989.00}, {Value: 1.00}}},
+	}).Once()
+
+	// price is already below the stop, exit is placed at the best bid, not above the market
+	price, quantity := orderExecutor.CalculateTakeProfitSell(tradeLimit, position, 2050.00)
+	assertion.Equal(2039.50, price)
+	assertion.InDelta(0.6, quantity, 0.0000001)
+
+	// price keeps falling and order book is empty, exit follows the close price
+	price, quantity = orderExecutor.CalculateTakeProfitSell(tradeLimit, position, 2050.00)
+	assertion.Equal(2030.00, price)
+	assertion.InDelta(0.6, quantity, 0.0000001)
+
+	// exit is not lower than min close price of position
+	price, quantity = orderExecutor.CalculateTakeProfitSell(tradeLimit, position, 2050.00)
+	assertion.Equal(2020.00, price)
+	assertion.InDelta(0.6, quantity, 0.0000001)
+
+	orderRepository.AssertNotCalled(t, "SetTakeProfitHighPrice", mock.Anything, mock.Anything)
+	exchangeRepository.AssertExpectations(t)
+	priceCalculator.AssertExpectations(t)
+}
+
+func TestTakeProfitSellIsNotChangedForPlacedOrder(t *testing.T) {
+	assertion := assert.New(t)
+
+	orderRepository := new(OrderStorageMock)
+	orderExecutor := service.OrderExecutor{
+		OrderRepository: orderRepository,
+		Formatter:       &service.Formatter{},
+	}
+
+	tradeLimit := model.TradeLimit{
+		Symbol:            "ETHUSDT",
+		MinPrice:          0.01,
+		MinQuantity:       0.0001,
+		TakeProfitOptions: model.TakeProfitOptions{{Index: 0, Percent: 2.00, QuantityPercent: 40}},
+	}
+	orderRepository.On("GetBinanceOrder", "ETHUSDT", "SELL").Return(&model.BinanceOrder{OrderId: 1, OrigQty: 1.00})
+
+	price, quantity := orderExecutor.CalculateTakeProfitSell(tradeLimit, model.Order{Symbol: "ETHUSDT", Price: 2000, ExecutedQuantity: 1.00}, 2030.00)
+	assertion.Equal(2030.00, price)
+	assertion.Equal(1.00, quantity)
+}
+
+func TestTakeProfitTradeLimitIsValidated(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeInfoAPIMock)
+	tradeLimitService := service.TradeLimitService{
+		ExchangeRepository: new(TradeLimitStorageMock),
+		OrderRepository:    new(OrderStorageMock),
+		Binance:            binance,
+	}
+
+	minNotional := 5.00
+	binance.On("GetExchangeData", mock.Anything).Return(&model.ExchangeInfo{Symbols: []model.ExchangeSymbol{
+		{Symbol: "ETHUSDT", Status: "TRADING", Filters: []model.ExchangeFilter{
+			{FilterType: "NOTIONAL", MinNotional: &minNotional},
+		}},
+	}}, nil)
+
+	report := tradeLimitService.Import([]model.TradeLimit{{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        40,
+		MinProfitPercent: 1,
+		TakeProfitOptions: model.TakeProfitOptions{
+			{Index: 0, Percent: 2.00, QuantityPercent: 60},
+			{Index: 1, Percent: 2.00, QuantityPercent: 50},
+			{Index: 2, Percent: -1.00, QuantityPercent: 0},
+		},
+	}}, false)
+
+	assertion.False(report.IsValid())
+	assertion.Len(report.Violations, 4)
+	assertion.Equal("take profit 1 percent 2.00 is duplicated", report.Violations[0].Message)
+	assertion.Equal("take profit 2 percent must be positive", report.Violations[1].Message)
+	assertion.Equal("take profit 2 quantity percent must be positive", report.Violations[2].Message)
+	assertion.Equal("take profit quantity percent sum 110.00 is greater than 100", report.Violations[3].Message)
+}
+
+func TestTakeProfitTrailingTierIsValidated(t *testing.T) {
+	assertion := assert.New(t)
+
+	binance := new(ExchangeInfoAPIMock)
+	tradeLimitService := service.TradeLimitService{
+		ExchangeRepository: new(TradeLimitStorageMock),
+		OrderRepository:    new(OrderStorageMock),
+		Binance:            binance,
+	}
+
+	minNotional := 5.00
+	binance.On("GetExchangeData", mock.Anything).Return(&model.ExchangeInfo{Symbols: []model.ExchangeSymbol{
+		{Symbol: "ETHUSDT", Status: "TRADING", Filters: []model.ExchangeFilter{
+			{FilterType: "NOTIONAL", MinNotional: &minNotional},
+		}},
+	}}, nil)
+
+	report := tradeLimitService.Import([]model.TradeLimit{{
+		Symbol:           "ETHUSDT",
+		USDTLimit:        40,
+		MinProfitPercent: 1,
+		TakeProfitOptions: model.TakeProfitOptions{
+			{Index: 0, Percent: 3.00, QuantityPercent: 40},
+			{Index: 1, Percent: 2.00, QuantityPercent: 10, TrailingPercent: 1.00},
+			{Index: 2, Percent: 5.00, QuantityPercent: 0, TrailingPercent: 100.00},
+		},
+	}}, false)
+
+	assertion.False(report.IsValid())
+	assertion.Len(report.Violations, 4)
+	assertion.Equal("take profit 1 trailing tier sells the rest of position, quantity percent must be 0", report.Violations[0].Message)
+	assertion.Equal("take profit 2 trailing percent must be between 0 and 100", report.Violations[1].Message)
+	assertion.Equal("take profit 1 trailing tier percent must be the highest", report.Violations[2].Message)
+	assertion.Equal("take profit can have only one trailing tier", report.Violations[3].Message)
+}
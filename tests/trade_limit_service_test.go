@@ -0,0 +1,141 @@
+package tests
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"gitlab.com/open-soft/go-crypto-bot/src/model"
+	"gitlab.com/open-soft/go-crypto-bot/src/service"
+	"testing"
+)
+
+func TestTradeLimitRemoveIsRefusedWithOpenedPosition(t *testing.T) {
+	assertion := assert.New(t)
+
+	exchangeRepository := new(TradeLimitStorageMock)
+	orderRepository := new(OrderStorageMock)
+	binance := new(ExchangeInfoAPIMock)
+	tradeLimitService := service.TradeLimitService{
+		ExchangeRepository: exchangeRepository,
+		OrderRepository:    orderRepository,
+		Binance:            binance,
+	}
+
+	eth := model.TradeLimit{Id: 1, Symbol: "ETHUSDT"}
+	perp := model.TradeLimit{Id: 2, Symbol: "PERPUSDT"}
+	sol := model.TradeLimit{Id: 3, Symbol: "SOLUSDT"}
+	xrp := model.TradeLimit{Id: 4, Symbol: "XRPUSDT"}
+	exchangeRepository.On("GetTradeLimit", "ETHUSDT").Return(eth, nil)
+	exchangeRepository.On("GetTradeLimit", "PERPUSDT").Return(perp, nil)
+	exchangeRepository.On("GetTradeLimit", "SOLUSDT").Return(sol, nil)
+	exchangeRepository.On("GetTradeLimit", "XRPUSDT").Return(xrp, nil)
+	exchangeRepository.On("GetTradeLimit", "BTCUSDT").Return(model.TradeLimit{}, errors.New("sql: no rows in result set"))
+
+	orderRepository.On("GetOpenedOrderCached", "ETHUSDT", "BUY").Return(model.Order{Id: 10, Symbol: "ETHUSDT"}, nil)
+	orderRepository.On("GetOpenedOrderCached", "PERPUSDT", "BUY").Return(model.Order{}, errors.New("not found"))
+	orderRepository.On("GetOpenedOrderCached", "SOLUSDT", "BUY").Return(model.Order{}, errors.New("not found"))
+	orderRepository.On("GetBinanceOrder", "PERPUSDT", "BUY").Return(&model.BinanceOrder{Symbol: "PERPUSDT", Side: "BUY"})
+	orderRepository.On("GetBinanceOrder", "SOLUSDT", "BUY").Return(nil)
+	orderRepository.On("GetBinanceOrder", "SOLUSDT", "SELL").Return(nil)
+	// order is not cached by bot, but it is opened on exchange
+	orderRepository.On("GetOpenedOrderCached", "XRPUSDT", "BUY").Return(model.Order{}, errors.New("not found"))
+	orderRepository.On("GetBinanceOrder", "XRPUSDT", mock.Anything).Return(nil)
+	binance.On("GetOpenedOrders").Return([]model.BinanceOrder{{Symbol: "XRPUSDT", Side: "SELL"}}, nil)
+	exchangeRepository.On("ArchiveTradeLimit", sol).Return(nil)
+
+	assertion.EqualError(tradeLimitService.Delete("ETHUSDT"), "Trade limit ETHUSDT has opened position")
+	assertion.EqualError(tradeLimitService.Archive("PERPUSDT"), "Trade limit PERPUSDT has opened BUY order on exchange")
+	assertion.EqualError(tradeLimitService.Delete("BTCUSDT"), "Trade limit BTCUSDT is not found")
+	assertion.Nil(tradeLimitService.Archive("SOLUSDT"))
+	assertion.EqualError(tradeLimitService.Delete("XRPUSDT"), "Trade limit XRPUSDT has opened SELL order on exchange")
+
+	exchangeRepository.AssertNotCalled(t, "DeleteTradeLimit", mock.Anything)
+	exchangeRepository.AssertNumberOfCalls(t, "ArchiveTradeLimit", 1)
+}
+
+func TestTradeLimitImportIsValidatedByExchangeFilters(t *testing.T) {
+	assertion := assert.New(t)
+
+	exchangeRepository := new(TradeLimitStorageMock)
+	binance := new(ExchangeInfoAPIMock)
+	tradeLimitService := service.TradeLimitService{
+		ExchangeRepository: exchangeRepository,
+		OrderRepository:    new(OrderStorageMock),
+		Binance:            binance,
+	}
+
+	minPrice := 0.01
+	minQuantity := 0.0001
+	minNotional := 5.00
+	filters := []model.ExchangeFilter{
+		{FilterType: "PRICE_FILTER", MinPrice: &minPrice},
+		{FilterType: "LOT_SIZE", MinQuantity: &minQuantity},
+		{FilterType: "NOTIONAL", MinNotional: &minNotional},
+	}
+	binance.On("GetExchangeData", mock.Anything).Return(&model.ExchangeInfo{Symbols: []model.ExchangeSymbol{
+		{Symbol: "ETHUSDT", Status: "TRADING", Filters: filters},
+		{Symbol: "BTCUSDT", Status: "TRADING", Filters: filters},
+		{Symbol: "LUNAUSDT", Status: "BREAK", Filters: filters},
+	}}, nil)
+
+	csv := "symbol,USDTLimit,minProfitPercent,isEnabled,frameInterval,extraChargeOptions\n" +
+		"ethusdt,100,2.5,true,2h,\"[{\"\"index\"\":0,\"\"percent\"\":-4.5,\"\"amountUsdt\"\":20}]\"\n" +
+		"BTCUSDT,50,1.5,false,1d,\n"
+	limits, err := tradeLimitService.ReadCsv(bytes.NewBufferString(csv))
+	assertion.Nil(err)
+	assertion.Len(limits, 2)
+	assertion.Equal("ETHUSDT", limits[0].Symbol)
+	assertion.Len(limits[0].ExtraChargeOptions, 1)
+	assertion.Equal(model.Percent(-4.5), limits[0].ExtraChargeOptions[0].Percent)
+
+	// invalid limits: nothing is saved
+	invalid := append(limits, model.TradeLimit{Symbol: "LUNAUSDT", USDTLimit: 100, MinProfitPercent: 1}, model.TradeLimit{
+		Symbol:             "ETHUSDT",
+		USDTLimit:          1,
+		MinProfitPercent:   1,
+		ExtraChargeOptions: model.ExtraChargeOptions{{Index: 0, Percent: -1, AmountUsdt: 1}},
+	})
+	report := tradeLimitService.Import(invalid, false)
+	assertion.False(report.IsValid())
+	assertion.Equal("symbol is duplicated", report.Violations[0].Message)
+
+	report = tradeLimitService.Import(append(limits, model.TradeLimit{Symbol: "LUNAUSDT", USDTLimit: 1, MinProfitPercent: 1}), false)
+	assertion.Len(report.Violations, 1)
+	assertion.Equal(model.TradeLimitViolation{Symbol: "LUNAUSDT", Message: "symbol status is BREAK"}, report.Violations[0])
+	exchangeRepository.AssertNotCalled(t, "ImportTradeLimits", mock.Anything)
+
+	// filters are taken from exchange, existing limit is updated by id, all limits are saved in one transaction
+	exchangeRepository.On("GetTradeLimit", "ETHUSDT").Return(model.TradeLimit{Id: 7, Symbol: "ETHUSDT"}, nil)
+	exchangeRepository.On("GetTradeLimit", "BTCUSDT").Return(model.TradeLimit{}, errors.New("sql: no rows in result set"))
+	exchangeRepository.On("ImportTradeLimits", mock.MatchedBy(func(saved []model.TradeLimit) bool {
+		return len(saved) == 2 &&
+			saved[0].Id == 7 && saved[0].MinPrice == 0.01 && saved[0].MinQuantity == 0.0001 && saved[0].MinNotional == 5.00 &&
+			saved[1].Id == 0 && saved[1].Symbol == "BTCUSDT"
+	})).Return(nil).Once()
+
+	report = tradeLimitService.Import(limits, true)
+	assertion.True(report.IsValid())
+	assertion.Equal([]string{"BTCUSDT"}, report.Created)
+	assertion.Equal([]string{"ETHUSDT"}, report.Updated)
+	exchangeRepository.AssertNotCalled(t, "ImportTradeLimits", mock.Anything)
+
+	report = tradeLimitService.Import(limits, false)
+	assertion.True(report.IsValid())
+	exchangeRepository.AssertNumberOfCalls(t, "ImportTradeLimits", 1)
+
+	// failed transaction is reported, nothing is created or updated
+	exchangeRepository.On("ImportTradeLimits", mock.Anything).Return(errors.New("BTCUSDT: Deadlock found")).Once()
+	report = tradeLimitService.Import(limits, false)
+	assertion.False(report.IsValid())
+	assertion.Equal("Import is rolled back: BTCUSDT: Deadlock found", report.Violations[0].Message)
+	assertion.Empty(report.Created)
+	assertion.Empty(report.Updated)
+
+	// export -> import keeps values
+	exported := bytes.NewBuffer(nil)
+	assertion.Nil(tradeLimitService.WriteCsv(exported, limits))
+	imported, err := tradeLimitService.ReadCsv(exported)
+	assertion.Nil(err)
+	assertion.Equal(limits, imported)
+}